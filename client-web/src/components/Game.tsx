@@ -1,8 +1,20 @@
-import { createSignal, onMount, onCleanup, Show } from 'solid-js';
+import { createSignal, onMount, onCleanup, Show, For } from 'solid-js';
 import { GameClient } from '../network/client';
 import { GameRenderer } from '../game/renderer';
 import { GameStateManager } from '../game/StateManager';
-import { InitData } from '../network/protocol';
+import { InitData, DeathData, ReplayStateData, SpectateStateData, MatchStateData } from '../network/protocol';
+
+// Скорости воспроизведения реплея (сервер ограничивает 0.25-8)
+const REPLAY_SPEEDS = [0.25, 0.5, 1, 2, 4, 8];
+
+// mm:ss от начала записи
+const formatReplayTime = (ticks: number, tickRate: number) => {
+  const seconds = Math.max(0, Math.floor(ticks / tickRate));
+  return `${Math.floor(seconds / 60)}:${String(seconds % 60).padStart(2, '0')}`;
+};
+
+// Шаг свободной камеры зрителя (стрелки/WASD), единицы мира
+const FREE_CAMERA_STEP = 300;
 
 export default function Game() {
   const [connected, setConnected] = createSignal(false);
@@ -10,6 +22,20 @@ export default function Game() {
   const [showJoin, setShowJoin] = createSignal(true);
   const [error, setError] = createSignal('');
   const [playerId, setPlayerId] = createSignal<string | null>(null);
+  const [death, setDeath] = createSignal<DeathData | null>(null);
+  const [replay, setReplay] = createSignal<ReplayStateData | null>(null);
+  const [spectating, setSpectating] = createSignal<SpectateStateData | null>(null);
+  const [match, setMatch] = createSignal<MatchStateData | null>(null);
+
+  // Пока идёт матч королевской битвы, войти нельзя - только смотреть
+  const matchRunning = () => match()?.phase === 'running';
+
+  // ?replay=<комната> - смотреть реплей, который воспроизводит эта комната
+  // ?spectate[=<комната>] - смотреть матч без игрока
+  // ?protocol=json - JSON вместо бинарного протокола (для отладки)
+  const params = new URLSearchParams(window.location.search);
+  const replayRoom = params.get('replay');
+  const spectateRoom = params.get('spectate');
 
   let canvasRef: HTMLCanvasElement | undefined;
   let client: GameClient | null = null;
@@ -19,7 +45,7 @@ export default function Game() {
   const WS_URL = 'ws://localhost:8090/ws';
 
   const handleJoin = async () => {
-    const name = playerName().trim();
+    const name = playerName().trim() || (replayRoom || spectateRoom !== null ? 'Viewer' : '');
     if (!name) {
       setError('Please enter your name');
       return;
@@ -29,18 +55,59 @@ export default function Game() {
 
     try {
       setError('');
-      client = new GameClient(WS_URL);
+      client = new GameClient(WS_URL, params.get('protocol') !== 'json');
       stateManager = new GameStateManager();
 
       client.setInitHandler((data: InitData) => {
         console.log('Init received:', data);
-        renderer = new GameRenderer(canvasRef!, data.worldSize.width, data.worldSize.height);
+        // После respawn приходит новый init - мир и renderer те же
+        if (!renderer) {
+          renderer = new GameRenderer(canvasRef!, data.worldSize.width, data.worldSize.height);
+        }
         renderer.setPlayerId(data.playerId);
-        setPlayerId(data.playerId);
+        renderer.setSpectator(!!data.spectator);
+        renderer.setMatchState(data.match ?? null);
+        renderer.setMap(data.map ?? null);
+        stateManager?.setMap(data.map ?? null);
+        setMatch(data.match ?? null);
+        setPlayerId(data.spectator ? null : data.playerId);
+        if (!data.spectator) {
+          // Зритель вошёл в следующий матч - камера снова у своего игрока
+          setSpectating(null);
+          renderer.setFollowId(null);
+          renderer.setFreeCamera(null);
+        }
+        setDeath(null);
         setConnected(true);
         setShowJoin(false);
       });
 
+      // Съеден: мир продолжает отображаться, пока игрок не нажмёт "Play again"
+      client.setDeathHandler((data: DeathData) => {
+        console.log('[GAME] Player died:', data);
+        setPlayerId(null);
+        setDeath(data);
+      });
+
+      // Реплей: камера у игрока, которого выбрал зритель (или у лидера)
+      client.setReplayStateHandler((data: ReplayStateData) => {
+        setReplay(data);
+        renderer?.setFollowId(data.following || null);
+      });
+
+      // Камера зрителя: игрок (выбранный или лидер) либо свободная точка
+      client.setSpectateStateHandler((data: SpectateStateData) => {
+        setSpectating(data);
+        renderer?.setFollowId(data.following || null);
+        renderer?.setFreeCamera(data.mode === 'free' ? { x: data.x ?? 0, y: data.y ?? 0 } : null);
+      });
+
+      // Этап матча, зона и победитель
+      client.setMatchStateHandler((data: MatchStateData) => {
+        setMatch(data);
+        renderer?.setMatchState(data);
+      });
+
       client.setStateHandler((message: any) => {
         if (!stateManager) return;
         
@@ -51,28 +118,14 @@ export default function Game() {
           // Single event (including world_snapshot)
           stateManager.handleEvent(message);
         }
-        
-        // Проверяем жив ли игрок
-        const player = stateManager.getPlayer(playerId());
-        if (!player && playerId()) {
-          console.log('[GAME] Player died, cleaning up...');
-          setConnected(false);
-          setShowJoin(true);
-          setPlayerId(null);
-          if (client) {
-            client.disconnect();
-            client = null;
-          }
-          if (renderer) renderer = null;
-          if (stateManager) {
-            stateManager.clear();
-            stateManager = null;
-          }
-        }
       });
 
       await client.connect();
-      client.join(name);
+      if (spectateRoom !== null) {
+        client.spectate({ room: spectateRoom || undefined });
+      } else {
+        client.join(name, replayRoom || undefined);
+      }
 
     } catch (err) {
       console.error('[JOIN] Error:', err);
@@ -80,12 +133,63 @@ export default function Game() {
     }
   };
 
+  const handleRespawn = () => {
+    if (client) {
+      client.respawn(playerName().trim());
+    }
+  };
+
+  const togglePause = () => {
+    const state = replay();
+    if (client && state) {
+      client.replayControl({ action: state.paused ? 'play' : 'pause' });
+    }
+  };
+
+  // Камера к игроку, ближайшему к точке клика
+  const followAt = (screenX: number, screenY: number) => {
+    if (!client || !renderer || !stateManager) return;
+    const pos = renderer.screenToWorld(screenX, screenY);
+    let nearest: string | null = null;
+    let best = Infinity;
+    for (const player of stateManager.getPlayers()) {
+      for (const cell of player.cells.values()) {
+        const dist = Math.hypot(cell.x - pos.x, cell.y - pos.y) - cell.radius;
+        if (dist < best) {
+          best = dist;
+          nearest = player.id;
+        }
+      }
+    }
+    if (!nearest) return;
+    if (replay()) {
+      client.replayControl({ action: 'follow', playerId: nearest });
+    } else {
+      client.spectate({ mode: 'player', playerId: nearest });
+    }
+  };
+
+  // Свободная камера от текущего центра экрана со сдвигом
+  const moveFreeCamera = (dx: number, dy: number) => {
+    if (!client || !renderer) return;
+    const state = spectating();
+    const center = state?.mode === 'free'
+      ? { x: state.x ?? 0, y: state.y ?? 0 }
+      : renderer.screenToWorld(window.innerWidth / 2, window.innerHeight / 2);
+    const x = center.x + dx;
+    const y = center.y + dy;
+    renderer.setFollowId(null);
+    renderer.setFreeCamera({ x, y });
+    setSpectating({ mode: 'free', x, y });
+    client.spectate({ mode: 'free', x, y });
+  };
+
   onMount(() => {
     if (!canvasRef) return;
 
     // Обработка мыши
     const handleMouseMove = (e: MouseEvent) => {
-      if (!renderer || !client || !connected()) return;
+      if (!renderer || !client || !connected() || death() || spectating()) return;
       
       const worldPos = renderer.screenToWorld(e.clientX, e.clientY);
       client.move(worldPos.x, worldPos.y);
@@ -93,7 +197,30 @@ export default function Game() {
 
     // Обработка клавиатуры
     const handleKeyDown = (e: KeyboardEvent) => {
-      if (!client || !connected()) return;
+      if (!client || !connected() || death()) return;
+
+      if (spectating() && !replay()) {
+        const moves: Record<string, [number, number]> = {
+          arrowleft: [-1, 0], a: [-1, 0],
+          arrowright: [1, 0], d: [1, 0],
+          arrowup: [0, -1], w: [0, -1],
+          arrowdown: [0, 1], s: [0, 1],
+        };
+        const move = moves[e.key.toLowerCase()];
+        if (move) {
+          e.preventDefault();
+          moveFreeCamera(move[0] * FREE_CAMERA_STEP, move[1] * FREE_CAMERA_STEP);
+        }
+        return;
+      }
+
+      if (replay()) {
+        if (e.key === ' ') {
+          e.preventDefault();
+          togglePause();
+        }
+        return;
+      }
 
       switch (e.key.toLowerCase()) {
         case ' ':
@@ -161,12 +288,135 @@ export default function Game() {
     }}>
       <canvas
         ref={canvasRef}
+        onClick={(e) => spectating() && followAt(e.clientX, e.clientY)}
         style={{
           display: 'block',
-          cursor: showJoin() ? 'default' : 'none',
+          cursor: showJoin() || death() ? 'default' : spectating() ? 'pointer' : 'none',
         }}
       />
 
+      <Show when={spectating() && !replay()}>
+        <div style={{
+          position: 'absolute',
+          bottom: '20px',
+          left: '50%',
+          transform: 'translateX(-50%)',
+          display: 'flex',
+          'align-items': 'center',
+          gap: '12px',
+          background: 'rgba(0, 0, 0, 0.7)',
+          padding: '10px 16px',
+          'border-radius': '8px',
+          color: '#fff',
+          'font-size': '14px',
+        }}>
+          <span>Spectating</span>
+          <button onClick={() => client?.spectate({ mode: 'leader' })}>
+            Leader
+          </button>
+          <button onClick={() => moveFreeCamera(0, 0)}>
+            Free camera
+          </button>
+          <span style={{ color: '#aaa' }}>Click a player to follow, arrows/WASD to pan</span>
+          <Show when={match() && !matchRunning() && spectateRoom === null}>
+            <button onClick={handleRespawn}>
+              Join next match
+            </button>
+          </Show>
+        </div>
+      </Show>
+
+      <Show when={replay()}>
+        {(state) => (
+          <div style={{
+            position: 'absolute',
+            bottom: '20px',
+            left: '50%',
+            transform: 'translateX(-50%)',
+            display: 'flex',
+            'align-items': 'center',
+            gap: '12px',
+            background: 'rgba(0, 0, 0, 0.7)',
+            padding: '10px 16px',
+            'border-radius': '8px',
+            color: '#fff',
+            'font-size': '14px',
+          }}>
+            <button onClick={togglePause} style={{ width: '70px' }}>
+              {state().paused ? 'Play' : 'Pause'}
+            </button>
+            <input
+              type="range"
+              min={state().startTick}
+              max={state().endTick}
+              value={state().tick}
+              onChange={(e) => client?.replayControl({ action: 'seek', tick: Number(e.currentTarget.value) })}
+              style={{ width: '360px' }}
+            />
+            <span>
+              {formatReplayTime(state().tick - state().startTick, state().tickRate)} / {formatReplayTime(state().endTick - state().startTick, state().tickRate)}
+            </span>
+            <select
+              value={state().speed}
+              onChange={(e) => client?.replayControl({ action: 'speed', speed: Number(e.currentTarget.value) })}
+            >
+              <For each={REPLAY_SPEEDS}>
+                {(speed) => <option value={speed}>{speed}x</option>}
+              </For>
+            </select>
+            <button onClick={() => client?.replayControl({ action: 'follow', playerId: '' })}>
+              Leader
+            </button>
+            <Show when={state().error}>
+              <span style={{ color: '#ff6b6b' }}>{state().error}</span>
+            </Show>
+          </div>
+        )}
+      </Show>
+
+      <Show when={death()}>
+        {(info) => (
+          <div style={{
+            position: 'absolute',
+            top: '50%',
+            left: '50%',
+            transform: 'translate(-50%, -50%)',
+            background: 'rgba(0, 0, 0, 0.8)',
+            padding: '40px',
+            'border-radius': '10px',
+            'text-align': 'center',
+            color: '#fff',
+          }}>
+            <h1 style={{ 'margin-bottom': '20px' }}>
+              {info().killerName
+                ? `Eaten by ${info().killerName}`
+                : info().cause === 'zone' ? 'Dissolved outside the zone' : 'You died'}
+            </h1>
+            <p>Final mass: {Math.floor(info().finalMass)}</p>
+            <p>Time alive: {Math.floor(info().timeAlive)}s</p>
+            <p>Players eaten: {info().kills}</p>
+
+            <button
+              onClick={handleRespawn}
+              disabled={matchRunning()}
+              style={{
+                'margin-top': '15px',
+                padding: '10px 30px',
+                'font-size': '16px',
+                background: '#4ECDC4',
+                color: '#fff',
+                border: 'none',
+                'border-radius': '5px',
+                cursor: 'pointer',
+                'font-weight': 'bold',
+              }}
+            >
+              {matchRunning() ? 'Wait for the next match' : 'Play again'}
+            </button>
+          </div>
+        )}
+      </Show>
+
       <Show when={showJoin()}>
         <div style={{
           position: 'absolute',
@@ -219,7 +469,7 @@ export default function Game() {
               'font-weight': 'bold',
             }}
           >
-            Play
+            {replayRoom ? 'Watch replay' : spectateRoom !== null ? 'Spectate' : 'Play'}
           </button>
 
           <div style={{
@@ -1,4 +1,6 @@
 // State Manager - управление состоянием игры на основе событий
+import { EffectInfo, FoodPulledData, LeaderEntry, LeaderboardData, MapLayout, MapShape, PlayerEffectsData, PowerUpKind, TeamEntry } from '../network/protocol';
+
 export interface Vector2D {
   x: number;
   y: number;
@@ -35,14 +37,75 @@ export interface Food {
   y: number;
   radius: number;
   color: string;
-  velocity?: Vector2D;
+  velocity?: Vector2D; // Выброшенная или притянутая магнитом еда
+}
+
+export interface PowerUp {
+  id: string;
+  kind: PowerUpKind;
+  x: number;
+  y: number;
+  radius: number;
+}
+
+// Действующий эффект бонуса; until - performance.now() окончания
+export interface ActiveEffect {
+  kind: PowerUpKind;
+  until: number;
+}
+
+// Вытолкнуть клетку из стены - как на сервере (game.Shape.push), чтобы предсказание не уходило в стену
+function pushOutOfWall(cell: Cell, wall: MapShape) {
+  if (wall.type === 'circle') {
+    const dx = cell.x - wall.x;
+    const dy = cell.y - wall.y;
+    const distance = Math.sqrt(dx * dx + dy * dy);
+    const min = (wall.radius ?? 0) + cell.radius;
+    if (distance >= min) return;
+    const nx = distance > 0 ? dx / distance : 1;
+    const ny = distance > 0 ? dy / distance : 0;
+    cell.x += nx * (min - distance);
+    cell.y += ny * (min - distance);
+    return;
+  }
+
+  const right = wall.x + (wall.width ?? 0);
+  const bottom = wall.y + (wall.height ?? 0);
+  const closestX = Math.max(wall.x, Math.min(right, cell.x));
+  const closestY = Math.max(wall.y, Math.min(bottom, cell.y));
+  const dx = cell.x - closestX;
+  const dy = cell.y - closestY;
+  const distance = Math.sqrt(dx * dx + dy * dy);
+  if (distance > 0) {
+    if (distance >= cell.radius) return;
+    cell.x += (dx / distance) * (cell.radius - distance);
+    cell.y += (dy / distance) * (cell.radius - distance);
+    return;
+  }
+
+  // Центр внутри прямоугольника - наружу через ближайшую сторону
+  const sides = [cell.x - wall.x, right - cell.x, cell.y - wall.y, bottom - cell.y];
+  const nearest = Math.min(...sides);
+  if (nearest === sides[0]) cell.x = wall.x - cell.radius;
+  else if (nearest === sides[1]) cell.x = right + cell.radius;
+  else if (nearest === sides[2]) cell.y = wall.y - cell.radius;
+  else cell.y = bottom + cell.radius;
 }
 
 export class GameStateManager {
   private players: Map<string, Player> = new Map();
   private food: Map<string, Food> = new Map();
+  private powerUps: Map<string, PowerUp> = new Map();
+  private effects: Map<string, ActiveEffect[]> = new Map(); // По ID игрока (player_effects приходят всем)
+  private walls: MapShape[] = []; // Стены карты комнаты (из init)
   private lastUpdateTime: number = 0;
 
+  // Таблица лидеров от сервера (клиент видит не всех игроков)
+  private leaders: LeaderEntry[] = [];
+  private ownRank: LeaderEntry | null = null;
+  private teams: TeamEntry[] = [];
+  private totalPlayers = 0;
+
   constructor() {
     console.log('[STATE] GameStateManager initialized');
   }
@@ -73,8 +136,15 @@ export class GameStateManager {
       case 'food_eaten':
         this.handleFoodEaten(data);
         break;
+      case 'food_pulled':
+        this.handleFoodPulled(data);
+        break;
       case 'cell_eaten':
-        this.handleCellEaten(data);
+        this.removeCell(data.eatenCellId);
+        break;
+      case 'cell_dissolved':
+        // Клетка растворилась вне зоны королевской битвы
+        this.removeCell(data.cellId);
         break;
       case 'cell_merged':
         this.handleCellMerged(data);
@@ -82,11 +152,42 @@ export class GameStateManager {
       case 'world_snapshot':
         this.handleWorldSnapshot(data);
         break;
+      case 'leaderboard':
+        this.handleLeaderboard(data);
+        break;
+      case 'powerup_spawned':
+        this.addPowerUps(data.powerUps);
+        break;
+      case 'powerup_collected':
+        this.powerUps.delete(data.powerUpId);
+        break;
+      case 'player_effects':
+        this.handlePlayerEffects(data);
+        break;
+      case 'entity_entered':
+        // Из входящих в область видимости entity клиент пока трекает только бонусы
+        this.addPowerUps(data.powerUps ?? []);
+        break;
+      case 'entity_left':
+        for (const id of data.ids) {
+          this.powerUps.delete(id);
+        }
+        break;
+      case 'match_lobby':
+      case 'match_countdown':
+      case 'match_started':
+      case 'match_ended':
+        // Состояние матча для интерфейса приходит сообщением match_state
+        break;
       default:
         console.warn(`[STATE] Unknown event type: ${eventType}`);
     }
   }
 
+  setMap(map: MapLayout | null) {
+    this.walls = map?.walls ?? [];
+  }
+
   // Обработка batch событий
   handleEventBatch(events: any[]) {
     for (const event of events) {
@@ -163,8 +264,46 @@ export class GameStateManager {
     }
   }
 
+  private handleLeaderboard(data: LeaderboardData) {
+    if (data.leaders) {
+      this.leaders = data.leaders;
+    }
+    this.ownRank = data.own;
+    this.totalPlayers = data.total;
+    this.teams = data.teams ?? [];
+  }
+
+  private addPowerUps(powerUps: any[]) {
+    for (const data of powerUps) {
+      this.powerUps.set(data.powerUpId, {
+        id: data.powerUpId,
+        kind: data.kind,
+        x: data.x,
+        y: data.y,
+        radius: data.radius,
+      });
+    }
+  }
+
+  private handlePlayerEffects(data: PlayerEffectsData) {
+    this.setEffects(data.playerId, data.effects);
+  }
+
+  private setEffects(playerId: string, effects: EffectInfo[] | undefined) {
+    if (!effects || effects.length === 0) {
+      this.effects.delete(playerId);
+      return;
+    }
+    const now = performance.now();
+    this.effects.set(playerId, effects.map(effect => ({
+      kind: effect.kind,
+      until: now + effect.remaining * 1000,
+    })));
+  }
+
   private handlePlayerDied(data: any) {
     this.players.delete(data.playerId);
+    this.effects.delete(data.playerId);
     console.log(`[STATE] Player died: ${data.playerId}`);
   }
 
@@ -182,6 +321,17 @@ export class GameStateManager {
     }
   }
 
+  private handleFoodPulled(data: FoodPulledData) {
+    for (const pulled of data.food) {
+      const food = this.food.get(pulled.foodId);
+      if (food) {
+        food.x = pulled.x;
+        food.y = pulled.y;
+        food.velocity = { x: pulled.velX, y: pulled.velY };
+      }
+    }
+  }
+
   private handleFoodEaten(data: any) {
     this.food.delete(data.foodId);
     
@@ -196,11 +346,11 @@ export class GameStateManager {
     }
   }
 
-  private handleCellEaten(data: any) {
-    // Находим игрока у которого съели клетку
+  private removeCell(cellId: string) {
+    // Находим игрока, у которого пропала клетка
     for (const player of this.players.values()) {
-      if (player.cells.has(data.eatenCellId)) {
-        player.cells.delete(data.eatenCellId);
+      if (player.cells.has(cellId)) {
+        player.cells.delete(cellId);
         
         // Если у игрока не осталось клеток - он мертв
         if (player.cells.size === 0) {
@@ -235,6 +385,7 @@ export class GameStateManager {
     // Полная синхронизация
     this.players.clear();
     this.food.clear();
+    this.powerUps.clear();
 
     // Загружаем игроков
     for (const playerData of data.players) {
@@ -258,6 +409,7 @@ export class GameStateManager {
       }
 
       this.players.set(player.id, player);
+      this.setEffects(player.id, playerData.effects);
     }
 
     // Загружаем еду
@@ -271,6 +423,17 @@ export class GameStateManager {
       };
       this.food.set(food.id, food);
     }
+
+    // Загружаем бонусы
+    for (const powerUpData of data.powerUps ?? []) {
+      this.powerUps.set(powerUpData.id, {
+        id: powerUpData.id,
+        kind: powerUpData.kind,
+        x: powerUpData.x,
+        y: powerUpData.y,
+        radius: powerUpData.radius,
+      });
+    }
     
     this.lastUpdateTime = data.timestamp;
   }
@@ -318,6 +481,11 @@ export class GameStateManager {
             // Границы мира
             cell.x = Math.max(cell.radius, Math.min(5000 - cell.radius, cell.x));
             cell.y = Math.max(cell.radius, Math.min(5000 - cell.radius, cell.y));
+            
+            // Стены карты
+            for (const wall of this.walls) {
+              pushOutOfWall(cell, wall);
+            }
           }
         }
         
@@ -354,7 +522,7 @@ export class GameStateManager {
       }
     }
 
-    // Интерполируем выброшенную еду
+    // Интерполируем выброшенную и притянутую еду
     for (const food of this.food.values()) {
       if (food.velocity) {
         food.x += food.velocity.x * dt;
@@ -379,12 +547,44 @@ export class GameStateManager {
     return Array.from(this.food.values());
   }
 
+  getPowerUps(): PowerUp[] {
+    return Array.from(this.powerUps.values());
+  }
+
+  // Действующие эффекты игрока (истёкшие по часам клиента отбрасываются)
+  getEffects(playerId: string): ActiveEffect[] {
+    const now = performance.now();
+    return (this.effects.get(playerId) ?? []).filter(effect => effect.until > now);
+  }
+
   getPlayer(playerId: string): Player | undefined {
     return this.players.get(playerId);
   }
 
+  getLeaders(): LeaderEntry[] {
+    return this.leaders;
+  }
+
+  getOwnRank(): LeaderEntry | null {
+    return this.ownRank;
+  }
+
+  getTeams(): TeamEntry[] {
+    return this.teams;
+  }
+
+  getTotalPlayers(): number {
+    return this.totalPlayers;
+  }
+
   clear() {
     this.players.clear();
     this.food.clear();
+    this.powerUps.clear();
+    this.effects.clear();
+    this.leaders = [];
+    this.ownRank = null;
+    this.teams = [];
+    this.totalPlayers = 0;
   }
 }
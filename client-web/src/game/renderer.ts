@@ -1,4 +1,13 @@
-import { GameStateManager, Player, Food } from './StateManager';
+import { ActiveEffect, GameStateManager, Player, Food, PowerUp } from './StateManager';
+import { LeaderEntry, MapLayout, MapShape, MatchStateData, PowerUpKind } from '../network/protocol';
+
+// Цвет и значок бонуса; цветом же рисуется кольцо эффекта вокруг клеток
+const POWER_UP_STYLE: Record<PowerUpKind, { color: string; icon: string; label: string }> = {
+  speed: { color: '#ff9800', icon: '⚡', label: 'Speed' },
+  shield: { color: '#2196f3', icon: '🛡', label: 'Shield' },
+  magnet: { color: '#e91e63', icon: '🧲', label: 'Magnet' },
+  merge: { color: '#4caf50', icon: '⊕', label: 'Merge' },
+};
 
 export interface Camera {
   x: number;
@@ -13,6 +22,12 @@ export class GameRenderer {
   private worldWidth: number;
   private worldHeight: number;
   private playerId: string | null = null;
+  private followId: string | null = null; // Игрок под камерой зрителя (без своего игрока)
+  private freeCamera: { x: number; y: number } | null = null; // Свободная камера зрителя
+  private spectator = false;
+  private match: MatchStateData | null = null; // Матч королевской битвы (null - бесконечная игра)
+  private matchReceivedAt = 0;
+  private map: MapLayout | null = null; // Карта комнаты (null - пустой мир)
   private targetX: number = 0;
   private targetY: number = 0;
 
@@ -41,12 +56,40 @@ export class GameRenderer {
     this.playerId = playerId;
   }
 
+  setFollowId(followId: string | null) {
+    this.followId = followId;
+  }
+
+  setFreeCamera(position: { x: number; y: number } | null) {
+    this.freeCamera = position;
+  }
+
+  setSpectator(spectator: boolean) {
+    this.spectator = spectator;
+  }
+
+  setMatchState(match: MatchStateData | null) {
+    this.match = match;
+    this.matchReceivedAt = performance.now();
+  }
+
+  setMap(map: MapLayout | null) {
+    this.map = map;
+  }
+
   render(stateManager: GameStateManager) {
     this.clear();
     
-    // Обновляем камеру чтобы следовать за игроком
-    if (this.playerId) {
-      this.updateCamera(stateManager.getPlayers());
+    // Обновляем камеру чтобы следовать за игроком (у зрителя - за выбранным)
+    const cameraId = this.playerId || this.followId;
+    if (cameraId) {
+      this.updateCamera(stateManager.getPlayers(), cameraId);
+    } else if (this.freeCamera) {
+      // Сервер показывает свободной камере вдвое больше обычного
+      const smoothing = 0.1;
+      this.camera.x += (this.freeCamera.x - this.camera.x) * smoothing;
+      this.camera.y += (this.freeCamera.y - this.camera.y) * smoothing;
+      this.camera.zoom += (0.5 - this.camera.zoom) * smoothing;
     }
 
     this.ctx.save();
@@ -59,11 +102,20 @@ export class GameRenderer {
     // Рисуем сетку
     this.drawGrid();
     
+    // Карта: области, стены, точки появления
+    this.drawMap();
+    
+    // Безопасная зона матча
+    this.drawZone();
+    
     // Рисуем еду
     stateManager.getFood().forEach(food => this.drawFood(food));
     
+    // Рисуем бонусы
+    stateManager.getPowerUps().forEach(powerUp => this.drawPowerUp(powerUp));
+    
     // Рисуем игроков
-    stateManager.getPlayers().forEach(player => this.drawPlayer(player));
+    stateManager.getPlayers().forEach(player => this.drawPlayer(player, stateManager.getEffects(player.id)));
 
     // Рисуем курсор/направление
     if (this.playerId) {
@@ -112,6 +164,87 @@ export class GameRenderer {
     this.ctx.strokeRect(0, 0, this.worldWidth, this.worldHeight);
   }
 
+  // Секунды с прихода последнего match_state
+  private matchElapsed(): number {
+    return (performance.now() - this.matchReceivedAt) / 1000;
+  }
+
+  // Между сообщениями зона сужается равномерно до zoneFinal за shrinkLeft секунд
+  private zoneRadius(match: MatchStateData): number {
+    if (!match.shrinkLeft) return match.zoneRadius;
+    const progress = Math.min(1, this.matchElapsed() / match.shrinkLeft);
+    return match.zoneRadius + (match.zoneFinal - match.zoneRadius) * progress;
+  }
+
+  private drawZone() {
+    const match = this.match;
+    if (!match || match.phase !== 'running') return;
+    const radius = this.zoneRadius(match);
+
+    // Всё за кругом затемнено: прямоугольник мира минус круг
+    this.ctx.fillStyle = 'rgba(200, 30, 30, 0.2)';
+    this.ctx.beginPath();
+    this.ctx.rect(0, 0, this.worldWidth, this.worldHeight);
+    this.ctx.arc(match.zoneX, match.zoneY, radius, 0, Math.PI * 2, true);
+    this.ctx.fill('evenodd');
+
+    this.ctx.strokeStyle = '#c0392b';
+    this.ctx.lineWidth = 6 / this.camera.zoom;
+    this.ctx.beginPath();
+    this.ctx.arc(match.zoneX, match.zoneY, radius, 0, Math.PI * 2);
+    this.ctx.stroke();
+  }
+
+  private shapePath(shape: MapShape) {
+    this.ctx.beginPath();
+    if (shape.type === 'circle') {
+      this.ctx.arc(shape.x, shape.y, shape.radius ?? 0, 0, Math.PI * 2);
+    } else {
+      this.ctx.rect(shape.x, shape.y, shape.width ?? 0, shape.height ?? 0);
+    }
+  }
+
+  private drawMap() {
+    const map = this.map;
+    if (!map) return;
+
+    // Богатые едой области - зелёная подложка
+    this.ctx.fillStyle = 'rgba(76, 175, 80, 0.08)';
+    for (const region of map.foodRegions ?? []) {
+      this.shapePath(region);
+      this.ctx.fill();
+    }
+
+    // Зоны без появления - пунктир
+    this.ctx.strokeStyle = 'rgba(0, 0, 0, 0.2)';
+    this.ctx.lineWidth = 2 / this.camera.zoom;
+    this.ctx.setLineDash([12 / this.camera.zoom, 8 / this.camera.zoom]);
+    for (const zone of map.noSpawn ?? []) {
+      this.shapePath(zone);
+      this.ctx.stroke();
+    }
+    this.ctx.setLineDash([]);
+
+    // Точки появления игроков
+    this.ctx.strokeStyle = 'rgba(33, 150, 243, 0.5)';
+    this.ctx.lineWidth = 3 / this.camera.zoom;
+    for (const point of map.spawnPoints ?? []) {
+      this.ctx.beginPath();
+      this.ctx.arc(point.x, point.y, 25, 0, Math.PI * 2);
+      this.ctx.stroke();
+    }
+
+    // Стены
+    this.ctx.fillStyle = '#555';
+    this.ctx.strokeStyle = '#333';
+    this.ctx.lineWidth = 4 / this.camera.zoom;
+    for (const wall of map.walls ?? []) {
+      this.shapePath(wall);
+      this.ctx.fill();
+      this.ctx.stroke();
+    }
+  }
+
   private drawFood(food: Food) {
     this.ctx.fillStyle = food.color;
     this.ctx.beginPath();
@@ -119,12 +252,48 @@ export class GameRenderer {
     this.ctx.fill();
   }
 
-  private drawPlayer(player: Player) {
+  private drawPowerUp(powerUp: PowerUp) {
+    const style = POWER_UP_STYLE[powerUp.kind];
+    if (!style) return;
+
+    // Пульсирующий круг со значком
+    const pulse = 1 + 0.1 * Math.sin(performance.now() / 200);
+    this.ctx.fillStyle = style.color;
+    this.ctx.beginPath();
+    this.ctx.arc(powerUp.x, powerUp.y, powerUp.radius * pulse, 0, Math.PI * 2);
+    this.ctx.fill();
+    this.ctx.strokeStyle = '#fff';
+    this.ctx.lineWidth = 2 / this.camera.zoom;
+    this.ctx.stroke();
+
+    this.ctx.font = `${powerUp.radius * 1.2}px Arial`;
+    this.ctx.fillStyle = '#fff';
+    this.ctx.textAlign = 'center';
+    this.ctx.textBaseline = 'middle';
+    this.ctx.fillText(style.icon, powerUp.x, powerUp.y);
+    this.ctx.textBaseline = 'alphabetic';
+  }
+
+  private drawPlayer(player: Player, effects: ActiveEffect[]) {
     for (const cell of player.cells.values()) {
       this.drawCell(cell, player);
+      this.drawEffectRings(cell, effects);
     }
   }
 
+  // Кольца действующих эффектов вокруг клетки (по одному на эффект)
+  private drawEffectRings(cell: any, effects: ActiveEffect[]) {
+    effects.forEach((effect, i) => {
+      const style = POWER_UP_STYLE[effect.kind];
+      if (!style) return;
+      this.ctx.strokeStyle = style.color;
+      this.ctx.lineWidth = 4 / this.camera.zoom;
+      this.ctx.beginPath();
+      this.ctx.arc(cell.x, cell.y, cell.radius + (6 + i * 6) / this.camera.zoom, 0, Math.PI * 2);
+      this.ctx.stroke();
+    });
+  }
+
   private drawCell(cell: any, player: Player) {
     const isOwnCell = player.id === this.playerId;
 
@@ -162,8 +331,8 @@ export class GameRenderer {
     }
   }
 
-  private updateCamera(players: Player[]) {
-    const player = players.find(p => p.id === this.playerId);
+  private updateCamera(players: Player[], cameraId: string) {
+    const player = players.find(p => p.id === cameraId);
     if (!player || player.cells.size === 0) return;
 
     // Находим центр масс всех клеток игрока
@@ -195,13 +364,14 @@ export class GameRenderer {
   private drawUI(stateManager: GameStateManager) {
     const players = stateManager.getPlayers();
     
-    // Топ-5 игроков
-    const sorted = [...players]
-      .sort((a, b) => b.score - a.score)
-      .slice(0, 5);
+    // Таблица лидеров от сервера; своё место - отдельной строкой, если не в топе
+    const leaders = stateManager.getLeaders();
+    const own = stateManager.getOwnRank();
+    const ownOutside = own !== null && !leaders.some(entry => entry.playerId === own.playerId);
+    const rows = leaders.length + (ownOutside ? 1 : 0);
 
     this.ctx.fillStyle = 'rgba(0, 0, 0, 0.5)';
-    this.ctx.fillRect(10, 10, 200, 30 + sorted.length * 25);
+    this.ctx.fillRect(10, 10, 200, 30 + rows * 25);
 
     this.ctx.font = 'bold 16px Arial';
     this.ctx.fillStyle = '#fff';
@@ -209,13 +379,37 @@ export class GameRenderer {
     this.ctx.fillText('Leaderboard', 20, 30);
 
     this.ctx.font = '14px Arial';
-    sorted.forEach((player, i) => {
-      const y = 55 + i * 25;
-      const isMe = player.id === this.playerId;
+    const drawEntry = (entry: LeaderEntry, y: number) => {
+      const isMe = entry.playerId === (this.playerId || this.followId);
       this.ctx.fillStyle = isMe ? '#ffeb3b' : '#fff';
-      const name = player.isBot ? `🤖 ${player.name}` : player.name;
-      this.ctx.fillText(`${i + 1}. ${name}: ${player.score}`, 20, y);
-    });
+      const name = entry.isBot ? `🤖 ${entry.name}` : entry.name;
+      this.ctx.fillText(`${entry.rank}. ${name}: ${entry.score}`, 20, y);
+    };
+    leaders.forEach((entry, i) => drawEntry(entry, 55 + i * 25));
+    if (own && ownOutside) {
+      drawEntry(own, 55 + leaders.length * 25);
+    }
+
+    // Командный режим: суммарная масса команд под таблицей лидеров
+    const teams = stateManager.getTeams();
+    if (teams.length > 0) {
+      const top = 50 + rows * 25;
+      this.ctx.fillStyle = 'rgba(0, 0, 0, 0.5)';
+      this.ctx.fillRect(10, top, 200, 30 + teams.length * 22);
+
+      this.ctx.font = 'bold 16px Arial';
+      this.ctx.fillStyle = '#fff';
+      this.ctx.fillText('Teams', 20, top + 20);
+
+      this.ctx.font = '14px Arial';
+      teams.forEach((team, i) => {
+        const y = top + 45 + i * 22;
+        this.ctx.fillStyle = team.color;
+        this.ctx.fillRect(20, y - 11, 12, 12);
+        this.ctx.fillStyle = team.team === own?.team ? '#ffeb3b' : '#fff';
+        this.ctx.fillText(`Team ${team.team}: ${team.mass} (${team.players})`, 40, y);
+      });
+    }
 
     // Счет игрока
     const player = players.find(p => p.id === this.playerId);
@@ -230,7 +424,28 @@ export class GameRenderer {
       this.ctx.fillText(`Cells: ${player.cells.size}`, this.canvas.width - 20, 55);
     }
 
-    // Подсказки управления
+    // Свои эффекты бонусов с оставшимся временем
+    const effects = this.playerId ? stateManager.getEffects(this.playerId) : [];
+    if (effects.length > 0) {
+      const now = performance.now();
+      this.ctx.fillStyle = 'rgba(0, 0, 0, 0.5)';
+      this.ctx.fillRect(this.canvas.width - 160, 70, 150, 10 + effects.length * 22);
+
+      this.ctx.font = '14px Arial';
+      this.ctx.textAlign = 'right';
+      effects.forEach((effect, i) => {
+        const style = POWER_UP_STYLE[effect.kind];
+        if (!style) return;
+        const left = Math.ceil((effect.until - now) / 1000);
+        this.ctx.fillStyle = style.color;
+        this.ctx.fillText(`${style.icon} ${style.label}: ${left}s`, this.canvas.width - 20, 92 + i * 22);
+      });
+    }
+
+    this.drawMatchBanner();
+
+    // Подсказки управления (зрителю управлять нечем)
+    if (this.spectator) return;
     this.ctx.fillStyle = 'rgba(0, 0, 0, 0.5)';
     this.ctx.fillRect(10, this.canvas.height - 80, 200, 70);
     
@@ -242,6 +457,42 @@ export class GameRenderer {
     this.ctx.fillText('W: Eject mass', 20, this.canvas.height - 20);
   }
 
+  // Этап матча и таймер - по центру сверху
+  private drawMatchBanner() {
+    const match = this.match;
+    if (!match) return;
+
+    const left = Math.max(0, Math.ceil((match.timeLeft ?? 0) - this.matchElapsed()));
+    let text: string;
+    switch (match.phase) {
+      case 'lobby':
+        text = `Match #${match.match}: waiting for ${match.minPlayers} players`;
+        break;
+      case 'countdown':
+        text = `Match #${match.match} starts in ${left}s`;
+        break;
+      case 'running': {
+        const shrink = Math.max(0, Math.ceil((match.shrinkLeft ?? 0) - this.matchElapsed()));
+        text = shrink > 0 ? `Alive: ${match.alive} · Zone shrinks: ${shrink}s` : `Alive: ${match.alive} · Final zone`;
+        break;
+      }
+      default:
+        text = match.winnerName
+          ? `🏆 ${match.winnerName} wins! Next match in ${left}s`
+          : `No winner. Next match in ${left}s`;
+    }
+
+    this.ctx.font = 'bold 18px Arial';
+    const width = this.ctx.measureText(text).width + 30;
+    this.ctx.fillStyle = 'rgba(0, 0, 0, 0.5)';
+    this.ctx.fillRect((this.canvas.width - width) / 2, 10, width, 36);
+    this.ctx.fillStyle = match.phase === 'ended' ? '#ffeb3b' : '#fff';
+    this.ctx.textAlign = 'center';
+    this.ctx.textBaseline = 'middle';
+    this.ctx.fillText(text, this.canvas.width / 2, 28);
+    this.ctx.textBaseline = 'alphabetic';
+  }
+
   screenToWorld(screenX: number, screenY: number): { x: number; y: number } {
     const x = (screenX - this.canvas.width / 2) / this.camera.zoom + this.camera.x;
     const y = (screenY - this.canvas.height / 2) / this.camera.zoom + this.camera.y;
@@ -0,0 +1,361 @@
+// Декодер бинарного протокола (формат - server/pkg/protocol/binary.go)
+// Кадр превращается в такой же event_batch, какой приходит в JSON
+
+export const SUBPROTOCOL_BINARY = 'agario-binary-v1';
+export const SUBPROTOCOL_JSON = 'agario-json-v1';
+
+const BINARY_MAGIC = 0xb1;
+const RADIUS_SCALE = 8;
+const MAX_UINT16 = 0xffff;
+
+const REC_BIND = 0x00;
+const REC_STATE_DELTA = 0x10;
+const REC_FOOD_SPAWNED = 0x11;
+const REC_SNAPSHOT = 0x12;
+const REC_ENTITY_ENTERED = 0x13;
+const REC_ENTITY_LEFT = 0x14;
+const REC_FOOD_EATEN = 0x15;
+const REC_POWER_UPS = 0x16;
+const REC_JSON = 0x7f;
+
+export interface DecodedBatch {
+  type: 'event_batch';
+  events: { type: string; timestamp: number; data: any }[];
+}
+
+// Чтение записей кадра; выход за границу кадра - исключение
+class Reader {
+  private view: DataView;
+  private bytes: Uint8Array;
+  pos = 0;
+  worldW = 0;
+  worldH = 0;
+
+  constructor(buffer: ArrayBuffer) {
+    this.view = new DataView(buffer);
+    this.bytes = new Uint8Array(buffer);
+  }
+
+  done(): boolean {
+    return this.pos >= this.bytes.length;
+  }
+
+  peek(): number {
+    return this.bytes[this.pos];
+  }
+
+  private need(n: number) {
+    if (this.pos + n > this.bytes.length) {
+      throw new Error(`binary frame truncated at ${this.pos}`);
+    }
+  }
+
+  u8(): number {
+    this.need(1);
+    return this.bytes[this.pos++];
+  }
+
+  u16(): number {
+    this.need(2);
+    const v = this.view.getUint16(this.pos, true);
+    this.pos += 2;
+    return v;
+  }
+
+  i16(): number {
+    this.need(2);
+    const v = this.view.getInt16(this.pos, true);
+    this.pos += 2;
+    return v;
+  }
+
+  f32(): number {
+    this.need(4);
+    const v = this.view.getFloat32(this.pos, true);
+    this.pos += 4;
+    return v;
+  }
+
+  // uvarint как в encoding/binary; до 2^53 точно
+  uvarint(): number {
+    let result = 0;
+    let scale = 1;
+    for (let i = 0; i < 10; i++) {
+      const b = this.u8();
+      result += (b & 0x7f) * scale;
+      if (b < 0x80) {
+        return result;
+      }
+      scale *= 128;
+    }
+    throw new Error(`bad uvarint at ${this.pos}`);
+  }
+
+  // varint - zigzag поверх uvarint
+  varint(): number {
+    const u = this.uvarint();
+    return u % 2 === 0 ? u / 2 : -(u + 1) / 2;
+  }
+
+  str(): string {
+    const n = this.uvarint();
+    this.need(n);
+    const s = new TextDecoder().decode(this.bytes.subarray(this.pos, this.pos + n));
+    this.pos += n;
+    return s;
+  }
+
+  position(): [number, number] {
+    const x = (this.u16() / MAX_UINT16) * this.worldW;
+    const y = (this.u16() / MAX_UINT16) * this.worldH;
+    return [x, y];
+  }
+
+  radius(): number {
+    return this.u16() / RADIUS_SCALE;
+  }
+
+  color(): string {
+    this.need(3);
+    let hex = '#';
+    for (let i = 0; i < 3; i++) {
+      hex += this.bytes[this.pos++].toString(16).padStart(2, '0').toUpperCase();
+    }
+    return hex;
+  }
+
+  flag(): boolean {
+    return this.u8() !== 0;
+  }
+}
+
+// BinaryDecoder - таблица ID соединения; новый сокет - новый декодер
+export class BinaryDecoder {
+  private ids = new Map<number, string>();
+  private refs: Set<number> | null = null; // ID, упомянутые в снимке
+
+  decode(buffer: ArrayBuffer): DecodedBatch {
+    const r = new Reader(buffer);
+    if (r.u8() !== BINARY_MAGIC) {
+      throw new Error('binary frame: bad magic');
+    }
+    r.worldW = r.f32();
+    r.worldH = r.f32();
+
+    // RecBind с номером 1 - новая таблица (пересинхронизация)
+    let firstBound = Infinity;
+    while (!r.done() && r.peek() === REC_BIND) {
+      r.u8();
+      const num = r.uvarint();
+      const id = r.str();
+      if (num === 1) {
+        this.ids.clear();
+      }
+      this.ids.set(num, id);
+      firstBound = Math.min(firstBound, num);
+    }
+
+    const now = Date.now();
+    const events: DecodedBatch['events'] = [];
+    const push = (type: string, data: any) => events.push({ type, timestamp: now, data });
+
+    while (!r.done()) {
+      const recType = r.u8();
+      switch (recType) {
+        case REC_STATE_DELTA:
+          push('state_delta', this.stateDelta(r));
+          break;
+        case REC_FOOD_SPAWNED:
+          push('food_spawned', { foods: this.foods(r) });
+          break;
+        case REC_SNAPSHOT:
+          push('world_snapshot', this.snapshot(r, firstBound));
+          break;
+        case REC_ENTITY_ENTERED:
+          push('entity_entered', this.entityEntered(r));
+          break;
+        case REC_ENTITY_LEFT: {
+          const nums: number[] = [];
+          const n = r.uvarint();
+          for (let i = 0; i < n; i++) {
+            nums.push(r.uvarint());
+          }
+          push('entity_left', { ids: nums.map((num) => this.lookup(num)) });
+          for (const num of nums) {
+            this.ids.delete(num);
+          }
+          break;
+        }
+        case REC_FOOD_EATEN: {
+          const foodNum = r.uvarint();
+          push('food_eaten', { foodId: this.lookup(foodNum), playerId: this.id(r), cellId: this.id(r) });
+          this.ids.delete(foodNum);
+          break;
+        }
+        case REC_POWER_UPS:
+          // Отдельная RecPowerUps - появление бонусов или бонусы из entity_entered
+          push('powerup_spawned', { powerUps: this.powerUps(r, 'powerUpId') });
+          break;
+        case REC_JSON: {
+          const event = JSON.parse(r.str());
+          if (event.type === 'powerup_collected') {
+            this.forget(event.data.powerUpId);
+          }
+          events.push(event);
+          break;
+        }
+        default:
+          throw new Error(`binary frame: unknown record 0x${recType.toString(16)} at ${r.pos - 1}`);
+      }
+    }
+
+    return { type: 'event_batch', events };
+  }
+
+  private lookup(num: number): string {
+    const id = this.ids.get(num);
+    if (id === undefined) {
+      throw new Error(`binary frame: unbound id ${num}`);
+    }
+    this.refs?.add(num);
+    return id;
+  }
+
+  private id(r: Reader): string {
+    return this.lookup(r.uvarint());
+  }
+
+  private forget(id: string) {
+    for (const [num, value] of this.ids) {
+      if (value === id) {
+        this.ids.delete(num);
+      }
+    }
+  }
+
+  private stateDelta(r: Reader) {
+    const data = {
+      tick: r.uvarint(),
+      timestamp: r.uvarint(),
+      ackSeq: r.uvarint(),
+      ackTimestamp: r.varint(),
+      ackTick: r.uvarint(),
+      entities: [] as any[],
+    };
+    const n = r.uvarint();
+    for (let i = 0; i < n; i++) {
+      const id = this.id(r);
+      const [x, y] = r.position();
+      const radius = r.radius();
+      const [targetX, targetY] = r.position();
+      data.entities.push({ id, x, y, radius, targetX, targetY });
+    }
+    return data;
+  }
+
+  private foods(r: Reader) {
+    const foods: any[] = [];
+    const n = r.uvarint();
+    for (let i = 0; i < n; i++) {
+      const foodId = this.id(r);
+      const [x, y] = r.position();
+      foods.push({ foodId, x, y, radius: r.radius(), color: r.color(), velX: r.i16(), velY: r.i16() });
+    }
+    return foods;
+  }
+
+  private viruses(r: Reader) {
+    const viruses: any[] = [];
+    const n = r.uvarint();
+    for (let i = 0; i < n; i++) {
+      const virusId = this.id(r);
+      const [x, y] = r.position();
+      viruses.push({ virusId, x, y, radius: r.radius(), velX: r.i16(), velY: r.i16() });
+    }
+    return viruses;
+  }
+
+  // idField - powerUpId в событиях, id в снимке (как в JSON)
+  private powerUps(r: Reader, idField: 'id' | 'powerUpId') {
+    const powerUps: any[] = [];
+    const n = r.uvarint();
+    for (let i = 0; i < n; i++) {
+      const id = this.id(r);
+      const [x, y] = r.position();
+      const radius = r.radius();
+      powerUps.push({ [idField]: id, x, y, radius, kind: r.str() });
+    }
+    return powerUps;
+  }
+
+  // Снимок и обязательная RecPowerUps следом; затем таблица сокращается, как на сервере
+  private snapshot(r: Reader, firstBound: number) {
+    const refs = new Set<number>();
+    this.refs = refs;
+    try {
+      const data = {
+        tick: r.uvarint(),
+        timestamp: r.uvarint(),
+        players: [] as any[],
+        food: [] as any[],
+        viruses: [] as any[],
+        powerUps: [] as any[],
+      };
+
+      let n = r.uvarint();
+      for (let i = 0; i < n; i++) {
+        const player = { id: this.id(r), name: r.str(), color: r.color(), isBot: r.flag(), score: r.uvarint(), cells: [] as any[] };
+        const cells = r.uvarint();
+        for (let j = 0; j < cells; j++) {
+          const id = this.id(r);
+          const [x, y] = r.position();
+          player.cells.push({ id, x, y, radius: r.radius() });
+        }
+        data.players.push(player);
+      }
+
+      n = r.uvarint();
+      for (let i = 0; i < n; i++) {
+        const id = this.id(r);
+        const [x, y] = r.position();
+        data.food.push({ id, x, y, radius: r.radius(), color: r.color() });
+      }
+
+      n = r.uvarint();
+      for (let i = 0; i < n; i++) {
+        const id = this.id(r);
+        const [x, y] = r.position();
+        data.viruses.push({ id, x, y, radius: r.radius() });
+      }
+
+      if (r.u8() !== REC_POWER_UPS) {
+        throw new Error('binary frame: snapshot without power-ups record');
+      }
+      data.powerUps = this.powerUps(r, 'id');
+
+      for (const num of [...this.ids.keys()]) {
+        if (!refs.has(num) && num < firstBound) {
+          this.ids.delete(num);
+        }
+      }
+      return data;
+    } finally {
+      this.refs = null;
+    }
+  }
+
+  private entityEntered(r: Reader) {
+    const cells: any[] = [];
+    const n = r.uvarint();
+    for (let i = 0; i < n; i++) {
+      const cellId = this.id(r);
+      const playerId = this.id(r);
+      const name = r.str();
+      const color = r.color();
+      const isBot = r.flag();
+      const [x, y] = r.position();
+      cells.push({ playerId, name, color, isBot, cellId, x, y, radius: r.radius() });
+    }
+    return { cells, food: this.foods(r), viruses: this.viruses(r) };
+  }
+}
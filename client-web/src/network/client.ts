@@ -5,19 +5,51 @@ import {
   StateData,
   JoinData,
   MoveData,
+  PendingInput,
+  DeathData,
+  RespawnData,
+  ReplayControlData,
+  ReplayStateData,
+  SpectateData,
+  SpectateStateData,
+  MatchStateData,
 } from './protocol';
+import { BinaryDecoder, SUBPROTOCOL_BINARY, SUBPROTOCOL_JSON } from './binary';
 
 export type GameStateHandler = (message: any) => void;
 export type InitHandler = (data: InitData) => void;
+export type DeathHandler = (data: DeathData) => void;
+export type ReplayStateHandler = (data: ReplayStateData) => void;
+export type SpectateStateHandler = (data: SpectateStateData) => void;
+export type MatchStateHandler = (data: MatchStateData) => void;
 
 export class GameClient {
   private ws: WebSocket | null = null;
   private url: string;
   private onStateUpdate?: GameStateHandler;
   private onInit?: InitHandler;
+  private onDeath?: DeathHandler;
+  private onReplayState?: ReplayStateHandler;
+  private onSpectateState?: SpectateStateHandler;
+  private onMatchState?: MatchStateHandler;
 
-  constructor(url: string) {
+  // Ввод, ещё не подтверждённый сервером (для prediction/reconciliation)
+  private inputSeq = 0;
+  private pendingInputs: PendingInput[] = [];
+  private rtt = 0;
+
+  // Переподключение к своему игроку после обрыва соединения
+  private reconnectToken: string | null = null;
+  private reconnectAttempts = 0;
+  private static readonly MAX_RECONNECT_ATTEMPTS = 10;
+
+  // Бинарный протокол (JSON остаётся для отладки: ?protocol=json)
+  private binary: boolean;
+  private decoder = new BinaryDecoder();
+
+  constructor(url: string, binary = true) {
     this.url = url;
+    this.binary = binary;
   }
 
   connect(): Promise<void> {
@@ -34,7 +66,10 @@ export class GameClient {
     return new Promise((resolve, reject) => {
       try {
         console.log('[WS] Creating WebSocket...');
-        this.ws = new WebSocket(this.url);
+        // Формат выбирает сервер из предложенных subprotocol; таблица ID - своя у каждого соединения
+        this.ws = new WebSocket(this.url, this.binary ? [SUBPROTOCOL_BINARY, SUBPROTOCOL_JSON] : [SUBPROTOCOL_JSON]);
+        this.ws.binaryType = 'arraybuffer';
+        this.decoder = new BinaryDecoder();
 
         this.ws.onopen = () => {
           console.log('[WS] ✅ Connected to server');
@@ -53,6 +88,7 @@ export class GameClient {
         this.ws.onclose = (event) => {
           console.log('[WS] Connection closed. Code:', event.code, 'Reason:', event.reason);
           this.ws = null;
+          this.scheduleResume();
         };
       } catch (error) {
         console.error('[WS] ❌ Failed to create WebSocket:', error);
@@ -61,20 +97,75 @@ export class GameClient {
     });
   }
 
-  private handleMessage(data: string) {
+  private handleMessage(data: string | ArrayBuffer) {
     try {
-      const message = JSON.parse(data);
+      // Бинарные кадры - только события; ответы (init, error...) всегда JSON
+      const message = typeof data === 'string' ? JSON.parse(data) : this.decoder.decode(data);
 
       // Обработка event batch
       if (message.type === 'event_batch') {
+        let tick = 0;
+        for (const event of message.events) {
+          if (event.type === 'state_delta' && event.data.ackSeq) {
+            this.acknowledge(event.data.ackSeq, event.data.ackTimestamp);
+          }
+          if (event.type === 'state_delta' || event.type === 'world_snapshot') {
+            tick = Math.max(tick, event.data.tick);
+          }
+        }
+        if (tick > 0) {
+          this.ackTick(tick);
+        }
         if (this.onStateUpdate) {
           this.onStateUpdate(message);
         }
         return;
       }
 
+      if (message.type === 'error') {
+        console.warn('[WS] Server error:', message.data);
+        if (message.data.code === 'session_expired') {
+          this.reconnectToken = null;
+        }
+        return;
+      }
+
+      // Игрок съеден: сессии больше нет, соединение остаётся для respawn
+      if (message.type === 'death') {
+        this.reconnectToken = null;
+        this.pendingInputs = [];
+        if (this.onDeath) {
+          this.onDeath(message.data as DeathData);
+        }
+        return;
+      }
+
+      if (message.type === 'replay_state') {
+        if (this.onReplayState) {
+          this.onReplayState(message.data as ReplayStateData);
+        }
+        return;
+      }
+
+      if (message.type === 'spectate_state') {
+        if (this.onSpectateState) {
+          this.onSpectateState(message.data as SpectateStateData);
+        }
+        return;
+      }
+
+      if (message.type === 'match_state') {
+        if (this.onMatchState) {
+          this.onMatchState(message.data as MatchStateData);
+        }
+        return;
+      }
+
       // Обработка одиночных событий (включая world_snapshot)
       if (message.type && message.type !== 'init') {
+        if (message.type === 'world_snapshot' && message.data.tick > 0) {
+          this.ackTick(message.data.tick);
+        }
         if (this.onStateUpdate) {
           this.onStateUpdate(message);
         }
@@ -83,6 +174,8 @@ export class GameClient {
 
       // Обработка init сообщения
       if (message.type === 'init') {
+        this.reconnectToken = message.data.reconnectToken || null;
+        this.reconnectAttempts = 0;
         if (this.onInit) {
           this.onInit(message.data as InitData);
         }
@@ -103,23 +196,97 @@ export class GameClient {
     }
   }
 
-  join(name: string) {
-    console.log('[WS] Sending join request for:', name);
-    const data: JoinData = { name };
+  join(name: string, room?: string) {
+    console.log('[WS] Sending join request for:', name, room ? `(room ${room})` : '');
+    const data: JoinData = room ? { name, room } : { name };
     this.send({ type: 'join', data });
   }
 
+  // Пауза, перемотка, скорость и камера в комнате реплея
+  replayControl(data: ReplayControlData) {
+    this.send({ type: 'replay_control', data });
+  }
+
+  // Смотреть без игрока; в той же комнате - сменить камеру
+  spectate(data: SpectateData = {}) {
+    this.send({ type: 'spectate', data });
+  }
+
+  // Новый игрок после гибели; без имени сервер берёт прежнее
+  respawn(name?: string) {
+    const data: RespawnData = name ? { name } : {};
+    this.send({ type: 'respawn', data });
+  }
+
+  // Вернуться к игроку по токену из init (клетки ждут reconnectGrace секунд)
+  private scheduleResume() {
+    if (!this.reconnectToken || this.reconnectAttempts >= GameClient.MAX_RECONNECT_ATTEMPTS) {
+      return;
+    }
+    const delay = Math.min(1000 * 2 ** this.reconnectAttempts, 8000);
+    this.reconnectAttempts++;
+    console.log(`[WS] Reconnecting in ${delay}ms (attempt ${this.reconnectAttempts})`);
+
+    setTimeout(async () => {
+      try {
+        await this.connect();
+        if (this.reconnectToken) {
+          this.send({ type: 'resume', data: { token: this.reconnectToken } });
+        }
+      } catch (error) {
+        console.error('[WS] Reconnect failed:', error);
+      }
+    }, delay);
+  }
+
   move(x: number, y: number) {
-    const data: MoveData = { x, y };
+    const input = this.nextInput('move');
+    input.x = x;
+    input.y = y;
+    const data: MoveData = { seq: input.seq, timestamp: input.timestamp, x, y };
     this.send({ type: 'move', data });
   }
 
   split() {
-    this.send({ type: 'split', data: null });
+    const input = this.nextInput('split');
+    this.send({ type: 'split', data: { seq: input.seq, timestamp: input.timestamp } });
   }
 
   eject() {
-    this.send({ type: 'eject', data: null });
+    const input = this.nextInput('eject');
+    this.send({ type: 'eject', data: { seq: input.seq, timestamp: input.timestamp } });
+  }
+
+  private nextInput(type: PendingInput['type']): PendingInput {
+    this.inputSeq = (this.inputSeq + 1) >>> 0 || 1; // 0 - ввод без нумерации
+    const input: PendingInput = { seq: this.inputSeq, timestamp: Date.now(), type };
+    this.pendingInputs.push(input);
+    if (this.pendingInputs.length > 256) {
+      this.pendingInputs.shift();
+    }
+    return input;
+  }
+
+  // Сервер применил ввод до ackSeq включительно
+  private acknowledge(ackSeq: number, ackTimestamp?: number) {
+    this.pendingInputs = this.pendingInputs.filter((input) => ((input.seq - ackSeq) | 0) > 0);
+    if (ackTimestamp) {
+      this.rtt = Date.now() - ackTimestamp;
+    }
+  }
+
+  // Сервер считает delta от последнего подтверждённого тика
+  private ackTick(tick: number) {
+    this.send({ type: 'ack', data: { tick } });
+  }
+
+  // Ввод, который нужно повторно применить поверх состояния сервера
+  getPendingInputs(): PendingInput[] {
+    return this.pendingInputs;
+  }
+
+  getRtt(): number {
+    return this.rtt;
   }
 
   setStateHandler(handler: GameStateHandler) {
@@ -130,8 +297,25 @@ export class GameClient {
     this.onInit = handler;
   }
 
+  setDeathHandler(handler: DeathHandler) {
+    this.onDeath = handler;
+  }
+
+  setReplayStateHandler(handler: ReplayStateHandler) {
+    this.onReplayState = handler;
+  }
+
+  setSpectateStateHandler(handler: SpectateStateHandler) {
+    this.onSpectateState = handler;
+  }
+
+  setMatchStateHandler(handler: MatchStateHandler) {
+    this.onMatchState = handler;
+  }
+
   disconnect() {
     console.log('[WS] Explicit disconnect called');
+    this.reconnectToken = null;
     
     if (this.ws) {
       this.ws.onclose = null;
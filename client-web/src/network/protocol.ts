@@ -1,14 +1,26 @@
 // Типы протокола (синхронизированы с сервером)
 
 export type MessageType =
+  | 'hello'
   | 'join'
   | 'move'
   | 'split'
   | 'eject'
+  | 'ack'
+  | 'resume'
+  | 'respawn'
+  | 'replay_control'
+  | 'spectate'
+  | 'welcome'
+  | 'error'
   | 'init'
   | 'state'
   | 'player_died'
-  | 'leaderboard';
+  | 'death'
+  | 'leaderboard'
+  | 'replay_state'
+  | 'spectate_state'
+  | 'match_state';
 
 export interface ClientMessage {
   type: MessageType;
@@ -21,19 +33,93 @@ export interface ServerMessage {
 }
 
 // Client -> Server
+export interface HelloData {
+  versions: number[];
+}
+
 export interface JoinData {
   name: string;
+  room?: string;
+}
+
+// Порядковый номер и время ввода (сервер подтверждает через ackSeq в state_delta)
+export interface InputHeader {
+  seq?: number;
+  timestamp?: number;
 }
 
-export interface MoveData {
+export interface MoveData extends InputHeader {
   x: number;
   y: number;
 }
 
+export type ActionData = InputHeader;
+
+export interface ResumeData {
+  token: string;
+}
+
+// Снова в игру после гибели; без имени - прежнее
+export interface RespawnData {
+  name?: string;
+}
+
+export interface AckData {
+  tick: number;
+}
+
+// Управление воспроизведением реплея (только в комнате реплея)
+export type ReplayAction = 'play' | 'pause' | 'seek' | 'speed' | 'follow';
+
+export interface ReplayControlData {
+  action: ReplayAction;
+  tick?: number;     // seek
+  speed?: number;    // speed
+  playerId?: string; // follow; пусто - лидер
+}
+
+export interface PendingInput {
+  seq: number;
+  timestamp: number;
+  type: 'move' | 'split' | 'eject';
+  x?: number;
+  y?: number;
+}
+
 // Server -> Client
+export interface WelcomeData {
+  version: number;
+  supportedVersions: number[];
+}
+
+export interface ErrorData {
+  code: string;
+  message: string;
+  type?: MessageType;
+}
+
+export type SpectateMode = 'leader' | 'player' | 'free';
+
+// Смотреть комнату без игрока; повторное сообщение переводит камеру
+export interface SpectateData {
+  room?: string;      // Пусто - текущая комната или комната по умолчанию
+  mode?: SpectateMode; // Пусто - leader
+  playerId?: string;  // player
+  x?: number;         // free
+  y?: number;
+}
+
 export interface InitData {
   playerId: string;
+  room: string;
   worldSize: WorldSize;
+  config: any;
+  reconnectToken?: string;
+  resumed?: boolean;
+  replay?: boolean; // Комната воспроизводит реплей: игрока нет, только камера
+  spectator?: boolean; // Зритель без игрока (spectate или реплей)
+  match?: MatchStateData; // Матч королевской битвы; нет - бесконечная игра
+  map?: MapLayout;        // Карта комнаты; нет - пустой мир
 }
 
 export interface WorldSize {
@@ -72,14 +158,141 @@ export interface FoodState {
 
 export interface PlayerDiedData {
   playerId: string;
+  cause?: 'eaten' | 'zone';
   killerId?: string;
+  killerName?: string;
+  finalMass: number;
+  timeAlive: number;
+  kills: number;
 }
 
+// Итоги жизни - только погибшему игроку
+export interface DeathData {
+  playerId: string;
+  cause?: 'eaten' | 'zone';
+  killerId?: string;
+  killerName?: string;
+  finalMass: number;
+  timeAlive: number; // Секунды
+  kills: number;
+}
+
+// Таблица лидеров приходит только при изменениях
 export interface LeaderboardData {
-  leaders: LeaderEntry[];
+  leaders: LeaderEntry[] | null; // null - топ не изменился
+  own: LeaderEntry | null;       // Своё место (null - не в игре)
+  total: number;
+  teams?: TeamEntry[];           // Командный режим: по убыванию массы
 }
 
 export interface LeaderEntry {
+  rank: number;
+  playerId: string;
   name: string;
   score: number;
+  isBot?: boolean;
+  team?: number; // Командный режим: номер команды с 1
+}
+
+export interface TeamEntry {
+  team: number;
+  color: string;
+  mass: number;
+  players: number;
+}
+
+// Состояние воспроизведения реплея (раз в секунду и после каждой команды)
+export interface ReplayStateData {
+  tick: number;
+  startTick: number;
+  endTick: number;
+  tickRate: number;
+  paused: boolean;
+  speed: number;
+  following?: string; // Игрок под камерой (выбранный или лидер)
+  error?: string;     // Воспроизведение разошлось с записью
+}
+
+// Матч королевской битвы (раз в секунду и при смене этапа)
+export type MatchPhase = 'lobby' | 'countdown' | 'running' | 'ended';
+
+export interface MatchStateData {
+  match: number;
+  phase: MatchPhase;
+  timeLeft?: number;   // Секунды до старта (countdown) или до нового лобби (ended)
+  alive: number;
+  minPlayers: number;  // Живых людей для начала отсчёта
+  zoneX: number;
+  zoneY: number;
+  zoneRadius: number;
+  zoneFinal: number;   // Радиус в конце сужения
+  shrinkLeft?: number; // Секунды до конца сужения
+  winnerId?: string;
+  winnerName?: string;
+  winnerTeam?: number;
+}
+
+// Камера зрителя (после spectate и при смене игрока под камерой)
+export interface SpectateStateData {
+  mode: SpectateMode;
+  following?: string; // Игрок под камерой
+  x?: number;         // Центр свободной камеры
+  y?: number;
+}
+
+// Бонусы: подбираются касанием, эффект действует несколько секунд
+export type PowerUpKind = 'speed' | 'shield' | 'magnet' | 'merge';
+
+// Бонус в событиях powerup_spawned и entity_entered (в снимке - id вместо powerUpId)
+export interface PowerUpInfo {
+  powerUpId: string;
+  kind: PowerUpKind;
+  x: number;
+  y: number;
+  radius: number;
+}
+
+// Событие food_pulled: магнит тянет еду к клеткам игрока (позиция и скорость, как у выброшенной еды)
+export interface FoodPulledData {
+  playerId: string;
+  food: {
+    foodId: string;
+    x: number;
+    y: number;
+    velX: number;
+    velY: number;
+  }[];
+}
+
+// Событие player_effects (приходит всем); пустой список - эффекты кончились
+export interface PlayerEffectsData {
+  playerId: string;
+  effects: EffectInfo[];
+}
+
+export interface EffectInfo {
+  kind: PowerUpKind;
+  remaining: number; // Секунды до окончания
+}
+
+// Карта: прямоугольник - x, y левого верхнего угла и width x height; круг - центр x, y и radius
+export interface MapShape {
+  type: 'rect' | 'circle';
+  x: number;
+  y: number;
+  width?: number;
+  height?: number;
+  radius?: number;
+}
+
+export interface FoodRegion extends MapShape {
+  share: number; // Доля новой еды, появляющейся в области
+}
+
+export interface MapLayout {
+  name?: string;
+  walls?: MapShape[];         // Клетки не проходят сквозь стены
+  noSpawn?: MapShape[];       // Здесь ничего не появляется случайно
+  foodRegions?: FoodRegion[];
+  spawnPoints?: { x: number; y: number }[];
 }
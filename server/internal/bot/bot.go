@@ -4,45 +4,62 @@ import (
 	"agario-server/internal/game"
 	"math"
 	"math/rand"
+	"sort"
 	"time"
 )
 
+// UpdateInterval - боты принимают решения на тиках, кратных этому числу
+const UpdateInterval = 10
+
+// Bot - AI игрока; случайность берётся из генератора мира, время - из World.Now,
+// поэтому в детерминированном мире боты тоже детерминированы
 type Bot struct {
 	Player         *game.Player
 	World          *game.World
-	rand           *rand.Rand
-	nextDecision   time.Time
+	NextDecision   time.Time // Время мира следующего решения; нулевое - на ближайшем Update
 	decisionDelay  time.Duration
+	Passive        bool // Не охотится и не делится: только бродит и убегает
 }
 
 // NewBot - создание бота (с локом для начальной инициализации)
 func NewBot(name string, world *game.World) *Bot {
-	color := randomColor()
-	player := world.AddPlayer(name, color, true)
+	world.Mu.Lock()
+	defer world.Mu.Unlock()
+	return NewBotUnlocked(name, world)
+}
+
+// NewBotUnlocked - создание бота БЕЗ лока (когда world.Mu.Lock уже есть)
+func NewBotUnlocked(name string, world *game.World) *Bot {
+	color := randomColor(world.Rand())
+	player := world.AddPlayerUnlocked(name, color, true)
 	
 	return &Bot{
 		Player:        player,
 		World:         world,
-		rand:          rand.New(rand.NewSource(time.Now().UnixNano())),
-		nextDecision:  time.Now(),
 		decisionDelay: 300 * time.Millisecond,
 	}
 }
 
-// NewBotUnlocked - создание бота БЕЗ лока (когда world.Mu.Lock уже есть)
-func NewBotUnlocked(name string, world *game.World) *Bot {
-	color := randomColor()
-	player := world.AddPlayerUnlocked(name, color, true)
-	
+// NewController - управление существующим игроком-ботом (например, из сохранения мира)
+func NewController(player *game.Player, world *game.World) *Bot {
 	return &Bot{
 		Player:        player,
 		World:         world,
-		rand:          rand.New(rand.NewSource(time.Now().UnixNano())),
-		nextDecision:  time.Now(),
 		decisionDelay: 300 * time.Millisecond,
 	}
 }
 
+// NewIdleController - пассивное управление клетками существующего игрока
+// (например, пока отключившийся игрок не переподключится)
+func NewIdleController(player *game.Player, world *game.World) *Bot {
+	return &Bot{
+		Player:        player,
+		World:         world,
+		decisionDelay: time.Second,
+		Passive:       true,
+	}
+}
+
 // Update - обновление AI бота
 // ВАЖНО: вызывается из world.Update() который уже держит world.Mu.Lock()
 // Поэтому НЕ берем никаких локов!
@@ -51,31 +68,41 @@ func (b *Bot) Update() {
 		return
 	}
 	
-	if time.Now().Before(b.nextDecision) {
+	now := b.World.Now()
+	if now.Before(b.NextDecision) {
 		return
 	}
 	
-	b.nextDecision = time.Now().Add(b.decisionDelay)
+	b.NextDecision = now.Add(b.decisionDelay)
 	
 	// Получаем центр масс БЕЗ ЛОКОВ (world lock уже есть)
 	if len(b.Player.Cells) == 0 {
 		return
 	}
 	
-	centerX, centerY := 0.0, 0.0
-	for _, cell := range b.Player.Cells {
-		centerX += cell.Position.X
-		centerY += cell.Position.Y
+	// Клетки расталкиваются, поэтому центр считаем с учётом массы
+	center := massCenter(b.Player.Cells)
+	
+	// Вне безопасной зоны матча важнее всего вернуться в неё
+	if back := b.returnToZone(center); back != nil {
+		b.Player.SetTarget(back.X, back.Y, now)
+		return
+	}
+	
+	if b.Passive {
+		if escape := b.findThreat(center); escape != nil {
+			b.Player.SetTarget(escape.X, escape.Y, now)
+		} else {
+			b.wanderRandomly(center)
+		}
+		return
 	}
-	centerX /= float64(len(b.Player.Cells))
-	centerY /= float64(len(b.Player.Cells))
-	center := game.Vector2D{X: centerX, Y: centerY}
 	
 	// Ищем ближайшую еду или слабого противника
 	target := b.findTarget(center)
 	
 	if target != nil {
-		b.Player.SetTarget(target.X, target.Y)
+		b.Player.SetTarget(target.X, target.Y, now)
 		
 		// Иногда пытаемся разделиться если противник близко
 		if b.shouldSplit(center, *target) {
@@ -93,8 +120,13 @@ func (b *Bot) findTarget(center game.Vector2D) *game.Vector2D {
 	var closestFood *game.Vector2D
 	closestFoodDist := math.MaxFloat64
 	
-	// Ищем ближайшую еду БЕЗ ЛОКОВ
-	for _, food := range b.World.Food {
+	// Ищем ближайшую еду через пространственный индекс БЕЗ ЛОКОВ
+	zone, zoneActive := b.World.SafeZoneUnlocked()
+	for _, food := range b.World.FoodInRadius(center, searchRadius) {
+		// За едой вне зоны не ходим
+		if zoneActive && !zone.Contains(food.Position) {
+			continue
+		}
 		dist := game.Distance(center, food.Position)
 		if dist < searchRadius && dist < closestFoodDist {
 			closestFoodDist = dist
@@ -113,8 +145,9 @@ func (b *Bot) findTarget(center game.Vector2D) *game.Vector2D {
 		botMass += cell.Mass()
 	}
 	
-	for _, player := range b.World.Players {
-		if player.ID == b.Player.ID || !player.IsAlive() {
+	for _, player := range b.World.PlayersInRadius(center, searchRadius) {
+		// Союзников не едим и не боимся
+		if player.ID == b.Player.ID || game.Teammates(player, b.Player) || !player.IsAlive() {
 			continue
 		}
 		
@@ -125,13 +158,7 @@ func (b *Bot) findTarget(center game.Vector2D) *game.Vector2D {
 		}
 		
 		if len(player.Cells) > 0 {
-			enemyCenter := game.Vector2D{X: 0, Y: 0}
-			for _, cell := range player.Cells {
-				enemyCenter.X += cell.Position.X
-				enemyCenter.Y += cell.Position.Y
-			}
-			enemyCenter.X /= float64(len(player.Cells))
-			enemyCenter.Y /= float64(len(player.Cells))
+			enemyCenter := massCenter(player.Cells)
 			
 			dist := game.Distance(center, enemyCenter)
 			
@@ -163,11 +190,49 @@ func (b *Bot) findTarget(center game.Vector2D) *game.Vector2D {
 	return nil
 }
 
+// returnToZone - куда идти, если бот у края безопасной зоны или за ней (nil - зона не мешает)
+// Запас в пятую часть радиуса: зона сужается, пока бот идёт к цели
+func (b *Bot) returnToZone(center game.Vector2D) *game.Vector2D {
+	zone, active := b.World.SafeZoneUnlocked()
+	if !active || game.Distance(center, zone.Center) <= zone.Radius*0.8 {
+		return nil
+	}
+	target := zone.Center
+	return &target
+}
+
+// findThreat - куда убегать от ближайшего более крупного противника (nil - угрозы нет)
+func (b *Bot) findThreat(center game.Vector2D) *game.Vector2D {
+	const searchRadius = 400.0
+	
+	botMass := 0.0
+	for _, cell := range b.Player.Cells {
+		botMass += cell.Mass()
+	}
+	
+	for _, player := range b.World.PlayersInRadius(center, searchRadius/2) {
+		if player.ID == b.Player.ID || game.Teammates(player, b.Player) || len(player.Cells) == 0 {
+			continue
+		}
+		
+		enemyMass := 0.0
+		for _, cell := range player.Cells {
+			enemyMass += cell.Mass()
+		}
+		if botMass < enemyMass*0.8 {
+			direction := center.Sub(massCenter(player.Cells)).Normalize()
+			escape := center.Add(direction.Mul(searchRadius))
+			return &escape
+		}
+	}
+	return nil
+}
+
 func (b *Bot) shouldSplit(center game.Vector2D, target game.Vector2D) bool {
 	dist := game.Distance(center, target)
 	
 	// БЕЗ ЛОКОВ - world lock уже есть
-	if len(b.Player.Cells) >= game.PlayerMaxCells/2 {
+	if len(b.Player.Cells) >= b.World.Config.PlayerMaxCells/2 {
 		return false
 	}
 	
@@ -179,9 +244,9 @@ func (b *Bot) shouldSplit(center game.Vector2D, target game.Vector2D) bool {
 	
 	// Сплитаемся если цель близко и у нас достаточно массы
 	if dist < 100 && totalMass > 80 {
-		if b.rand.Float64() < 0.3 { // 30% шанс
+		if b.World.Rand().Float64() < 0.3 { // 30% шанс
 			for _, cell := range b.Player.Cells {
-				if cell.CanSplit() {
+				if cell.CanSplit(b.World.Config, b.World.Now()) {
 					return true
 				}
 			}
@@ -193,27 +258,43 @@ func (b *Bot) shouldSplit(center game.Vector2D, target game.Vector2D) bool {
 
 func (b *Bot) wanderRandomly(center game.Vector2D) {
 	// Движемся к случайной точке недалеко от текущей позиции
-	angle := b.rand.Float64() * 2 * math.Pi
-	distance := 200.0 + b.rand.Float64()*300.0
+	r := b.World.Rand()
+	angle := r.Float64() * 2 * math.Pi
+	distance := 200.0 + r.Float64()*300.0
 	
 	targetX := center.X + math.Cos(angle)*distance
 	targetY := center.Y + math.Sin(angle)*distance
 	
 	// Ограничиваем мир
-	targetX = math.Max(50, math.Min(game.WorldWidth-50, targetX))
-	targetY = math.Max(50, math.Min(game.WorldHeight-50, targetY))
+	targetX = math.Max(50, math.Min(b.World.Config.WorldWidth-50, targetX))
+	targetY = math.Max(50, math.Min(b.World.Config.WorldHeight-50, targetY))
 	
-	b.Player.SetTarget(targetX, targetY)
+	b.Player.SetTarget(targetX, targetY, b.World.Now())
 	// Позиция обновляется через state delta, события не нужны
 }
 
-func randomColor() string {
+// massCenter - центр масс клеток (БЕЗ ЛОКОВ)
+func massCenter(cells []*game.Cell) game.Vector2D {
+	center := game.Vector2D{X: 0, Y: 0}
+	totalMass := 0.0
+	for _, cell := range cells {
+		mass := cell.Mass()
+		center = center.Add(cell.Position.Mul(mass))
+		totalMass += mass
+	}
+	if totalMass == 0 {
+		return center
+	}
+	return center.Mul(1 / totalMass)
+}
+
+func randomColor(r *rand.Rand) string {
 	colors := []string{
 		"#FF6B6B", "#4ECDC4", "#45B7D1", "#FFA07A",
 		"#98D8C8", "#F7DC6F", "#BB8FCE", "#85C1E2",
 		"#F8B739", "#52BE80", "#EC7063", "#5DADE2",
 	}
-	return colors[rand.Intn(len(colors))]
+	return colors[r.Intn(len(colors))]
 }
 
 // BotManager - управление ботами
@@ -222,7 +303,7 @@ type BotManager struct {
 	World     *game.World
 	MaxBots   int
 	botNames  []string
-	nameIndex int
+	NameIndex int // Номер следующего имени бота
 }
 
 func NewBotManager(world *game.World, maxBots int) *BotManager {
@@ -236,15 +317,15 @@ func NewBotManager(world *game.World, maxBots int) *BotManager {
 			"BotIota", "BotKappa", "BotLambda", "BotMu",
 			"BotNu", "BotXi", "BotOmicron", "BotPi",
 		},
-		nameIndex: 0,
+		NameIndex: 0,
 	}
 }
 
 // SpawnBots - создание ботов (с локом для начальной инициализации)
 func (bm *BotManager) SpawnBots() {
 	for len(bm.Bots) < bm.MaxBots {
-		name := bm.botNames[bm.nameIndex%len(bm.botNames)]
-		bm.nameIndex++
+		name := bm.botNames[bm.NameIndex%len(bm.botNames)]
+		bm.NameIndex++
 		
 		bot := NewBot(name, bm.World)
 		bm.Bots = append(bm.Bots, bot)
@@ -254,22 +335,37 @@ func (bm *BotManager) SpawnBots() {
 // SpawnBotsUnlocked - создание ботов БЕЗ лока (когда world.Mu.Lock уже есть)
 func (bm *BotManager) SpawnBotsUnlocked() {
 	for len(bm.Bots) < bm.MaxBots {
-		name := bm.botNames[bm.nameIndex%len(bm.botNames)]
-		bm.nameIndex++
+		name := bm.botNames[bm.NameIndex%len(bm.botNames)]
+		bm.NameIndex++
 		
 		bot := NewBotUnlocked(name, bm.World)
 		bm.Bots = append(bm.Bots, bot)
 	}
 }
 
+// AdoptBotsUnlocked - управлять ботами, которые уже есть в мире (БЕЗ лока)
+// Вызывается после восстановления мира из сохранения
+func (bm *BotManager) AdoptBotsUnlocked() {
+	bm.Bots = bm.Bots[:0]
+	for _, player := range bm.World.Players {
+		if player.IsBot {
+			bm.Bots = append(bm.Bots, NewController(player, bm.World))
+		}
+	}
+	// Порядок обновления ботов не должен зависеть от порядка map
+	sort.Slice(bm.Bots, func(i, j int) bool {
+		return bm.Bots[i].Player.ID < bm.Bots[j].Player.ID
+	})
+}
+
 // Update - обновление ботов БЕЗ локов (вызывается когда world.Mu.Lock уже есть)
 func (bm *BotManager) Update() {
 	// Обновляем всех ботов
 	for i := len(bm.Bots) - 1; i >= 0; i-- {
 		bot := bm.Bots[i]
 		
-		if !bot.Player.IsAlive() {
-			// Удаляем мертвого бота
+		if !bot.Player.IsAlive() || bm.World.Players[bot.Player.ID] != bot.Player {
+			// Удаляем мертвого или убранного из мира бота
 			bm.Bots = append(bm.Bots[:i], bm.Bots[i+1:]...)
 			continue
 		}
@@ -278,5 +374,8 @@ func (bm *BotManager) Update() {
 	}
 	
 	// Пополняем ботов если их мало (БЕЗ лока - он уже есть!)
-	bm.SpawnBotsUnlocked()
+	// Во время матча вход закрыт и для ботов
+	if bm.World.JoinOpenUnlocked() {
+		bm.SpawnBotsUnlocked()
+	}
 }
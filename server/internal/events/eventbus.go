@@ -7,6 +7,7 @@ import (
 )
 
 // EventHandler - функция-обработчик события
+// Вызывается прямо в Publish, обычно под локом мира: долгую работу обработчик уносит в свою горутину
 type EventHandler func(*Event)
 
 // EventBus - шина событий
@@ -41,9 +42,9 @@ func (eb *EventBus) Publish(event *Event) {
 	handlers := eb.handlers[event.Type]
 	eb.mu.RUnlock()
 	
-	// Вызываем обработчики
+	// Вызываем обработчики в порядке публикации
 	for _, handler := range handlers {
-		go handler(event)
+		handler(event)
 	}
 	
 	// Добавляем в буфер для батчинга
@@ -79,6 +80,11 @@ func (eb *EventBus) FlushEvents() []*Event {
 
 // SerializeEvents - сериализовать события в JSON
 func (eb *EventBus) SerializeEvents(events []*Event) ([]byte, error) {
+	return SerializeBatch(events)
+}
+
+// SerializeBatch - event_batch сообщение в JSON (без EventBus, например из writePump клиента)
+func SerializeBatch(events []*Event) ([]byte, error) {
 	if len(events) == 0 {
 		return nil, nil
 	}
@@ -11,14 +11,40 @@ const (
 	EventPlayerSplit   EventType = "player_split"
 	EventPlayerEjected EventType = "player_ejected"
 	EventPlayerDied    EventType = "player_died"
+	EventPlayerLeft    EventType = "player_left"
 	
 	// События клеток
-	EventCellMerged  EventType = "cell_merged"
-	EventCellEaten   EventType = "cell_eaten"
+	EventCellMerged    EventType = "cell_merged"
+	EventCellEaten     EventType = "cell_eaten"
+	EventCellDissolved EventType = "cell_dissolved"
 	
 	// События еды
 	EventFoodSpawned EventType = "food_spawned"
 	EventFoodEaten   EventType = "food_eaten"
+	EventFoodPulled  EventType = "food_pulled"
+	
+	// События вирусов
+	EventVirusSpawned EventType = "virus_spawned"
+	EventVirusFed     EventType = "virus_fed"
+	EventVirusPopped  EventType = "virus_popped"
+	
+	// События бонусов
+	EventPowerUpSpawned   EventType = "powerup_spawned"
+	EventPowerUpCollected EventType = "powerup_collected"
+	EventPlayerEffects    EventType = "player_effects"
+	
+	// Область видимости клиента
+	EventEntityEntered EventType = "entity_entered"
+	EventEntityLeft    EventType = "entity_left"
+	
+	// Правила игры изменены на лету
+	EventConfigChanged EventType = "config_changed"
+	
+	// Этапы матча королевской битвы
+	EventMatchLobby     EventType = "match_lobby"
+	EventMatchCountdown EventType = "match_countdown"
+	EventMatchStarted   EventType = "match_started"
+	EventMatchEnded     EventType = "match_ended"
 	
 	// State updates
 	EventStateDelta    EventType = "state_delta" // НОВОЕ: delta updates
@@ -59,6 +85,11 @@ type StateDeltaEvent struct {
 	Tick      int64          `json:"tick"`
 	Timestamp int64          `json:"timestamp"`
 	Entities  []EntityDelta  `json:"entities"`
+	
+	// Подтверждение ввода конкретному клиенту (заполняется при рассылке)
+	AckSeq       uint32 `json:"ackSeq,omitempty"`       // Последний применённый seq
+	AckTimestamp int64  `json:"ackTimestamp,omitempty"` // Время клиента из этого ввода
+	AckTick      int64  `json:"ackTick,omitempty"`      // Тик, на котором ввод применён
 }
 
 // PlayerSplitEvent - игрок разделился
@@ -110,6 +141,12 @@ type FoodEatenEvent struct {
 	CellID   string `json:"cellId"`
 }
 
+// FoodPulledEvent - магнит игрока тянет еду (позиция и скорость, как у выброшенной еды)
+type FoodPulledEvent struct {
+	PlayerID string     `json:"playerId"`
+	Food     []FoodInfo `json:"food"`
+}
+
 // CellEatenEvent - клетка съедена
 type CellEatenEvent struct {
 	EatenCellID string `json:"eatenCellId"`
@@ -117,30 +154,187 @@ type CellEatenEvent struct {
 	EaterCellID string `json:"eaterCellId"`
 }
 
+// CellDissolvedEvent - клетка исчезла без съевшего её игрока
+type CellDissolvedEvent struct {
+	CellID   string `json:"cellId"`
+	PlayerID string `json:"playerId"`
+	Cause    string `json:"cause"` // DeathZone
+}
+
 // FoodSpawnedEvent - еда создана
 type FoodSpawnedEvent struct {
 	Foods []FoodInfo `json:"foods"`
 }
 
+// VirusSpawnedEvent - вирусы созданы (пополнение или отстрел)
+type VirusSpawnedEvent struct {
+	Viruses []VirusInfo `json:"viruses"`
+}
+
+type VirusInfo struct {
+	VirusID string  `json:"virusId"`
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	Radius  float64 `json:"radius"`
+	VelX    float64 `json:"velX"`
+	VelY    float64 `json:"velY"`
+}
+
+// VirusFedEvent - выброшенная масса попала в вирус
+type VirusFedEvent struct {
+	VirusID string  `json:"virusId"`
+	FoodID  string  `json:"foodId"`
+	Radius  float64 `json:"radius"`
+}
+
+// VirusPoppedEvent - большая клетка съела вирус и разорвалась
+type VirusPoppedEvent struct {
+	VirusID  string     `json:"virusId"`
+	PlayerID string     `json:"playerId"`
+	CellID   string     `json:"cellId"`
+	Radius   float64    `json:"radius"` // Новый радиус разорванной клетки
+	NewCells []CellInfo `json:"newCells"`
+}
+
+// PowerUpSpawnedEvent - бонусы появились на карте
+type PowerUpSpawnedEvent struct {
+	PowerUps []PowerUpInfo `json:"powerUps"`
+}
+
+type PowerUpInfo struct {
+	PowerUpID string  `json:"powerUpId"`
+	Kind      string  `json:"kind"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	Radius    float64 `json:"radius"`
+}
+
+// PowerUpCollectedEvent - клетка подобрала бонус
+type PowerUpCollectedEvent struct {
+	PowerUpID string  `json:"powerUpId"`
+	PlayerID  string  `json:"playerId"`
+	CellID    string  `json:"cellId"`
+	Kind      string  `json:"kind"`
+	Duration  float64 `json:"duration"` // Секунды действия эффекта
+}
+
+// PlayerEffectsEvent - набор действующих эффектов игрока изменился
+type PlayerEffectsEvent struct {
+	PlayerID string       `json:"playerId"`
+	Effects  []EffectInfo `json:"effects"` // Пусто - эффектов больше нет
+}
+
+type EffectInfo struct {
+	Kind      string  `json:"kind"`
+	Remaining float64 `json:"remaining"` // Секунды до окончания
+}
+
+// Причины гибели игрока (PlayerDiedEvent.Cause)
+const (
+	DeathEaten = "eaten" // последнюю клетку съел другой игрок
+	DeathZone  = "zone"  // последняя клетка растворилась вне зоны королевской битвы
+)
+
 // PlayerDiedEvent - игрок умер
 type PlayerDiedEvent struct {
-	PlayerID string `json:"playerId"`
+	PlayerID   string  `json:"playerId"`
+	Name       string  `json:"name"`
+	IsBot      bool    `json:"isBot"`
+	Cause      string  `json:"cause,omitempty"` // DeathEaten, DeathZone; пусто - причина неизвестна
+	KillerID   string  `json:"killerId,omitempty"` // Пусто - умер не от игрока
+	KillerName string  `json:"killerName,omitempty"`
+	FinalMass  float64 `json:"finalMass"`
+	PeakMass   float64 `json:"peakMass"`
+	TimeAlive  float64 `json:"timeAlive"` // Секунды
+	Kills      int     `json:"kills"`
+}
+
+// Причины ухода игрока из мира без гибели (PlayerLeftEvent.Cause)
+const (
+	LeaveQuit     = "quit"     // клиент отключился без ожидания переподключения или ушёл в другую комнату
+	LeaveExpired  = "expired"  // клиент не переподключился за ReconnectGrace
+	LeaveSpectate = "spectate" // игрок перешёл в зрители
+	LeaveKicked   = "kicked"   // игрока убрал администратор
+	LeaveRestore  = "restore"  // мир загружен из сохранения, где игрока нет
+)
+
+// PlayerLeftEvent - игрок ушёл из мира живым
+type PlayerLeftEvent struct {
+	PlayerID  string  `json:"playerId"`
+	Name      string  `json:"name"`
+	IsBot     bool    `json:"isBot"`
+	Cause     string  `json:"cause"`
+	FinalMass float64 `json:"finalMass"`
+	PeakMass  float64 `json:"peakMass"`
+	TimeAlive float64 `json:"timeAlive"` // Секунды
+	Kills     int     `json:"kills"`
+}
+
+// EntityEnteredEvent - entity вошли в область видимости клиента
+type EntityEnteredEvent struct {
+	Cells    []EnteredCell `json:"cells,omitempty"`
+	Food     []FoodInfo    `json:"food,omitempty"`
+	Viruses  []VirusInfo   `json:"viruses,omitempty"`
+	PowerUps []PowerUpInfo `json:"powerUps,omitempty"`
+}
+
+type EnteredCell struct {
+	PlayerID string  `json:"playerId"`
+	Name     string  `json:"name"`
+	Color    string  `json:"color"`
+	IsBot    bool    `json:"isBot"`
+	CellID   string  `json:"cellId"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Radius   float64 `json:"radius"`
+}
+
+// EntityLeftEvent - entity покинули область видимости клиента
+type EntityLeftEvent struct {
+	IDs []string `json:"ids"`
+}
+
+// ConfigChangedEvent - правила игры изменены через админку
+type ConfigChangedEvent struct {
+	Changes []ConfigChange `json:"changes"`
+	Config  interface{}    `json:"config"` // Полный набор новых правил
+}
+
+type ConfigChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// MatchEvent - матч королевской битвы перешёл на новый этап
+type MatchEvent struct {
+	Match      int     `json:"match"`
+	Phase      string  `json:"phase"`
+	Players    int     `json:"players"`           // Живых участников
+	Seconds    float64 `json:"seconds,omitempty"` // Длительность отсчёта или сыгранного матча
+	WinnerID   string  `json:"winnerId,omitempty"`
+	WinnerName string  `json:"winnerName,omitempty"`
+	WinnerTeam int     `json:"winnerTeam,omitempty"`
 }
 
 // WorldSnapshotEvent - полный снимок мира для синхронизации
 type WorldSnapshotEvent struct {
+	Tick      int64          `json:"tick"`
 	Timestamp int64          `json:"timestamp"`
 	Players   []PlayerState  `json:"players"`
 	Food      []FoodState    `json:"food"`
+	Viruses   []VirusState   `json:"viruses"`
+	PowerUps  []PowerUpState `json:"powerUps"`
 }
 
 type PlayerState struct {
-	ID    string      `json:"id"`
-	Name  string      `json:"name"`
-	Color string      `json:"color"`
-	IsBot bool        `json:"isBot"`
-	Score int         `json:"score"`
-	Cells []CellState `json:"cells"`
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Color   string       `json:"color"`
+	IsBot   bool         `json:"isBot"`
+	Score   int          `json:"score"`
+	Cells   []CellState  `json:"cells"`
+	Effects []EffectInfo `json:"effects,omitempty"` // Действующие эффекты бонусов
 }
 
 type CellState struct {
@@ -158,6 +352,21 @@ type FoodState struct {
 	Color  string  `json:"color"`
 }
 
+type VirusState struct {
+	ID     string  `json:"id"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Radius float64 `json:"radius"`
+}
+
+type PowerUpState struct {
+	ID     string  `json:"id"`
+	Kind   string  `json:"kind"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Radius float64 `json:"radius"`
+}
+
 // NewEvent - создать событие
 func NewEvent(eventType EventType, data interface{}) *Event {
 	return &Event{
@@ -0,0 +1,316 @@
+package game
+
+import (
+	"agario-server/internal/events"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GameConfig - правила игры, которые можно менять без перекомпиляции
+type GameConfig struct {
+	// Мир
+	WorldWidth     float64 `json:"worldWidth" yaml:"worldWidth"`
+	WorldHeight    float64 `json:"worldHeight" yaml:"worldHeight"`
+	MaxFoodCount   int     `json:"maxFoodCount" yaml:"maxFoodCount"`
+	PlayerMaxCells int     `json:"playerMaxCells" yaml:"playerMaxCells"`
+
+	// Карта (nil - пустой мир); в файле правил - прямо в map или путём в mapFile
+	Map     *MapLayout `json:"map,omitempty" yaml:"map"`
+	MapFile string     `json:"mapFile,omitempty" yaml:"mapFile"` // путь от файла правил; при загрузке читается в Map
+
+	// Физика
+	BaseSpeed        float64 `json:"baseSpeed" yaml:"baseSpeed"`               // базовая скорость
+	SpeedDecay       float64 `json:"speedDecay" yaml:"speedDecay"`             // замедление от массы
+	SplitCooldown    float64 `json:"splitCooldown" yaml:"splitCooldown"`       // секунды до следующего сплита
+	MergeCooldown    float64 `json:"mergeCooldown" yaml:"mergeCooldown"`       // секунды до слияния клеток
+	SplitMinMass     float64 `json:"splitMinMass" yaml:"splitMinMass"`         // минимальная масса для сплита
+	SplitImpulse     float64 `json:"splitImpulse" yaml:"splitImpulse"`         // начальная скорость отделившейся клетки
+	CellFriction     float64 `json:"cellFriction" yaml:"cellFriction"`         // затухание импульса клетки за тик
+	EjectMass        float64 `json:"ejectMass" yaml:"ejectMass"`               // масса выброшенной еды
+	EjectSpeed       float64 `json:"ejectSpeed" yaml:"ejectSpeed"`             // скорость выброса
+	SelfPushStrength float64 `json:"selfPushStrength" yaml:"selfPushStrength"` // доля перекрытия своих клеток, разрешаемая за тик
+	MergeAttraction  float64 `json:"mergeAttraction" yaml:"mergeAttraction"`   // скорость сближения клеток, готовых к слиянию
+
+	// Деградация массы больших клеток
+	DecaySafeMass          float64 `json:"decaySafeMass" yaml:"decaySafeMass"`                   // ниже этой массы деградации нет
+	DecayLinearFactor      float64 `json:"decayLinearFactor" yaml:"decayLinearFactor"`           // линейная часть
+	DecayExponentialFactor float64 `json:"decayExponentialFactor" yaml:"decayExponentialFactor"` // квадратичная часть для очень больших клеток
+
+	// Вирусы
+	VirusCount        int     `json:"virusCount" yaml:"virusCount"`
+	VirusMass         float64 `json:"virusMass" yaml:"virusMass"`
+	VirusFeedMass     float64 `json:"virusFeedMass" yaml:"virusFeedMass"`
+	VirusFeedLimit    int     `json:"virusFeedLimit" yaml:"virusFeedLimit"`
+	VirusShootSpeed   float64 `json:"virusShootSpeed" yaml:"virusShootSpeed"`
+	VirusPieceMinMass float64 `json:"virusPieceMinMass" yaml:"virusPieceMinMass"`
+
+	// Геймплей
+	MassToEat     float64 `json:"massToEat" yaml:"massToEat"`         // во сколько раз нужно быть больше чтобы съесть
+	DeltaInterval int     `json:"deltaInterval" yaml:"deltaInterval"` // state delta каждые N тиков
+	Teams         int     `json:"teams" yaml:"teams"`                 // 0 - каждый сам за себя, 2-4 - командный режим
+	Mode          string  `json:"mode" yaml:"mode"`                   // ffa - бесконечная игра, battle_royale - матчи с сужающейся зоной
+
+	// Королевская битва
+	MatchMinPlayers int     `json:"matchMinPlayers" yaml:"matchMinPlayers"` // живых людей для начала отсчёта
+	MatchCountdown  float64 `json:"matchCountdown" yaml:"matchCountdown"`   // секунды отсчёта перед стартом
+	MatchEndDelay   float64 `json:"matchEndDelay" yaml:"matchEndDelay"`     // секунды показа победителя до нового лобби
+	ZoneShrinkTime  float64 `json:"zoneShrinkTime" yaml:"zoneShrinkTime"`   // секунды сужения зоны до zoneMinRadius
+	ZoneMinRadius   float64 `json:"zoneMinRadius" yaml:"zoneMinRadius"`     // радиус зоны в конце сужения
+	ZoneDrain       float64 `json:"zoneDrain" yaml:"zoneDrain"`             // доля массы в секунду, которую теряет клетка вне зоны
+
+	// Бонусы
+	PowerUpCount        int     `json:"powerUpCount" yaml:"powerUpCount"`               // бонусов на карте одновременно (0 - бонусов нет)
+	PowerUpInterval     float64 `json:"powerUpInterval" yaml:"powerUpInterval"`         // секунды между появлениями бонусов
+	PowerUpDuration     float64 `json:"powerUpDuration" yaml:"powerUpDuration"`         // секунды действия эффекта
+	PowerUpSpeedBoost   float64 `json:"powerUpSpeedBoost" yaml:"powerUpSpeedBoost"`     // множитель скорости ускорения
+	PowerUpMagnetRadius float64 `json:"powerUpMagnetRadius" yaml:"powerUpMagnetRadius"` // на сколько дальше края клетки магнит притягивает еду
+
+	// Сессии
+	ReconnectGrace float64 `json:"reconnectGrace" yaml:"reconnectGrace"` // секунды, которые клетки отключившегося игрока ждут переподключения
+}
+
+// DefaultGameConfig - стандартные правила
+func DefaultGameConfig() *GameConfig {
+	return &GameConfig{
+		WorldWidth:     5000.0,
+		WorldHeight:    5000.0,
+		MaxFoodCount:   3000,
+		PlayerMaxCells: 16,
+
+		BaseSpeed:        600.0, // x3
+		SpeedDecay:       0.3,
+		SplitCooldown:    0.5, // было 1.0
+		MergeCooldown:    15.0,
+		SplitMinMass:     20.0, // уменьшили с 40 до 20
+		SplitImpulse:     800.0,
+		CellFriction:     0.9,
+		EjectMass:        12.0,
+		EjectSpeed:       1200.0, // x3
+		SelfPushStrength: 0.5,
+		MergeAttraction:  120.0,
+
+		DecaySafeMass:          100.0,
+		DecayLinearFactor:      0.0002,
+		DecayExponentialFactor: 0.000005,
+
+		VirusCount:        30,
+		VirusMass:         100.0,
+		VirusFeedMass:     14.0,
+		VirusFeedLimit:    7,
+		VirusShootSpeed:   1100.0,
+		VirusPieceMinMass: 16.0,
+
+		MassToEat:     1.15, // нужно быть на 15% больше
+		DeltaInterval: 3,    // 10 раз/сек
+		Mode:          ModeFFA,
+
+		MatchMinPlayers: 2,
+		MatchCountdown:  10.0,
+		MatchEndDelay:   10.0,
+		ZoneShrinkTime:  180.0,
+		ZoneMinRadius:   300.0,
+		ZoneDrain:       0.1,
+
+		PowerUpCount:        8,
+		PowerUpInterval:     10.0,
+		PowerUpDuration:     10.0,
+		PowerUpSpeedBoost:   1.5,
+		PowerUpMagnetRadius: 150.0,
+
+		ReconnectGrace: 30.0,
+	}
+}
+
+// LoadGameConfig - загрузить правила из YAML или JSON файла
+// Отсутствующие в файле поля берутся из DefaultGameConfig
+func LoadGameConfig(path string) (*GameConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read game config: %w", err)
+	}
+
+	cfg := DefaultGameConfig()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	default:
+		err = json.Unmarshal(data, cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse game config %s: %w", path, err)
+	}
+
+	if cfg.MapFile != "" {
+		if cfg.Map != nil {
+			return nil, fmt.Errorf("invalid game config %s: map and mapFile are mutually exclusive", path)
+		}
+		mapPath := cfg.MapFile
+		if !filepath.IsAbs(mapPath) {
+			mapPath = filepath.Join(filepath.Dir(path), mapPath)
+		}
+		if cfg.Map, err = LoadMapLayout(mapPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid game config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Clone - независимая копия правил
+func (c *GameConfig) Clone() *GameConfig {
+	clone := *c
+	return &clone
+}
+
+// Validate - проверка значений на допустимость
+func (c *GameConfig) Validate() error {
+	var errs []error
+	check := func(ok bool, field string, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: "+format, append([]interface{}{field}, args...)...))
+		}
+	}
+
+	check(c.WorldWidth >= StartRadius*4, "worldWidth", "must be at least %v", StartRadius*4)
+	check(c.WorldHeight >= StartRadius*4, "worldHeight", "must be at least %v", StartRadius*4)
+	check(c.MaxFoodCount >= 0, "maxFoodCount", "must not be negative")
+	check(c.PlayerMaxCells >= 1 && c.PlayerMaxCells <= 64, "playerMaxCells", "must be between 1 and 64")
+	if c.Map != nil {
+		errs = append(errs, c.Map.Validate(c.WorldWidth, c.WorldHeight))
+	}
+
+	check(c.BaseSpeed > 0, "baseSpeed", "must be positive")
+	check(c.SpeedDecay >= 0 && c.SpeedDecay <= 1, "speedDecay", "must be between 0 and 1")
+	check(c.SplitCooldown >= 0, "splitCooldown", "must not be negative")
+	check(c.MergeCooldown >= 0, "mergeCooldown", "must not be negative")
+	check(c.SplitMinMass > 0, "splitMinMass", "must be positive")
+	check(c.SplitImpulse >= 0, "splitImpulse", "must not be negative")
+	check(c.CellFriction >= 0 && c.CellFriction < 1, "cellFriction", "must be in [0, 1)")
+	check(c.EjectMass > 0, "ejectMass", "must be positive")
+	check(c.EjectSpeed >= 0, "ejectSpeed", "must not be negative")
+	check(c.SelfPushStrength >= 0 && c.SelfPushStrength <= 1, "selfPushStrength", "must be between 0 and 1")
+	check(c.MergeAttraction >= 0, "mergeAttraction", "must not be negative")
+
+	check(c.DecaySafeMass >= 0, "decaySafeMass", "must not be negative")
+	check(c.DecayLinearFactor >= 0, "decayLinearFactor", "must not be negative")
+	check(c.DecayExponentialFactor >= 0, "decayExponentialFactor", "must not be negative")
+
+	check(c.VirusCount >= 0, "virusCount", "must not be negative")
+	check(c.VirusMass > 0, "virusMass", "must be positive")
+	check(c.VirusFeedMass >= 0, "virusFeedMass", "must not be negative")
+	check(c.VirusFeedLimit >= 1, "virusFeedLimit", "must be at least 1")
+	check(c.VirusShootSpeed >= 0, "virusShootSpeed", "must not be negative")
+	check(c.VirusPieceMinMass > 0, "virusPieceMinMass", "must be positive")
+
+	check(c.MassToEat >= 1, "massToEat", "must be at least 1")
+	check(c.DeltaInterval >= 1, "deltaInterval", "must be at least 1")
+	check(c.Teams == 0 || (c.Teams >= 2 && c.Teams <= MaxTeams), "teams", "must be 0 or between 2 and %d", MaxTeams)
+	check(c.Mode == "" || c.Mode == ModeFFA || c.Mode == ModeBattleRoyale, "mode", "must be %q or %q", ModeFFA, ModeBattleRoyale)
+
+	// Правила матча проверяются только там, где они действуют: в режиме ffa нули допустимы
+	if c.BattleRoyale() {
+		check(c.MatchMinPlayers >= 1, "matchMinPlayers", "must be at least 1")
+		check(c.MatchCountdown >= 0, "matchCountdown", "must not be negative")
+		check(c.MatchEndDelay >= 0, "matchEndDelay", "must not be negative")
+		check(c.ZoneShrinkTime > 0, "zoneShrinkTime", "must be positive")
+		check(c.ZoneMinRadius >= 0, "zoneMinRadius", "must not be negative")
+		check(c.ZoneDrain >= 0 && c.ZoneDrain <= 1, "zoneDrain", "must be between 0 and 1")
+	}
+
+	// Без бонусов (powerUpCount: 0) остальные их правила не проверяются
+	check(c.PowerUpCount >= 0, "powerUpCount", "must not be negative")
+	if c.PowerUpCount > 0 {
+		check(c.PowerUpInterval >= 0, "powerUpInterval", "must not be negative")
+		check(c.PowerUpDuration > 0, "powerUpDuration", "must be positive")
+		check(c.PowerUpSpeedBoost >= 1, "powerUpSpeedBoost", "must be at least 1")
+		check(c.PowerUpMagnetRadius >= 0, "powerUpMagnetRadius", "must not be negative")
+	}
+
+	check(c.ReconnectGrace >= 0, "reconnectGrace", "must not be negative")
+
+	return errors.Join(errs...)
+}
+
+// ErrWorldShrink - уменьшать мир на лету нельзя (клетки и еда остались бы за границей)
+var ErrWorldShrink = errors.New("world size can only grow at runtime")
+
+// ErrTeamsChange - команды назначаются при входе, поэтому режим задаётся при создании мира
+var ErrTeamsChange = errors.New("teams cannot change at runtime")
+
+// ErrModeChange - матч идёт по правилам режима, поэтому режим задаётся при создании мира
+var ErrModeChange = errors.New("mode cannot change at runtime")
+
+// ErrMapChange - стены и точки появления задаются при создании мира
+var ErrMapChange = errors.New("map cannot change at runtime")
+
+// BattleRoyale - мир играет матчами королевской битвы
+func (c *GameConfig) BattleRoyale() bool {
+	return c.Mode == ModeBattleRoyale
+}
+
+// PatchGameConfig - применить частичный JSON поверх копии правил и проверить результат
+func PatchGameConfig(current *GameConfig, patch []byte) (*GameConfig, error) {
+	next := current.Clone()
+	next.Map = nil // Иначе JSON запишется в карту, общую с current
+
+	decoder := json.NewDecoder(bytes.NewReader(patch))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(next); err != nil {
+		return nil, fmt.Errorf("parse config patch: %w", err)
+	}
+
+	if next.WorldWidth < current.WorldWidth || next.WorldHeight < current.WorldHeight {
+		return nil, ErrWorldShrink
+	}
+	if next.Teams != current.Teams {
+		return nil, ErrTeamsChange
+	}
+	if next.BattleRoyale() != current.BattleRoyale() {
+		return nil, ErrModeChange
+	}
+	// Админка присылает правила целиком, вместе с картой: такая же карта - не изменение
+	if next.MapFile != current.MapFile || (next.Map != nil && !reflect.DeepEqual(next.Map, current.Map)) {
+		return nil, ErrMapChange
+	}
+	next.Map = current.Map
+	if err := next.Validate(); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// DiffGameConfig - список изменившихся полей (имена как в JSON)
+func DiffGameConfig(old, new *GameConfig) []events.ConfigChange {
+	changes := []events.ConfigChange{}
+
+	oldValue := reflect.ValueOf(old).Elem()
+	newValue := reflect.ValueOf(new).Elem()
+	fields := oldValue.Type()
+
+	for i := 0; i < fields.NumField(); i++ {
+		a := oldValue.Field(i).Interface()
+		b := newValue.Field(i).Interface()
+		if a == b {
+			continue
+		}
+
+		name := strings.Split(fields.Field(i).Tag.Get("json"), ",")[0]
+		changes = append(changes, events.ConfigChange{
+			Field: name,
+			Old:   a,
+			New:   b,
+		})
+	}
+	return changes
+}
@@ -0,0 +1,147 @@
+package game
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPatchGameConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		current func(cfg *GameConfig)
+		patch   string
+		err     error    // ожидаемая ошибка правил на лету
+		invalid []string // поля, которые не прошли Validate
+		changed []string
+	}{
+		{name: "empty patch", patch: `{}`, changed: []string{}},
+		{name: "same value", patch: `{"baseSpeed": 500}`, changed: []string{}},
+		{name: "one field", patch: `{"maxFoodCount": 10}`, changed: []string{"maxFoodCount"}},
+		{
+			name:    "several fields in struct order",
+			patch:   `{"virusCount": 3, "baseSpeed": 400, "deltaInterval": 2}`,
+			changed: []string{"baseSpeed", "virusCount", "deltaInterval"},
+		},
+		{name: "world grows", patch: `{"worldWidth": 6000}`, changed: []string{"worldWidth"}},
+		{name: "world shrinks", patch: `{"worldHeight": 1000}`, err: ErrWorldShrink},
+		{name: "teams enabled", patch: `{"teams": 2}`, err: ErrTeamsChange},
+		{name: "mode switched", patch: `{"mode": "battle_royale"}`, err: ErrModeChange},
+		{
+			name:    "ffa alias of empty mode",
+			current: func(cfg *GameConfig) { cfg.Mode = "" },
+			patch:   `{"mode": "ffa"}`,
+			changed: []string{"mode"},
+		},
+		{name: "inline map", patch: `{"map": {"walls": []}}`, err: ErrMapChange},
+		{
+			name:    "unchanged map sent back",
+			current: func(cfg *GameConfig) { cfg.Map = &MapLayout{Name: "arena", SpawnPoints: []Vector2D{{X: 10, Y: 10}}} },
+			patch:   `{"map": {"name": "arena", "spawnPoints": [{"x": 10, "y": 10}]}, "maxFoodCount": 10}`,
+			changed: []string{"maxFoodCount"},
+		},
+		{
+			name:    "edited map sent back",
+			current: func(cfg *GameConfig) { cfg.Map = &MapLayout{Name: "arena", SpawnPoints: []Vector2D{{X: 10, Y: 10}}} },
+			patch:   `{"map": {"name": "arena", "spawnPoints": [{"x": 20, "y": 10}]}}`,
+			err:     ErrMapChange,
+		},
+		{name: "map file", patch: `{"mapFile": "arena.yaml"}`, err: ErrMapChange},
+		{name: "invalid value", patch: `{"cellFriction": 1.5}`, invalid: []string{"cellFriction"}},
+		{name: "several invalid values", patch: `{"massToEat": 0.5, "deltaInterval": 0}`, invalid: []string{"massToEat", "deltaInterval"}},
+		{name: "unknown field", patch: `{"baseSpeeed": 400}`, invalid: []string{"unknown field"}},
+		{name: "wrong type", patch: `{"baseSpeed": "fast"}`, invalid: []string{"baseSpeed"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := DefaultGameConfig()
+			current.BaseSpeed = 500
+			if tt.current != nil {
+				tt.current(current)
+			}
+			before := *current
+
+			next, err := PatchGameConfig(current, []byte(tt.patch))
+			if !reflect.DeepEqual(*current, before) {
+				t.Fatal("patch modified the current config")
+			}
+
+			switch {
+			case tt.err != nil:
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error %v, want %v", err, tt.err)
+				}
+				return
+			case tt.invalid != nil:
+				if err == nil {
+					t.Fatalf("patch accepted, want errors about %v", tt.invalid)
+				}
+				for _, field := range tt.invalid {
+					if !strings.Contains(err.Error(), field) {
+						t.Fatalf("error %v, want one about %s", err, field)
+					}
+				}
+				return
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			fields := []string{}
+			for _, change := range DiffGameConfig(current, next) {
+				fields = append(fields, change.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.changed) {
+				t.Fatalf("changed fields %v, want %v", fields, tt.changed)
+			}
+		})
+	}
+}
+
+func TestDiffGameConfigValues(t *testing.T) {
+	old := DefaultGameConfig()
+	next := old.Clone()
+	next.MaxFoodCount = old.MaxFoodCount + 1
+	next.SplitImpulse = old.SplitImpulse / 2
+
+	changes := DiffGameConfig(old, next)
+	if len(changes) != 2 {
+		t.Fatalf("changes %+v, want 2", changes)
+	}
+	if c := changes[0]; c.Field != "maxFoodCount" || c.Old != old.MaxFoodCount || c.New != next.MaxFoodCount {
+		t.Fatalf("first change %+v, want maxFoodCount %d -> %d", c, old.MaxFoodCount, next.MaxFoodCount)
+	}
+	if c := changes[1]; c.Field != "splitImpulse" || c.Old != old.SplitImpulse || c.New != next.SplitImpulse {
+		t.Fatalf("second change %+v, want splitImpulse %v -> %v", c, old.SplitImpulse, next.SplitImpulse)
+	}
+}
+
+func TestApplyConfigResizesWorld(t *testing.T) {
+	w := NewWorld(DefaultGameConfig())
+	next, err := PatchGameConfig(w.Config, []byte(`{"worldWidth": 8000, "worldHeight": 8000}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.EventBus.FlushEvents()
+
+	changes := w.ApplyConfigUnlocked(next)
+	if len(changes) != 2 || w.Config != next {
+		t.Fatalf("changes %+v, config replaced %v", changes, w.Config == next)
+	}
+	if published := w.EventBus.FlushEvents(); len(published) != 1 {
+		t.Fatalf("%d events published, want one config_changed", len(published))
+	}
+
+	// Еда в новой части мира находится через сетку
+	food := NewFood("far", Vector2D{X: 7500, Y: 7500}, "#000000", w.Now())
+	w.addFood(food)
+	if found := w.foodGrid.QueryRadius(food.Position, 10, nil); len(found) != 1 {
+		t.Fatalf("food in the grown area not found: %d results", len(found))
+	}
+
+	// Повторное применение тех же правил ничего не публикует
+	if changes := w.ApplyConfigUnlocked(next.Clone()); len(changes) != 0 {
+		t.Fatalf("reapplying the same config reported %+v", changes)
+	}
+}
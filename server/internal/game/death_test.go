@@ -0,0 +1,77 @@
+package game
+
+import (
+	"math"
+	"testing"
+
+	"agario-server/internal/events"
+)
+
+func TestDeathAttribution(t *testing.T) {
+	tests := []struct {
+		name        string
+		victimCells int  // клеток у жертвы; под убийцей только первая
+		victimFirst bool // жертва передаётся в checkPlayerCollision первой
+		died        bool
+	}{
+		{name: "last cell eaten", victimCells: 1, died: true},
+		{name: "last cell eaten from the other side", victimCells: 1, victimFirst: true, died: true},
+		{name: "one of two cells eaten", victimCells: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWorld(DefaultGameConfig())
+			center := Vector2D{X: w.Config.WorldWidth / 2, Y: w.Config.WorldHeight / 2}
+
+			killer := w.AddPlayerUnlocked("killer", "#FF0000", false)
+			killer.Cells[0].Position = center
+			killer.Cells[0].SetMass(400)
+			w.cellGrid.Update(killer.Cells[0].ID)
+
+			victim := w.AddPlayerUnlocked("victim", "#00FF00", false)
+			victim.Cells[0].Position = center
+			victim.Cells[0].SetMass(50)
+			w.cellGrid.Update(victim.Cells[0].ID)
+			for len(victim.Cells) < tt.victimCells {
+				far := NewCell(w.newID(), Vector2D{X: 100, Y: 100}, StartRadius, w.Now())
+				victim.Cells = append(victim.Cells, far)
+				w.cellGrid.InsertCell(victim, far)
+			}
+			w.EventBus.FlushEvents()
+
+			if tt.victimFirst {
+				w.checkPlayerCollision(victim, killer)
+			} else {
+				w.checkPlayerCollision(killer, victim)
+			}
+			w.removeDeadPlayers()
+
+			var died *events.PlayerDiedEvent
+			for _, event := range w.EventBus.FlushEvents() {
+				if data, ok := event.Data.(*events.PlayerDiedEvent); ok {
+					died = data
+				}
+			}
+			if !tt.died {
+				if died != nil || killer.Kills != 0 || w.Players[victim.ID] == nil {
+					t.Fatalf("victim died %+v with %d cells left, killer kills %d", died, len(victim.Cells), killer.Kills)
+				}
+				return
+			}
+
+			if died == nil || died.PlayerID != victim.ID {
+				t.Fatalf("player_died %+v, want the victim", died)
+			}
+			if died.KillerID != killer.ID || died.KillerName != "killer" {
+				t.Fatalf("killed by %s (%q), want %s", died.KillerID, died.KillerName, killer.ID)
+			}
+			if math.Abs(died.FinalMass-50) > 0.01 || died.TimeAlive < 0 {
+				t.Fatalf("final mass %.2f after %.2fs, want 50", died.FinalMass, died.TimeAlive)
+			}
+			if killer.Kills != 1 {
+				t.Fatalf("killer has %d kills, want 1", killer.Kills)
+			}
+		})
+	}
+}
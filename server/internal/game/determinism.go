@@ -0,0 +1,149 @@
+package game
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Epoch - время нулевого тика детерминированного мира
+var Epoch = time.Unix(0, 0).UTC()
+
+// Deterministic - мир работает в детерминированном режиме
+func (w *World) Deterministic() bool {
+	return w.deterministic
+}
+
+// Seed - seed, которым последний раз инициализирован генератор мира
+func (w *World) Seed() int64 {
+	return w.seed
+}
+
+// Now - время мира (БЕЗ лока, мир уже залочен)
+// В детерминированном режиме это Epoch + CurrentTick тиков, иначе - часы сервера
+func (w *World) Now() time.Time {
+	if w.deterministic {
+		return Epoch.Add(time.Duration(w.CurrentTick) * TickDuration)
+	}
+	return time.Now()
+}
+
+// countingSource - источник случайных чисел, считающий выдачи
+// Состояние генератора - seed и число выдач: его можно сохранить,
+// не перезапуская генератор, и восстановить пропуском выдач
+type countingSource struct {
+	src   rand.Source64
+	draws uint64
+}
+
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{src: rand.NewSource(seed).(rand.Source64)}
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.draws = 0
+	s.src.Seed(seed)
+}
+
+// skip - пропустить n выдач (восстановление состояния из сохранения)
+func (s *countingSource) skip(n uint64) {
+	for i := uint64(0); i < n; i++ {
+		s.src.Uint64()
+	}
+	s.draws += n
+}
+
+// Rand - генератор мира (только под Lock мира)
+// Все случайные решения (в том числе ботов) берутся отсюда, чтобы мир
+// определялся одним seed
+func (w *World) Rand() *rand.Rand {
+	return w.rand
+}
+
+// reseed - перезапустить генератор мира с seed и пропустить draws выдач
+// Генератор меняется на месте: ссылки из Rand() остаются действительными
+func (w *World) reseed(seed int64, draws uint64) {
+	w.seed = seed
+	w.rand.Seed(seed)
+	w.source.skip(draws)
+}
+
+// newID - ID новой entity (в детерминированном режиме - UUID из генератора мира)
+// rand.Read не используется: он буферизует байты вне источника, и такое
+// состояние генератора нельзя сохранить
+func (w *World) newID() string {
+	if !w.deterministic {
+		return uuid.New().String()
+	}
+	var id uuid.UUID
+	binary.BigEndian.PutUint64(id[:8], w.rand.Uint64())
+	binary.BigEndian.PutUint64(id[8:], w.rand.Uint64())
+	id[6] = id[6]&0x0f | 0x40 // версия 4
+	id[8] = id[8]&0x3f | 0x80 // вариант RFC 4122
+	return id.String()
+}
+
+// spawnPosition - стартовая точка игрока: одна из точек появления карты или случайная
+func (w *World) spawnPosition() Vector2D {
+	if layout := w.Config.Map; layout != nil && len(layout.SpawnPoints) > 0 {
+		return layout.SpawnPoints[w.rand.Intn(len(layout.SpawnPoints))]
+	}
+	return w.freePoint(StartRadius, func() Vector2D {
+		x := MinCellRadius + math.Floor(w.rand.Float64()*math.Max(0, w.Config.WorldWidth-MinCellRadius*2))
+		y := MinCellRadius + math.Floor(w.rand.Float64()*math.Max(0, w.Config.WorldHeight-MinCellRadius*2))
+		return Vector2D{X: x, Y: y}
+	})
+}
+
+// eachPlayer, eachFood, eachVirus, eachPowerUp - обход entity мира
+// Порядок map случаен, поэтому в детерминированном режиме обход идёт по
+// возрастанию ID. Entity, удалённые во время обхода, пропускаются
+func (w *World) eachPlayer(fn func(*Player)) {
+	each(w.Players, w.deterministic, fn)
+}
+
+func (w *World) eachFood(fn func(*Food)) {
+	each(w.Food, w.deterministic, fn)
+}
+
+func (w *World) eachVirus(fn func(*Virus)) {
+	each(w.Viruses, w.deterministic, fn)
+}
+
+func (w *World) eachPowerUp(fn func(*PowerUp)) {
+	each(w.PowerUps, w.deterministic, fn)
+}
+
+func each[T any](m map[string]T, ordered bool, fn func(T)) {
+	if !ordered {
+		for _, v := range m {
+			fn(v)
+		}
+		return
+	}
+
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if v, exists := m[id]; exists {
+			fn(v)
+		}
+	}
+}
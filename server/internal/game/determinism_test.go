@@ -0,0 +1,142 @@
+package game_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"agario-server/internal/bot"
+	"agario-server/internal/events"
+	"agario-server/internal/game"
+)
+
+const determinismTicks = 900 // 30 секунд игры
+
+type runStats struct {
+	splits int
+	pops   int
+	ejects int
+	eaten  int
+	events [sha256.Size]byte // хеш всех событий по порядку (без времени публикации)
+}
+
+// runSeeded - детерминированный мир с ботами и двумя людьми, ввод которых задан скриптом
+// Тик устроен как Server.simulateUnlocked: ввод, Update, боты на тиках bot.UpdateInterval
+func runSeeded(t *testing.T, seed int64) ([]byte, runStats) {
+	t.Helper()
+	w := game.NewDeterministicWorld(nil, seed)
+	w.Mu.Lock()
+	defer w.Mu.Unlock()
+
+	bots := bot.NewBotManager(w, 8)
+	bots.SpawnBotsUnlocked()
+
+	// Большой игрок ходит по вирусам (разрывается), маленький делится и выбрасывает массу
+	big := w.AddPlayerUnlocked("big", "#FF0000", false)
+	big.Cells[0].SetMass(600)
+	small := w.AddPlayerUnlocked("small", "#00FF00", false)
+	small.Cells[0].SetMass(120)
+
+	script := rand.New(rand.NewSource(7)) // ввод людей одинаков для обоих миров
+	stats := runStats{}
+	stream := sha256.New()
+	for tick := 0; tick < determinismTicks; tick++ {
+		now := w.Now()
+		if w.Players[big.ID] == big && tick%15 == 0 {
+			if target, ok := nearestVirus(w, big); ok {
+				big.SetTarget(target.X, target.Y, now)
+			}
+		}
+		if w.Players[small.ID] == small {
+			if tick%20 == 0 {
+				small.SetTarget(script.Float64()*w.Config.WorldWidth, script.Float64()*w.Config.WorldHeight, now)
+			}
+			switch tick % 90 {
+			case 30:
+				w.SplitPlayerUnlocked(small)
+			case 60:
+				w.EjectPlayerUnlocked(small)
+			}
+		}
+
+		w.UpdateUnlocked(game.TickDuration.Seconds())
+		if w.CurrentTick%bot.UpdateInterval == 0 {
+			bots.Update()
+		}
+
+		for _, event := range w.EventBus.FlushEvents() {
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stream.Write([]byte(event.Type))
+			stream.Write(data)
+
+			switch event.Data.(type) {
+			case *events.PlayerSplitEvent:
+				stats.splits++
+			case *events.VirusPoppedEvent:
+				stats.pops++
+			case *events.PlayerEjectedEvent:
+				stats.ejects++
+			case *events.CellEatenEvent:
+				stats.eaten++
+			}
+		}
+	}
+
+	copy(stats.events[:], stream.Sum(nil))
+	state, err := json.Marshal(w.SaveStateUnlocked())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state, stats
+}
+
+func nearestVirus(w *game.World, player *game.Player) (game.Vector2D, bool) {
+	center := player.Cells[0].Position
+	best, found := game.Vector2D{}, false
+	for _, virus := range w.Viruses {
+		if !found || game.Distance(center, virus.Position) < game.Distance(center, best) {
+			best, found = virus.Position, true
+		}
+	}
+	return best, found
+}
+
+func TestDeterministicWorldsMatch(t *testing.T) {
+	first, stats := runSeeded(t, 42)
+	second, secondStats := runSeeded(t, 42)
+
+	// Прогон должен задействовать то, что чаще всего ломает детерминизм
+	if stats.splits == 0 || stats.pops == 0 || stats.ejects == 0 {
+		t.Fatalf("scenario too quiet: %d splits, %d virus pops, %d ejects", stats.splits, stats.pops, stats.ejects)
+	}
+	t.Logf("%d ticks: %d splits, %d virus pops, %d ejects, %d cells eaten, state %d bytes",
+		determinismTicks, stats.splits, stats.pops, stats.ejects, stats.eaten, len(first))
+
+	if !bytes.Equal(first, second) {
+		t.Fatalf("same seed and input, different state at byte %d", firstDiff(first, second))
+	}
+
+	// Порядок событий ловит обход map, который не успел изменить итоговое состояние
+	if stats.events != secondStats.events {
+		t.Fatal("same seed and input, different event stream")
+	}
+
+	other, _ := runSeeded(t, 43)
+	if bytes.Equal(first, other) {
+		t.Fatal("different seeds produced the same state")
+	}
+}
+
+func firstDiff(a, b []byte) int {
+	for i := range a {
+		if i >= len(b) || a[i] != b[i] {
+			return i
+		}
+	}
+	return len(a)
+}
@@ -4,33 +4,20 @@ import (
 	"math"
 	"sync"
 	"time"
-
-	"github.com/google/uuid"
 )
 
-// Игровые константы
+// Игровые константы (настраиваемые правила - в GameConfig)
 const (
-	WorldWidth  = 5000.0
-	WorldHeight = 5000.0
-
-	MinCellRadius  = 10.0
-	MaxCellRadius  = 2500.0 // x5
-	StartRadius    = 20.0
-	FoodRadius     = 5.0
-	MaxFoodCount   = 3000
-	PlayerMaxCells = 16
-
-	// Физика
-	BaseSpeed     = 600.0  // базовая скорость (x3)
-	SpeedDecay    = 0.3    // замедление от массы
-	SplitCooldown = 0.5    // секунды до следующего сплита (было 1.0)
-	MergeCooldown = 15.0   // секунды до слияния клеток
-	EjectMass     = 12.0   // масса выброшенной еды
-	EjectSpeed    = 1200.0 // скорость выброса (x3)
-
-	// Геймплей
-	MassToEat    = 1.15 // нужно быть на 15% больше чтобы съесть
-	TickRate     = 30   // обновлений в секунду
+	MinCellRadius = 10.0
+	MaxCellRadius = 2500.0 // x5
+	StartRadius   = 20.0
+	FoodRadius    = 5.0
+	PowerUpRadius = 15.0
+	MagnetSpeed   = 400.0 // скорость, с которой магнит тянет еду
+	GridCellSize  = 200.0 // размер бакета пространственного индекса
+	MinVelocity   = 0.1   // ниже этой скорости импульс обнуляется
+
+	TickRate     = 30 // обновлений в секунду
 	TickDuration = time.Second / TickRate
 )
 
@@ -80,14 +67,15 @@ type Cell struct {
 	LastMergeTime time.Time
 }
 
-func NewCell(pos Vector2D, radius float64) *Cell {
+// NewCell - клетка; ID и время выдаёт мир (World.newID, World.Now)
+func NewCell(id string, pos Vector2D, radius float64, now time.Time) *Cell {
 	return &Cell{
-		ID:            uuid.New().String(),
+		ID:            id,
 		Position:      pos,
 		Radius:        radius,
 		Velocity:      Vector2D{X: 0, Y: 0},
-		LastSplitTime: time.Now(),
-		LastMergeTime: time.Now(),
+		LastSplitTime: now,
+		LastMergeTime: now,
 	}
 }
 
@@ -105,16 +93,21 @@ func (c *Cell) SetMass(mass float64) {
 	}
 }
 
-func (c *Cell) Speed() float64 {
-	return BaseSpeed / math.Pow(c.Mass(), SpeedDecay)
+func (c *Cell) Speed(cfg *GameConfig) float64 {
+	return cfg.BaseSpeed / math.Pow(c.Mass(), cfg.SpeedDecay)
+}
+
+// IsLaunched - клетка ещё летит после сплита
+func (c *Cell) IsLaunched() bool {
+	return c.Velocity.Length() > MinVelocity
 }
 
-func (c *Cell) CanSplit() bool {
-	return time.Since(c.LastSplitTime).Seconds() >= SplitCooldown
+func (c *Cell) CanSplit(cfg *GameConfig, now time.Time) bool {
+	return now.Sub(c.LastSplitTime).Seconds() >= cfg.SplitCooldown
 }
 
-func (c *Cell) CanMerge() bool {
-	return time.Since(c.LastMergeTime).Seconds() >= MergeCooldown
+func (c *Cell) CanMerge(cfg *GameConfig, now time.Time) bool {
+	return now.Sub(c.LastMergeTime).Seconds() >= cfg.MergeCooldown
 }
 
 // Player - игрок
@@ -125,25 +118,35 @@ type Player struct {
 	Cells         []*Cell
 	TargetPos     Vector2D
 	IsBot         bool
+	Team          int // Команда с 1; 0 - без команды
 	LastInputTime time.Time
 	Mu            sync.RWMutex
-}
-
-func NewPlayer(name string, color string, isBot bool) *Player {
-	// Случайная стартовая позиция
-	startX := MinCellRadius + math.Floor(math.Max(0, math.Min(WorldWidth-MinCellRadius*2, float64(time.Now().UnixNano()%int64(WorldWidth)))))
-	startY := MinCellRadius + math.Floor(math.Max(0, math.Min(WorldHeight-MinCellRadius*2, float64((time.Now().UnixNano()/1000)%int64(WorldHeight)))))
 
-	startCell := NewCell(Vector2D{X: startX, Y: startY}, StartRadius)
+	// Статистика жизни игрока
+	SpawnedAt  time.Time
+	Kills      int     // Съеденные игроки
+	KilledBy   string  // Кто съел последнюю клетку
+	KillerName string
+	DeathCause string  // events.DeathEaten, events.DeathZone
+	FinalMass  float64 // Масса в момент гибели
+	PeakMass   float64 // Наибольшая масса за жизнь
+
+	// Действующие эффекты бонусов: вид -> время окончания
+	Effects map[PowerUpKind]time.Time
+}
 
+// NewPlayer - игрок из одной стартовой клетки (позицию выбирает мир)
+func NewPlayer(id string, name string, color string, isBot bool, startCell *Cell, now time.Time) *Player {
 	return &Player{
-		ID:            uuid.New().String(),
+		ID:            id,
 		Name:          name,
 		Color:         color,
 		Cells:         []*Cell{startCell},
-		TargetPos:     Vector2D{X: startX, Y: startY},
+		TargetPos:     startCell.Position,
 		IsBot:         isBot,
-		LastInputTime: time.Now(),
+		LastInputTime: now,
+		SpawnedAt:     now,
+		PeakMass:      startCell.Mass(),
 	}
 }
 
@@ -175,11 +178,19 @@ func (p *Player) IsAlive() bool {
 	return len(p.Cells) > 0
 }
 
-func (p *Player) SetTarget(x, y float64) {
+// SetTarget - новая цель движения (now - время мира, World.Now)
+func (p *Player) SetTarget(x, y float64, now time.Time) {
 	p.Mu.Lock()
 	defer p.Mu.Unlock()
 	p.TargetPos = Vector2D{X: x, Y: y}
-	p.LastInputTime = time.Now()
+	p.LastInputTime = now
+}
+
+// HasEffect - эффект бонуса действует в момент now
+// Без лока: вызывается под локом мира или игрока
+func (p *Player) HasEffect(kind PowerUpKind, now time.Time) bool {
+	until, ok := p.Effects[kind]
+	return ok && now.Before(until)
 }
 
 // Food - еда
@@ -191,31 +202,92 @@ type Food struct {
 	Mass      float64   // Масса которую даёт эта еда
 	Velocity  Vector2D  // Скорость движения (для выброшенной еды)
 	SpawnTime time.Time // Время создания (чтобы не съедали сразу)
+	Ejected   bool      // Выброшена игроком (кормит вирусы)
 }
 
-func NewFood(pos Vector2D, color string) *Food {
+func NewFood(id string, pos Vector2D, color string, now time.Time) *Food {
 	return &Food{
-		ID:        uuid.New().String(),
+		ID:        id,
 		Position:  pos,
 		Color:     color,
 		Radius:    FoodRadius,
 		Mass:      1.0,
 		Velocity:  Vector2D{X: 0, Y: 0},
-		SpawnTime: time.Now(),
+		SpawnTime: now,
 	}
 }
 
 // NewEjectedFood - создаёт выброшенную игроком еду
-func NewEjectedFood(pos Vector2D, color string, mass float64, velocity Vector2D) *Food {
+func NewEjectedFood(id string, pos Vector2D, color string, mass float64, velocity Vector2D, now time.Time) *Food {
 	// Радиус зависит от массы для визуального отличия
 	radius := FoodRadius * math.Sqrt(mass)
 	return &Food{
-		ID:        uuid.New().String(),
+		ID:        id,
 		Position:  pos,
 		Color:     color,
 		Radius:    radius,
 		Mass:      mass,
 		Velocity:  velocity,
-		SpawnTime: time.Now(),
+		SpawnTime: now,
+		Ejected:   true,
+	}
+}
+
+// Virus - вирус: разрывает большие клетки, маленькие могут за ним прятаться
+type Virus struct {
+	ID        string
+	Position  Vector2D
+	Radius    float64
+	Mass      float64
+	Velocity  Vector2D // Скорость отстреленного вируса
+	FedCount  int      // Сколько раз вирус покормили выбросом
+	SpawnTime time.Time
+}
+
+func NewVirus(id string, pos Vector2D, velocity Vector2D, mass float64, now time.Time) *Virus {
+	v := &Virus{
+		ID:        id,
+		Position:  pos,
+		Velocity:  velocity,
+		SpawnTime: now,
+	}
+	v.SetMass(mass)
+	return v
+}
+
+func (v *Virus) SetMass(mass float64) {
+	v.Mass = mass
+	v.Radius = math.Sqrt(mass * 100.0)
+}
+
+// PowerUpKind - вид бонуса
+type PowerUpKind string
+
+const (
+	PowerUpSpeed  PowerUpKind = "speed"  // клетки быстрее (PowerUpSpeedBoost)
+	PowerUpShield PowerUpKind = "shield" // клетки нельзя съесть
+	PowerUpMagnet PowerUpKind = "magnet" // еда притягивается издалека (PowerUpMagnetRadius)
+	PowerUpMerge  PowerUpKind = "merge"  // клетки сливаются без cooldown
+)
+
+// PowerUpKinds - все виды бонусов (случайный выбор при появлении)
+var PowerUpKinds = []PowerUpKind{PowerUpSpeed, PowerUpShield, PowerUpMagnet, PowerUpMerge}
+
+// PowerUp - бонус: подбирается касанием и даёт игроку эффект на PowerUpDuration
+type PowerUp struct {
+	ID        string
+	Kind      PowerUpKind
+	Position  Vector2D
+	Radius    float64
+	SpawnTime time.Time
+}
+
+func NewPowerUp(id string, kind PowerUpKind, pos Vector2D, now time.Time) *PowerUp {
+	return &PowerUp{
+		ID:        id,
+		Kind:      kind,
+		Position:  pos,
+		Radius:    PowerUpRadius,
+		SpawnTime: now,
 	}
 }
@@ -0,0 +1,264 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ShapeType - форма стены или зоны карты
+type ShapeType string
+
+const (
+	ShapeRect   ShapeType = "rect"   // X, Y - левый верхний угол, Width x Height
+	ShapeCircle ShapeType = "circle" // X, Y - центр, Radius
+)
+
+// Shape - прямоугольник или круг в координатах мира
+type Shape struct {
+	Type   ShapeType `json:"type" yaml:"type"`
+	X      float64   `json:"x" yaml:"x"`
+	Y      float64   `json:"y" yaml:"y"`
+	Width  float64   `json:"width,omitempty" yaml:"width"`
+	Height float64   `json:"height,omitempty" yaml:"height"`
+	Radius float64   `json:"radius,omitempty" yaml:"radius"`
+}
+
+// FoodRegion - область, богатая едой
+type FoodRegion struct {
+	Shape `yaml:",inline"`
+	Share float64 `json:"share" yaml:"share"` // доля новой еды, появляющейся в области
+}
+
+// MapLayout - карта: стены, зоны без появления, богатые едой области, точки появления
+// Не меняется после загрузки: копии правил (GameConfig.Clone) делят одну карту
+type MapLayout struct {
+	Name        string       `json:"name,omitempty" yaml:"name"`
+	Walls       []Shape      `json:"walls,omitempty" yaml:"walls"`             // клетки, еда и вирусы от них отталкиваются
+	NoSpawn     []Shape      `json:"noSpawn,omitempty" yaml:"noSpawn"`         // здесь ничего не появляется случайно
+	FoodRegions []FoodRegion `json:"foodRegions,omitempty" yaml:"foodRegions"` // сюда попадает доля Share новой еды
+	SpawnPoints []Vector2D   `json:"spawnPoints,omitempty" yaml:"spawnPoints"` // игроки появляются здесь, а не где попало
+}
+
+// spawnAttempts - сколько случайных точек пробовать, прежде чем согласиться на занятую
+const spawnAttempts = 20
+
+// LoadMapLayout - загрузить карту из YAML или JSON файла
+// Размеры мира карта не знает - её проверяет GameConfig.Validate
+func LoadMapLayout(path string) (*MapLayout, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read map: %w", err)
+	}
+
+	layout := &MapLayout{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, layout)
+	default:
+		err = json.Unmarshal(data, layout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse map %s: %w", path, err)
+	}
+	return layout, nil
+}
+
+// Validate - фигуры корректны, богатые едой области и точки появления внутри мира width x height
+func (l *MapLayout) Validate(width, height float64) error {
+	var errs []error
+	shape := func(field string, s Shape) {
+		if !finite(s.X) || !finite(s.Y) {
+			errs = append(errs, fmt.Errorf("%s: invalid position", field))
+		}
+		switch s.Type {
+		case ShapeRect:
+			if !(s.Width > 0) || !(s.Height > 0) || !finite(s.Width) || !finite(s.Height) {
+				errs = append(errs, fmt.Errorf("%s: width and height must be positive", field))
+			}
+		case ShapeCircle:
+			if !(s.Radius > 0) || !finite(s.Radius) {
+				errs = append(errs, fmt.Errorf("%s: radius must be positive", field))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("%s: type must be %q or %q", field, ShapeRect, ShapeCircle))
+		}
+	}
+
+	for i, wall := range l.Walls {
+		shape(fmt.Sprintf("map.walls[%d]", i), wall)
+	}
+	for i, zone := range l.NoSpawn {
+		shape(fmt.Sprintf("map.noSpawn[%d]", i), zone)
+	}
+	total := 0.0
+	for i, region := range l.FoodRegions {
+		field := fmt.Sprintf("map.foodRegions[%d]", i)
+		shape(field, region.Shape)
+		if !region.inside(width, height) {
+			errs = append(errs, fmt.Errorf("%s: must be inside the world", field))
+		}
+		if !(region.Share > 0 && region.Share <= 1) {
+			errs = append(errs, fmt.Errorf("%s: share must be in (0, 1]", field))
+		}
+		total += region.Share
+	}
+	if total > 1 {
+		errs = append(errs, errors.New("map.foodRegions: shares must not sum above 1"))
+	}
+	for i, p := range l.SpawnPoints {
+		if !(p.X >= 0 && p.X <= width && p.Y >= 0 && p.Y <= height) {
+			errs = append(errs, fmt.Errorf("map.spawnPoints[%d]: must be inside the world", i))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Contains - точка внутри фигуры, расширенной на margin
+func (s Shape) Contains(pos Vector2D, margin float64) bool {
+	if s.Type == ShapeCircle {
+		return Distance(pos, Vector2D{X: s.X, Y: s.Y}) < s.Radius+margin
+	}
+	return pos.X > s.X-margin && pos.X < s.X+s.Width+margin &&
+		pos.Y > s.Y-margin && pos.Y < s.Y+s.Height+margin
+}
+
+// inside - фигура целиком внутри мира width x height
+func (s Shape) inside(width, height float64) bool {
+	if s.Type == ShapeCircle {
+		return s.X-s.Radius >= 0 && s.X+s.Radius <= width && s.Y-s.Radius >= 0 && s.Y+s.Radius <= height
+	}
+	return s.X >= 0 && s.X+s.Width <= width && s.Y >= 0 && s.Y+s.Height <= height
+}
+
+// push - сдвиг, выталкивающий круг (center, radius) из фигуры; false - они не пересекаются
+func (s Shape) push(center Vector2D, radius float64) (Vector2D, bool) {
+	if s.Type == ShapeCircle {
+		delta := center.Sub(Vector2D{X: s.X, Y: s.Y})
+		dist := delta.Length()
+		if dist >= s.Radius+radius {
+			return Vector2D{}, false
+		}
+		direction := delta.Normalize()
+		if dist == 0 {
+			direction = Vector2D{X: 1, Y: 0}
+		}
+		return direction.Mul(s.Radius + radius - dist), true
+	}
+
+	// Ближайшая к центру точка прямоугольника
+	closest := Vector2D{
+		X: math.Max(s.X, math.Min(s.X+s.Width, center.X)),
+		Y: math.Max(s.Y, math.Min(s.Y+s.Height, center.Y)),
+	}
+	delta := center.Sub(closest)
+	if dist := delta.Length(); dist > 0 {
+		if dist >= radius {
+			return Vector2D{}, false
+		}
+		return delta.Mul((radius - dist) / dist), true
+	}
+
+	// Центр внутри прямоугольника - наружу через ближайшую сторону
+	left := center.X - s.X
+	right := s.X + s.Width - center.X
+	top := center.Y - s.Y
+	bottom := s.Y + s.Height - center.Y
+	switch math.Min(math.Min(left, right), math.Min(top, bottom)) {
+	case left:
+		return Vector2D{X: -(left + radius)}, true
+	case right:
+		return Vector2D{X: right + radius}, true
+	case top:
+		return Vector2D{Y: -(top + radius)}, true
+	}
+	return Vector2D{Y: bottom + radius}, true
+}
+
+// randomPoint - равномерно распределённая точка фигуры
+func (s Shape) randomPoint(r *rand.Rand) Vector2D {
+	if s.Type == ShapeCircle {
+		angle := r.Float64() * 2 * math.Pi
+		dist := s.Radius * math.Sqrt(r.Float64())
+		return Vector2D{X: s.X + math.Cos(angle)*dist, Y: s.Y + math.Sin(angle)*dist}
+	}
+	return Vector2D{X: s.X + r.Float64()*s.Width, Y: s.Y + r.Float64()*s.Height}
+}
+
+// blocked - в точке нельзя появиться: стена ближе margin или зона без появления
+func (l *MapLayout) blocked(pos Vector2D, margin float64) bool {
+	for _, wall := range l.Walls {
+		if wall.Contains(pos, margin) {
+			return true
+		}
+	}
+	for _, zone := range l.NoSpawn {
+		if zone.Contains(pos, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// freePoint - случайная точка sample вне стен (с запасом margin) и зон без появления
+// Если свободной точки не нашлось за spawnAttempts попыток - последняя попытка
+func (w *World) freePoint(margin float64, sample func() Vector2D) Vector2D {
+	pos := sample()
+	layout := w.Config.Map
+	if layout == nil {
+		return pos
+	}
+	for i := 1; i < spawnAttempts && layout.blocked(pos, margin); i++ {
+		pos = sample()
+	}
+	return pos
+}
+
+// foodPoint - точка новой еды: с долей Share - в богатой едой области, иначе в любом месте мира
+func (w *World) foodPoint() Vector2D {
+	if layout := w.Config.Map; layout != nil && len(layout.FoodRegions) > 0 {
+		roll := w.rand.Float64()
+		for _, region := range layout.FoodRegions {
+			if roll < region.Share {
+				return region.randomPoint(w.rand)
+			}
+			roll -= region.Share
+		}
+	}
+	return Vector2D{X: w.rand.Float64() * w.Config.WorldWidth, Y: w.rand.Float64() * w.Config.WorldHeight}
+}
+
+// collideWalls - вытолкнуть круг из стен карты
+// normal - направление от стен (нулевой вектор - касания не было)
+func (w *World) collideWalls(pos Vector2D, radius float64) (Vector2D, Vector2D) {
+	layout := w.Config.Map
+	if layout == nil {
+		return pos, Vector2D{}
+	}
+
+	normal := Vector2D{}
+	for _, wall := range layout.Walls {
+		if push, ok := wall.push(pos, radius); ok {
+			pos = pos.Add(push)
+			normal = normal.Add(push.Normalize())
+		}
+	}
+	return pos, normal.Normalize()
+}
+
+// bounceOff - составляющая скорости в стену гасится (restitution 0)
+// или отражается с затуханием (restitution 0.5 - как от границы мира)
+func bounceOff(velocity, normal Vector2D, restitution float64) Vector2D {
+	dot := velocity.X*normal.X + velocity.Y*normal.Y
+	if dot >= 0 {
+		return velocity
+	}
+	return velocity.Sub(normal.Mul(dot * (1 + restitution)))
+}
@@ -0,0 +1,86 @@
+package game
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMapLayoutValidate(t *testing.T) {
+	rect := func(x, y, width, height float64) Shape {
+		return Shape{Type: ShapeRect, X: x, Y: y, Width: width, Height: height}
+	}
+	circle := func(x, y, radius float64) Shape {
+		return Shape{Type: ShapeCircle, X: x, Y: y, Radius: radius}
+	}
+
+	tests := []struct {
+		name    string
+		layout  MapLayout
+		wantErr string // "" - карта подходит миру 1000x1000
+	}{
+		{name: "empty"},
+		{
+			name: "regions touching the edges",
+			layout: MapLayout{FoodRegions: []FoodRegion{
+				{Shape: rect(0, 0, 1000, 100), Share: 0.5},
+				{Shape: circle(900, 900, 100), Share: 0.5},
+			}},
+		},
+		{
+			name:    "rect region past the edge",
+			layout:  MapLayout{FoodRegions: []FoodRegion{{Shape: rect(900, 0, 200, 100), Share: 0.5}}},
+			wantErr: "map.foodRegions[0]: must be inside the world",
+		},
+		{
+			name:    "rect region before the origin",
+			layout:  MapLayout{FoodRegions: []FoodRegion{{Shape: rect(-50, 10, 100, 100), Share: 0.5}}},
+			wantErr: "map.foodRegions[0]: must be inside the world",
+		},
+		{
+			name:    "circle region crossing the edge",
+			layout:  MapLayout{FoodRegions: []FoodRegion{{Shape: circle(950, 500, 100), Share: 0.5}}},
+			wantErr: "map.foodRegions[0]: must be inside the world",
+		},
+		{
+			name:   "wall past the edge",
+			layout: MapLayout{Walls: []Shape{rect(900, 0, 200, 100)}},
+		},
+		{
+			name:    "shares above 1",
+			layout:  MapLayout{FoodRegions: []FoodRegion{{Shape: rect(0, 0, 10, 10), Share: 0.6}, {Shape: rect(0, 0, 10, 10), Share: 0.6}}},
+			wantErr: "shares must not sum above 1",
+		},
+		{
+			name:    "spawn point outside",
+			layout:  MapLayout{SpawnPoints: []Vector2D{{X: 500, Y: 1500}}},
+			wantErr: "map.spawnPoints[0]: must be inside the world",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.layout.Validate(1000, 1000)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestArenaMapFitsDefaultWorld(t *testing.T) {
+	layout, err := LoadMapLayout(filepath.Join("..", "..", "configs", "maps", "arena.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultGameConfig()
+	if err := layout.Validate(cfg.WorldWidth, cfg.WorldHeight); err != nil {
+		t.Fatalf("arena map: %v", err)
+	}
+}
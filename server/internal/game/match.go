@@ -0,0 +1,272 @@
+package game
+
+import (
+	"agario-server/internal/events"
+	"math"
+	"time"
+)
+
+// Режимы игры (GameConfig.Mode)
+const (
+	ModeFFA          = "ffa"           // бесконечная игра
+	ModeBattleRoyale = "battle_royale" // матчи с сужающейся безопасной зоной
+)
+
+// MatchPhase - этап матча королевской битвы
+type MatchPhase string
+
+const (
+	MatchLobby     MatchPhase = "lobby"     // ждём игроков
+	MatchCountdown MatchPhase = "countdown" // отсчёт до старта
+	MatchRunning   MatchPhase = "running"   // зона сужается, вход закрыт
+	MatchEnded     MatchPhase = "ended"     // победитель объявлен, скоро новое лобби
+)
+
+// Zone - безопасный круг; клетки за ним теряют массу
+type Zone struct {
+	Center Vector2D `json:"center"`
+	Radius float64  `json:"radius"`
+}
+
+// Contains - точка внутри зоны
+func (z Zone) Contains(pos Vector2D) bool {
+	return Distance(pos, z.Center) <= z.Radius
+}
+
+// Match - состояние матча (используется под локом мира)
+// Времена - время мира, поэтому в детерминированном мире матч повторяется
+type Match struct {
+	Number     int        `json:"number"` // Номер матча, начиная с 1
+	Phase      MatchPhase `json:"phase"`
+	PhaseEnds  time.Time  `json:"phaseEnds"` // Конец отсчёта или показа победителя
+	StartedAt  time.Time  `json:"startedAt"` // Старт (от него сужается зона)
+	Zone       Zone       `json:"zone"`
+	Players    int        `json:"players"` // Участников на старте
+	WinnerID   string     `json:"winnerId,omitempty"`
+	WinnerName string     `json:"winnerName,omitempty"`
+	WinnerTeam int        `json:"winnerTeam,omitempty"`
+}
+
+// zoneDrainMin - наименьшая потеря массы в секунду вне зоны: маленькие клетки тоже умирают
+const zoneDrainMin = 1.0
+
+// resetMatch - лобби матча number (nil - мир не в режиме королевской битвы)
+func (w *World) resetMatch(number int) {
+	if !w.Config.BattleRoyale() {
+		w.match = nil
+		return
+	}
+	w.match = &Match{Number: number, Phase: MatchLobby, Zone: w.fullZone()}
+}
+
+// fullZone - зона, накрывающая весь мир
+func (w *World) fullZone() Zone {
+	return Zone{
+		Center: Vector2D{X: w.Config.WorldWidth / 2, Y: w.Config.WorldHeight / 2},
+		Radius: math.Hypot(w.Config.WorldWidth, w.Config.WorldHeight) / 2,
+	}
+}
+
+// MatchUnlocked - копия состояния матча (БЕЗ лока); false - мир не в режиме королевской битвы
+func (w *World) MatchUnlocked() (Match, bool) {
+	if w.match == nil {
+		return Match{}, false
+	}
+	return *w.match, true
+}
+
+// Match - копия состояния матча (с локом)
+func (w *World) Match() (Match, bool) {
+	w.Mu.RLock()
+	defer w.Mu.RUnlock()
+	return w.MatchUnlocked()
+}
+
+// JoinOpenUnlocked - в мир можно войти новым игроком (БЕЗ лока)
+// Пока идёт матч, вход закрыт: последний выживший определяется среди стартовавших
+func (w *World) JoinOpenUnlocked() bool {
+	return w.match == nil || w.match.Phase != MatchRunning
+}
+
+// SafeZoneUnlocked - безопасная зона идущего матча (БЕЗ лока); false - зона не действует
+func (w *World) SafeZoneUnlocked() (Zone, bool) {
+	if w.match == nil || w.match.Phase != MatchRunning {
+		return Zone{}, false
+	}
+	return w.match.Zone, true
+}
+
+// ShrinkEndsUnlocked - когда зона сожмётся до ZoneMinRadius (БЕЗ лока)
+func (w *World) ShrinkEndsUnlocked() time.Time {
+	if w.match == nil {
+		return time.Time{}
+	}
+	return w.match.StartedAt.Add(seconds(w.Config.ZoneShrinkTime))
+}
+
+// zoneRadius - радиус зоны идущего матча в момент now
+func (w *World) zoneRadius(now time.Time) float64 {
+	full := w.fullZone().Radius
+	final := math.Min(w.Config.ZoneMinRadius, full)
+	progress := now.Sub(w.match.StartedAt).Seconds() / w.Config.ZoneShrinkTime
+	progress = math.Max(0, math.Min(1, progress))
+	return full - (full-final)*progress
+}
+
+// applyZoneDrain - клетки вне зоны теряют массу и в конце концов исчезают
+func (w *World) applyZoneDrain(dt float64) {
+	zone, active := w.SafeZoneUnlocked()
+	if !active || w.Config.ZoneDrain == 0 {
+		return
+	}
+
+	w.eachPlayer(func(player *Player) {
+		player.Mu.Lock()
+		for i := len(player.Cells) - 1; i >= 0; i-- {
+			cell := player.Cells[i]
+			if zone.Contains(cell.Position) {
+				continue
+			}
+
+			mass := cell.Mass()
+			loss := math.Max(mass*w.Config.ZoneDrain, zoneDrainMin) * dt
+			if mass-loss > MinCellRadius*MinCellRadius/100 {
+				cell.SetMass(mass - loss)
+				w.cellGrid.Update(cell.ID)
+				continue
+			}
+
+			// Меньше наименьшей клетки - клетка растворяется
+			player.Cells = append(player.Cells[:i], player.Cells[i+1:]...)
+			w.cellGrid.Remove(cell.ID)
+			if len(player.Cells) == 0 {
+				player.FinalMass = mass
+				player.PeakMass = math.Max(player.PeakMass, mass)
+				player.DeathCause = events.DeathZone
+			}
+			w.EventBus.PublishEvent(events.EventCellDissolved, &events.CellDissolvedEvent{
+				CellID:   cell.ID,
+				PlayerID: player.ID,
+				Cause:    events.DeathZone,
+			})
+		}
+		player.Mu.Unlock()
+	})
+}
+
+// updateMatch - смена этапов матча (после удаления погибших игроков)
+func (w *World) updateMatch() {
+	m := w.match
+	if m == nil {
+		return
+	}
+
+	now := w.Now()
+	switch m.Phase {
+	case MatchLobby:
+		m.Zone = w.fullZone()
+		if w.humansAlive() >= w.Config.MatchMinPlayers {
+			m.Phase = MatchCountdown
+			m.PhaseEnds = now.Add(seconds(w.Config.MatchCountdown))
+			w.publishMatch(events.EventMatchCountdown, w.Config.MatchCountdown)
+		}
+
+	case MatchCountdown:
+		m.Zone = w.fullZone()
+		if w.humansAlive() < w.Config.MatchMinPlayers {
+			m.Phase = MatchLobby
+			w.publishMatch(events.EventMatchLobby, 0)
+		} else if !now.Before(m.PhaseEnds) {
+			m.Phase = MatchRunning
+			m.StartedAt = now
+			m.Players = len(w.alivePlayers())
+			w.publishMatch(events.EventMatchStarted, 0)
+		}
+
+	case MatchRunning:
+		m.Zone = Zone{Center: w.fullZone().Center, Radius: w.zoneRadius(now)}
+		alive := w.alivePlayers()
+		if sides(alive) > 1 {
+			return
+		}
+
+		m.Phase = MatchEnded
+		m.PhaseEnds = now.Add(seconds(w.Config.MatchEndDelay))
+		if winner := heaviest(alive); winner != nil {
+			m.WinnerID, m.WinnerName, m.WinnerTeam = winner.ID, winner.Name, winner.Team
+		}
+		w.publishMatch(events.EventMatchEnded, now.Sub(m.StartedAt).Seconds())
+
+	case MatchEnded:
+		if !now.Before(m.PhaseEnds) {
+			w.resetMatch(m.Number + 1)
+			w.publishMatch(events.EventMatchLobby, 0)
+		}
+	}
+}
+
+func (w *World) publishMatch(eventType events.EventType, secs float64) {
+	m := w.match
+	w.EventBus.PublishEvent(eventType, &events.MatchEvent{
+		Match:      m.Number,
+		Phase:      string(m.Phase),
+		Players:    len(w.alivePlayers()),
+		Seconds:    secs,
+		WinnerID:   m.WinnerID,
+		WinnerName: m.WinnerName,
+		WinnerTeam: m.WinnerTeam,
+	})
+}
+
+// alivePlayers - живые игроки (в детерминированном мире - по возрастанию ID)
+func (w *World) alivePlayers() []*Player {
+	alive := []*Player{}
+	w.eachPlayer(func(player *Player) {
+		if player.IsAlive() {
+			alive = append(alive, player)
+		}
+	})
+	return alive
+}
+
+// humansAlive - живые игроки-люди (боты не начинают матч)
+func (w *World) humansAlive() int {
+	count := 0
+	for _, player := range w.Players {
+		if !player.IsBot && player.IsAlive() {
+			count++
+		}
+	}
+	return count
+}
+
+// sides - сколько сторон ещё в игре: команд в командном режиме, иначе игроков
+func sides(players []*Player) int {
+	seen := make(map[int]bool)
+	count := 0
+	for _, player := range players {
+		if player.Team == 0 {
+			count++
+		} else if !seen[player.Team] {
+			seen[player.Team] = true
+			count++
+		}
+	}
+	return count
+}
+
+// heaviest - самый тяжёлый из игроков (при равенстве - первый)
+func heaviest(players []*Player) *Player {
+	var best *Player
+	bestMass := -1.0
+	for _, player := range players {
+		if mass := player.TotalMass(); mass > bestMass {
+			best, bestMass = player, mass
+		}
+	}
+	return best
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
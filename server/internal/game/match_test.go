@@ -0,0 +1,64 @@
+package game
+
+import (
+	"testing"
+
+	"agario-server/internal/events"
+)
+
+func TestZoneDeathCause(t *testing.T) {
+	cfg := DefaultGameConfig()
+	cfg.MaxFoodCount = 0
+	cfg.VirusCount = 0
+	cfg.PowerUpCount = 0
+	cfg.Mode = ModeBattleRoyale
+	cfg.ZoneDrain = 1 // вся масса за секунду
+	w := NewDeterministicWorld(cfg, 1)
+
+	inside := w.AddPlayerUnlocked("inside", "#FFFFFF", false)
+	outside := w.AddPlayerUnlocked("outside", "#000000", false)
+	// Игроки стоят на месте: цель - текущая позиция
+	inside.Cells[0].Position, inside.TargetPos = w.fullZone().Center, w.fullZone().Center
+	outside.Cells[0].Position, outside.TargetPos = Vector2D{X: 100, Y: 100}, Vector2D{X: 100, Y: 100}
+	outside.Cells[0].SetMass(30)
+	w.cellGrid.Update(inside.Cells[0].ID)
+	w.cellGrid.Update(outside.Cells[0].ID)
+
+	// Матч идёт давно: зона уже сжалась до ZoneMinRadius
+	w.match = &Match{
+		Number:    1,
+		Phase:     MatchRunning,
+		StartedAt: w.Now().Add(-seconds(cfg.ZoneShrinkTime)),
+		Zone:      Zone{Center: w.fullZone().Center, Radius: cfg.ZoneMinRadius},
+		Players:   2,
+	}
+
+	var dissolved []*events.CellDissolvedEvent
+	var died *events.PlayerDiedEvent
+	for tick := 0; tick < 10*TickRate && died == nil; tick++ {
+		w.UpdateUnlocked(TickDuration.Seconds())
+		for _, event := range w.EventBus.FlushEvents() {
+			switch data := event.Data.(type) {
+			case *events.CellDissolvedEvent:
+				dissolved = append(dissolved, data)
+			case *events.PlayerDiedEvent:
+				died = data
+			case *events.CellEatenEvent:
+				t.Fatalf("zone drain published cell_eaten %+v", data)
+			}
+		}
+	}
+
+	if died == nil {
+		t.Fatal("player outside the zone did not die")
+	}
+	if died.PlayerID != outside.ID || died.Cause != events.DeathZone || died.KillerID != "" {
+		t.Fatalf("died %+v, want %s with cause %q and no killer", died, outside.ID, events.DeathZone)
+	}
+	if len(dissolved) != 1 || dissolved[0].PlayerID != outside.ID || dissolved[0].Cause != events.DeathZone {
+		t.Fatalf("dissolved %+v, want the one cell of %s", dissolved, outside.ID)
+	}
+	if _, alive := w.Players[inside.ID]; !alive {
+		t.Fatal("player inside the zone died")
+	}
+}
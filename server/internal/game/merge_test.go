@@ -0,0 +1,155 @@
+package game
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// twoCells - игрок с двумя клетками на расстоянии gap между центрами
+func twoCells(w *World, mass1, mass2, gap float64, mergeable bool) (*Player, *Cell, *Cell) {
+	player := w.AddPlayerUnlocked("pair", "#FFFFFF", false)
+	c1 := player.Cells[0]
+	c1.Position = Vector2D{X: w.Config.WorldWidth / 2, Y: w.Config.WorldHeight / 2}
+	c1.SetMass(mass1)
+	c2 := NewCell(w.newID(), c1.Position.Add(Vector2D{X: gap}), 0, w.Now())
+	c2.SetMass(mass2)
+	player.Cells = append(player.Cells, c2)
+	w.cellGrid.InsertCell(player, c2)
+	w.cellGrid.Update(c1.ID)
+
+	if mergeable {
+		c1.LastMergeTime = w.Now().Add(-time.Minute)
+		c2.LastMergeTime = c1.LastMergeTime
+	}
+	return player, c1, c2
+}
+
+func TestSelfCollision(t *testing.T) {
+	cfg := DefaultGameConfig()
+	const dt = 1.0 / TickRate
+
+	tests := []struct {
+		name      string
+		mass1     float64
+		mass2     float64
+		gap       float64
+		mergeable bool
+		launched  bool
+		// Ожидаемое изменение расстояния между центрами и сдвиги клеток
+		check func(t *testing.T, before, after float64, moved1, moved2 float64)
+	}{
+		{
+			name:  "overlapping cells are pushed apart",
+			mass1: 100, mass2: 100, gap: 50,
+			check: func(t *testing.T, before, after, moved1, moved2 float64) {
+				if after <= before {
+					t.Fatalf("distance %.1f -> %.1f, want cells pushed apart", before, after)
+				}
+				if math.Abs(moved1-moved2) > 1e-9 {
+					t.Fatalf("equal cells moved %.2f and %.2f", moved1, moved2)
+				}
+			},
+		},
+		{
+			name:  "heavy cell moves less",
+			mass1: 400, mass2: 100, gap: 100,
+			check: func(t *testing.T, before, after, moved1, moved2 float64) {
+				if after <= before || moved1 >= moved2 {
+					t.Fatalf("heavy moved %.2f, light moved %.2f", moved1, moved2)
+				}
+			},
+		},
+		{
+			name:  "separated cells stay",
+			mass1: 100, mass2: 100, gap: 500,
+			check: func(t *testing.T, before, after, moved1, moved2 float64) {
+				if moved1 != 0 || moved2 != 0 {
+					t.Fatalf("cells moved %.2f and %.2f", moved1, moved2)
+				}
+			},
+		},
+		{
+			name:  "mergeable cells attract",
+			mass1: 100, mass2: 100, gap: 500, mergeable: true,
+			check: func(t *testing.T, before, after, moved1, moved2 float64) {
+				if math.Abs(before-after-cfg.MergeAttraction*dt) > 1e-9 {
+					t.Fatalf("distance %.2f -> %.2f, want closer by %.2f", before, after, cfg.MergeAttraction*dt)
+				}
+			},
+		},
+		{
+			name:  "attraction stops at the midpoint",
+			mass1: 100, mass2: 100, gap: 1, mergeable: true,
+			check: func(t *testing.T, before, after, moved1, moved2 float64) {
+				if after < 0 || after >= before {
+					t.Fatalf("distance %.2f -> %.2f, want closer without crossing", before, after)
+				}
+			},
+		},
+		{
+			name:  "launched piece passes through",
+			mass1: 100, mass2: 100, gap: 50, launched: true,
+			check: func(t *testing.T, before, after, moved1, moved2 float64) {
+				if moved1 != 0 || moved2 != 0 {
+					t.Fatalf("launched piece pushed: moved %.2f and %.2f", moved1, moved2)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewDeterministicWorld(cfg, 1)
+			player, c1, c2 := twoCells(w, tt.mass1, tt.mass2, tt.gap, tt.mergeable)
+			if tt.launched {
+				c2.Velocity = Vector2D{X: cfg.SplitImpulse}
+			}
+			p1, p2 := c1.Position, c2.Position
+
+			w.checkSelfCollision(player, dt)
+
+			tt.check(t, Distance(p1, p2), Distance(c1.Position, c2.Position),
+				Distance(p1, c1.Position), Distance(p2, c2.Position))
+		})
+	}
+}
+
+func TestCellMerging(t *testing.T) {
+	cfg := DefaultGameConfig()
+	tests := []struct {
+		name      string
+		gap       float64
+		mergeable bool
+		merged    bool
+	}{
+		{name: "close and mergeable", gap: 10, mergeable: true, merged: true},
+		{name: "on cooldown", gap: 10},
+		{name: "too far apart", gap: 300, mergeable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewDeterministicWorld(cfg, 1)
+			player, c1, c2 := twoCells(w, 100, 50, tt.gap, tt.mergeable)
+
+			w.checkCellMerging()
+
+			if !tt.merged {
+				if len(player.Cells) != 2 {
+					t.Fatalf("%d cells, want both kept", len(player.Cells))
+				}
+				return
+			}
+			if len(player.Cells) != 1 || player.Cells[0] != c1 {
+				t.Fatalf("cells %v, want the first cell only", player.Cells)
+			}
+			if math.Abs(c1.Mass()-150) > 0.01 {
+				t.Fatalf("merged mass %.2f, want 150", c1.Mass())
+			}
+			if found := w.cellGrid.QueryRadius(c2.Position, 1, nil); len(found) != 1 || found[0].Cell != c1 {
+				t.Fatalf("merged cell still indexed: %+v", found)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package game
+
+import (
+	"agario-server/internal/events"
+	"sort"
+	"time"
+)
+
+// spawnPowerUp - бонус случайного вида в случайной точке (не у самого края)
+func (w *World) spawnPowerUp() *PowerUp {
+	kind := PowerUpKinds[w.rand.Intn(len(PowerUpKinds))]
+	pos := w.freePoint(PowerUpRadius, func() Vector2D {
+		x := PowerUpRadius + w.rand.Float64()*(w.Config.WorldWidth-PowerUpRadius*2)
+		y := PowerUpRadius + w.rand.Float64()*(w.Config.WorldHeight-PowerUpRadius*2)
+		return Vector2D{X: x, Y: y}
+	})
+
+	powerUp := NewPowerUp(w.newID(), kind, pos, w.Now())
+	w.addPowerUp(powerUp)
+	return powerUp
+}
+
+// addPowerUp - регистрирует бонус в мире и в индексе
+func (w *World) addPowerUp(powerUp *PowerUp) {
+	w.PowerUps[powerUp.ID] = powerUp
+	w.powerUpGrid.InsertPowerUp(powerUp)
+}
+
+// removePowerUp - удаляет бонус из мира и из индекса
+func (w *World) removePowerUp(powerUpID string) {
+	delete(w.PowerUps, powerUpID)
+	w.powerUpGrid.Remove(powerUpID)
+}
+
+// maintainPowerUps - по одному бонусу раз в PowerUpInterval, пока их меньше PowerUpCount
+func (w *World) maintainPowerUps() {
+	if len(w.PowerUps) >= w.Config.PowerUpCount {
+		return
+	}
+	now := w.Now()
+	if now.Before(w.nextPowerUp) {
+		return
+	}
+	w.nextPowerUp = now.Add(seconds(w.Config.PowerUpInterval))
+
+	w.EventBus.PublishEvent(events.EventPowerUpSpawned, &events.PowerUpSpawnedEvent{
+		PowerUps: []events.PowerUpInfo{powerUpInfo(w.spawnPowerUp())},
+	})
+}
+
+// checkPowerUpPickups - клетка, коснувшаяся бонуса, подбирает его
+// Повторный бонус того же вида продлевает эффект с текущего момента
+func (w *World) checkPowerUpPickups() {
+	if len(w.PowerUps) == 0 {
+		return
+	}
+
+	var nearby []*SpatialEntry
+	now := w.Now()
+	w.eachPlayer(func(player *Player) {
+		player.Mu.Lock()
+		collected := false
+		for _, cell := range player.Cells {
+			nearby = w.powerUpGrid.QueryRadius(cell.Position, cell.Radius+PowerUpRadius, nearby[:0])
+			for _, entry := range nearby {
+				powerUp := entry.PowerUp
+				if Distance(cell.Position, powerUp.Position) >= cell.Radius+powerUp.Radius {
+					continue
+				}
+
+				w.removePowerUp(powerUp.ID)
+				if player.Effects == nil {
+					player.Effects = make(map[PowerUpKind]time.Time)
+				}
+				player.Effects[powerUp.Kind] = now.Add(seconds(w.Config.PowerUpDuration))
+				collected = true
+
+				w.EventBus.PublishEvent(events.EventPowerUpCollected, &events.PowerUpCollectedEvent{
+					PowerUpID: powerUp.ID,
+					PlayerID:  player.ID,
+					CellID:    cell.ID,
+					Kind:      string(powerUp.Kind),
+					Duration:  w.Config.PowerUpDuration,
+				})
+			}
+		}
+		if collected {
+			w.publishEffects(player, now)
+		}
+		player.Mu.Unlock()
+	})
+}
+
+// applyMagnets - магнит тянет еду в PowerUpMagnetRadius от края клетки
+// Еду двигает updateFood, съедается она как обычно - при касании клетки.
+// Клиенты получают позицию и скорость притянутой еды на тиках delta.
+func (w *World) applyMagnets() {
+	var nearby []*SpatialEntry
+	now := w.Now()
+	w.eachPlayer(func(player *Player) {
+		player.Mu.RLock()
+		defer player.Mu.RUnlock()
+		if !player.HasEffect(PowerUpMagnet, now) {
+			return
+		}
+
+		pulled := []*Food{}
+		seen := make(map[string]bool)
+		for _, cell := range player.Cells {
+			reach := cell.Radius + w.Config.PowerUpMagnetRadius
+			nearby = w.foodGrid.QueryRadius(cell.Position, reach, nearby[:0])
+			for _, entry := range nearby {
+				food := entry.Food
+				// Только что выброшенную еду магнит не трогает (как и поедание)
+				if now.Sub(food.SpawnTime).Seconds() < 0.2 {
+					continue
+				}
+				delta := cell.Position.Sub(food.Position)
+				if delta.Length() >= reach {
+					continue
+				}
+
+				food.Velocity = delta.Normalize().Mul(MagnetSpeed)
+				if !seen[food.ID] {
+					seen[food.ID] = true
+					pulled = append(pulled, food)
+				}
+			}
+		}
+
+		if len(pulled) == 0 || !w.IsDeltaTick() {
+			return
+		}
+		infos := make([]events.FoodInfo, 0, len(pulled))
+		for _, food := range pulled {
+			infos = append(infos, events.FoodInfo{
+				FoodID: food.ID,
+				X:      food.Position.X,
+				Y:      food.Position.Y,
+				Radius: food.Radius,
+				Color:  food.Color,
+				VelX:   food.Velocity.X,
+				VelY:   food.Velocity.Y,
+			})
+		}
+		w.EventBus.PublishEvent(events.EventFoodPulled, &events.FoodPulledEvent{
+			PlayerID: player.ID,
+			Food:     infos,
+		})
+	})
+}
+
+// updateEffects - снять истёкшие эффекты бонусов
+func (w *World) updateEffects() {
+	now := w.Now()
+	w.eachPlayer(func(player *Player) {
+		player.Mu.Lock()
+		expired := false
+		for kind, until := range player.Effects {
+			if !now.Before(until) {
+				delete(player.Effects, kind)
+				expired = true
+			}
+		}
+		if expired {
+			w.publishEffects(player, now)
+		}
+		player.Mu.Unlock()
+	})
+}
+
+// publishEffects - разослать действующие эффекты игрока (под локом игрока)
+func (w *World) publishEffects(player *Player, now time.Time) {
+	w.EventBus.PublishEvent(events.EventPlayerEffects, &events.PlayerEffectsEvent{
+		PlayerID: player.ID,
+		Effects:  player.ActiveEffects(now),
+	})
+}
+
+// ActiveEffects - действующие эффекты по виду (без лока: под локом мира или игрока)
+func (p *Player) ActiveEffects(now time.Time) []events.EffectInfo {
+	effects := []events.EffectInfo{}
+	for kind, until := range p.Effects {
+		if now.Before(until) {
+			effects = append(effects, events.EffectInfo{
+				Kind:      string(kind),
+				Remaining: until.Sub(now).Seconds(),
+			})
+		}
+	}
+	sort.Slice(effects, func(i, j int) bool { return effects[i].Kind < effects[j].Kind })
+	return effects
+}
+
+func powerUpInfo(powerUp *PowerUp) events.PowerUpInfo {
+	return events.PowerUpInfo{
+		PowerUpID: powerUp.ID,
+		Kind:      string(powerUp.Kind),
+		X:         powerUp.Position.X,
+		Y:         powerUp.Position.Y,
+		Radius:    powerUp.Radius,
+	}
+}
@@ -0,0 +1,85 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"agario-server/internal/events"
+)
+
+// emptyWorld - детерминированный мир без еды, вирусов и бонусов
+func emptyWorld() *World {
+	cfg := DefaultGameConfig()
+	cfg.MaxFoodCount = 0
+	cfg.VirusCount = 0
+	cfg.PowerUpCount = 0
+	return NewDeterministicWorld(cfg, 1)
+}
+
+func TestMagnetPullsFood(t *testing.T) {
+	w := emptyWorld()
+	player := w.AddPlayerUnlocked("magnet", "#FFFFFF", false)
+	cell := player.Cells[0]
+	player.Effects = map[PowerUpKind]time.Time{PowerUpMagnet: w.Now().Add(time.Minute)}
+
+	// Еда дальше края клетки, но в радиусе магнита; вторая - за радиусом
+	near := NewFood("near", cell.Position.Add(Vector2D{X: cell.Radius + 100}), "#000000", w.Now().Add(-time.Second))
+	far := NewFood("far", cell.Position.Add(Vector2D{X: -(cell.Radius + w.Config.PowerUpMagnetRadius + 50)}), "#000000", w.Now().Add(-time.Second))
+	w.addFood(near)
+	w.addFood(far)
+	farPos := far.Position
+	w.EventBus.FlushEvents()
+
+	startDist := Distance(cell.Position, near.Position)
+	pulled := false
+	for tick := 0; tick < 3; tick++ {
+		w.UpdateUnlocked(TickDuration.Seconds())
+		for _, event := range w.EventBus.FlushEvents() {
+			if data, ok := event.Data.(*events.FoodPulledEvent); ok && data.PlayerID == player.ID {
+				pulled = pulled || (len(data.Food) == 1 && data.Food[0].FoodID == "near")
+			}
+		}
+	}
+	if _, ok := w.Food["near"]; !ok {
+		t.Fatal("food eaten from a distance: magnet must pull it, not widen the eat radius")
+	}
+	if dist := Distance(cell.Position, near.Position); dist >= startDist {
+		t.Fatalf("food not pulled: distance %.1f -> %.1f", startDist, dist)
+	}
+	if !pulled {
+		t.Fatal("no food_pulled event for the pulled food")
+	}
+	if far.Position != farPos {
+		t.Fatalf("food outside the magnet radius moved: %v -> %v", farPos, far.Position)
+	}
+
+	// Притянутая еда по индексу находится на новом месте и в итоге съедается
+	if found := w.FoodInRadius(near.Position, 1); len(found) != 1 {
+		t.Fatalf("pulled food not found in the spatial index at its new position")
+	}
+	for tick := 0; tick < TickRate && w.Food["near"] != nil; tick++ {
+		w.UpdateUnlocked(TickDuration.Seconds())
+	}
+	if _, ok := w.Food["near"]; ok {
+		t.Fatal("pulled food never eaten")
+	}
+}
+
+func TestMagnetExpires(t *testing.T) {
+	w := emptyWorld()
+	player := w.AddPlayerUnlocked("magnet", "#FFFFFF", false)
+	cell := player.Cells[0]
+	player.Effects = map[PowerUpKind]time.Time{PowerUpMagnet: w.Now()}
+
+	food := NewFood("food", cell.Position.Add(Vector2D{Y: cell.Radius + 100}), "#000000", w.Now().Add(-time.Second))
+	w.addFood(food)
+	pos := food.Position
+
+	w.UpdateUnlocked(TickDuration.Seconds())
+	if food.Position != pos {
+		t.Fatalf("expired magnet pulled food: %v -> %v", pos, food.Position)
+	}
+	if len(player.Effects) != 0 {
+		t.Fatalf("expired effect kept: %v", player.Effects)
+	}
+}
@@ -0,0 +1,481 @@
+package game
+
+import (
+	"agario-server/internal/events"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// SaveFormatVersion - версия формата сохранения мира
+// Повышается при каждом изменении полей WorldState: файлы других версий не читаются
+// 2 - seed и детерминированный режим, команды, матч, бонусы и эффекты
+const SaveFormatVersion = 2
+
+var ErrSaveVersion = errors.New("unsupported world save version")
+
+// WorldState - полное состояние мира для сохранения на диск
+// Время (cooldown, появление еды) при загрузке сдвигается на время простоя,
+// чтобы таймеры продолжились с того же места. Время детерминированного мира
+// логическое (от тика), поэтому не сдвигается
+type WorldState struct {
+	Version       int         `json:"version"`
+	SavedAt       time.Time   `json:"savedAt"`
+	Tick          int64       `json:"tick"`
+	Seed          int64       `json:"seed"`                // Генератор мира продолжает с этого seed...
+	RandDraws     uint64      `json:"randDraws,omitempty"` // ...пропустив столько выдач
+	Deterministic bool        `json:"deterministic,omitempty"`
+	Config        *GameConfig `json:"config"`
+
+	Players []SavedPlayer `json:"players"`
+	Food    []SavedFood   `json:"food"`
+	Viruses []SavedVirus  `json:"viruses"`
+
+	PowerUps    []SavedPowerUp `json:"powerUps,omitempty"`
+	NextPowerUp time.Time      `json:"nextPowerUp"` // Когда может появиться следующий бонус
+
+	Match *Match `json:"match,omitempty"` // Матч королевской битвы
+
+	// Токены переподключения -> ID игрока (заполняет network)
+	Sessions map[string]string `json:"sessions,omitempty"`
+}
+
+type SavedPlayer struct {
+	ID            string      `json:"id"`
+	Name          string      `json:"name"`
+	Color         string      `json:"color"`
+	IsBot         bool        `json:"isBot"`
+	Team          int         `json:"team,omitempty"`
+	Target        Vector2D    `json:"target"`
+	LastInputTime time.Time   `json:"lastInputTime"`
+	SpawnedAt     time.Time   `json:"spawnedAt"`
+	Kills         int         `json:"kills"`
+	PeakMass      float64     `json:"peakMass"`
+	Cells         []SavedCell `json:"cells"`
+
+	Effects map[PowerUpKind]time.Time `json:"effects,omitempty"` // Эффекты бонусов: вид -> время окончания
+}
+
+type SavedCell struct {
+	ID            string    `json:"id"`
+	Position      Vector2D  `json:"position"`
+	Radius        float64   `json:"radius"`
+	Velocity      Vector2D  `json:"velocity"`
+	LastSplitTime time.Time `json:"lastSplitTime"`
+	LastMergeTime time.Time `json:"lastMergeTime"`
+}
+
+type SavedFood struct {
+	ID        string    `json:"id"`
+	Position  Vector2D  `json:"position"`
+	Color     string    `json:"color"`
+	Radius    float64   `json:"radius"`
+	Mass      float64   `json:"mass"`
+	Velocity  Vector2D  `json:"velocity"`
+	SpawnTime time.Time `json:"spawnTime"`
+	Ejected   bool      `json:"ejected"`
+}
+
+type SavedVirus struct {
+	ID        string    `json:"id"`
+	Position  Vector2D  `json:"position"`
+	Mass      float64   `json:"mass"`
+	Velocity  Vector2D  `json:"velocity"`
+	FedCount  int       `json:"fedCount"`
+	SpawnTime time.Time `json:"spawnTime"`
+}
+
+type SavedPowerUp struct {
+	ID        string      `json:"id"`
+	Kind      PowerUpKind `json:"kind"`
+	Position  Vector2D    `json:"position"`
+	SpawnTime time.Time   `json:"spawnTime"`
+}
+
+// SaveStateUnlocked - снимок состояния мира (под Lock мира)
+// Генератор мира не меняется: сохранение не влияет на ход симуляции.
+// Детерминированный мир сохраняет seed и число выдач, и его восстановленная копия
+// получает ту же последовательность (реплеи); обычному миру при загрузке
+// достаточно нового seed из общего генератора
+func (w *World) SaveStateUnlocked() *WorldState {
+	seed, draws := w.seed, w.source.draws
+	if !w.deterministic {
+		seed, draws = rand.Int63(), 0
+	}
+
+	state := &WorldState{
+		Version:       SaveFormatVersion,
+		SavedAt:       w.Now(),
+		Tick:          w.CurrentTick,
+		Seed:          seed,
+		RandDraws:     draws,
+		Deterministic: w.deterministic,
+		Config:        w.Config.Clone(),
+		Players:       make([]SavedPlayer, 0, len(w.Players)),
+		Food:          make([]SavedFood, 0, len(w.Food)),
+		Viruses:       make([]SavedVirus, 0, len(w.Viruses)),
+		PowerUps:      make([]SavedPowerUp, 0, len(w.PowerUps)),
+		NextPowerUp:   w.nextPowerUp,
+	}
+	if w.match != nil {
+		match := *w.match
+		state.Match = &match
+	}
+
+	for _, player := range w.Players {
+		player.Mu.RLock()
+		saved := SavedPlayer{
+			ID:            player.ID,
+			Name:          player.Name,
+			Color:         player.Color,
+			IsBot:         player.IsBot,
+			Team:          player.Team,
+			Target:        player.TargetPos,
+			LastInputTime: player.LastInputTime,
+			SpawnedAt:     player.SpawnedAt,
+			Kills:         player.Kills,
+			PeakMass:      player.PeakMass,
+			Cells:         make([]SavedCell, 0, len(player.Cells)),
+		}
+		if len(player.Effects) > 0 {
+			saved.Effects = make(map[PowerUpKind]time.Time, len(player.Effects))
+			for kind, until := range player.Effects {
+				saved.Effects[kind] = until
+			}
+		}
+		for _, cell := range player.Cells {
+			saved.Cells = append(saved.Cells, SavedCell{
+				ID:            cell.ID,
+				Position:      cell.Position,
+				Radius:        cell.Radius,
+				Velocity:      cell.Velocity,
+				LastSplitTime: cell.LastSplitTime,
+				LastMergeTime: cell.LastMergeTime,
+			})
+		}
+		player.Mu.RUnlock()
+		state.Players = append(state.Players, saved)
+	}
+
+	for _, food := range w.Food {
+		state.Food = append(state.Food, SavedFood{
+			ID:        food.ID,
+			Position:  food.Position,
+			Color:     food.Color,
+			Radius:    food.Radius,
+			Mass:      food.Mass,
+			Velocity:  food.Velocity,
+			SpawnTime: food.SpawnTime,
+			Ejected:   food.Ejected,
+		})
+	}
+
+	for _, virus := range w.Viruses {
+		state.Viruses = append(state.Viruses, SavedVirus{
+			ID:        virus.ID,
+			Position:  virus.Position,
+			Mass:      virus.Mass,
+			Velocity:  virus.Velocity,
+			FedCount:  virus.FedCount,
+			SpawnTime: virus.SpawnTime,
+		})
+	}
+
+	for _, powerUp := range w.PowerUps {
+		state.PowerUps = append(state.PowerUps, SavedPowerUp{
+			ID:        powerUp.ID,
+			Kind:      powerUp.Kind,
+			Position:  powerUp.Position,
+			SpawnTime: powerUp.SpawnTime,
+		})
+	}
+
+	// Порядок map случаен - сортируем, чтобы файлы можно было сравнивать
+	sort.Slice(state.Players, func(i, j int) bool { return state.Players[i].ID < state.Players[j].ID })
+	sort.Slice(state.Food, func(i, j int) bool { return state.Food[i].ID < state.Food[j].ID })
+	sort.Slice(state.Viruses, func(i, j int) bool { return state.Viruses[i].ID < state.Viruses[j].ID })
+	sort.Slice(state.PowerUps, func(i, j int) bool { return state.PowerUps[i].ID < state.PowerUps[j].ID })
+
+	return state
+}
+
+// Validate - сохранение можно загрузить в мир
+func (s *WorldState) Validate() error {
+	if s.Version != SaveFormatVersion {
+		return fmt.Errorf("%w: %d (expected %d)", ErrSaveVersion, s.Version, SaveFormatVersion)
+	}
+	if s.Config == nil {
+		return errors.New("world save has no config")
+	}
+	if err := s.Config.Validate(); err != nil {
+		return fmt.Errorf("world save config: %w", err)
+	}
+
+	ids := make(map[string]struct{})
+	check := func(id string, pos Vector2D, radius float64) error {
+		if id == "" {
+			return errors.New("world save has entity without id")
+		}
+		if _, dup := ids[id]; dup {
+			return fmt.Errorf("world save has duplicate id %s", id)
+		}
+		ids[id] = struct{}{}
+		if !finite(pos.X) || !finite(pos.Y) || !finite(radius) || radius <= 0 {
+			return fmt.Errorf("world save entity %s has invalid position or radius", id)
+		}
+		return nil
+	}
+
+	for _, p := range s.Players {
+		if _, dup := ids[p.ID]; dup || p.ID == "" {
+			return fmt.Errorf("world save has invalid player id %q", p.ID)
+		}
+		ids[p.ID] = struct{}{}
+		if p.Team < 0 || p.Team > s.Config.Teams {
+			return fmt.Errorf("world save player %s has invalid team %d", p.ID, p.Team)
+		}
+		for _, c := range p.Cells {
+			if err := check(c.ID, c.Position, c.Radius); err != nil {
+				return err
+			}
+		}
+		for kind := range p.Effects {
+			if !validPowerUpKind(kind) {
+				return fmt.Errorf("world save player %s has unknown effect %q", p.ID, kind)
+			}
+		}
+	}
+	if m := s.Match; m != nil {
+		switch m.Phase {
+		case MatchLobby, MatchCountdown, MatchRunning, MatchEnded:
+		default:
+			return fmt.Errorf("world save has unknown match phase %q", m.Phase)
+		}
+		if !finite(m.Zone.Radius) || m.Zone.Radius < 0 {
+			return errors.New("world save has invalid match zone")
+		}
+	}
+	for _, f := range s.Food {
+		if err := check(f.ID, f.Position, f.Radius); err != nil {
+			return err
+		}
+	}
+	for _, v := range s.Viruses {
+		if err := check(v.ID, v.Position, v.Mass); err != nil {
+			return err
+		}
+	}
+	for _, pu := range s.PowerUps {
+		if err := check(pu.ID, pu.Position, PowerUpRadius); err != nil {
+			return err
+		}
+		if !validPowerUpKind(pu.Kind) {
+			return fmt.Errorf("world save power-up %s has unknown kind %q", pu.ID, pu.Kind)
+		}
+	}
+	return nil
+}
+
+func validPowerUpKind(kind PowerUpKind) bool {
+	for _, known := range PowerUpKinds {
+		if kind == known {
+			return true
+		}
+	}
+	return false
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// RestoreStateUnlocked - заменить содержимое мира сохранением (БЕЗ лока, мир уже залочен)
+// Накопленные события старого мира отбрасываются, режим мира берётся из сохранения
+func (w *World) RestoreStateUnlocked(state *WorldState) error {
+	if err := state.Validate(); err != nil {
+		return err
+	}
+	w.leaveMissingPlayers(state)
+	w.deterministic = state.Deterministic
+	w.CurrentTick = state.Tick
+	shift := w.Now().Sub(state.SavedAt)
+	if shift < 0 {
+		shift = 0
+	}
+	at := func(t time.Time) time.Time {
+		if t.IsZero() {
+			return t
+		}
+		return t.Add(shift)
+	}
+
+	w.EventBus.FlushEvents()
+	w.ApplyConfigUnlocked(state.Config.Clone())
+
+	w.Players = make(map[string]*Player, len(state.Players))
+	w.Food = make(map[string]*Food, len(state.Food))
+	w.Viruses = make(map[string]*Virus, len(state.Viruses))
+	w.PowerUps = make(map[string]*PowerUp, len(state.PowerUps))
+	w.foodGrid = NewSpatialGrid(w.Config.WorldWidth, w.Config.WorldHeight, GridCellSize)
+	w.cellGrid = NewSpatialGrid(w.Config.WorldWidth, w.Config.WorldHeight, GridCellSize)
+	w.virusGrid = NewSpatialGrid(w.Config.WorldWidth, w.Config.WorldHeight, GridCellSize)
+	w.powerUpGrid = NewSpatialGrid(w.Config.WorldWidth, w.Config.WorldHeight, GridCellSize)
+	w.nextPowerUp = at(state.NextPowerUp)
+
+	for _, saved := range state.Players {
+		player := &Player{
+			ID:            saved.ID,
+			Name:          saved.Name,
+			Color:         saved.Color,
+			IsBot:         saved.IsBot,
+			Team:          saved.Team,
+			TargetPos:     saved.Target,
+			LastInputTime: at(saved.LastInputTime),
+			SpawnedAt:     at(saved.SpawnedAt),
+			Kills:         saved.Kills,
+			PeakMass:      saved.PeakMass,
+			Cells:         make([]*Cell, 0, len(saved.Cells)),
+		}
+		for kind, until := range saved.Effects {
+			if player.Effects == nil {
+				player.Effects = make(map[PowerUpKind]time.Time, len(saved.Effects))
+			}
+			player.Effects[kind] = at(until)
+		}
+		for _, c := range saved.Cells {
+			player.Cells = append(player.Cells, &Cell{
+				ID:            c.ID,
+				Position:      c.Position,
+				Radius:        c.Radius,
+				Velocity:      c.Velocity,
+				LastSplitTime: at(c.LastSplitTime),
+				LastMergeTime: at(c.LastMergeTime),
+			})
+		}
+		w.Players[player.ID] = player
+		for _, cell := range player.Cells {
+			w.cellGrid.InsertCell(player, cell)
+		}
+	}
+
+	for _, f := range state.Food {
+		w.addFood(&Food{
+			ID:        f.ID,
+			Position:  f.Position,
+			Color:     f.Color,
+			Radius:    f.Radius,
+			Mass:      f.Mass,
+			Velocity:  f.Velocity,
+			SpawnTime: at(f.SpawnTime),
+			Ejected:   f.Ejected,
+		})
+	}
+
+	for _, v := range state.Viruses {
+		virus := &Virus{
+			ID:        v.ID,
+			Position:  v.Position,
+			Velocity:  v.Velocity,
+			FedCount:  v.FedCount,
+			SpawnTime: at(v.SpawnTime),
+		}
+		virus.SetMass(v.Mass)
+		w.addVirus(virus)
+	}
+
+	for _, pu := range state.PowerUps {
+		w.addPowerUp(NewPowerUp(pu.ID, pu.Kind, pu.Position, at(pu.SpawnTime)))
+	}
+
+	// Сохранение без матча (или из бесконечной игры) начинает с лобби
+	w.resetMatch(1)
+	if w.match != nil && state.Match != nil {
+		match := *state.Match
+		match.PhaseEnds, match.StartedAt = at(match.PhaseEnds), at(match.StartedAt)
+		w.match = &match
+	}
+
+	w.reseed(state.Seed, state.RandDraws)
+	return nil
+}
+
+// leaveMissingPlayers - игроки прежнего мира, которых нет в сохранении, уходят из игры
+// (по времени прежнего мира; события тика до загрузки отбрасываются, итоги получают только подписчики)
+func (w *World) leaveMissingPlayers(state *WorldState) {
+	saved := make(map[string]bool, len(state.Players))
+	for _, p := range state.Players {
+		saved[p.ID] = true
+	}
+	missing := []string{}
+	for id := range w.Players {
+		if !saved[id] {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+	for _, id := range missing {
+		w.LeavePlayerUnlocked(id, events.LeaveRestore)
+	}
+}
+
+// NewWorldFromState - мир, восстановленный из сохранения
+func NewWorldFromState(state *WorldState) (*World, error) {
+	if err := state.Validate(); err != nil {
+		return nil, err
+	}
+	w := NewWorld(state.Config.Clone())
+	if err := w.RestoreStateUnlocked(state); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// WriteWorldState - записать сохранение в файл (через временный файл и rename)
+func WriteWorldState(path string, state *WorldState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode world save: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write world save: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write world save: %w", err)
+	}
+	return nil
+}
+
+// ReadWorldState - прочитать и проверить сохранение
+func ReadWorldState(path string) (*WorldState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read world save: %w", err)
+	}
+
+	// Неизвестное поле - сохранение более новой версии или опечатка в файле
+	var state WorldState
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&state); err != nil {
+		return nil, fmt.Errorf("parse world save %s: %w", path, err)
+	}
+	if err := state.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid world save %s: %w", path, err)
+	}
+	return &state, nil
+}
@@ -0,0 +1,272 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"agario-server/internal/events"
+)
+
+// saveJSON - сохранение мира в виде, который пишется в файл
+func saveJSON(t *testing.T, w *World) string {
+	t.Helper()
+	data, err := json.Marshal(w.SaveStateUnlocked())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// stepWorld - n тиков с периодическим вводом игрока
+func stepWorld(w *World, playerID string, n int) {
+	for i := 0; i < n; i++ {
+		if player, ok := w.Players[playerID]; ok {
+			switch w.CurrentTick % 60 {
+			case 0:
+				player.SetTarget(w.Config.WorldWidth/3, w.Config.WorldHeight/4, w.Now())
+			case 20:
+				w.SplitPlayerUnlocked(player)
+			case 40:
+				w.EjectPlayerUnlocked(player)
+			}
+		}
+		w.UpdateUnlocked(TickDuration.Seconds())
+		w.EventBus.FlushEvents()
+	}
+}
+
+func TestSaveRestoreRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(cfg *GameConfig)
+		ticks int
+	}{
+		{name: "fresh world", setup: func(*GameConfig) {}},
+		{name: "after play", setup: func(*GameConfig) {}, ticks: 100},
+		{name: "teams", setup: func(cfg *GameConfig) { cfg.Teams = 2 }, ticks: 100},
+		{name: "battle royale", setup: func(cfg *GameConfig) { cfg.Mode = ModeBattleRoyale; cfg.MatchMinPlayers = 1 }, ticks: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultGameConfig()
+			tt.setup(cfg)
+			w := NewDeterministicWorld(cfg, 3)
+			player := w.AddPlayerUnlocked("saved", "#FFFFFF", false)
+			player.Cells[0].SetMass(150)
+			w.AddPlayerUnlocked("other", "#000000", false)
+			player.Effects = map[PowerUpKind]time.Time{PowerUpShield: w.Now().Add(time.Hour)}
+			stepWorld(w, player.ID, tt.ticks)
+
+			path := filepath.Join(t.TempDir(), "saves", "world.json")
+			if err := WriteWorldState(path, w.SaveStateUnlocked()); err != nil {
+				t.Fatal(err)
+			}
+			state, err := ReadWorldState(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			restored, err := NewWorldFromState(state)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if before, after := saveJSON(t, w), saveJSON(t, restored); before != after {
+				t.Fatalf("restored world saves differently:\n%s\n%s", before, after)
+			}
+
+			// Восстановленный мир продолжает ту же симуляцию
+			stepWorld(w, player.ID, 200)
+			stepWorld(restored, player.ID, 200)
+			if before, after := saveJSON(t, w), saveJSON(t, restored); before != after {
+				t.Fatal("restored world diverged from the original")
+			}
+		})
+	}
+}
+
+func TestWorldStateValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(s *WorldState)
+		wantErr string
+	}{
+		{name: "valid", edit: func(*WorldState) {}},
+		{name: "wrong version", edit: func(s *WorldState) { s.Version = SaveFormatVersion + 1 }, wantErr: "unsupported world save version"},
+		{name: "no config", edit: func(s *WorldState) { s.Config = nil }, wantErr: "no config"},
+		{name: "invalid config", edit: func(s *WorldState) { s.Config.BaseSpeed = 0 }, wantErr: "baseSpeed"},
+		{name: "empty player id", edit: func(s *WorldState) { s.Players[0].ID = "" }, wantErr: "invalid player id"},
+		{name: "duplicate id", edit: func(s *WorldState) { s.Food[0].ID = s.Players[0].Cells[0].ID }, wantErr: "duplicate id"},
+		{name: "team out of range", edit: func(s *WorldState) { s.Players[0].Team = 1 }, wantErr: "invalid team"},
+		{name: "position not finite", edit: func(s *WorldState) { s.Players[0].Cells[0].Position.X = math.NaN() }, wantErr: "invalid position"},
+		{name: "zero radius", edit: func(s *WorldState) { s.Food[0].Radius = 0 }, wantErr: "invalid position or radius"},
+		{
+			name:    "unknown effect",
+			edit:    func(s *WorldState) { s.Players[0].Effects = map[PowerUpKind]time.Time{"flight": {}} },
+			wantErr: "unknown effect",
+		},
+		{
+			name: "unknown power-up kind",
+			edit: func(s *WorldState) {
+				s.PowerUps = append(s.PowerUps, SavedPowerUp{ID: "pu", Kind: "flight", Position: Vector2D{X: 10, Y: 10}})
+			},
+			wantErr: "unknown kind",
+		},
+		{name: "unknown match phase", edit: func(s *WorldState) { s.Match = &Match{Phase: "overtime"} }, wantErr: "match phase"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewDeterministicWorld(nil, 1)
+			w.AddPlayerUnlocked("saved", "#FFFFFF", false)
+			state := w.SaveStateUnlocked()
+			tt.edit(state)
+
+			err := state.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate error %v, want %q", err, tt.wantErr)
+			}
+			// Неправильное сохранение не трогает мир
+			if err := w.RestoreStateUnlocked(state); err == nil {
+				t.Fatal("RestoreStateUnlocked accepted an invalid save")
+			}
+			if len(w.Players) != 1 {
+				t.Fatalf("failed restore left %d players", len(w.Players))
+			}
+		})
+	}
+}
+
+// writeSave - сохранение мира, изменённое edit перед записью в файл
+func writeSave(t *testing.T, edit func(raw map[string]interface{})) string {
+	t.Helper()
+	w := NewDeterministicWorld(nil, 1)
+	w.Mu.Lock()
+	state := w.SaveStateUnlocked()
+	w.Mu.Unlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	edit(raw)
+	if data, err = json.Marshal(raw); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "world.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadWorldStateFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(raw map[string]interface{})
+		wantErr string // "" - файл читается
+	}{
+		{name: "current", edit: func(map[string]interface{}) {}},
+		{
+			name:    "old version",
+			edit:    func(raw map[string]interface{}) { raw["version"] = 1 },
+			wantErr: ErrSaveVersion.Error(),
+		},
+		{
+			name:    "newer version",
+			edit:    func(raw map[string]interface{}) { raw["version"] = SaveFormatVersion + 1 },
+			wantErr: ErrSaveVersion.Error(),
+		},
+		{
+			name:    "unknown field",
+			edit:    func(raw map[string]interface{}) { raw["weather"] = "rain" },
+			wantErr: `unknown field "weather"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := ReadWorldState(writeSave(t, tt.edit))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ReadWorldState: %v", err)
+				}
+				if state.Version != SaveFormatVersion {
+					t.Fatalf("version %d, want %d", state.Version, SaveFormatVersion)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ReadWorldState error %v, want %q", err, tt.wantErr)
+			}
+			if tt.wantErr == ErrSaveVersion.Error() && !errors.Is(err, ErrSaveVersion) {
+				t.Fatalf("error %v does not wrap ErrSaveVersion", err)
+			}
+		})
+	}
+}
+
+func TestSaveKeepsWorldRand(t *testing.T) {
+	for _, deterministic := range []bool{false, true} {
+		w := NewWorld(nil)
+		if deterministic {
+			w = NewDeterministicWorld(nil, 7)
+		}
+		w.Mu.Lock()
+		seed, draws := w.seed, w.source.draws
+		state := w.SaveStateUnlocked()
+		if w.seed != seed || w.source.draws != draws {
+			t.Errorf("deterministic=%v: save changed world rand (seed %d -> %d, draws %d -> %d)",
+				deterministic, seed, w.seed, draws, w.source.draws)
+		}
+		if deterministic && (state.Seed != seed || state.RandDraws != draws) {
+			t.Errorf("deterministic save has seed %d draws %d, want %d %d", state.Seed, state.RandDraws, seed, draws)
+		}
+		w.Mu.Unlock()
+	}
+}
+
+func TestRestorePublishesLeftPlayers(t *testing.T) {
+	w := NewDeterministicWorld(nil, 1)
+	kept := w.AddPlayerUnlocked("kept", "#FFFFFF", false)
+	state := w.SaveStateUnlocked()
+	gone := w.AddPlayerUnlocked("gone", "#000000", false)
+	// События тика до загрузки отбрасываются, итоги получают подписчики
+	left := make(chan *events.PlayerLeftEvent, 2)
+	w.EventBus.Subscribe(events.EventPlayerLeft, func(event *events.Event) {
+		left <- event.Data.(*events.PlayerLeftEvent)
+	})
+
+	if err := w.RestoreStateUnlocked(state); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case data := <-left:
+		if data.PlayerID != gone.ID || data.Cause != events.LeaveRestore {
+			t.Fatalf("player_left %+v, want %s with %q", data, gone.ID, events.LeaveRestore)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no player_left for the player missing from the save")
+	}
+	if _, ok := w.Players[kept.ID]; !ok {
+		t.Fatal("saved player missing after restore")
+	}
+}
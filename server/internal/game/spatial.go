@@ -0,0 +1,218 @@
+package game
+
+import (
+	"math"
+	"sort"
+)
+
+// EntityKind - тип entity в пространственном индексе
+type EntityKind uint8
+
+const (
+	EntityFood EntityKind = iota
+	EntityCell
+	EntityVirus
+	EntityPowerUp
+)
+
+// SpatialEntry - запись в пространственном индексе
+type SpatialEntry struct {
+	ID     string
+	Kind   EntityKind
+	Food   *Food   // Для EntityFood
+	Cell   *Cell   // Для EntityCell
+	Player *Player // Владелец клетки (для EntityCell)
+	Virus  *Virus  // Для EntityVirus
+
+	PowerUp *PowerUp // Для EntityPowerUp
+
+	// Диапазон бакетов, которые занимает entity
+	minCol, minRow int
+	maxCol, maxRow int
+
+	// Отметка последнего запроса (для дедупликации без аллокаций)
+	queryStamp uint64
+}
+
+func (e *SpatialEntry) bounds() (Vector2D, float64) {
+	switch e.Kind {
+	case EntityCell:
+		return e.Cell.Position, e.Cell.Radius
+	case EntityVirus:
+		return e.Virus.Position, e.Virus.Radius
+	case EntityPowerUp:
+		return e.PowerUp.Position, e.PowerUp.Radius
+	}
+	return e.Food.Position, e.Food.Radius
+}
+
+// SpatialGrid - равномерная сетка для быстрого поиска соседей
+// Entity регистрируется во всех бакетах, которые пересекает её bounding box
+type SpatialGrid struct {
+	cellSize float64
+	cols     int
+	rows     int
+	buckets  [][]*SpatialEntry
+	entries  map[string]*SpatialEntry
+	stamp    uint64
+}
+
+func NewSpatialGrid(width, height, cellSize float64) *SpatialGrid {
+	g := &SpatialGrid{
+		cellSize: cellSize,
+		entries:  make(map[string]*SpatialEntry),
+	}
+	g.Resize(width, height)
+	return g
+}
+
+// Resize - перестроить сетку под новый размер мира
+func (g *SpatialGrid) Resize(width, height float64) {
+	g.cols = int(math.Ceil(width/g.cellSize)) + 1
+	g.rows = int(math.Ceil(height/g.cellSize)) + 1
+	g.buckets = make([][]*SpatialEntry, g.cols*g.rows)
+
+	for _, entry := range g.entries {
+		g.place(entry)
+	}
+}
+
+// Len - количество entity в индексе
+func (g *SpatialGrid) Len() int {
+	return len(g.entries)
+}
+
+func (g *SpatialGrid) InsertFood(food *Food) {
+	g.insert(&SpatialEntry{ID: food.ID, Kind: EntityFood, Food: food})
+}
+
+func (g *SpatialGrid) InsertCell(player *Player, cell *Cell) {
+	g.insert(&SpatialEntry{ID: cell.ID, Kind: EntityCell, Cell: cell, Player: player})
+}
+
+func (g *SpatialGrid) InsertVirus(virus *Virus) {
+	g.insert(&SpatialEntry{ID: virus.ID, Kind: EntityVirus, Virus: virus})
+}
+
+func (g *SpatialGrid) InsertPowerUp(powerUp *PowerUp) {
+	g.insert(&SpatialEntry{ID: powerUp.ID, Kind: EntityPowerUp, PowerUp: powerUp})
+}
+
+func (g *SpatialGrid) insert(entry *SpatialEntry) {
+	if old, exists := g.entries[entry.ID]; exists {
+		g.unplace(old)
+	}
+	g.entries[entry.ID] = entry
+	g.place(entry)
+}
+
+// Remove - удалить entity из индекса
+func (g *SpatialGrid) Remove(id string) {
+	entry, exists := g.entries[id]
+	if !exists {
+		return
+	}
+	g.unplace(entry)
+	delete(g.entries, id)
+}
+
+// Update - пересчитать бакеты после движения или изменения радиуса
+func (g *SpatialGrid) Update(id string) {
+	entry, exists := g.entries[id]
+	if !exists {
+		return
+	}
+
+	minCol, minRow, maxCol, maxRow := g.bucketRange(entry.bounds())
+	if minCol == entry.minCol && minRow == entry.minRow && maxCol == entry.maxCol && maxRow == entry.maxRow {
+		return
+	}
+
+	g.unplace(entry)
+	g.place(entry)
+}
+
+// Query - все entity, чьи бакеты пересекают прямоугольник
+// Результат дописывается в buf; проверку точного пересечения делает вызывающий
+func (g *SpatialGrid) Query(minX, minY, maxX, maxY float64, buf []*SpatialEntry) []*SpatialEntry {
+	g.stamp++
+	minCol, minRow := g.bucketAt(minX, minY)
+	maxCol, maxRow := g.bucketAt(maxX, maxY)
+
+	for row := minRow; row <= maxRow; row++ {
+		for col := minCol; col <= maxCol; col++ {
+			for _, entry := range g.buckets[row*g.cols+col] {
+				if entry.queryStamp == g.stamp {
+					continue
+				}
+				entry.queryStamp = g.stamp
+				buf = append(buf, entry)
+			}
+		}
+	}
+	return buf
+}
+
+// QueryRadius - entity в квадрате вокруг точки
+func (g *SpatialGrid) QueryRadius(center Vector2D, radius float64, buf []*SpatialEntry) []*SpatialEntry {
+	return g.Query(center.X-radius, center.Y-radius, center.X+radius, center.Y+radius, buf)
+}
+
+// place - добавить entity в её бакеты
+// Бакеты упорядочены по ID: результат Query не зависит от истории вставок
+// (нужно детерминированному режиму и восстановлению из сохранения)
+func (g *SpatialGrid) place(entry *SpatialEntry) {
+	entry.minCol, entry.minRow, entry.maxCol, entry.maxRow = g.bucketRange(entry.bounds())
+	for row := entry.minRow; row <= entry.maxRow; row++ {
+		for col := entry.minCol; col <= entry.maxCol; col++ {
+			idx := row*g.cols + col
+			bucket := g.buckets[idx]
+			i := sort.Search(len(bucket), func(i int) bool { return bucket[i].ID >= entry.ID })
+			bucket = append(bucket, nil)
+			copy(bucket[i+1:], bucket[i:])
+			bucket[i] = entry
+			g.buckets[idx] = bucket
+		}
+	}
+}
+
+func (g *SpatialGrid) unplace(entry *SpatialEntry) {
+	for row := entry.minRow; row <= entry.maxRow; row++ {
+		for col := entry.minCol; col <= entry.maxCol; col++ {
+			idx := row*g.cols + col
+			bucket := g.buckets[idx]
+			i := sort.Search(len(bucket), func(i int) bool { return bucket[i].ID >= entry.ID })
+			if i < len(bucket) && bucket[i] == entry {
+				last := len(bucket) - 1
+				copy(bucket[i:], bucket[i+1:])
+				bucket[last] = nil
+				g.buckets[idx] = bucket[:last]
+			}
+		}
+	}
+}
+
+func (g *SpatialGrid) bucketRange(pos Vector2D, radius float64) (int, int, int, int) {
+	minCol, minRow := g.bucketAt(pos.X-radius, pos.Y-radius)
+	maxCol, maxRow := g.bucketAt(pos.X+radius, pos.Y+radius)
+	return minCol, minRow, maxCol, maxRow
+}
+
+// bucketAt - индекс бакета для точки (за границами мира - крайний бакет)
+func (g *SpatialGrid) bucketAt(x, y float64) (int, int) {
+	col := int(math.Floor(x / g.cellSize))
+	row := int(math.Floor(y / g.cellSize))
+	if col < 0 {
+		col = 0
+	}
+	if col >= g.cols {
+		col = g.cols - 1
+	}
+	if row < 0 {
+		row = 0
+	}
+	if row >= g.rows {
+		row = g.rows - 1
+	}
+	return col, row
+}
@@ -0,0 +1,169 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// queryIDs - ID найденных entity в порядке выдачи
+func queryIDs(g *SpatialGrid, minX, minY, maxX, maxY float64) []string {
+	ids := []string{}
+	for _, entry := range g.Query(minX, minY, maxX, maxY, nil) {
+		ids = append(ids, entry.ID)
+	}
+	return ids
+}
+
+// testFood - еда с заданным ID
+func testFood(id string, x, y float64) *Food {
+	return NewFood(id, Vector2D{X: x, Y: y}, "#000000", time.Time{})
+}
+
+// testCell - клетка с заданным ID
+func testCell(id string, x, y, radius float64) *Cell {
+	return NewCell(id, Vector2D{X: x, Y: y}, radius, time.Time{})
+}
+
+func TestSpatialGridQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(g *SpatialGrid)
+		rect  [4]float64
+		want  []string
+	}{
+		{
+			name:  "empty grid",
+			setup: func(g *SpatialGrid) {},
+			rect:  [4]float64{0, 0, 1000, 1000},
+			want:  []string{},
+		},
+		{
+			name: "only buckets that intersect the rect",
+			setup: func(g *SpatialGrid) {
+				g.InsertFood(testFood("a", 50, 50))
+				g.InsertFood(testFood("b", 550, 550))
+			},
+			rect: [4]float64{0, 0, 150, 150},
+			want: []string{"a"},
+		},
+		{
+			name: "results ordered by id, not by insertion",
+			setup: func(g *SpatialGrid) {
+				g.InsertFood(testFood("c", 50, 50))
+				g.InsertFood(testFood("a", 60, 60))
+				g.InsertFood(testFood("b", 70, 70))
+			},
+			rect: [4]float64{0, 0, 99, 99},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "entity spanning several buckets is returned once",
+			setup: func(g *SpatialGrid) {
+				g.InsertCell(&Player{ID: "p"}, testCell("c", 200, 200, 150))
+			},
+			rect: [4]float64{0, 0, 1000, 1000},
+			want: []string{"c"},
+		},
+		{
+			name: "large entity found from a neighbouring bucket",
+			setup: func(g *SpatialGrid) {
+				g.InsertCell(&Player{ID: "p"}, testCell("c", 250, 250, 120))
+			},
+			rect: [4]float64{340, 340, 360, 360},
+			want: []string{"c"},
+		},
+		{
+			name: "reinsert with the same id replaces the entry",
+			setup: func(g *SpatialGrid) {
+				g.InsertFood(testFood("a", 50, 50))
+				g.InsertFood(testFood("a", 850, 850))
+			},
+			rect: [4]float64{0, 0, 150, 150},
+			want: []string{},
+		},
+		{
+			name: "removed entity is not returned",
+			setup: func(g *SpatialGrid) {
+				g.InsertFood(testFood("a", 50, 50))
+				g.InsertFood(testFood("b", 60, 60))
+				g.Remove("a")
+				g.Remove("missing")
+			},
+			rect: [4]float64{0, 0, 150, 150},
+			want: []string{"b"},
+		},
+		{
+			name: "update moves entity to its new buckets",
+			setup: func(g *SpatialGrid) {
+				f := testFood("a", 50, 50)
+				g.InsertFood(f)
+				f.Position = Vector2D{X: 850, Y: 850}
+				g.Update("a")
+			},
+			rect: [4]float64{800, 800, 900, 900},
+			want: []string{"a"},
+		},
+		{
+			name: "points outside the world clamp to the edge buckets",
+			setup: func(g *SpatialGrid) {
+				g.InsertFood(testFood("low", -500, -500))
+				g.InsertFood(testFood("high", 5000, 5000))
+			},
+			rect: [4]float64{-10, -10, 10, 10},
+			want: []string{"low"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewSpatialGrid(1000, 1000, 100)
+			tt.setup(g)
+			got := queryIDs(g, tt.rect[0], tt.rect[1], tt.rect[2], tt.rect[3])
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("query %v = %v, want %v", tt.rect, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpatialGridUpdateLeavesOldBuckets(t *testing.T) {
+	g := NewSpatialGrid(1000, 1000, 100)
+	player := &Player{ID: "p"}
+	cell := testCell("c", 50, 50, 10)
+	g.InsertCell(player, cell)
+
+	// Клетка выросла и сдвинулась: старые бакеты должны освободиться
+	cell.Position = Vector2D{X: 500, Y: 500}
+	cell.SetMass(400)
+	g.Update(cell.ID)
+
+	if got := queryIDs(g, 0, 0, 150, 150); len(got) != 0 {
+		t.Fatalf("cell still found in its old buckets: %v", got)
+	}
+	near := g.QueryRadius(Vector2D{X: 500 + cell.Radius, Y: 500}, 1, nil)
+	if len(near) != 1 || near[0].Cell != cell || near[0].Player != player {
+		t.Fatalf("grown cell not found at its edge: %+v", near)
+	}
+	if g.Len() != 1 {
+		t.Fatalf("grid holds %d entries, want 1", g.Len())
+	}
+}
+
+func TestSpatialGridResizeKeepsEntries(t *testing.T) {
+	g := NewSpatialGrid(500, 500, 100)
+	g.InsertFood(testFood("far", 900, 900))
+
+	// До расширения точка прижата к краю сетки 500x500
+	if got := queryIDs(g, 450, 450, 500, 500); len(got) != 1 {
+		t.Fatalf("before resize query = %v, want the clamped entity", got)
+	}
+
+	g.Resize(1000, 1000)
+	if got := queryIDs(g, 450, 450, 500, 500); len(got) != 0 {
+		t.Fatalf("after resize entity still in the old edge bucket: %v", got)
+	}
+	if got := queryIDs(g, 850, 850, 950, 950); len(got) != 1 || got[0] != "far" {
+		t.Fatalf("after resize query = %v, want [far]", got)
+	}
+}
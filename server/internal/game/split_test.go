@@ -0,0 +1,123 @@
+package game
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// splitReady - игрок с одной клеткой заданной массы в центре мира, готовой к сплиту
+func splitReady(w *World, mass float64) *Player {
+	player := w.AddPlayerUnlocked("split", "#FFFFFF", false)
+	cell := player.Cells[0]
+	cell.Position = Vector2D{X: w.Config.WorldWidth / 2, Y: w.Config.WorldHeight / 2}
+	cell.SetMass(mass)
+	cell.LastSplitTime = w.Now().Add(-time.Minute)
+	w.cellGrid.Update(cell.ID)
+	player.TargetPos = cell.Position.Add(Vector2D{X: 1000})
+	return player
+}
+
+func TestSplitPlayer(t *testing.T) {
+	cfg := DefaultGameConfig()
+	tests := []struct {
+		name  string
+		mass  float64
+		cells int // клеток у игрока до сплита
+		split bool
+	}{
+		{name: "splits in half", mass: 100, cells: 1, split: true},
+		{name: "too light", mass: 19, cells: 1},
+		{name: "at the cell limit", mass: 100, cells: cfg.PlayerMaxCells},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewDeterministicWorld(cfg, 1)
+			player := splitReady(w, tt.mass)
+			for len(player.Cells) < tt.cells {
+				extra := NewCell(w.newID(), player.Cells[0].Position, StartRadius, w.Now())
+				player.Cells = append(player.Cells, extra)
+				w.cellGrid.InsertCell(player, extra)
+			}
+			original := player.Cells[0]
+			massBefore := original.Mass()
+
+			w.splitPlayer(player)
+
+			if !tt.split {
+				if len(player.Cells) != tt.cells || original.Mass() != massBefore {
+					t.Fatalf("split happened: %d cells, mass %.1f", len(player.Cells), original.Mass())
+				}
+				return
+			}
+			if len(player.Cells) != 2 {
+				t.Fatalf("%d cells after split, want 2", len(player.Cells))
+			}
+			piece := player.Cells[1]
+			if math.Abs(original.Mass()-massBefore/2) > 0.01 || math.Abs(piece.Mass()-massBefore/2) > 0.01 {
+				t.Fatalf("halves %.1f and %.1f, want %.1f each", original.Mass(), piece.Mass(), massBefore/2)
+			}
+			if piece.Velocity.X != cfg.SplitImpulse || piece.Velocity.Y != 0 {
+				t.Fatalf("piece velocity %+v, want %.0f towards the target", piece.Velocity, cfg.SplitImpulse)
+			}
+			if original.CanMerge(cfg, w.Now()) || piece.CanMerge(cfg, w.Now()) {
+				t.Fatal("halves can merge right after the split")
+			}
+		})
+	}
+}
+
+func TestSplitImpulse(t *testing.T) {
+	cfg := DefaultGameConfig()
+	tests := []struct {
+		name     string
+		start    Vector2D // смещение клетки от центра мира
+		velocity Vector2D
+		check    func(t *testing.T, cell *Cell, start Vector2D)
+	}{
+		{
+			name:     "friction stops the piece",
+			velocity: Vector2D{X: 800},
+			check: func(t *testing.T, cell *Cell, start Vector2D) {
+				if cell.IsLaunched() || cell.Velocity != (Vector2D{}) {
+					t.Fatalf("piece still flying with velocity %+v", cell.Velocity)
+				}
+				if cell.Position.X-start.X <= 0 {
+					t.Fatalf("piece did not fly forward: %+v -> %+v", start, cell.Position)
+				}
+			},
+		},
+		{
+			name:     "world edge cancels the impulse",
+			start:    Vector2D{X: cfg.WorldWidth/2 - 20},
+			velocity: Vector2D{X: 800, Y: 100},
+			check: func(t *testing.T, cell *Cell, start Vector2D) {
+				if cell.Velocity.X != 0 {
+					t.Fatalf("impulse into the wall kept velocity %+v", cell.Velocity)
+				}
+				if cell.Position.X > cfg.WorldWidth-cell.Radius {
+					t.Fatalf("piece left the world: %+v", cell.Position)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewDeterministicWorld(cfg, 1)
+			player := splitReady(w, 50)
+			cell := player.Cells[0]
+			cell.Position = cell.Position.Add(tt.start)
+			cell.Velocity = tt.velocity
+			start := cell.Position
+
+			// Игрок не управляет клеткой: цель совпадает с позицией
+			for tick := 0; tick < 10*TickRate; tick++ {
+				player.TargetPos = cell.Position
+				w.updatePlayerMovement(player, TickDuration.Seconds())
+			}
+			tt.check(t, cell, start)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package game
+
+import "math"
+
+// MaxTeams - наибольшее число команд в командном режиме
+const MaxTeams = 4
+
+// TeamColors - цвета команд (команда N - TeamColors[N-1]); заменяют цвет игрока
+var TeamColors = [MaxTeams]string{"#E74C3C", "#3498DB", "#2ECC71", "#F1C40F"}
+
+// TeamTotal - живые игроки и их суммарная масса в команде
+type TeamTotal struct {
+	Team    int
+	Color   string
+	Mass    float64
+	Players int
+}
+
+// Teammates - игроки одной команды (команда 0 - каждый сам за себя)
+func Teammates(a, b *Player) bool {
+	return a.Team != 0 && a.Team == b.Team
+}
+
+// assignTeam - команда с наименьшим числом игроков; при равенстве - с меньшим номером
+// Случайность не используется: в детерминированном мире распределение повторяется
+func (w *World) assignTeam() int {
+	counts := make([]int, w.Config.Teams+1)
+	for _, player := range w.Players {
+		if player.Team > 0 && player.Team <= w.Config.Teams {
+			counts[player.Team]++
+		}
+	}
+
+	best := 1
+	for team := 2; team <= w.Config.Teams; team++ {
+		if counts[team] < counts[best] {
+			best = team
+		}
+	}
+	return best
+}
+
+// TeamTotalsUnlocked - итоги команд по возрастанию номера (БЕЗ лока)
+// Пустой список - мир не в командном режиме
+func (w *World) TeamTotalsUnlocked() []TeamTotal {
+	totals := make([]TeamTotal, w.Config.Teams)
+	for i := range totals {
+		totals[i] = TeamTotal{Team: i + 1, Color: TeamColors[i]}
+	}
+	for _, player := range w.Players {
+		if player.Team < 1 || player.Team > len(totals) || !player.IsAlive() {
+			continue
+		}
+		totals[player.Team-1].Mass += player.TotalMass()
+		totals[player.Team-1].Players++
+	}
+	return totals
+}
+
+// pushTeammates - клетки союзников не едят друг друга, а расталкиваются
+// (как свои клетки до слияния; локи обоих игроков уже взяты)
+func (w *World) pushTeammates(p1, p2 *Player) {
+	moved := make(map[*Cell]bool)
+	for _, c1 := range p1.Cells {
+		for _, c2 := range p2.Cells {
+			// Только что отделившиеся клетки пролетают сквозь союзников
+			if c1.IsLaunched() || c2.IsLaunched() {
+				continue
+			}
+
+			delta := c2.Position.Sub(c1.Position)
+			dist := delta.Length()
+			overlap := c1.Radius + c2.Radius - dist
+			if overlap <= 0 {
+				continue
+			}
+			direction := delta.Normalize()
+			if dist == 0 {
+				direction = Vector2D{X: 1, Y: 0}
+			}
+
+			// Тяжелая клетка сдвигается меньше
+			m1, m2 := c1.Mass(), c2.Mass()
+			push := overlap * w.Config.SelfPushStrength
+			c1.Position = c1.Position.Sub(direction.Mul(push * m2 / (m1 + m2)))
+			c2.Position = c2.Position.Add(direction.Mul(push * m1 / (m1 + m2)))
+			moved[c1], moved[c2] = true, true
+		}
+	}
+
+	for _, cells := range [][]*Cell{p1.Cells, p2.Cells} {
+		for _, cell := range cells {
+			if !moved[cell] {
+				continue
+			}
+			cell.Position, _ = w.collideWalls(cell.Position, cell.Radius)
+			cell.Position.X = math.Max(cell.Radius, math.Min(w.Config.WorldWidth-cell.Radius, cell.Position.X))
+			cell.Position.Y = math.Max(cell.Radius, math.Min(w.Config.WorldHeight-cell.Radius, cell.Position.Y))
+			w.cellGrid.Update(cell.ID)
+		}
+	}
+}
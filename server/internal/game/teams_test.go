@@ -0,0 +1,39 @@
+package game
+
+import "testing"
+
+func TestPushTeammatesStopsAtWalls(t *testing.T) {
+	wall := Shape{Type: ShapeRect, X: 1000, Y: 0, Width: 100, Height: 5000}
+	cfg := DefaultGameConfig()
+	cfg.MaxFoodCount = 0
+	cfg.VirusCount = 0
+	cfg.PowerUpCount = 0
+	cfg.Teams = 2
+	cfg.Map = &MapLayout{Walls: []Shape{wall}}
+	w := NewDeterministicWorld(cfg, 1)
+
+	heavy := w.AddPlayerUnlocked("heavy", "", false)
+	light := w.AddPlayerUnlocked("light", "", false)
+	heavy.Team, light.Team = 1, 1
+	big, small := heavy.Cells[0], light.Cells[0]
+	big.SetMass(400)
+	small.SetMass(100)
+
+	// Лёгкая клетка касается стены, тяжёлый союзник вдавливает её в стену
+	small.Position = Vector2D{X: wall.X - small.Radius, Y: 2500}
+	big.Position = Vector2D{X: small.Position.X - small.Radius - big.Radius + 100, Y: 2500}
+	w.cellGrid.Update(small.ID)
+	w.cellGrid.Update(big.ID)
+
+	w.pushTeammates(heavy, light)
+
+	if big.Position.X >= 1000-small.Radius-big.Radius {
+		t.Fatalf("teammates were not pushed apart: heavy at %.1f", big.Position.X)
+	}
+	if edge := small.Position.X + small.Radius; edge > wall.X+1e-6 {
+		t.Fatalf("light cell pushed %.1f into the wall", edge-wall.X)
+	}
+	if entries := w.cellGrid.QueryRadius(small.Position, 1, nil); len(entries) == 0 {
+		t.Fatal("cell grid was not updated after the wall push")
+	}
+}
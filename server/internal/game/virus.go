@@ -0,0 +1,236 @@
+package game
+
+import (
+	"agario-server/internal/events"
+	"math"
+)
+
+func (w *World) spawnInitialViruses() {
+	for i := 0; i < w.Config.VirusCount; i++ {
+		w.spawnVirus()
+	}
+}
+
+// spawnVirus - вирус в случайной точке (не у самого края)
+func (w *World) spawnVirus() *Virus {
+	margin := math.Sqrt(w.Config.VirusMass * 100.0)
+	pos := w.freePoint(margin, func() Vector2D {
+		x := margin + w.rand.Float64()*(w.Config.WorldWidth-margin*2)
+		y := margin + w.rand.Float64()*(w.Config.WorldHeight-margin*2)
+		return Vector2D{X: x, Y: y}
+	})
+
+	virus := NewVirus(w.newID(), pos, Vector2D{X: 0, Y: 0}, w.Config.VirusMass, w.Now())
+	w.addVirus(virus)
+	return virus
+}
+
+// addVirus - регистрирует вирус в мире и в индексе
+func (w *World) addVirus(virus *Virus) {
+	w.Viruses[virus.ID] = virus
+	w.virusGrid.InsertVirus(virus)
+}
+
+// removeVirus - удаляет вирус из мира и из индекса
+func (w *World) removeVirus(virusID string) {
+	delete(w.Viruses, virusID)
+	w.virusGrid.Remove(virusID)
+}
+
+// updateViruses - движение отстреленных вирусов (как у выброшенной еды)
+func (w *World) updateViruses(dt float64) {
+	w.eachVirus(func(virus *Virus) {
+		if virus.Velocity.Length() <= MinVelocity {
+			return
+		}
+
+		virus.Position = virus.Position.Add(virus.Velocity.Mul(dt))
+		virus.Velocity = virus.Velocity.Mul(0.95)
+
+		// Отскок от границ мира
+		if virus.Position.X < virus.Radius || virus.Position.X > w.Config.WorldWidth-virus.Radius {
+			virus.Velocity.X *= -0.5
+			virus.Position.X = math.Max(virus.Radius, math.Min(w.Config.WorldWidth-virus.Radius, virus.Position.X))
+		}
+		if virus.Position.Y < virus.Radius || virus.Position.Y > w.Config.WorldHeight-virus.Radius {
+			virus.Velocity.Y *= -0.5
+			virus.Position.Y = math.Max(virus.Radius, math.Min(w.Config.WorldHeight-virus.Radius, virus.Position.Y))
+		}
+
+		// Отскок от стен карты
+		var normal Vector2D
+		virus.Position, normal = w.collideWalls(virus.Position, virus.Radius)
+		virus.Velocity = bounceOff(virus.Velocity, normal, 0.5)
+
+		w.virusGrid.Update(virus.ID)
+	})
+}
+
+// checkVirusFeeding - выброшенная масса кормит вирус, сытый вирус отстреливает новый
+func (w *World) checkVirusFeeding() {
+	shot := []*Virus{}
+	var nearby []*SpatialEntry
+
+	w.eachVirus(func(virus *Virus) {
+		nearby = w.foodGrid.QueryRadius(virus.Position, virus.Radius, nearby[:0])
+		for _, entry := range nearby {
+			food := entry.Food
+			if !food.Ejected || Distance(virus.Position, food.Position) >= virus.Radius {
+				continue
+			}
+
+			// Направление отстрела - куда летела последняя порция
+			direction := food.Velocity.Normalize()
+			if direction.Length() == 0 {
+				direction = food.Position.Sub(virus.Position).Normalize()
+			}
+
+			w.removeFood(food.ID)
+			virus.FedCount++
+			virus.SetMass(virus.Mass + w.Config.VirusFeedMass)
+
+			w.EventBus.PublishEvent(events.EventVirusFed, &events.VirusFedEvent{
+				VirusID: virus.ID,
+				FoodID:  food.ID,
+				Radius:  virus.Radius,
+			})
+
+			if virus.FedCount >= w.Config.VirusFeedLimit {
+				virus.FedCount = 0
+				virus.SetMass(w.Config.VirusMass)
+
+				pos := virus.Position.Add(direction.Mul(virus.Radius * 2))
+				shot = append(shot, NewVirus(w.newID(), pos, direction.Mul(w.Config.VirusShootSpeed), w.Config.VirusMass, w.Now()))
+			}
+		}
+		w.virusGrid.Update(virus.ID)
+	})
+
+	if len(shot) == 0 {
+		return
+	}
+
+	infos := []events.VirusInfo{}
+	for _, virus := range shot {
+		w.addVirus(virus)
+		infos = append(infos, virusInfo(virus))
+	}
+	w.EventBus.PublishEvent(events.EventVirusSpawned, &events.VirusSpawnedEvent{
+		Viruses: infos,
+	})
+}
+
+// checkVirusCollisions - большая клетка, накрывшая вирус, съедает его и разрывается
+// Маленькие клетки проходят под вирусом без последствий
+func (w *World) checkVirusCollisions() {
+	var nearby []*SpatialEntry
+
+	w.eachPlayer(func(player *Player) {
+		player.Mu.Lock()
+		for _, cell := range player.Cells {
+			nearby = w.virusGrid.QueryRadius(cell.Position, cell.Radius, nearby[:0])
+			for _, entry := range nearby {
+				virus := entry.Virus
+				if cell.Mass() <= virus.Mass*w.Config.MassToEat {
+					continue
+				}
+				if Distance(cell.Position, virus.Position) >= cell.Radius {
+					continue
+				}
+
+				cell.SetMass(cell.Mass() + virus.Mass)
+				w.removeVirus(virus.ID)
+				pieces := w.popCell(player, cell)
+
+				newCellsInfo := []events.CellInfo{}
+				for _, piece := range pieces {
+					newCellsInfo = append(newCellsInfo, events.CellInfo{
+						CellID: piece.ID,
+						X:      piece.Position.X,
+						Y:      piece.Position.Y,
+						Radius: piece.Radius,
+						VelX:   piece.Velocity.X,
+						VelY:   piece.Velocity.Y,
+					})
+				}
+
+				w.EventBus.PublishEvent(events.EventVirusPopped, &events.VirusPoppedEvent{
+					VirusID:  virus.ID,
+					PlayerID: player.ID,
+					CellID:   cell.ID,
+					Radius:   cell.Radius,
+					NewCells: newCellsInfo,
+				})
+
+				// Одна клетка съедает не больше одного вируса за тик
+				break
+			}
+		}
+		player.Mu.Unlock()
+	})
+}
+
+// popCell - разрывает клетку на осколки, разлетающиеся по кругу
+// Вызывается под локом игрока
+func (w *World) popCell(player *Player, cell *Cell) []*Cell {
+	free := w.Config.PlayerMaxCells - len(player.Cells)
+	pieces := int(cell.Mass()/w.Config.VirusPieceMinMass) - 1
+	if pieces > free {
+		pieces = free
+	}
+	if pieces <= 0 {
+		w.cellGrid.Update(cell.ID)
+		return nil
+	}
+
+	pieceMass := cell.Mass() / float64(pieces+1)
+	cell.SetMass(pieceMass)
+	now := w.Now()
+	cell.LastSplitTime = now
+	cell.LastMergeTime = now
+	w.cellGrid.Update(cell.ID)
+
+	newCells := []*Cell{}
+	for i := 0; i < pieces; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(pieces)
+		direction := Vector2D{X: math.Cos(angle), Y: math.Sin(angle)}
+
+		piece := NewCell(w.newID(), cell.Position.Add(direction.Mul(cell.Radius)), 0, now)
+		piece.SetMass(pieceMass)
+		piece.Velocity = direction.Mul(w.Config.SplitImpulse)
+		newCells = append(newCells, piece)
+	}
+
+	player.Cells = append(player.Cells, newCells...)
+	for _, piece := range newCells {
+		w.cellGrid.InsertCell(player, piece)
+	}
+	return newCells
+}
+
+func (w *World) maintainViruses() {
+	toSpawn := w.Config.VirusCount - len(w.Viruses)
+	if toSpawn <= 0 {
+		return
+	}
+
+	infos := []events.VirusInfo{}
+	for i := 0; i < toSpawn; i++ {
+		infos = append(infos, virusInfo(w.spawnVirus()))
+	}
+
+	w.EventBus.PublishEvent(events.EventVirusSpawned, &events.VirusSpawnedEvent{
+		Viruses: infos,
+	})
+}
+
+func virusInfo(virus *Virus) events.VirusInfo {
+	return events.VirusInfo{
+		VirusID: virus.ID,
+		X:       virus.Position.X,
+		Y:       virus.Position.Y,
+		Radius:  virus.Radius,
+		VelX:    virus.Velocity.X,
+		VelY:    virus.Velocity.Y,
+	}
+}
@@ -0,0 +1,156 @@
+package game
+
+import (
+	"math"
+	"testing"
+
+	"agario-server/internal/events"
+)
+
+// virusWorld - мир без случайной еды и вирусов
+func virusWorld(cfg *GameConfig) *World {
+	w := NewDeterministicWorld(cfg, 1)
+	for id := range w.Food {
+		w.removeFood(id)
+	}
+	for id := range w.Viruses {
+		w.removeVirus(id)
+	}
+	w.EventBus.FlushEvents()
+	return w
+}
+
+func TestVirusPop(t *testing.T) {
+	cfg := DefaultGameConfig()
+	center := Vector2D{X: cfg.WorldWidth / 2, Y: cfg.WorldHeight / 2}
+
+	tests := []struct {
+		name   string
+		mass   float64
+		offset float64 // расстояние от центра клетки до вируса
+		cells  int     // клеток у игрока до столкновения
+		eaten  bool
+		pieces int
+	}{
+		{name: "small cell hides under the virus", mass: cfg.VirusMass, cells: 1},
+		{name: "big cell not covering the center", mass: 400, offset: 250, cells: 1},
+		{name: "pieces follow the mass", mass: 150, cells: 1, eaten: true, pieces: 14}, // (150+100)/16 - 1
+		{name: "pieces limited by free cells", mass: 400, cells: cfg.PlayerMaxCells - 3, eaten: true, pieces: 3},
+		{name: "no free cells", mass: 400, cells: cfg.PlayerMaxCells, eaten: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := virusWorld(cfg)
+			player := w.AddPlayerUnlocked("big", "#FFFFFF", false)
+			cell := player.Cells[0]
+			cell.Position = center
+			cell.SetMass(tt.mass)
+			w.cellGrid.Update(cell.ID)
+			for len(player.Cells) < tt.cells {
+				extra := NewCell(w.newID(), Vector2D{X: 100, Y: 100}, StartRadius, w.Now())
+				player.Cells = append(player.Cells, extra)
+				w.cellGrid.InsertCell(player, extra)
+			}
+			virus := NewVirus("v", center.Add(Vector2D{X: tt.offset}), Vector2D{}, cfg.VirusMass, w.Now())
+			w.addVirus(virus)
+			totalBefore := cell.Mass() + virus.Mass
+			w.EventBus.FlushEvents()
+
+			w.checkVirusCollisions()
+
+			_, alive := w.Viruses[virus.ID]
+			if alive == tt.eaten {
+				t.Fatalf("virus alive %v, want eaten %v", alive, tt.eaten)
+			}
+			if !tt.eaten {
+				if len(player.Cells) != tt.cells || cell.Mass() != tt.mass {
+					t.Fatalf("cell changed: %d cells, mass %.1f", len(player.Cells), cell.Mass())
+				}
+				return
+			}
+
+			if got := len(player.Cells) - tt.cells; got != tt.pieces {
+				t.Fatalf("%d pieces, want %d", got, tt.pieces)
+			}
+			total := cell.Mass()
+			for _, piece := range player.Cells[tt.cells:] {
+				total += piece.Mass()
+				if !piece.IsLaunched() {
+					t.Fatalf("piece %s is not flying", piece.ID)
+				}
+			}
+			if math.Abs(total-totalBefore) > 0.01 {
+				t.Fatalf("mass after pop %.2f, want %.2f", total, totalBefore)
+			}
+
+			var popped *events.VirusPoppedEvent
+			for _, event := range w.EventBus.FlushEvents() {
+				if data, ok := event.Data.(*events.VirusPoppedEvent); ok {
+					popped = data
+				}
+			}
+			if popped == nil || popped.VirusID != virus.ID || len(popped.NewCells) != tt.pieces {
+				t.Fatalf("virus_popped %+v, want %d new cells", popped, tt.pieces)
+			}
+		})
+	}
+}
+
+func TestVirusFeeding(t *testing.T) {
+	cfg := DefaultGameConfig()
+	tests := []struct {
+		name    string
+		feeds   int
+		ejected bool
+		fed     int // FedCount после кормления
+		shot    bool
+	}{
+		{name: "one feed", feeds: 1, ejected: true, fed: 1},
+		{name: "almost full", feeds: cfg.VirusFeedLimit - 1, ejected: true, fed: cfg.VirusFeedLimit - 1},
+		{name: "full virus shoots", feeds: cfg.VirusFeedLimit, ejected: true, shot: true},
+		{name: "ordinary food is ignored", feeds: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := virusWorld(cfg)
+			virus := NewVirus("v", Vector2D{X: cfg.WorldWidth / 2, Y: cfg.WorldHeight / 2}, Vector2D{}, cfg.VirusMass, w.Now())
+			w.addVirus(virus)
+
+			for i := 0; i < tt.feeds; i++ {
+				pos := virus.Position.Add(Vector2D{X: -virus.Radius / 2})
+				food := NewFood(w.newID(), pos, "#000000", w.Now())
+				if tt.ejected {
+					food = NewEjectedFood(w.newID(), pos, "#000000", cfg.EjectMass, Vector2D{X: 100}, w.Now())
+				}
+				w.addFood(food)
+				w.checkVirusFeeding()
+			}
+
+			if virus.FedCount != tt.fed {
+				t.Fatalf("fed count %d, want %d", virus.FedCount, tt.fed)
+			}
+			wantMass := cfg.VirusMass + float64(tt.fed)*cfg.VirusFeedMass
+			if math.Abs(virus.Mass-wantMass) > 0.01 {
+				t.Fatalf("virus mass %.1f, want %.1f", virus.Mass, wantMass)
+			}
+			if !tt.ejected && len(w.Food) != tt.feeds {
+				t.Fatalf("%d food left, want ordinary food kept", len(w.Food))
+			}
+
+			if got := len(w.Viruses); (got == 2) != tt.shot {
+				t.Fatalf("%d viruses, want shot %v", got, tt.shot)
+			}
+			for _, other := range w.Viruses {
+				if other == virus {
+					continue
+				}
+				// Новый вирус летит туда же, куда летела еда
+				if other.Velocity.X <= 0 || other.Velocity.Y != 0 || other.Position.X <= virus.Position.X {
+					t.Fatalf("shot virus at %+v with velocity %+v", other.Position, other.Velocity)
+				}
+			}
+		})
+	}
+}
@@ -11,54 +11,104 @@ import (
 type World struct {
 	Players  map[string]*Player
 	Food     map[string]*Food
+	Viruses  map[string]*Virus
+	PowerUps map[string]*PowerUp
 	Mu       sync.RWMutex
 	rand     *rand.Rand
+	source   *countingSource // Источник rand (считает выдачи для сохранения)
 	EventBus *events.EventBus
+	Config   *GameConfig
 	
-	// Для delta tracking
-	CurrentTick   int64
-	entityStates  map[string]*EntityState // Последнее отправленное состояние
+	// Пространственные индексы для коллизий и поиска соседей
+	foodGrid    *SpatialGrid
+	cellGrid    *SpatialGrid
+	virusGrid   *SpatialGrid
+	powerUpGrid *SpatialGrid
+	
+	// Когда может появиться следующий бонус
+	nextPowerUp time.Time
+	
+	// Номер тика (state delta строятся для каждого клиента в network)
+	CurrentTick int64
+	
+	// Детерминированный режим: время мира считается от CurrentTick,
+	// ID берутся из генератора мира, обход map - по возрастанию ID
+	deterministic bool
+	seed          int64
+	
+	// Матч королевской битвы (nil - бесконечная игра)
+	match *Match
+}
+
+// NewWorld - создание мира по правилам cfg (nil - правила по умолчанию)
+func NewWorld(cfg *GameConfig) *World {
+	return newWorld(cfg, time.Now().UnixNano(), false)
 }
 
-// EntityState - последнее известное состояние entity
-type EntityState struct {
-	LastX      float64
-	LastY      float64
-	LastRadius float64
+// NewDeterministicWorld - мир, полностью определяемый seed и вводом игроков
+func NewDeterministicWorld(cfg *GameConfig, seed int64) *World {
+	return newWorld(cfg, seed, true)
 }
 
-func NewWorld() *World {
+func newWorld(cfg *GameConfig, seed int64, deterministic bool) *World {
+	if cfg == nil {
+		cfg = DefaultGameConfig()
+	}
+	
+	source := newCountingSource(seed)
 	w := &World{
-		Players:      make(map[string]*Player),
-		Food:         make(map[string]*Food),
-		rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
-		EventBus:     events.NewEventBus(),
-		CurrentTick:  0,
-		entityStates: make(map[string]*EntityState),
+		Players:       make(map[string]*Player),
+		Food:          make(map[string]*Food),
+		Viruses:       make(map[string]*Virus),
+		PowerUps:      make(map[string]*PowerUp),
+		rand:          rand.New(source),
+		source:        source,
+		EventBus:      events.NewEventBus(),
+		Config:        cfg,
+		foodGrid:      NewSpatialGrid(cfg.WorldWidth, cfg.WorldHeight, GridCellSize),
+		cellGrid:      NewSpatialGrid(cfg.WorldWidth, cfg.WorldHeight, GridCellSize),
+		virusGrid:     NewSpatialGrid(cfg.WorldWidth, cfg.WorldHeight, GridCellSize),
+		powerUpGrid:   NewSpatialGrid(cfg.WorldWidth, cfg.WorldHeight, GridCellSize),
+		CurrentTick:   0,
+		deterministic: deterministic,
+		seed:          seed,
 	}
 	
-	// Инициализируем еду
+	// Инициализируем еду и вирусы
 	w.spawnInitialFood()
+	w.spawnInitialViruses()
+	w.resetMatch(1)
 	
 	return w
 }
 
 func (w *World) spawnInitialFood() {
-	for i := 0; i < MaxFoodCount; i++ {
+	for i := 0; i < w.Config.MaxFoodCount; i++ {
 		w.spawnFood()
 	}
 }
 
 func (w *World) spawnFood() *Food {
-	x := w.rand.Float64() * WorldWidth
-	y := w.rand.Float64() * WorldHeight
+	pos := w.freePoint(FoodRadius, w.foodPoint)
 	color := randomFoodColor(w.rand)
 	
-	food := NewFood(Vector2D{X: x, Y: y}, color)
-	w.Food[food.ID] = food
+	food := NewFood(w.newID(), pos, color, w.Now())
+	w.addFood(food)
 	return food
 }
 
+// addFood - регистрирует еду в мире и в индексе
+func (w *World) addFood(food *Food) {
+	w.Food[food.ID] = food
+	w.foodGrid.InsertFood(food)
+}
+
+// removeFood - удаляет еду из мира и из индекса
+func (w *World) removeFood(foodID string) {
+	delete(w.Food, foodID)
+	w.foodGrid.Remove(foodID)
+}
+
 // SpawnFoodUnlocked - создание еды БЕЗ лока
 func (w *World) SpawnFoodUnlocked() {
 	w.spawnFood()
@@ -72,8 +122,17 @@ func (w *World) AddPlayer(name string, color string, isBot bool) *Player {
 
 // AddPlayerUnlocked - добавление игрока БЕЗ лока (когда лок уже есть)
 func (w *World) AddPlayerUnlocked(name string, color string, isBot bool) *Player {
-	player := NewPlayer(name, color, isBot)
+	now := w.Now()
+	startCell := NewCell(w.newID(), w.spawnPosition(), StartRadius, now)
+	player := NewPlayer(w.newID(), name, color, isBot, startCell, now)
+	if w.Config.Teams > 0 {
+		player.Team = w.assignTeam()
+		player.Color = TeamColors[player.Team-1]
+	}
 	w.Players[player.ID] = player
+	for _, cell := range player.Cells {
+		w.cellGrid.InsertCell(player, cell)
+	}
 	
 	// Публикуем событие PlayerJoined
 	if len(player.Cells) > 0 {
@@ -96,9 +155,118 @@ func (w *World) AddPlayerUnlocked(name string, color string, isBot bool) *Player
 func (w *World) RemovePlayer(playerID string) {
 	w.Mu.Lock()
 	defer w.Mu.Unlock()
+	w.RemovePlayerUnlocked(playerID)
+}
+
+// RemovePlayerUnlocked - удаление игрока БЕЗ лока (когда лок уже есть)
+func (w *World) RemovePlayerUnlocked(playerID string) {
+	player, exists := w.Players[playerID]
+	if !exists {
+		return
+	}
+	for _, cell := range player.Cells {
+		w.cellGrid.Remove(cell.ID)
+	}
 	delete(w.Players, playerID)
 }
 
+// LeavePlayerUnlocked - игрок уходит из мира живым (БЕЗ лока); итоги публикуются в player_left
+func (w *World) LeavePlayerUnlocked(playerID, cause string) {
+	player, exists := w.Players[playerID]
+	if !exists {
+		return
+	}
+	w.EventBus.PublishEvent(events.EventPlayerLeft, w.leftEvent(player, cause))
+	w.RemovePlayerUnlocked(playerID)
+}
+
+// leftEvent - итоги игрока, ушедшего живым
+func (w *World) leftEvent(player *Player, cause string) *events.PlayerLeftEvent {
+	finalMass := player.TotalMass()
+	player.Mu.RLock()
+	defer player.Mu.RUnlock()
+	return &events.PlayerLeftEvent{
+		PlayerID:  player.ID,
+		Name:      player.Name,
+		IsBot:     player.IsBot,
+		Cause:     cause,
+		FinalMass: finalMass,
+		PeakMass:  math.Max(player.PeakMass, finalMass),
+		TimeAlive: w.Now().Sub(player.SpawnedAt).Seconds(),
+		Kills:     player.Kills,
+	}
+}
+
+// ApplyConfigUnlocked - заменить правила мира между тиками (БЕЗ лока, мир уже залочен)
+// Возвращает список изменений и публикует событие config_changed
+func (w *World) ApplyConfigUnlocked(cfg *GameConfig) []events.ConfigChange {
+	changes := DiffGameConfig(w.Config, cfg)
+	if len(changes) == 0 {
+		return changes
+	}
+	
+	if cfg.WorldWidth != w.Config.WorldWidth || cfg.WorldHeight != w.Config.WorldHeight {
+		w.foodGrid.Resize(cfg.WorldWidth, cfg.WorldHeight)
+		w.cellGrid.Resize(cfg.WorldWidth, cfg.WorldHeight)
+		w.virusGrid.Resize(cfg.WorldWidth, cfg.WorldHeight)
+		w.powerUpGrid.Resize(cfg.WorldWidth, cfg.WorldHeight)
+	}
+	w.Config = cfg
+	
+	w.EventBus.PublishEvent(events.EventConfigChanged, &events.ConfigChangedEvent{
+		Changes: changes,
+		Config:  cfg,
+	})
+	
+	return changes
+}
+
+// FoodInRadius - еда в радиусе от точки (БЕЗ лока, мир уже залочен)
+func (w *World) FoodInRadius(center Vector2D, radius float64) []*Food {
+	foods := []*Food{}
+	for _, entry := range w.foodGrid.QueryRadius(center, radius, nil) {
+		if Distance(center, entry.Food.Position) <= radius {
+			foods = append(foods, entry.Food)
+		}
+	}
+	return foods
+}
+
+// PlayersInRadius - игроки, у которых хотя бы одна клетка в радиусе от точки (БЕЗ лока)
+func (w *World) PlayersInRadius(center Vector2D, radius float64) []*Player {
+	players := []*Player{}
+	seen := make(map[string]bool)
+	for _, entry := range w.cellGrid.QueryRadius(center, radius, nil) {
+		if seen[entry.Player.ID] {
+			continue
+		}
+		if Distance(center, entry.Cell.Position) <= radius+entry.Cell.Radius {
+			seen[entry.Player.ID] = true
+			players = append(players, entry.Player)
+		}
+	}
+	return players
+}
+
+// EntitiesInRect - клетки, еда, вирусы и бонусы, пересекающие прямоугольник (БЕЗ лока)
+func (w *World) EntitiesInRect(minX, minY, maxX, maxY float64) []*SpatialEntry {
+	var candidates []*SpatialEntry
+	candidates = w.cellGrid.Query(minX, minY, maxX, maxY, candidates)
+	candidates = w.foodGrid.Query(minX, minY, maxX, maxY, candidates)
+	candidates = w.virusGrid.Query(minX, minY, maxX, maxY, candidates)
+	candidates = w.powerUpGrid.Query(minX, minY, maxX, maxY, candidates)
+	
+	entries := candidates[:0]
+	for _, entry := range candidates {
+		pos, radius := entry.bounds()
+		if pos.X+radius < minX || pos.X-radius > maxX || pos.Y+radius < minY || pos.Y-radius > maxY {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
 func (w *World) GetPlayer(playerID string) (*Player, bool) {
 	w.Mu.RLock()
 	defer w.Mu.RUnlock()
@@ -118,71 +286,119 @@ func (w *World) UpdateUnlocked(dt float64) {
 	w.CurrentTick++
 	
 	// Обновляем движение всех клеток
-	for _, player := range w.Players {
+	w.eachPlayer(func(player *Player) {
 		w.updatePlayerMovement(player, dt)
-	}
+	})
 	
 	// Применяем деградацию массы для больших клеток
 	w.applyMassDegradation(dt)
 	
-	// Обновляем выброшенную еду
+	// Вне безопасной зоны матча клетки теряют массу
+	w.applyZoneDrain(dt)
+	
+	// Истёкшие эффекты бонусов снимаются
+	w.updateEffects()
+	
+	// Магнит тянет еду к клеткам
+	w.applyMagnets()
+	
+	// Обновляем выброшенную и притянутую еду, отстреленные вирусы
 	w.updateFood(dt)
+	w.updateViruses(dt)
+	
+	// Выброшенная масса кормит вирусы
+	w.checkVirusFeeding()
 	
 	// Проверяем коллизии
-	w.checkCollisions()
+	w.checkCollisions(dt)
+	
+	// Большие клетки разрываются о вирусы
+	w.checkVirusCollisions()
+	
+	// Клетки подбирают бонусы
+	w.checkPowerUpPickups()
 	
 	// Проверяем слияние клеток
 	w.checkCellMerging()
 	
+	// Рекорд массы за жизнь (для статистики)
+	w.trackPeakMass()
+	
 	// Удаляем мертвых игроков
 	w.removeDeadPlayers()
 	
-	// Пополняем еду
-	w.maintainFood()
+	// Этапы матча: старт, сужение зоны, победитель
+	w.updateMatch()
 	
-	// ВАЖНО: Публикуем state delta каждые 3 тика (10 раз/сек)
-	if w.CurrentTick%3 == 0 {
-		w.publishStateDelta()
-	}
+	// Пополняем еду, вирусы и бонусы
+	w.maintainFood()
+	w.maintainViruses()
+	w.maintainPowerUps()
+}
+
+// IsDeltaTick - на этом тике клиентам отправляются state delta
+// (каждые DeltaInterval тиков, по умолчанию 3 - 10 раз/сек)
+func (w *World) IsDeltaTick() bool {
+	return w.CurrentTick%int64(w.Config.DeltaInterval) == 0
 }
 
 func (w *World) updatePlayerMovement(player *Player, dt float64) {
-player.Mu.Lock()
-defer player.Mu.Unlock()
+	player.Mu.Lock()
+	defer player.Mu.Unlock()
 
-for _, cell := range player.Cells {
-// Направление к цели
-direction := player.TargetPos.Sub(cell.Position).Normalize()
+	boosted := player.HasEffect(PowerUpSpeed, w.Now())
+	for _, cell := range player.Cells {
+		// Направление к цели
+		direction := player.TargetPos.Sub(cell.Position).Normalize()
 
-// Скорость зависит от массы
-speed := cell.Speed()
+		// Скорость зависит от массы (бонус ускоряет)
+		speed := cell.Speed(w.Config)
+		if boosted {
+			speed *= w.Config.PowerUpSpeedBoost
+		}
 
-// Обновляем позицию
-velocity := direction.Mul(speed * dt)
-newPos := cell.Position.Add(velocity)
+		// Обновляем позицию: управление игрока + импульс от сплита
+		velocity := direction.Mul(speed * dt).Add(cell.Velocity.Mul(dt))
+		newPos := cell.Position.Add(velocity)
 
-// Ограничиваем мир
-newPos.X = math.Max(cell.Radius, math.Min(WorldWidth-cell.Radius, newPos.X))
-newPos.Y = math.Max(cell.Radius, math.Min(WorldHeight-cell.Radius, newPos.Y))
+		// Импульс затухает так же, как у выброшенной еды
+		if cell.IsLaunched() {
+			cell.Velocity = cell.Velocity.Mul(w.Config.CellFriction)
+		} else {
+			cell.Velocity = Vector2D{X: 0, Y: 0}
+		}
 
-cell.Position = newPos
-}
+		// Ограничиваем мир (импульс в стену гасится)
+		if newPos.X < cell.Radius || newPos.X > w.Config.WorldWidth-cell.Radius {
+			cell.Velocity.X = 0
+		}
+		if newPos.Y < cell.Radius || newPos.Y > w.Config.WorldHeight-cell.Radius {
+			cell.Velocity.Y = 0
+		}
+		newPos.X = math.Max(cell.Radius, math.Min(w.Config.WorldWidth-cell.Radius, newPos.X))
+		newPos.Y = math.Max(cell.Radius, math.Min(w.Config.WorldHeight-cell.Radius, newPos.Y))
+
+		// Стены карты: клетка скользит вдоль стены, импульс в стену гасится
+		newPos, normal := w.collideWalls(newPos, cell.Radius)
+		cell.Velocity = bounceOff(cell.Velocity, normal, 0)
+
+		cell.Position = newPos
+		w.cellGrid.Update(cell.ID)
+	}
 }
 
 // applyMassDegradation - применяет деградацию массы для больших клеток
 func (w *World) applyMassDegradation(dt float64) {
-	const (
-		// Минимальная "безопасная" масса - ниже этого порога деградации нет
-		safeMassThreshold = 100.0
-		
-		// Базовый коэффициент деградации (очень малый)
-		baseDegradationFactor = 0.0002
-		
-		// Экспоненциальный коэффициент для очень больших клеток
-		exponentialFactor = 0.000005
-	)
+	// Минимальная "безопасная" масса - ниже этого порога деградации нет
+	safeMassThreshold := w.Config.DecaySafeMass
 	
-	for _, player := range w.Players {
+	// Базовый коэффициент деградации (очень малый)
+	baseDegradationFactor := w.Config.DecayLinearFactor
+	
+	// Экспоненциальный коэффициент для очень больших клеток
+	exponentialFactor := w.Config.DecayExponentialFactor
+	
+	w.eachPlayer(func(player *Player) {
 		player.Mu.Lock()
 		
 		for _, cell := range player.Cells {
@@ -207,15 +423,16 @@ func (w *World) applyMassDegradation(dt float64) {
 			// Применяем потерю, но не ниже порога
 			newMass := math.Max(safeMassThreshold, currentMass-totalLoss)
 			cell.SetMass(newMass)
+			w.cellGrid.Update(cell.ID)
 		}
 		
 		player.Mu.Unlock()
-	}
+	})
 }
 
 // updateFood - обновление движения выброшенной еды
 func (w *World) updateFood(dt float64) {
-	for _, food := range w.Food {
+	w.eachFood(func(food *Food) {
 		// Если еда движется
 		if food.Velocity.Length() > 0.1 {
 			// Обновляем позицию
@@ -225,61 +442,105 @@ func (w *World) updateFood(dt float64) {
 			food.Velocity = food.Velocity.Mul(0.95)
 			
 			// Ограничиваем мир
-			if food.Position.X < 0 || food.Position.X > WorldWidth {
+			if food.Position.X < 0 || food.Position.X > w.Config.WorldWidth {
 				food.Velocity.X *= -0.5
-				food.Position.X = math.Max(0, math.Min(WorldWidth, food.Position.X))
+				food.Position.X = math.Max(0, math.Min(w.Config.WorldWidth, food.Position.X))
 			}
-			if food.Position.Y < 0 || food.Position.Y > WorldHeight {
+			if food.Position.Y < 0 || food.Position.Y > w.Config.WorldHeight {
 				food.Velocity.Y *= -0.5
-				food.Position.Y = math.Max(0, math.Min(WorldHeight, food.Position.Y))
+				food.Position.Y = math.Max(0, math.Min(w.Config.WorldHeight, food.Position.Y))
 			}
+			
+			// Отскок от стен карты
+			var normal Vector2D
+			food.Position, normal = w.collideWalls(food.Position, food.Radius)
+			food.Velocity = bounceOff(food.Velocity, normal, 0.5)
+			
+			w.foodGrid.Update(food.ID)
 		}
-	}
+	})
 }
 
-func (w *World) checkCollisions() {
-	// Проверяем столкновения с едой
-	for _, player := range w.Players {
+func (w *World) checkCollisions(dt float64) {
+	// Проверяем столкновения с едой (только еда рядом с клеткой)
+	var nearby []*SpatialEntry
+	now := w.Now()
+	w.eachPlayer(func(player *Player) {
 		player.Mu.Lock()
 		for _, cell := range player.Cells {
-			for foodID, food := range w.Food {
+			nearby = w.foodGrid.QueryRadius(cell.Position, cell.Radius, nearby[:0])
+			eaten := false
+			for _, entry := range nearby {
+				food := entry.Food
 				// Не съедаем еду которая только что выброшена (0.2 секунды защиты)
-				if time.Since(food.SpawnTime).Seconds() < 0.2 {
+				if now.Sub(food.SpawnTime).Seconds() < 0.2 {
 					continue
 				}
 				if Distance(cell.Position, food.Position) < cell.Radius {
 					// Клетка съела еду - добавляем массу еды
 					cell.SetMass(cell.Mass() + food.Mass)
-					delete(w.Food, foodID)
+					w.removeFood(food.ID)
+					eaten = true
 					
 					// Публикуем событие
 					w.EventBus.PublishEvent(events.EventFoodEaten, &events.FoodEatenEvent{
-						FoodID:   foodID,
+						FoodID:   food.ID,
 						PlayerID: player.ID,
 						CellID:   cell.ID,
 					})
 				}
 			}
+			if eaten {
+				w.cellGrid.Update(cell.ID)
+			}
 		}
 		player.Mu.Unlock()
-	}
+	})
 	
-	// Проверяем столкновения между игроками
-	players := make([]*Player, 0, len(w.Players))
-	for _, p := range w.Players {
-		players = append(players, p)
+	// Проверяем столкновения между игроками - только пары, чьи клетки рядом
+	for _, pair := range w.nearbyPlayerPairs() {
+		w.checkPlayerCollision(pair[0], pair[1])
 	}
 	
-	for i := 0; i < len(players); i++ {
-		for j := i + 1; j < len(players); j++ {
-			w.checkPlayerCollision(players[i], players[j])
+	// Проверяем столкновения клеток одного игрока
+	w.eachPlayer(func(player *Player) {
+		w.checkSelfCollision(player, dt)
+	})
+}
+
+// nearbyPlayerPairs - пары разных игроков, у которых есть пересекающиеся клетки
+func (w *World) nearbyPlayerPairs() [][2]*Player {
+	pairs := [][2]*Player{}
+	seen := make(map[[2]string]bool)
+	var nearby []*SpatialEntry
+	
+	w.eachPlayer(func(player *Player) {
+		player.Mu.RLock()
+		for _, cell := range player.Cells {
+			nearby = w.cellGrid.QueryRadius(cell.Position, cell.Radius, nearby[:0])
+			for _, entry := range nearby {
+				other := entry.Player
+				if other == player {
+					continue
+				}
+				
+				key := [2]string{player.ID, other.ID}
+				p1, p2 := player, other
+				if other.ID < player.ID {
+					key = [2]string{other.ID, player.ID}
+					p1, p2 = other, player
+				}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				pairs = append(pairs, [2]*Player{p1, p2})
+			}
 		}
-	}
+		player.Mu.RUnlock()
+	})
 	
-	// Проверяем столкновения клеток одного игрока
-	for _, player := range w.Players {
-		w.checkSelfCollision(player)
-	}
+	return pairs
 }
 
 func (w *World) checkPlayerCollision(p1, p2 *Player) {
@@ -288,6 +549,16 @@ func (w *World) checkPlayerCollision(p1, p2 *Player) {
 	p2.Mu.Lock()
 	defer p2.Mu.Unlock()
 	
+	if Teammates(p1, p2) {
+		w.pushTeammates(p1, p2)
+		return
+	}
+	
+	// Клетки игрока под щитом съесть нельзя
+	now := w.Now()
+	shield1 := p1.HasEffect(PowerUpShield, now)
+	shield2 := p2.HasEffect(PowerUpShield, now)
+	
 	for i := len(p1.Cells) - 1; i >= 0; i-- {
 		for j := len(p2.Cells) - 1; j >= 0; j-- {
 			c1 := p1.Cells[i]
@@ -296,10 +567,15 @@ func (w *World) checkPlayerCollision(p1, p2 *Player) {
 			dist := Distance(c1.Position, c2.Position)
 			if dist < c1.Radius || dist < c2.Radius {
 			// Клетки касаются
-			if c1.Mass() > c2.Mass()*MassToEat {
+			if !shield2 && c1.Mass() > c2.Mass()*w.Config.MassToEat {
 			// c1 съедает c2
 			c1.SetMass(c1.Mass() + c2.Mass())
 			p2.Cells = append(p2.Cells[:j], p2.Cells[j+1:]...)
+			w.cellGrid.Remove(c2.ID)
+			w.cellGrid.Update(c1.ID)
+			if len(p2.Cells) == 0 {
+				recordKill(p1, p2, c2)
+			}
 			 
 			// Публикуем событие
 			w.EventBus.PublishEvent(events.EventCellEaten, &events.CellEatenEvent{
@@ -307,10 +583,15 @@ func (w *World) checkPlayerCollision(p1, p2 *Player) {
 			 EatenBy:     p1.ID,
 			  EaterCellID: c1.ID,
 			  })
-					} else if c2.Mass() > c1.Mass()*MassToEat {
+					} else if !shield1 && c2.Mass() > c1.Mass()*w.Config.MassToEat {
 						// c2 съедает c1
 						c2.SetMass(c2.Mass() + c1.Mass())
 						p1.Cells = append(p1.Cells[:i], p1.Cells[i+1:]...)
+						w.cellGrid.Remove(c1.ID)
+						w.cellGrid.Update(c2.ID)
+						if len(p1.Cells) == 0 {
+							recordKill(p2, p1, c1)
+						}
 						
 						// Публикуем событие
 						w.EventBus.PublishEvent(events.EventCellEaten, &events.CellEatenEvent{
@@ -325,23 +606,87 @@ func (w *World) checkPlayerCollision(p1, p2 *Player) {
 	}
 }
 
-func (w *World) checkSelfCollision(player *Player) {
+func (w *World) checkSelfCollision(player *Player, dt float64) {
 	player.Mu.Lock()
 	defer player.Mu.Unlock()
 	
-	// Клетки одного игрока не едят друг друга, только сливаются при cooldown
+	// Клетки одного игрока не едят друг друга:
+	// до cooldown они расталкиваются, после - притягиваются для слияния
+	moved := false
+	now := w.Now()
+	instant := player.HasEffect(PowerUpMerge, now)
+	for i := 0; i < len(player.Cells); i++ {
+		for j := i + 1; j < len(player.Cells); j++ {
+			c1 := player.Cells[i]
+			c2 := player.Cells[j]
+			
+			// Только что отделившиеся клетки пролетают сквозь свои
+			if c1.IsLaunched() || c2.IsLaunched() {
+				continue
+			}
+			
+			delta := c2.Position.Sub(c1.Position)
+			dist := delta.Length()
+			direction := delta.Normalize()
+			if dist == 0 {
+				direction = Vector2D{X: 1, Y: 0}
+			}
+			
+			m1 := c1.Mass()
+			m2 := c2.Mass()
+			
+			if instant || (c1.CanMerge(w.Config, now) && c2.CanMerge(w.Config, now)) {
+				// Притяжение: сближаем центры, но не дальше середины
+				pull := math.Min(w.Config.MergeAttraction*dt, dist/2)
+				c1.Position = c1.Position.Add(direction.Mul(pull * m2 / (m1 + m2)))
+				c2.Position = c2.Position.Sub(direction.Mul(pull * m1 / (m1 + m2)))
+				moved = true
+				continue
+			}
+			
+			overlap := c1.Radius + c2.Radius - dist
+			if overlap <= 0 {
+				continue
+			}
+			
+			// Отталкивание по глубине перекрытия: тяжелая клетка сдвигается меньше
+			push := overlap * w.Config.SelfPushStrength
+			c1.Position = c1.Position.Sub(direction.Mul(push * m2 / (m1 + m2)))
+			c2.Position = c2.Position.Add(direction.Mul(push * m1 / (m1 + m2)))
+			moved = true
+		}
+	}
+	
+	if !moved {
+		return
+	}
+	
+	for _, cell := range player.Cells {
+		cell.Position, _ = w.collideWalls(cell.Position, cell.Radius)
+		cell.Position.X = math.Max(cell.Radius, math.Min(w.Config.WorldWidth-cell.Radius, cell.Position.X))
+		cell.Position.Y = math.Max(cell.Radius, math.Min(w.Config.WorldHeight-cell.Radius, cell.Position.Y))
+		w.cellGrid.Update(cell.ID)
+	}
 }
 
 func (w *World) checkCellMerging() {
-	for _, player := range w.Players {
+	now := w.Now()
+	w.eachPlayer(func(player *Player) {
 		player.Mu.Lock()
 		
+		// Бонус слияния снимает cooldown
+		instant := player.HasEffect(PowerUpMerge, now)
 		for i := 0; i < len(player.Cells); i++ {
 			for j := i + 1; j < len(player.Cells); j++ {
 				c1 := player.Cells[i]
 				c2 := player.Cells[j]
 				
-				if !c1.CanMerge() || !c2.CanMerge() {
+				if !instant && (!c1.CanMerge(w.Config, now) || !c2.CanMerge(w.Config, now)) {
+					continue
+				}
+				
+				// Летящие после сплита клетки не сливаются до остановки
+				if c1.IsLaunched() || c2.IsLaunched() {
 					continue
 				}
 				
@@ -350,8 +695,10 @@ func (w *World) checkCellMerging() {
 					// Сливаем клетки
 					c2ID := c2.ID // Сохраняем ID перед удалением
 					c1.SetMass(c1.Mass() + c2.Mass())
-					c1.LastMergeTime = time.Now()
+					c1.LastMergeTime = now
 					player.Cells = append(player.Cells[:j], player.Cells[j+1:]...)
+					w.cellGrid.Remove(c2ID)
+					w.cellGrid.Update(c1.ID)
 					j--
 					
 					// Публикуем событие
@@ -369,26 +716,63 @@ func (w *World) checkCellMerging() {
 		}
 		
 		player.Mu.Unlock()
-	}
+	})
+}
+
+// trackPeakMass - обновить наибольшую массу игроков
+func (w *World) trackPeakMass() {
+	w.eachPlayer(func(player *Player) {
+		player.Mu.Lock()
+		mass := 0.0
+		for _, cell := range player.Cells {
+			mass += cell.Mass()
+		}
+		if mass > player.PeakMass {
+			player.PeakMass = mass
+		}
+		player.Mu.Unlock()
+	})
+}
+
+// recordKill - killer съел последнюю клетку victim (оба игрока под Mu)
+func recordKill(killer, victim *Player, lastCell *Cell) {
+	killer.Kills++
+	victim.KilledBy = killer.ID
+	victim.KillerName = killer.Name
+	victim.DeathCause = events.DeathEaten
+	victim.FinalMass = lastCell.Mass()
+	victim.PeakMass = math.Max(victim.PeakMass, victim.FinalMass)
 }
 
 func (w *World) removeDeadPlayers() {
-	for id, player := range w.Players {
+	w.eachPlayer(func(player *Player) {
+		id := player.ID
 		if !player.IsAlive() {
-			delete(w.Players, id)
+			w.RemovePlayerUnlocked(id)
 			
 			// Публикуем событие
+			player.Mu.RLock()
 			w.EventBus.PublishEvent(events.EventPlayerDied, &events.PlayerDiedEvent{
-				PlayerID: id,
+				PlayerID:   id,
+				Name:       player.Name,
+				IsBot:      player.IsBot,
+				Cause:      player.DeathCause,
+				KillerID:   player.KilledBy,
+				KillerName: player.KillerName,
+				FinalMass:  player.FinalMass,
+				PeakMass:   player.PeakMass,
+				TimeAlive:  w.Now().Sub(player.SpawnedAt).Seconds(),
+				Kills:      player.Kills,
 			})
+			player.Mu.RUnlock()
 		}
-	}
+	})
 }
 
 func (w *World) maintainFood() {
 	currentFood := len(w.Food)
-	if currentFood < MaxFoodCount {
-		toSpawn := MaxFoodCount - currentFood
+	if currentFood < w.Config.MaxFoodCount {
+		toSpawn := w.Config.MaxFoodCount - currentFood
 		
 		// Собираем информацию о созданной еде
 		if toSpawn > 0 {
@@ -438,21 +822,26 @@ func (w *World) splitPlayer(player *Player) {
 	player.Mu.Lock()
 	defer player.Mu.Unlock()
 	
-	if len(player.Cells) >= PlayerMaxCells {
+	if len(player.Cells) >= w.Config.PlayerMaxCells {
 		return
 	}
 	
 	newCells := []*Cell{}
+	now := w.Now()
 	
 	for _, cell := range player.Cells {
-		if !cell.CanSplit() || cell.Mass() < 20 { // уменьшили с 40 до 20
+		if len(player.Cells)+len(newCells) >= w.Config.PlayerMaxCells {
+			break
+		}
+		if !cell.CanSplit(w.Config, now) || cell.Mass() < w.Config.SplitMinMass {
 			continue
 		}
 		
-		// Делим клетку пополам
+		// Делим клетку пополам; обе половины получают cooldown слияния
 		newMass := cell.Mass() / 2
 		cell.SetMass(newMass)
-		cell.LastSplitTime = time.Now()
+		cell.LastSplitTime = now
+		cell.LastMergeTime = now
 		
 		// Направление split
 		direction := player.TargetPos.Sub(cell.Position).Normalize()
@@ -461,19 +850,20 @@ func (w *World) splitPlayer(player *Player) {
 		offset := direction.Mul(cell.Radius * 1.2)
 		newPos := cell.Position.Add(offset)
 		
-		newCell := NewCell(newPos, 0)
+		newCell := NewCell(w.newID(), newPos, 0, now)
 		newCell.SetMass(newMass)
-		newCell.LastSplitTime = time.Now()
-		newCell.LastMergeTime = time.Now()
 		
-		// Небольшой импульс вперед (не далеко!)
-		impulseSpeed := 800.0 // Фиксированная скорость
-		newCell.Velocity = direction.Mul(impulseSpeed)
+		// Импульс вперед - гасится трением в updatePlayerMovement ("split to kill")
+		newCell.Velocity = direction.Mul(w.Config.SplitImpulse)
 		
 		newCells = append(newCells, newCell)
+		w.cellGrid.Update(cell.ID)
 	}
 	
 	player.Cells = append(player.Cells, newCells...)
+	for _, cell := range newCells {
+		w.cellGrid.InsertCell(player, cell)
+	}
 	
 	// Публикуем событие если были созданы новые клетки
 	if len(newCells) > 0 {
@@ -509,6 +899,11 @@ func (w *World) Eject(playerID string) {
 	w.ejectMass(player)
 }
 
+// EjectPlayerUnlocked - выброс массы (вызывающий уже держит w.Mu)
+func (w *World) EjectPlayerUnlocked(player *Player) {
+	w.ejectMass(player)
+}
+
 // ejectMass - внутренний метод без локов
 func (w *World) ejectMass(player *Player) {
 	player.Mu.Lock()
@@ -517,12 +912,13 @@ func (w *World) ejectMass(player *Player) {
 	ejectedFoods := []events.FoodInfo{}
 	
 	for _, cell := range player.Cells {
-		if cell.Mass() < EjectMass+10 {
+		if cell.Mass() < w.Config.EjectMass+10 {
 			continue
 		}
 		
 		// Уменьшаем массу клетки
-		cell.SetMass(cell.Mass() - EjectMass)
+		cell.SetMass(cell.Mass() - w.Config.EjectMass)
+		w.cellGrid.Update(cell.ID)
 		
 		// Направление выброса
 		direction := player.TargetPos.Sub(cell.Position).Normalize()
@@ -533,15 +929,15 @@ func (w *World) ejectMass(player *Player) {
 		foodPos := cell.Position.Add(offset)
 		
 		// Скорость выброса пропорциональна массе (но не слишком быстро)
-		throwSpeed := EjectSpeed * math.Sqrt(cell.Mass()) / 10.0
-		if throwSpeed > EjectSpeed * 2 {
-			throwSpeed = EjectSpeed * 2
+		throwSpeed := w.Config.EjectSpeed * math.Sqrt(cell.Mass()) / 10.0
+		if throwSpeed > w.Config.EjectSpeed * 2 {
+			throwSpeed = w.Config.EjectSpeed * 2
 		}
 		velocity := direction.Mul(throwSpeed)
 		
 		// Добавляем еду напрямую (мир уже залочен)
-		food := NewEjectedFood(foodPos, player.Color, EjectMass, velocity)
-		w.Food[food.ID] = food
+		food := NewEjectedFood(w.newID(), foodPos, player.Color, w.Config.EjectMass, velocity, w.Now())
+		w.addFood(food)
 		
 		// Собираем информацию для события
 		ejectedFoods = append(ejectedFoods, events.FoodInfo{
@@ -573,92 +969,3 @@ func randomFoodColor(r *rand.Rand) string {
 	return colors[r.Intn(len(colors))]
 }
 
-// publishStateDelta - публикует только изменившиеся entity
-func (w *World) publishStateDelta() {
-	const POSITION_THRESHOLD = 5.0  // Игнорируем изменения < 5 пикселей
-	const RADIUS_THRESHOLD = 0.5    // Игнорируем изменения радиуса < 0.5
-	
-	deltas := []events.EntityDelta{}
-	
-	// Проверяем все клетки игроков
-	for _, player := range w.Players {
-		player.Mu.RLock()
-		
-		// Получаем target игрока
-		targetX := player.TargetPos.X
-		targetY := player.TargetPos.Y
-		
-		for _, cell := range player.Cells {
-			lastState := w.entityStates[cell.ID]
-			if lastState == nil {
-				// Новая клетка - создаем state
-				lastState = &EntityState{
-					LastX:      cell.Position.X,
-					LastY:      cell.Position.Y,
-					LastRadius: cell.Radius,
-				}
-				w.entityStates[cell.ID] = lastState
-				// Новые entity всегда отправляем
-				deltas = append(deltas, events.EntityDelta{
-					ID:      cell.ID,
-					X:       cell.Position.X,
-					Y:       cell.Position.Y,
-					Radius:  cell.Radius,
-					TargetX: targetX,
-					TargetY: targetY,
-				})
-				continue
-			}
-			
-			// Проверяем изменения
-			dx := math.Abs(cell.Position.X - lastState.LastX)
-			dy := math.Abs(cell.Position.Y - lastState.LastY)
-			dr := math.Abs(cell.Radius - lastState.LastRadius)
-			
-			if dx > POSITION_THRESHOLD || dy > POSITION_THRESHOLD || dr > RADIUS_THRESHOLD {
-				deltas = append(deltas, events.EntityDelta{
-					ID:      cell.ID,
-					X:       cell.Position.X,
-					Y:       cell.Position.Y,
-					Radius:  cell.Radius,
-					TargetX: targetX,
-					TargetY: targetY,
-				})
-				
-				// Обновляем last state
-				lastState.LastX = cell.Position.X
-				lastState.LastY = cell.Position.Y
-				lastState.LastRadius = cell.Radius
-			}
-		}
-		player.Mu.RUnlock()
-	}
-	
-	// Публикуем delta если есть изменения
-	if len(deltas) > 0 {
-		w.EventBus.PublishEvent(events.EventStateDelta, &events.StateDeltaEvent{
-			Tick:      w.CurrentTick,
-			Timestamp: time.Now().UnixMilli(),
-			Entities:  deltas,
-		})
-	}
-	
-	// Очистка: удаляем states для несуществующих клеток
-	for cellID := range w.entityStates {
-		exists := false
-		for _, player := range w.Players {
-			for _, cell := range player.Cells {
-				if cell.ID == cellID {
-					exists = true
-					break
-				}
-			}
-			if exists {
-				break
-			}
-		}
-		if !exists {
-			delete(w.entityStates, cellID)
-		}
-	}
-}
@@ -3,11 +3,17 @@ package network
 import (
 	"agario-server/internal/bot"
 	"agario-server/internal/game"
+	"agario-server/internal/replay"
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,21 +21,60 @@ import (
 )
 
 type AdminServer struct {
-	World      *game.World
-	BotManager *bot.BotManager
-	Server     *Server
-	startTime  time.Time
+	Rooms     *RoomManager
+	startTime time.Time
 }
 
-func NewAdminServer(world *game.World, botManager *bot.BotManager, server *Server) *AdminServer {
+func NewAdminServer(rooms *RoomManager) *AdminServer {
 	return &AdminServer{
-		World:      world,
-		BotManager: botManager,
-		Server:     server,
-		startTime:  time.Now(),
+		Rooms:     rooms,
+		startTime: time.Now(),
 	}
 }
 
+// ConfigDir - каталог файлов правил, которые админка может указать в ?config=
+const ConfigDir = "configs"
+
+// MapDir - каталог карт для ?map=
+var MapDir = filepath.Join(ConfigDir, "maps")
+
+var ErrUnsafePath = errors.New("path must be relative and must not contain ..")
+
+// safePath - файл name внутри каталога dir
+// Пути из запросов админки не выходят за свой каталог: абсолютные пути и .. запрещены
+func safePath(dir, name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		return "", ErrUnsafePath
+	}
+	for _, part := range strings.Split(filepath.ToSlash(name), "/") {
+		if part == ".." {
+			return "", ErrUnsafePath
+		}
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// room - комната из параметра ?room= (по умолчанию DefaultRoomName)
+func (a *AdminServer) room(c *gin.Context) (*Room, bool) {
+	name := c.DefaultQuery("room", DefaultRoomName)
+	room, exists := a.Rooms.GetRoom(name)
+	if !exists {
+		c.JSON(404, gin.H{"success": false, "error": ErrRoomNotFound.Error()})
+		return nil, false
+	}
+	return room, true
+}
+
+// liveRoom - комната из ?room=, кроме комнат реплея: их мир меняет только запись
+func (a *AdminServer) liveRoom(c *gin.Context) (*Room, bool) {
+	room, ok := a.room(c)
+	if ok && room.Server.Playback != nil {
+		c.JSON(400, gin.H{"success": false, "error": ErrReplayRoom.Error()})
+		return nil, false
+	}
+	return room, ok
+}
+
 func (a *AdminServer) Run() {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
@@ -42,6 +87,20 @@ func (a *AdminServer) Run() {
 	r.POST("/api/player/kick/:id", a.kickPlayer)
 	r.POST("/api/food/spawn", a.spawnFood)
 	r.POST("/api/gc", a.forceGC)
+	r.GET("/api/config", a.getConfig)
+	r.POST("/api/config", a.updateConfig)
+	r.GET("/api/config/audit", a.configAudit)
+	r.GET("/api/rooms", a.listRooms)
+	r.POST("/api/rooms", a.createRoom)
+	r.DELETE("/api/rooms/:name", a.destroyRoom)
+	r.POST("/api/world/save", a.saveWorld)
+	r.POST("/api/world/load", a.loadWorld)
+	r.GET("/api/scores", a.getScores)
+	r.GET("/api/history", a.getHistory)
+	r.POST("/api/replay/start", a.startRecording)
+	r.POST("/api/replay/stop", a.stopRecording)
+	r.POST("/api/replay/play", a.playReplay)
+	r.GET("/api/replays", a.listReplays)
 
 	log.Println("[ADMIN] Admin panel: http://localhost:8091/admin")
 	go r.Run(":8091")
@@ -53,11 +112,20 @@ func (a *AdminServer) serveAdminPage(c *gin.Context) {
 }
 
 func (a *AdminServer) getStats(c *gin.Context) {
-	stats := a.collectStats()
+	room, ok := a.room(c)
+	if !ok {
+		return
+	}
+	stats := a.collectStats(room)
 	c.JSON(200, stats)
 }
 
 func (a *AdminServer) statsWebSocket(c *gin.Context) {
+	room, ok := a.room(c)
+	if !ok {
+		return
+	}
+
 	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -69,7 +137,7 @@ func (a *AdminServer) statsWebSocket(c *gin.Context) {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		stats := a.collectStats()
+		stats := a.collectStats(room)
 		data, _ := json.Marshal(stats)
 		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
 			break
@@ -77,16 +145,16 @@ func (a *AdminServer) statsWebSocket(c *gin.Context) {
 	}
 }
 
-func (a *AdminServer) collectStats() map[string]interface{} {
-	a.World.Mu.RLock()
-	defer a.World.Mu.RUnlock()
+func (a *AdminServer) collectStats(room *Room) map[string]interface{} {
+	room.World.Mu.RLock()
+	defer room.World.Mu.RUnlock()
 
 	playerCount := 0
 	botCount := 0
 	totalMass := 0.0
 	cellCount := 0
 
-	for _, p := range a.World.Players {
+	for _, p := range room.World.Players {
 		if p.IsBot {
 			botCount++
 		} else {
@@ -103,19 +171,20 @@ func (a *AdminServer) collectStats() map[string]interface{} {
 	runtime.ReadMemStats(&m)
 
 	// Количество активных WebSocket соединений
-	a.Server.mu.RLock()
-	activeConnections := len(a.Server.Clients)
-	a.Server.mu.RUnlock()
+	activeConnections := room.PlayerCount() + room.SpectatorCount()
 
 	return map[string]interface{}{
+		"room":      room.Name,
+		"rooms":     len(a.Rooms.Rooms()),
 		"players":   playerCount,
 		"bots":      botCount,
-		"food":      len(a.World.Food),
+		"food":      len(room.World.Food),
+		"viruses":   len(room.World.Viruses),
 		"cells":     cellCount,
 		"totalMass": int(totalMass),
 		"worldSize": map[string]float64{
-			"width":  game.WorldWidth,
-			"height": game.WorldHeight,
+			"width":  room.World.Config.WorldWidth,
+			"height": room.World.Config.WorldHeight,
 		},
 		"performance": map[string]interface{}{
 			"goroutines":    runtime.NumGoroutine(),
@@ -124,65 +193,366 @@ func (a *AdminServer) collectStats() map[string]interface{} {
 			"gcRuns":        m.NumGC,
 			"connections":   activeConnections,
 		},
+		"clients": room.Server.ClientStats(),
 		"uptime": int(time.Since(a.startTime).Seconds()),
 	}
 }
 
 func (a *AdminServer) addBots(c *gin.Context) {
+	room, ok := a.liveRoom(c)
+	if !ok {
+		return
+	}
 	count, _ := strconv.Atoi(c.DefaultQuery("count", "5"))
 	
 	// Добавляем ботов с локом
+	room.World.Mu.Lock()
+	if !room.World.JoinOpenUnlocked() {
+		room.World.Mu.Unlock()
+		c.JSON(400, gin.H{"success": false, "error": "match in progress"})
+		return
+	}
 	for i := 0; i < count; i++ {
 		name := "Bot" + strconv.Itoa(int(time.Now().UnixNano()%100000)+i)
-		newBot := bot.NewBot(name, a.World)
-		a.BotManager.Bots = append(a.BotManager.Bots, newBot)
+		newBot := bot.NewBotUnlocked(name, room.World)
+		room.BotManager.Bots = append(room.BotManager.Bots, newBot)
+		room.BotManager.MaxBots++
+		room.Server.record(replay.Entry{Kind: replay.KindBotAdd, Player: newBot.Player.ID, Name: name, Count: room.BotManager.MaxBots})
 	}
-	a.BotManager.MaxBots += count
+	room.World.Mu.Unlock()
 
-	c.JSON(200, gin.H{"success": true, "added": count, "total": len(a.BotManager.Bots)})
+	c.JSON(200, gin.H{"success": true, "added": count, "total": len(room.BotManager.Bots)})
 }
 
 func (a *AdminServer) removeBots(c *gin.Context) {
+	room, ok := a.liveRoom(c)
+	if !ok {
+		return
+	}
 	count, _ := strconv.Atoi(c.DefaultQuery("count", "5"))
-	removed := 0
-
-	a.World.Mu.Lock()
-	for i := len(a.BotManager.Bots) - 1; i >= 0 && removed < count; i-- {
-		bot := a.BotManager.Bots[i]
-		delete(a.World.Players, bot.Player.ID)
-		a.BotManager.Bots = append(a.BotManager.Bots[:i], a.BotManager.Bots[i+1:]...)
-		removed++
+	removed := []string{}
+
+	room.World.Mu.Lock()
+	for i := len(room.BotManager.Bots) - 1; i >= 0 && len(removed) < count; i-- {
+		bot := room.BotManager.Bots[i]
+		room.World.RemovePlayerUnlocked(bot.Player.ID)
+		room.BotManager.Bots = append(room.BotManager.Bots[:i], room.BotManager.Bots[i+1:]...)
+		removed = append(removed, bot.Player.ID)
 	}
-	if a.BotManager.MaxBots > removed {
-		a.BotManager.MaxBots -= removed
+	if room.BotManager.MaxBots > len(removed) {
+		room.BotManager.MaxBots -= len(removed)
 	}
-	a.World.Mu.Unlock()
+	for _, id := range removed {
+		room.Server.record(replay.Entry{Kind: replay.KindBotRemove, Player: id, Count: room.BotManager.MaxBots})
+	}
+	room.World.Mu.Unlock()
 
-	c.JSON(200, gin.H{"success": true, "removed": removed, "total": len(a.BotManager.Bots)})
+	c.JSON(200, gin.H{"success": true, "removed": len(removed), "total": len(room.BotManager.Bots)})
 }
 
 func (a *AdminServer) kickPlayer(c *gin.Context) {
+	room, ok := a.liveRoom(c)
+	if !ok {
+		return
+	}
 	playerID := c.Param("id")
 	
-	a.World.Mu.Lock()
-	delete(a.World.Players, playerID)
-	a.World.Mu.Unlock()
+	kicked := room.Server.KickPlayer(playerID)
 
-	c.JSON(200, gin.H{"success": true})
+	c.JSON(200, gin.H{"success": true, "kicked": kicked})
 }
 
 func (a *AdminServer) spawnFood(c *gin.Context) {
+	room, ok := a.liveRoom(c)
+	if !ok {
+		return
+	}
 	count, _ := strconv.Atoi(c.DefaultQuery("count", "100"))
 	
-	a.World.Mu.Lock()
+	room.World.Mu.Lock()
 	for i := 0; i < count; i++ {
-		a.World.SpawnFoodUnlocked()
+		room.World.SpawnFoodUnlocked()
 	}
-	a.World.Mu.Unlock()
+	room.Server.record(replay.Entry{Kind: replay.KindFood, Count: count})
+	room.World.Mu.Unlock()
 
 	c.JSON(200, gin.H{"success": true, "spawned": count})
 }
 
+// getConfig - правила комнаты (только чтение)
+func (a *AdminServer) getConfig(c *gin.Context) {
+	room, ok := a.room(c)
+	if !ok {
+		return
+	}
+
+	room.World.Mu.RLock()
+	cfg := room.World.Config.Clone()
+	room.World.Mu.RUnlock()
+
+	c.JSON(200, gin.H{"success": true, "room": room.Name, "config": cfg})
+}
+
+// updateConfig - изменить правила комнаты на лету (тело - частичный JSON GameConfig)
+func (a *AdminServer) updateConfig(c *gin.Context) {
+	room, ok := a.room(c)
+	if !ok {
+		return
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	actor := c.DefaultQuery("by", c.ClientIP())
+	result := room.Server.UpdateConfig(patch, actor)
+	if result.Err != nil {
+		c.JSON(400, gin.H{"success": false, "error": result.Err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "changes": result.Changes, "config": result.Config})
+}
+
+// configAudit - журнал изменений правил комнаты
+func (a *AdminServer) configAudit(c *gin.Context) {
+	room, ok := a.room(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "room": room.Name, "entries": room.Server.Audit.Entries()})
+}
+
+func (a *AdminServer) listRooms(c *gin.Context) {
+	rooms := []gin.H{}
+	for _, room := range a.Rooms.Rooms() {
+		var match interface{}
+		if m, ok := room.World.Match(); ok {
+			match = gin.H{"number": m.Number, "phase": m.Phase, "winner": m.WinnerName}
+		}
+		rooms = append(rooms, gin.H{
+			"name":       room.Name,
+			"players":    room.PlayerCount(),
+			"spectators": room.SpectatorCount(),
+			"maxPlayers": room.Settings.MaxPlayers,
+			"bots":       room.Settings.Bots,
+			"teams":      room.World.Config.Teams,
+			"mode":       room.World.Config.Mode,
+			"map":        mapName(room.World.Config),
+			"match":      match,
+			"uptime":     int(time.Since(room.CreatedAt).Seconds()),
+			"replay":     room.Settings.Replay,
+			"recording":  room.Server.RecordingPath(),
+		})
+	}
+	c.JSON(200, gin.H{"success": true, "rooms": rooms})
+}
+
+func (a *AdminServer) createRoom(c *gin.Context) {
+	settings := DefaultRoomSettings()
+	if v, err := strconv.Atoi(c.Query("maxPlayers")); err == nil {
+		settings.MaxPlayers = v
+	}
+	if v, err := strconv.Atoi(c.Query("bots")); err == nil {
+		settings.Bots = v
+	}
+	if v, err := strconv.Atoi(c.Query("teams")); err == nil {
+		settings.Teams = v
+	}
+	settings.Mode = c.Query("mode")
+	if name := c.Query("map"); name != "" {
+		path, err := safePath(MapDir, name)
+		if err != nil {
+			c.JSON(400, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		settings.Map = path
+	}
+	if seed := c.Query("seed"); seed != "" {
+		v, err := strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			c.JSON(400, gin.H{"success": false, "error": "invalid seed"})
+			return
+		}
+		settings.Seed = &v
+	}
+	if name := c.Query("config"); name != "" {
+		path, err := safePath(ConfigDir, name)
+		if err != nil {
+			c.JSON(400, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		cfg, err := game.LoadGameConfig(path)
+		if err != nil {
+			c.JSON(400, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		settings.Config = cfg
+	}
+
+	room, err := a.Rooms.CreateRoom(c.Query("name"), settings)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "name": room.Name})
+}
+
+func (a *AdminServer) destroyRoom(c *gin.Context) {
+	if err := a.Rooms.DestroyRoom(c.Param("name")); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true})
+}
+
+// worldSavePath - файл сохранения из ?path= внутри SaveDir (по умолчанию <комната>.json)
+func worldSavePath(c *gin.Context, room *Room) (string, error) {
+	return safePath(SaveDir, c.DefaultQuery("path", room.Name+".json"))
+}
+
+func (a *AdminServer) saveWorld(c *gin.Context) {
+	room, ok := a.room(c)
+	if !ok {
+		return
+	}
+
+	path, err := worldSavePath(c, room)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	state, err := room.Server.SaveWorld(path)
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"path":    path,
+		"tick":    state.Tick,
+		"players": len(state.Players),
+		"food":    len(state.Food),
+		"viruses": len(state.Viruses),
+	})
+}
+
+func (a *AdminServer) loadWorld(c *gin.Context) {
+	room, ok := a.room(c)
+	if !ok {
+		return
+	}
+
+	path, err := worldSavePath(c, room)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	state, err := game.ReadWorldState(path)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err := room.Server.RestoreWorld(state); err != nil {
+		c.JSON(500, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "path": path, "tick": state.Tick, "players": len(state.Players)})
+}
+
+// startRecording - начать запись реплея комнаты (?path= внутри ReplayDir, по умолчанию <комната>-<время>.replay)
+func (a *AdminServer) startRecording(c *gin.Context) {
+	room, ok := a.room(c)
+	if !ok {
+		return
+	}
+
+	path, err := safePath(ReplayDir, c.DefaultQuery("path", room.Name+"-"+time.Now().Format("20060102-150405")+".replay"))
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	result := room.Server.StartRecording(path)
+	if result.Err != nil {
+		c.JSON(400, gin.H{"success": false, "error": result.Err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "path": result.Path, "startTick": result.StartTick})
+}
+
+func (a *AdminServer) stopRecording(c *gin.Context) {
+	room, ok := a.room(c)
+	if !ok {
+		return
+	}
+
+	result := room.Server.StopRecording()
+	if result.Err != nil {
+		c.JSON(400, gin.H{"success": false, "error": result.Err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success":   true,
+		"path":      result.Path,
+		"startTick": result.StartTick,
+		"endTick":   result.EndTick,
+		"entries":   result.Entries,
+	})
+}
+
+// playReplay - комната, воспроизводящая реплей ?path= внутри ReplayDir (?name=, по умолчанию replay-<файл>)
+func (a *AdminServer) playReplay(c *gin.Context) {
+	if c.Query("path") == "" {
+		c.JSON(400, gin.H{"success": false, "error": "path is required"})
+		return
+	}
+	path, err := safePath(ReplayDir, c.Query("path"))
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	name := c.DefaultQuery("name", "replay-"+strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+
+	room, err := a.Rooms.CreatePlaybackRoom(name, path)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "name": room.Name})
+}
+
+// listReplays - файлы реплеев в ReplayDir (path - как его ждёт playReplay)
+func (a *AdminServer) listReplays(c *gin.Context) {
+	replays := []gin.H{}
+	entries, err := os.ReadDir(ReplayDir)
+	if err != nil && !os.IsNotExist(err) {
+		c.JSON(500, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		replays = append(replays, gin.H{
+			"path":     entry.Name(),
+			"size":     info.Size(),
+			"modified": info.ModTime(),
+		})
+	}
+	c.JSON(200, gin.H{"success": true, "replays": replays})
+}
+
 func (a *AdminServer) forceGC(c *gin.Context) {
 	var m1 runtime.MemStats
 	runtime.ReadMemStats(&m1)
@@ -293,6 +663,24 @@ input{padding:8px;margin:5px;border-radius:5px;border:1px solid #444;background:
 <button class="danger" onclick="removeBots(20)">-20 Bots</button>
 </div>
 
+<div class="panel">
+<h2>🚪 Rooms</h2>
+<div id="rooms"></div>
+<input id="roomName" placeholder="room name">
+<input id="roomMax" placeholder="max players" size="6">
+<input id="roomBots" placeholder="bots" size="4">
+<button onclick="createRoom()">Create Room</button>
+</div>
+
+<div class="panel">
+<h2>⚙️ Game Rules</h2>
+<textarea id="config" rows="12" cols="60" style="background:#333;color:#fff;border:1px solid #444;border-radius:5px;font-family:monospace"></textarea>
+<br>
+<button onclick="applyConfig()">Apply Changes</button>
+<button onclick="loadConfig()">Reload</button>
+<div id="audit" style="font-size:12px;color:#aaa;margin-top:10px"></div>
+</div>
+
 <div class="panel">
 <h2>🍕 Food Management</h2>
 <button onclick="spawnFood(100)">+100 Food</button>
@@ -302,7 +690,9 @@ input{padding:8px;margin:5px;border-radius:5px;border:1px solid #444;background:
 </div>
 
 <script>
-const ws = new WebSocket('ws://localhost:8091/api/ws');
+const room = new URLSearchParams(location.search).get('room') || 'default';
+const q = 'room=' + encodeURIComponent(room);
+const ws = new WebSocket('ws://localhost:8091/api/ws?' + q);
 ws.onmessage = (e) => {
   const data = JSON.parse(e.data);
   document.getElementById('players').textContent = data.players;
@@ -351,23 +741,74 @@ ws.onerror = () => {
 };
 
 function addBots(n) { 
-  fetch('/api/bots/add?count='+n, {method:'POST'})
+  fetch('/api/bots/add?count='+n+'&'+q, {method:'POST'})
     .then(r=>r.json())
     .then(d=>console.log('✅ Added',d.added,'bots. Total:',d.total)); 
 }
 
 function removeBots(n) { 
-  fetch('/api/bots/remove?count='+n, {method:'POST'})
+  fetch('/api/bots/remove?count='+n+'&'+q, {method:'POST'})
     .then(r=>r.json())
     .then(d=>console.log('✅ Removed',d.removed,'bots. Total:',d.total)); 
 }
 
 function spawnFood(n) { 
-  fetch('/api/food/spawn?count='+n, {method:'POST'})
+  fetch('/api/food/spawn?count='+n+'&'+q, {method:'POST'})
     .then(r=>r.json())
     .then(d=>console.log('✅ Spawned',d.spawned,'food')); 
 }
 
+function loadRooms() {
+  fetch('/api/rooms')
+    .then(r=>r.json())
+    .then(d=>{
+      document.getElementById('rooms').innerHTML = d.rooms.map(r =>
+        '<div>' + (r.name === room ? '▶ ' : '') +
+        '<a href="/admin?room=' + encodeURIComponent(r.name) + '" style="color:#4ECDC4">' + r.name + '</a> ' +
+        r.players + '/' + r.maxPlayers + ' players, ' + r.bots + ' bots ' +
+        (r.name === 'default' ? '' : '<button class="danger" onclick="destroyRoom(\'' + r.name + '\')">Destroy</button>') +
+        '</div>').join('');
+    });
+}
+
+function createRoom() {
+  const name = document.getElementById('roomName').value;
+  const max = document.getElementById('roomMax').value;
+  const bots = document.getElementById('roomBots').value;
+  fetch('/api/rooms?name='+encodeURIComponent(name)+'&maxPlayers='+max+'&bots='+bots, {method:'POST'})
+    .then(r=>r.json())
+    .then(d=>{ if (!d.success) alert(d.error); loadRooms(); });
+}
+
+function destroyRoom(name) {
+  fetch('/api/rooms/'+encodeURIComponent(name), {method:'DELETE'})
+    .then(r=>r.json())
+    .then(d=>{ if (!d.success) alert(d.error); loadRooms(); });
+}
+
+function loadConfig() {
+  fetch('/api/config?'+q)
+    .then(r=>r.json())
+    .then(d=>{ document.getElementById('config').value = JSON.stringify(d.config, null, 2); });
+  fetch('/api/config/audit?'+q)
+    .then(r=>r.json())
+    .then(d=>{
+      document.getElementById('audit').innerHTML = d.entries.slice(-10).reverse().map(e =>
+        '<div>' + new Date(e.time).toLocaleTimeString() + ' ' + e.actor + ': ' +
+        e.changes.map(c => c.field + ' ' + c.old + ' → ' + c.new).join(', ') + '</div>').join('');
+    });
+}
+
+function applyConfig() {
+  fetch('/api/config?'+q, {method:'POST', body: document.getElementById('config').value})
+    .then(r=>r.json())
+    .then(d=>{ if (!d.success) alert(d.error); loadConfig(); });
+}
+
+loadRooms();
+loadConfig();
+setInterval(loadRooms, 3000);
+
 function forceGC() {
   fetch('/api/gc', {method:'POST'})
     .then(r=>r.json())
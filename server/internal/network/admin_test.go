@@ -0,0 +1,89 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"agario-server/internal/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSafePath(t *testing.T) {
+	tests := []struct {
+		name string
+		want string // "" - путь отклоняется
+	}{
+		{name: "default.json", want: filepath.Join(SaveDir, "default.json")},
+		{name: "nightly/default.json", want: filepath.Join(SaveDir, "nightly", "default.json")},
+		{name: "./default.json", want: filepath.Join(SaveDir, "default.json")},
+		{name: ""},
+		{name: "/etc/passwd"},
+		{name: "../server.go"},
+		{name: "nightly/../../server.go"},
+		{name: "nightly/../default.json"},
+		{name: ".."},
+	}
+
+	for _, tt := range tests {
+		got, err := safePath(SaveDir, tt.name)
+		if tt.want == "" {
+			if err != ErrUnsafePath {
+				t.Errorf("safePath(%q) = %q, %v; want ErrUnsafePath", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("safePath(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestConfigRoundTripWithMap(t *testing.T) {
+	rooms := NewRoomManager(nil)
+	stopRooms(t, rooms)
+	if _, err := rooms.CreateRoom("arena", RoomSettings{Map: filepath.Join("..", "..", MapDir, "arena.yaml")}); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	admin := NewAdminServer(rooms)
+	router := gin.New()
+	router.GET("/api/config", admin.getConfig)
+	router.POST("/api/config", admin.updateConfig)
+
+	// Админка отправляет обратно то, что получила, с изменённым полем
+	get := httptest.NewRecorder()
+	router.ServeHTTP(get, httptest.NewRequest("GET", "/api/config?room=arena", nil))
+	var loaded struct {
+		Config map[string]interface{} `json:"config"`
+	}
+	if err := json.Unmarshal(get.Body.Bytes(), &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Config["map"] == nil {
+		t.Fatalf("config of a room with a map has no map: %s", get.Body.String())
+	}
+	loaded.Config["maxFoodCount"] = 10
+	body, err := json.Marshal(loaded.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := httptest.NewRecorder()
+	router.ServeHTTP(post, httptest.NewRequest("POST", "/api/config?room=arena", bytes.NewReader(body)))
+	var applied struct {
+		Success bool                  `json:"success"`
+		Error   string                `json:"error"`
+		Changes []events.ConfigChange `json:"changes"`
+	}
+	if err := json.Unmarshal(post.Body.Bytes(), &applied); err != nil {
+		t.Fatal(err)
+	}
+	if !applied.Success || len(applied.Changes) != 1 || applied.Changes[0].Field != "maxFoodCount" {
+		t.Fatalf("apply returned %+v, want only maxFoodCount changed", applied)
+	}
+}
@@ -0,0 +1,308 @@
+package network
+
+import (
+	"agario-server/internal/events"
+	"agario-server/pkg/protocol"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// binaryCodec - кодировщик бинарного протокола одного клиента
+// Хранит таблицу коротких числовых ID сессии (используется только из writePump:
+// Run сбрасывает таблицу через Outbound.ResetCodec, а не напрямую)
+type binaryCodec struct {
+	ids    map[string]uint64
+	nextID uint64
+
+	// Текущий кадр
+	worldW float64
+	worldH float64
+	binds  []byte
+	body   []byte
+}
+
+func newBinaryCodec() *binaryCodec {
+	return &binaryCodec{
+		ids:    make(map[string]uint64),
+		nextID: 1,
+	}
+}
+
+// encode - собрать кадр из событий
+func (bc *binaryCodec) encode(batch []*events.Event, worldW, worldH float64) ([]byte, error) {
+	bc.worldW = worldW
+	bc.worldH = worldH
+	bc.binds = bc.binds[:0]
+	bc.body = bc.body[:0]
+
+	for _, event := range batch {
+		if err := bc.encodeEvent(event); err != nil {
+			return nil, err
+		}
+	}
+
+	frame := make([]byte, 0, 9+len(bc.binds)+len(bc.body))
+	frame = append(frame, protocol.BinaryMagic)
+	frame = binary.LittleEndian.AppendUint32(frame, math.Float32bits(float32(worldW)))
+	frame = binary.LittleEndian.AppendUint32(frame, math.Float32bits(float32(worldH)))
+	frame = append(frame, bc.binds...)
+	frame = append(frame, bc.body...)
+	return frame, nil
+}
+
+func (bc *binaryCodec) encodeEvent(event *events.Event) error {
+	switch data := event.Data.(type) {
+	case *events.StateDeltaEvent:
+		bc.body = append(bc.body, protocol.RecStateDelta)
+		bc.body = binary.AppendUvarint(bc.body, uint64(data.Tick))
+		bc.body = binary.AppendUvarint(bc.body, uint64(data.Timestamp))
+		bc.body = binary.AppendUvarint(bc.body, uint64(data.AckSeq))
+		bc.body = binary.AppendVarint(bc.body, data.AckTimestamp)
+		bc.body = binary.AppendUvarint(bc.body, uint64(data.AckTick))
+		bc.body = binary.AppendUvarint(bc.body, uint64(len(data.Entities)))
+		for _, e := range data.Entities {
+			bc.id(e.ID)
+			bc.position(e.X, e.Y)
+			bc.radius(e.Radius)
+			bc.position(e.TargetX, e.TargetY)
+		}
+
+	case *events.FoodSpawnedEvent:
+		bc.body = append(bc.body, protocol.RecFoodSpawned)
+		bc.foods(data.Foods)
+
+	case *events.WorldSnapshotEvent:
+		return bc.snapshot(event, data)
+
+	case *events.EntityEnteredEvent:
+		bc.body = append(bc.body, protocol.RecEntityEntered)
+		bc.body = binary.AppendUvarint(bc.body, uint64(len(data.Cells)))
+		for _, c := range data.Cells {
+			bc.id(c.CellID)
+			bc.id(c.PlayerID)
+			bc.str(c.Name)
+			bc.color(c.Color)
+			bc.flag(c.IsBot)
+			bc.position(c.X, c.Y)
+			bc.radius(c.Radius)
+		}
+		bc.foods(data.Food)
+		bc.viruses(data.Viruses)
+		if len(data.PowerUps) > 0 {
+			bc.powerUps(data.PowerUps)
+		}
+
+	case *events.PowerUpSpawnedEvent:
+		bc.powerUps(data.PowerUps)
+
+	case *events.EntityLeftEvent:
+		bc.body = append(bc.body, protocol.RecEntityLeft)
+		bc.body = binary.AppendUvarint(bc.body, uint64(len(data.IDs)))
+		for _, id := range data.IDs {
+			bc.id(id)
+		}
+		for _, id := range data.IDs {
+			delete(bc.ids, id)
+		}
+
+	case *events.FoodEatenEvent:
+		bc.body = append(bc.body, protocol.RecFoodEaten)
+		bc.id(data.FoodID)
+		bc.id(data.PlayerID)
+		bc.id(data.CellID)
+		delete(bc.ids, data.FoodID)
+
+	case *events.PowerUpCollectedEvent:
+		if err := bc.json(event); err != nil {
+			return err
+		}
+		delete(bc.ids, data.PowerUpID)
+
+	default:
+		// Редкие события - JSON внутри бинарного кадра
+		return bc.json(event)
+	}
+	return nil
+}
+
+func (bc *binaryCodec) json(event *events.Event) error {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	bc.body = append(bc.body, protocol.RecJSON)
+	bc.body = binary.AppendUvarint(bc.body, uint64(len(raw)))
+	bc.body = append(bc.body, raw...)
+	return nil
+}
+
+// snapshot - после снимка таблица ID содержит только упомянутые в нём entity
+// Бонусы идут следующей записью RecPowerUps, эффекты игроков - событиями player_effects
+func (bc *binaryCodec) snapshot(event *events.Event, data *events.WorldSnapshotEvent) error {
+	referenced := make(map[string]struct{})
+	ref := func(id string) {
+		referenced[id] = struct{}{}
+		bc.id(id)
+	}
+
+	bc.body = append(bc.body, protocol.RecSnapshot)
+	bc.body = binary.AppendUvarint(bc.body, uint64(data.Tick))
+	bc.body = binary.AppendUvarint(bc.body, uint64(data.Timestamp))
+
+	bc.body = binary.AppendUvarint(bc.body, uint64(len(data.Players)))
+	for _, p := range data.Players {
+		ref(p.ID)
+		bc.str(p.Name)
+		bc.color(p.Color)
+		bc.flag(p.IsBot)
+		bc.body = binary.AppendUvarint(bc.body, uint64(p.Score))
+		bc.body = binary.AppendUvarint(bc.body, uint64(len(p.Cells)))
+		for _, c := range p.Cells {
+			ref(c.ID)
+			bc.position(c.X, c.Y)
+			bc.radius(c.Radius)
+		}
+	}
+
+	bc.body = binary.AppendUvarint(bc.body, uint64(len(data.Food)))
+	for _, f := range data.Food {
+		ref(f.ID)
+		bc.position(f.X, f.Y)
+		bc.radius(f.Radius)
+		bc.color(f.Color)
+	}
+
+	bc.body = binary.AppendUvarint(bc.body, uint64(len(data.Viruses)))
+	for _, v := range data.Viruses {
+		ref(v.ID)
+		bc.position(v.X, v.Y)
+		bc.radius(v.Radius)
+	}
+
+	bc.body = append(bc.body, protocol.RecPowerUps)
+	bc.body = binary.AppendUvarint(bc.body, uint64(len(data.PowerUps)))
+	for _, pu := range data.PowerUps {
+		ref(pu.ID)
+		bc.position(pu.X, pu.Y)
+		bc.radius(pu.Radius)
+		bc.str(pu.Kind)
+	}
+
+	for id := range bc.ids {
+		if _, ok := referenced[id]; !ok {
+			delete(bc.ids, id)
+		}
+	}
+
+	for _, p := range data.Players {
+		if len(p.Effects) == 0 {
+			continue
+		}
+		effects := events.NewEvent(events.EventPlayerEffects, &events.PlayerEffectsEvent{PlayerID: p.ID, Effects: p.Effects})
+		effects.Timestamp = event.Timestamp
+		if err := bc.json(effects); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (bc *binaryCodec) foods(foods []events.FoodInfo) {
+	bc.body = binary.AppendUvarint(bc.body, uint64(len(foods)))
+	for _, f := range foods {
+		bc.id(f.FoodID)
+		bc.position(f.X, f.Y)
+		bc.radius(f.Radius)
+		bc.color(f.Color)
+		bc.velocity(f.VelX, f.VelY)
+	}
+}
+
+func (bc *binaryCodec) viruses(viruses []events.VirusInfo) {
+	bc.body = binary.AppendUvarint(bc.body, uint64(len(viruses)))
+	for _, v := range viruses {
+		bc.id(v.VirusID)
+		bc.position(v.X, v.Y)
+		bc.radius(v.Radius)
+		bc.velocity(v.VelX, v.VelY)
+	}
+}
+
+// powerUps - запись RecPowerUps (появление или вход в область видимости)
+func (bc *binaryCodec) powerUps(powerUps []events.PowerUpInfo) {
+	bc.body = append(bc.body, protocol.RecPowerUps)
+	bc.body = binary.AppendUvarint(bc.body, uint64(len(powerUps)))
+	for _, pu := range powerUps {
+		bc.id(pu.PowerUpID)
+		bc.position(pu.X, pu.Y)
+		bc.radius(pu.Radius)
+		bc.str(pu.Kind)
+	}
+}
+
+// id - числовой ID сессии; новый ID сначала объявляется записью RecBind
+func (bc *binaryCodec) id(stringID string) {
+	num, ok := bc.ids[stringID]
+	if !ok {
+		num = bc.nextID
+		bc.nextID++
+		bc.ids[stringID] = num
+
+		bc.binds = append(bc.binds, protocol.RecBind)
+		bc.binds = binary.AppendUvarint(bc.binds, num)
+		bc.binds = binary.AppendUvarint(bc.binds, uint64(len(stringID)))
+		bc.binds = append(bc.binds, stringID...)
+	}
+	bc.body = binary.AppendUvarint(bc.body, num)
+}
+
+func (bc *binaryCodec) position(x, y float64) {
+	bc.body = binary.LittleEndian.AppendUint16(bc.body, quantize(x, bc.worldW))
+	bc.body = binary.LittleEndian.AppendUint16(bc.body, quantize(y, bc.worldH))
+}
+
+func (bc *binaryCodec) radius(r float64) {
+	bc.body = binary.LittleEndian.AppendUint16(bc.body, uint16(math.Min(math.Round(r*protocol.RadiusScale), math.MaxUint16)))
+}
+
+func (bc *binaryCodec) velocity(vx, vy float64) {
+	bc.body = binary.LittleEndian.AppendUint16(bc.body, uint16(clampInt16(vx)))
+	bc.body = binary.LittleEndian.AppendUint16(bc.body, uint16(clampInt16(vy)))
+}
+
+func (bc *binaryCodec) color(hex string) {
+	rgb, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	if err != nil {
+		rgb = 0xFFFFFF
+	}
+	bc.body = append(bc.body, byte(rgb>>16), byte(rgb>>8), byte(rgb))
+}
+
+func (bc *binaryCodec) flag(v bool) {
+	if v {
+		bc.body = append(bc.body, 1)
+	} else {
+		bc.body = append(bc.body, 0)
+	}
+}
+
+func (bc *binaryCodec) str(s string) {
+	bc.body = binary.AppendUvarint(bc.body, uint64(len(s)))
+	bc.body = append(bc.body, s...)
+}
+
+// quantize - координата в uint16 относительно размера мира
+func quantize(v, size float64) uint16 {
+	if size <= 0 {
+		return 0
+	}
+	q := math.Round(v / size * math.MaxUint16)
+	return uint16(math.Max(0, math.Min(math.MaxUint16, q)))
+}
+
+func clampInt16(v float64) int16 {
+	return int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(v))))
+}
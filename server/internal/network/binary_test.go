@@ -0,0 +1,295 @@
+package network
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"agario-server/internal/events"
+	"agario-server/pkg/protocol"
+)
+
+const (
+	testWorldW = 5000.0
+	testWorldH = 5000.0
+)
+
+// Точность квантования: координата - 1/65535 мира, радиус - 1/RadiusScale
+var (
+	posTolerance    = testWorldW / math.MaxUint16
+	radiusTolerance = 0.5 / protocol.RadiusScale
+)
+
+func testID(kind string, i int) string {
+	return fmt.Sprintf("%s-%08d-4e5f-9a0b-%012d", kind, i, i)
+}
+
+func testSnapshot() *events.WorldSnapshotEvent {
+	snapshot := &events.WorldSnapshotEvent{Tick: 12345, Timestamp: 1700000000123}
+	for p := 0; p < 10; p++ {
+		player := events.PlayerState{ID: testID("player", p), Name: fmt.Sprintf("Player %d", p), Color: "#4ECDC4", IsBot: p%2 == 0, Score: 100 + p}
+		for c := 0; c < 4; c++ {
+			player.Cells = append(player.Cells, events.CellState{ID: testID("cell", p*10+c), X: 100.25 + float64(p*400+c), Y: 4321.5 - float64(c*17), Radius: 20 + float64(c)*3.3})
+		}
+		snapshot.Players = append(snapshot.Players, player)
+	}
+	for f := 0; f < 300; f++ {
+		snapshot.Food = append(snapshot.Food, events.FoodState{ID: testID("food", f), X: float64(f) * 16.6, Y: float64(f%50) * 99.9, Radius: 5, Color: "#F7DC6F"})
+	}
+	for v := 0; v < 8; v++ {
+		snapshot.Viruses = append(snapshot.Viruses, events.VirusState{ID: testID("virus", v), X: 600 * float64(v), Y: 2500, Radius: 100})
+	}
+	snapshot.PowerUps = []events.PowerUpState{{ID: testID("powerup", 1), Kind: "magnet", X: 1234.5, Y: 678.9, Radius: 15}}
+	return snapshot
+}
+
+func testDelta() *events.StateDeltaEvent {
+	delta := &events.StateDeltaEvent{Tick: 12348, Timestamp: 1700000000223, AckSeq: 77, AckTimestamp: -5, AckTick: 12347}
+	for i := 0; i < 40; i++ {
+		delta.Entities = append(delta.Entities, events.EntityDelta{ID: testID("cell", i), X: 10 + float64(i)*111.1, Y: 4990 - float64(i)*7.7, Radius: 31.4, TargetX: 2500, TargetY: 2500})
+	}
+	return delta
+}
+
+func testFoodSpawned() *events.FoodSpawnedEvent {
+	spawned := &events.FoodSpawnedEvent{}
+	for i := 0; i < 50; i++ {
+		spawned.Foods = append(spawned.Foods, events.FoodInfo{FoodID: testID("food", 1000+i), X: float64(i) * 97.3, Y: float64(i) * 13.1, Radius: 5, Color: "#EC7063", VelX: float64(i) - 25, VelY: -float64(i)})
+	}
+	return spawned
+}
+
+func near(t *testing.T, field string, got, want, tolerance float64) {
+	t.Helper()
+	if math.Abs(got-want) > tolerance {
+		t.Errorf("%s = %v, want %v (±%v)", field, got, want, tolerance)
+	}
+}
+
+func checkSnapshot(t *testing.T, want *events.WorldSnapshotEvent, got *protocol.SnapshotRecord) {
+	if got.Tick != want.Tick || got.Timestamp != want.Timestamp {
+		t.Errorf("tick/timestamp = %d/%d, want %d/%d", got.Tick, got.Timestamp, want.Tick, want.Timestamp)
+	}
+	if len(got.Players) != len(want.Players) || len(got.Food) != len(want.Food) || len(got.Viruses) != len(want.Viruses) || len(got.PowerUps) != len(want.PowerUps) {
+		t.Fatalf("snapshot sizes differ: got %d/%d/%d/%d players/food/viruses/power-ups", len(got.Players), len(got.Food), len(got.Viruses), len(got.PowerUps))
+	}
+	for i, p := range want.Players {
+		g := got.Players[i]
+		if g.ID != p.ID || g.Name != p.Name || g.Color != p.Color || g.IsBot != p.IsBot || g.Score != p.Score || len(g.Cells) != len(p.Cells) {
+			t.Fatalf("player %d = %+v, want %+v", i, g, p)
+		}
+		for j, c := range p.Cells {
+			if g.Cells[j].ID != c.ID {
+				t.Errorf("cell id = %s, want %s", g.Cells[j].ID, c.ID)
+			}
+			near(t, "cell.x", g.Cells[j].X, c.X, posTolerance)
+			near(t, "cell.y", g.Cells[j].Y, c.Y, posTolerance)
+			near(t, "cell.radius", g.Cells[j].Radius, c.Radius, radiusTolerance)
+		}
+	}
+	for i, f := range want.Food {
+		g := got.Food[i]
+		if g.ID != f.ID || g.Color != f.Color {
+			t.Fatalf("food %d = %+v, want %+v", i, g, f)
+		}
+		near(t, "food.x", g.X, f.X, posTolerance)
+		near(t, "food.y", g.Y, f.Y, posTolerance)
+	}
+	for i, v := range want.Viruses {
+		if got.Viruses[i].ID != v.ID {
+			t.Errorf("virus id = %s, want %s", got.Viruses[i].ID, v.ID)
+		}
+		near(t, "virus.radius", got.Viruses[i].Radius, v.Radius, radiusTolerance)
+	}
+	if pu := got.PowerUps[0]; pu.ID != want.PowerUps[0].ID || pu.Kind != want.PowerUps[0].Kind {
+		t.Errorf("power-up = %+v, want %+v", pu, want.PowerUps[0])
+	}
+}
+
+func checkDelta(t *testing.T, want *events.StateDeltaEvent, got *protocol.StateDeltaRecord) {
+	if got.Tick != want.Tick || got.Timestamp != want.Timestamp || got.AckSeq != want.AckSeq || got.AckTimestamp != want.AckTimestamp || got.AckTick != want.AckTick {
+		t.Errorf("delta header = %+v, want %+v", got, want)
+	}
+	if len(got.Entities) != len(want.Entities) {
+		t.Fatalf("delta entities = %d, want %d", len(got.Entities), len(want.Entities))
+	}
+	for i, e := range want.Entities {
+		g := got.Entities[i]
+		if g.ID != e.ID {
+			t.Errorf("entity id = %s, want %s", g.ID, e.ID)
+		}
+		near(t, "x", g.X, e.X, posTolerance)
+		near(t, "y", g.Y, e.Y, posTolerance)
+		near(t, "radius", g.Radius, e.Radius, radiusTolerance)
+		near(t, "targetX", g.TargetX, e.TargetX, posTolerance)
+		near(t, "targetY", g.TargetY, e.TargetY, posTolerance)
+	}
+}
+
+func checkFoodSpawned(t *testing.T, want *events.FoodSpawnedEvent, got *protocol.FoodSpawnedRecord) {
+	if len(got.Foods) != len(want.Foods) {
+		t.Fatalf("foods = %d, want %d", len(got.Foods), len(want.Foods))
+	}
+	for i, f := range want.Foods {
+		g := got.Foods[i]
+		if g.ID != f.FoodID || g.Color != f.Color || g.VelX != f.VelX || g.VelY != f.VelY {
+			t.Errorf("food %d = %+v, want %+v", i, g, f)
+		}
+		near(t, "x", g.X, f.X, posTolerance)
+		near(t, "y", g.Y, f.Y, posTolerance)
+		near(t, "radius", g.Radius, f.Radius, radiusTolerance)
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		event *events.Event
+		check func(t *testing.T, record interface{})
+		// Минимальный выигрыш относительно JSON: первый кадр объявляет строковые ID,
+		// повторный (ID уже в таблице) - только числовые
+		minRatio       float64
+		minSteadyRatio float64
+	}{
+		{
+			name:  "snapshot",
+			event: events.NewEvent(events.EventWorldSnapshot, testSnapshot()),
+			check: func(t *testing.T, record interface{}) {
+				checkSnapshot(t, testSnapshot(), record.(*protocol.SnapshotRecord))
+			},
+			minRatio:       2,
+			minSteadyRatio: 5,
+		},
+		{
+			name:  "state delta",
+			event: events.NewEvent(events.EventStateDelta, testDelta()),
+			check: func(t *testing.T, record interface{}) {
+				checkDelta(t, testDelta(), record.(*protocol.StateDeltaRecord))
+			},
+			minRatio:       2,
+			minSteadyRatio: 8,
+		},
+		{
+			name:  "food spawned",
+			event: events.NewEvent(events.EventFoodSpawned, testFoodSpawned()),
+			check: func(t *testing.T, record interface{}) {
+				checkFoodSpawned(t, testFoodSpawned(), record.(*protocol.FoodSpawnedRecord))
+			},
+			minRatio:       2,
+			minSteadyRatio: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonFrame, err := events.SerializeBatch([]*events.Event{tt.event})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			codec := newBinaryCodec()
+			ids := protocol.BinaryIDs{}
+			var sizes [2]int
+			for pass := range sizes {
+				frame, err := codec.encode([]*events.Event{tt.event}, testWorldW, testWorldH)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sizes[pass] = len(frame)
+
+				decoded, err := protocol.DecodeBinary(frame, ids)
+				if err != nil {
+					t.Fatalf("pass %d: %v", pass, err)
+				}
+				if decoded.WorldWidth != testWorldW || decoded.WorldHeight != testWorldH {
+					t.Errorf("world = %vx%v", decoded.WorldWidth, decoded.WorldHeight)
+				}
+				if len(decoded.Records) != 1 {
+					t.Fatalf("pass %d: %d records, want 1", pass, len(decoded.Records))
+				}
+				tt.check(t, decoded.Records[0])
+			}
+
+			ratio := float64(len(jsonFrame)) / float64(sizes[0])
+			steady := float64(len(jsonFrame)) / float64(sizes[1])
+			t.Logf("JSON %d bytes, binary %d (x%.1f), with known IDs %d (x%.1f)", len(jsonFrame), sizes[0], ratio, sizes[1], steady)
+			if ratio < tt.minRatio || steady < tt.minSteadyRatio {
+				t.Errorf("size cut x%.1f / x%.1f, want at least x%.0f / x%.0f", ratio, steady, tt.minRatio, tt.minSteadyRatio)
+			}
+		})
+	}
+}
+
+// Таблица ID получателя меняется так же, как у сервера
+func TestBinaryIDTable(t *testing.T) {
+	codec := newBinaryCodec()
+	ids := protocol.BinaryIDs{}
+	decode := func(batch ...*events.Event) *protocol.BinaryFrame {
+		t.Helper()
+		frame, err := codec.encode(batch, testWorldW, testWorldH)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := protocol.DecodeBinary(frame, ids)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return decoded
+	}
+	sameTable := func(step string) {
+		t.Helper()
+		if len(ids) != len(codec.ids) {
+			t.Fatalf("%s: receiver has %d ids, sender %d", step, len(ids), len(codec.ids))
+		}
+		for id, num := range codec.ids {
+			if ids[num] != id {
+				t.Fatalf("%s: id %d = %q, want %q", step, num, ids[num], id)
+			}
+		}
+	}
+
+	decode(events.NewEvent(events.EventFoodSpawned, testFoodSpawned()))
+	sameTable("food spawned")
+
+	decode(events.NewEvent(events.EventFoodEaten, &events.FoodEatenEvent{FoodID: testID("food", 1000), PlayerID: testID("player", 1), CellID: testID("cell", 10)}))
+	sameTable("food eaten")
+
+	decode(events.NewEvent(events.EventEntityLeft, &events.EntityLeftEvent{IDs: []string{testID("food", 1001), testID("food", 1002)}}))
+	sameTable("entity left")
+
+	// Снимок и события после него в одном кадре: ID, объявленные после снимка, не забываются
+	decoded := decode(
+		events.NewEvent(events.EventWorldSnapshot, testSnapshot()),
+		events.NewEvent(events.EventStateDelta, &events.StateDeltaEvent{Tick: 1, Entities: []events.EntityDelta{{ID: "late-cell", X: 1, Y: 2, Radius: 20}}}),
+		events.NewEvent(events.EventPowerUpCollected, &events.PowerUpCollectedEvent{PowerUpID: testID("powerup", 1), PlayerID: testID("player", 0)}),
+	)
+	if len(decoded.Records) != 3 {
+		t.Fatalf("%d records, want 3", len(decoded.Records))
+	}
+	sameTable("snapshot")
+
+	// Пересинхронизация: новая таблица с номера 1
+	codec = newBinaryCodec()
+	decode(events.NewEvent(events.EventWorldSnapshot, &events.WorldSnapshotEvent{Players: []events.PlayerState{{ID: "p", Cells: []events.CellState{{ID: "c"}}}}}))
+	sameTable("resync")
+}
+
+func TestDecodeBinaryRejectsCorruptFrames(t *testing.T) {
+	frame, err := newBinaryCodec().encode([]*events.Event{events.NewEvent(events.EventStateDelta, testDelta())}, testWorldW, testWorldH)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]byte{
+		"empty":          {},
+		"bad magic":      append([]byte{0x00}, frame[1:]...),
+		"truncated":      frame[:len(frame)-3],
+		"unknown record": append(append([]byte(nil), frame...), 0x42),
+		"unbound id":     {protocol.BinaryMagic, 0, 0, 0, 0, 0, 0, 0, 0, protocol.RecFoodEaten, 9, 9, 9},
+	}
+	for name, data := range tests {
+		if _, err := protocol.DecodeBinary(data, protocol.BinaryIDs{}); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package network
+
+import (
+	"agario-server/internal/events"
+	"agario-server/internal/game"
+	"agario-server/internal/replay"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// maxAuditEntries - сколько последних изменений правил хранится в памяти
+const maxAuditEntries = 200
+
+var ErrConfigUpdateTimeout = errors.New("config update was not applied in time")
+
+// ConfigUpdate - запрос на изменение правил, применяется в Run между тиками
+type ConfigUpdate struct {
+	Patch  []byte // Частичный JSON поверх текущих правил
+	Actor  string // Кто меняет (для аудита)
+	Result chan ConfigUpdateResult
+}
+
+type ConfigUpdateResult struct {
+	Changes []events.ConfigChange
+	Config  *game.GameConfig
+	Err     error
+}
+
+// ConfigAuditEntry - запись журнала изменений правил
+type ConfigAuditEntry struct {
+	Time    time.Time             `json:"time"`
+	Room    string                `json:"room"`
+	Actor   string                `json:"actor"`
+	Changes []events.ConfigChange `json:"changes"`
+}
+
+// ConfigAudit - журнал изменений правил комнаты
+type ConfigAudit struct {
+	entries []ConfigAuditEntry
+	mu      sync.RWMutex
+}
+
+func (a *ConfigAudit) Record(entry ConfigAuditEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.entries = append(a.entries, entry)
+	if len(a.entries) > maxAuditEntries {
+		a.entries = a.entries[len(a.entries)-maxAuditEntries:]
+	}
+}
+
+// Entries - копия журнала, от старых к новым
+func (a *ConfigAudit) Entries() []ConfigAuditEntry {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	entries := make([]ConfigAuditEntry, len(a.entries))
+	copy(entries, a.entries)
+	return entries
+}
+
+// UpdateConfig - поставить изменение правил в очередь и дождаться применения
+func (s *Server) UpdateConfig(patch []byte, actor string) ConfigUpdateResult {
+	update := &ConfigUpdate{
+		Patch:  patch,
+		Actor:  actor,
+		Result: make(chan ConfigUpdateResult, 1),
+	}
+
+	select {
+	case s.ConfigUpdates <- update:
+	case <-s.done:
+		return ConfigUpdateResult{Err: ErrRoomNotFound}
+	}
+
+	select {
+	case result := <-update.Result:
+		return result
+	case <-time.After(5 * time.Second):
+		return ConfigUpdateResult{Err: ErrConfigUpdateTimeout}
+	}
+}
+
+// applyConfigUpdate - вызывается из Run между тиками
+func (s *Server) applyConfigUpdate(update *ConfigUpdate) {
+	if s.Playback != nil {
+		update.Result <- ConfigUpdateResult{Err: ErrReplayRoom}
+		return
+	}
+
+	s.World.Mu.Lock()
+	next, err := game.PatchGameConfig(s.World.Config, update.Patch)
+	if err != nil {
+		s.World.Mu.Unlock()
+		update.Result <- ConfigUpdateResult{Err: err}
+		return
+	}
+	changes := s.World.ApplyConfigUnlocked(next)
+	s.record(replay.Entry{Kind: replay.KindConfig, Config: next})
+	s.World.Mu.Unlock()
+
+	if len(changes) > 0 {
+		s.Audit.Record(ConfigAuditEntry{
+			Time:    time.Now(),
+			Room:    s.Room,
+			Actor:   update.Actor,
+			Changes: changes,
+		})
+		for _, change := range changes {
+			log.Printf("[CONFIG] Room %q: %s changed %v -> %v by %s", s.Room, change.Field, change.Old, change.New, update.Actor)
+		}
+	}
+
+	update.Result <- ConfigUpdateResult{Changes: changes, Config: next}
+}
@@ -0,0 +1,194 @@
+package network
+
+import (
+	"agario-server/internal/events"
+	"agario-server/pkg/protocol"
+	"math"
+	"time"
+)
+
+// Параметры state delta
+const (
+	DeltaPositionThreshold = 5.0 // изменения меньше не отправляются
+	DeltaRadiusThreshold   = 0.5
+	MaxAckGapTicks         = 90 // 3 секунды без ack отправленного кадра - повторный snapshot
+)
+
+// cellBaseline - состояние клетки, которое есть у клиента
+type cellBaseline struct {
+	X      float64
+	Y      float64
+	Radius float64
+}
+
+func (b cellBaseline) changed(other cellBaseline) bool {
+	return math.Abs(b.X-other.X) > DeltaPositionThreshold ||
+		math.Abs(b.Y-other.Y) > DeltaPositionThreshold ||
+		math.Abs(b.Radius-other.Radius) > DeltaRadiusThreshold
+}
+
+// DeltaTracker - базовое состояние клиента для state delta (используется только из Run)
+// Delta считается от последнего подтверждённого клиентом кадра, поэтому
+// потерянный кадр не ломает синхронизацию: изменения уйдут повторно.
+// Клиент без ack (старый протокол) считается получившим всё отправленное.
+type DeltaTracker struct {
+	Acks      bool  // Клиент подтверждает тики
+	AckedTick int64 // Последний подтверждённый тик
+
+	acked  map[string]cellBaseline           // Состояние клиента на AckedTick
+	frames map[int64]map[string]cellBaseline // Отправленные, ещё не подтверждённые кадры
+	oldest int64                             // Самый старый неподтверждённый кадр (0 - нет)
+}
+
+func NewDeltaTracker() *DeltaTracker {
+	return &DeltaTracker{
+		acked:  make(map[string]cellBaseline),
+		frames: make(map[int64]map[string]cellBaseline),
+	}
+}
+
+// sent - кадр tick ушёл клиенту
+func (dt *DeltaTracker) sent(tick int64, frame map[string]cellBaseline) {
+	if !dt.Acks {
+		dt.acked = frame
+		dt.AckedTick = tick
+		return
+	}
+	dt.frames[tick] = frame
+	if dt.oldest == 0 {
+		dt.oldest = tick
+	}
+}
+
+// ack - клиент получил кадр tick; более старые кадры больше не нужны
+func (dt *DeltaTracker) ack(tick int64) {
+	if !dt.Acks {
+		// Первый ack: дальше считаем только от подтверждённых кадров
+		dt.Acks = true
+		dt.frames = make(map[int64]map[string]cellBaseline)
+	}
+	if tick <= dt.AckedTick {
+		return
+	}
+
+	if frame, ok := dt.frames[tick]; ok {
+		dt.acked = frame
+		dt.AckedTick = tick
+	}
+
+	dt.oldest = 0
+	for t := range dt.frames {
+		if t <= tick {
+			delete(dt.frames, t)
+		} else if dt.oldest == 0 || t < dt.oldest {
+			dt.oldest = t
+		}
+	}
+}
+
+// needsResync - клиент слишком долго не подтверждает отправленное
+func (dt *DeltaTracker) needsResync(tick int64) bool {
+	return dt.Acks && dt.oldest != 0 && tick-dt.oldest > MaxAckGapTicks
+}
+
+// reset - после snapshot старые кадры не имеют значения
+func (dt *DeltaTracker) reset() {
+	dt.frames = make(map[int64]map[string]cellBaseline)
+	dt.oldest = 0
+}
+
+// processAck - подтверждение тика от клиента
+func (s *Server) processAck(cmd *PlayerCommand) {
+	data, ok := cmd.Data.(*protocol.AckData)
+	if !ok {
+		return
+	}
+
+	s.mu.RLock()
+	client, ok := s.Clients[cmd.ClientID]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	client.Delta.ack(data.Tick)
+}
+
+// appendDelta - state delta клиента относительно его базового состояния (под локом мира)
+// Пустая delta отправляется только если нужно подтвердить ввод
+func (s *Server) appendDelta(client *Client, batch []*events.Event) []*events.Event {
+	in := client.Interest
+	dt := client.Delta
+	tick := s.World.CurrentTick
+
+	frame := make(map[string]cellBaseline)
+	entities := []events.EntityDelta{}
+
+	for _, player := range s.World.Players {
+		player.Mu.RLock()
+		targetX := player.TargetPos.X
+		targetY := player.TargetPos.Y
+
+		for _, cell := range player.Cells {
+			if !in.knows(cell.ID) {
+				continue
+			}
+
+			current := cellBaseline{X: cell.Position.X, Y: cell.Position.Y, Radius: cell.Radius}
+			if prev, ok := dt.acked[cell.ID]; ok && !prev.changed(current) {
+				// У клиента остаётся прежнее состояние
+				frame[cell.ID] = prev
+				continue
+			}
+
+			frame[cell.ID] = current
+			entities = append(entities, events.EntityDelta{
+				ID:      cell.ID,
+				X:       current.X,
+				Y:       current.Y,
+				Radius:  current.Radius,
+				TargetX: targetX,
+				TargetY: targetY,
+			})
+		}
+		player.Mu.RUnlock()
+	}
+
+	if len(entities) == 0 && !client.Input.pendingAck() {
+		return batch
+	}
+
+	delta := &events.StateDeltaEvent{
+		Tick:      tick,
+		Timestamp: time.Now().UnixMilli(),
+		Entities:  entities,
+	}
+	s.attachAck(client, delta)
+	dt.sent(tick, frame)
+
+	return append(batch, events.NewEvent(events.EventStateDelta, delta))
+}
+
+// recordSnapshot - snapshot становится кадром базового состояния клиента
+func (dt *DeltaTracker) recordSnapshot(snapshot *events.WorldSnapshotEvent) {
+	frame := make(map[string]cellBaseline)
+	for _, player := range snapshot.Players {
+		for _, cell := range player.Cells {
+			frame[cell.ID] = cellBaseline{X: cell.X, Y: cell.Y, Radius: cell.Radius}
+		}
+	}
+	dt.reset()
+	dt.sent(snapshot.Tick, frame)
+}
+
+// resync - полный snapshot области видимости вместо delta (под локом мира)
+// Бинарный клиент получает новую таблицу ID: часть RecBind могла потеряться
+func (s *Server) resync(client *Client, batch []*events.Event) []*events.Event {
+	client.Out.ResetCodec()
+
+	snapshot := s.buildSnapshot(client)
+	client.Delta.recordSnapshot(snapshot)
+
+	// Глобальные события тика (смерть, смена правил) не должны теряться
+	resynced := []*events.Event{events.NewEvent(events.EventWorldSnapshot, snapshot)}
+	return append(resynced, s.filterEvents(client, batch)...)
+}
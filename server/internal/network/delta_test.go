@@ -0,0 +1,188 @@
+package network
+
+import (
+	"testing"
+
+	"agario-server/internal/events"
+	"agario-server/internal/game"
+)
+
+func TestDeltaTrackerAck(t *testing.T) {
+	frame := func(x float64) map[string]cellBaseline {
+		return map[string]cellBaseline{"c": {X: x, Y: 0, Radius: 10}}
+	}
+
+	tests := []struct {
+		name      string
+		steps     func(dt *DeltaTracker)
+		ackedTick int64
+		baselineX float64 // X клетки "c" в подтверждённом состоянии (-1 - нет)
+		resyncAt  int64
+		resync    bool
+	}{
+		{
+			name: "client without acks takes everything sent",
+			steps: func(dt *DeltaTracker) {
+				dt.sent(3, frame(30))
+				dt.sent(6, frame(60))
+			},
+			ackedTick: 6,
+			baselineX: 60,
+			resyncAt:  6 + 10*MaxAckGapTicks,
+		},
+		{
+			name: "ack adopts the acked frame",
+			steps: func(dt *DeltaTracker) {
+				dt.ack(0)
+				dt.sent(3, frame(30))
+				dt.sent(6, frame(60))
+				dt.ack(3)
+			},
+			ackedTick: 3,
+			baselineX: 30,
+			resyncAt:  6 + MaxAckGapTicks,
+		},
+		{
+			name: "lost frame leaves the older baseline",
+			steps: func(dt *DeltaTracker) {
+				dt.ack(0)
+				dt.sent(3, frame(30))
+				dt.sent(6, frame(60))
+				dt.sent(9, frame(90))
+				dt.ack(9)
+			},
+			ackedTick: 9,
+			baselineX: 90,
+			resyncAt:  9 + 10*MaxAckGapTicks,
+		},
+		{
+			name: "stale ack is ignored",
+			steps: func(dt *DeltaTracker) {
+				dt.ack(0)
+				dt.sent(3, frame(30))
+				dt.sent(6, frame(60))
+				dt.ack(6)
+				dt.ack(3)
+			},
+			ackedTick: 6,
+			baselineX: 60,
+		},
+		{
+			name: "ack of an unknown tick keeps the baseline",
+			steps: func(dt *DeltaTracker) {
+				dt.ack(0)
+				dt.sent(3, frame(30))
+				dt.ack(3)
+				dt.sent(6, frame(60))
+				dt.ack(5)
+			},
+			ackedTick: 3,
+			baselineX: 30,
+			resyncAt:  6 + MaxAckGapTicks,
+		},
+		{
+			name: "no ack for too long",
+			steps: func(dt *DeltaTracker) {
+				dt.ack(0)
+				dt.sent(3, frame(30))
+				dt.sent(6, frame(60))
+			},
+			baselineX: -1,
+			resyncAt:  3 + MaxAckGapTicks + 1,
+			resync:    true,
+		},
+		{
+			name: "snapshot restarts the gap",
+			steps: func(dt *DeltaTracker) {
+				dt.ack(0)
+				dt.sent(3, frame(30))
+				dt.recordSnapshot(&events.WorldSnapshotEvent{
+					Tick: 100,
+					Players: []events.PlayerState{{
+						ID:    "p",
+						Cells: []events.CellState{{ID: "c", X: 1000, Radius: 10}},
+					}},
+				})
+				dt.ack(100)
+			},
+			ackedTick: 100,
+			baselineX: 1000,
+			resyncAt:  100 + 10*MaxAckGapTicks,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dt := NewDeltaTracker()
+			tt.steps(dt)
+
+			if dt.AckedTick != tt.ackedTick {
+				t.Fatalf("acked tick %d, want %d", dt.AckedTick, tt.ackedTick)
+			}
+			baseline, ok := dt.acked["c"]
+			switch {
+			case tt.baselineX < 0 && ok:
+				t.Fatalf("baseline %+v, want none", baseline)
+			case tt.baselineX >= 0 && (!ok || baseline.X != tt.baselineX):
+				t.Fatalf("baseline %+v (present %v), want x=%.0f", baseline, ok, tt.baselineX)
+			}
+			if tt.resyncAt != 0 && dt.needsResync(tt.resyncAt) != tt.resync {
+				t.Fatalf("needsResync(%d) = %v, want %v", tt.resyncAt, !tt.resync, tt.resync)
+			}
+		})
+	}
+}
+
+// deltaCells - ID клеток в state delta, которую сервер собрал бы для клиента
+func deltaCells(s *Server, client *Client) map[string]bool {
+	cells := map[string]bool{}
+	for _, event := range s.appendDelta(client, nil) {
+		for _, entity := range event.Data.(*events.StateDeltaEvent).Entities {
+			cells[entity.ID] = true
+		}
+	}
+	return cells
+}
+
+func TestDeltaResendsUntilAcked(t *testing.T) {
+	world := game.NewDeterministicWorld(nil, 1)
+	server := NewServer(world)
+	player := world.AddPlayerUnlocked("mover", "#FFFFFF", false)
+	cell := player.Cells[0]
+
+	client := wholeWorldClient(world)
+	client.Input = NewInputState()
+	server.diffInterest(client)
+	client.Delta.ack(0)
+
+	world.CurrentTick = 3
+	if cells := deltaCells(server, client); !cells[cell.ID] {
+		t.Fatalf("first delta %v has no cell %s", cells, cell.ID)
+	}
+	client.Delta.ack(3)
+
+	// Клетка сдвинулась, но кадр с этим изменением не подтверждён
+	cell.Position.X += 10 * DeltaPositionThreshold
+	world.CurrentTick = 6
+	if cells := deltaCells(server, client); !cells[cell.ID] {
+		t.Fatalf("moved cell missing from delta %v", cells)
+	}
+	world.CurrentTick = 9
+	if cells := deltaCells(server, client); !cells[cell.ID] {
+		t.Fatalf("unacked change not resent: delta %v", cells)
+	}
+
+	// После ack изменение у клиента есть, delta пустая
+	client.Delta.ack(9)
+	world.CurrentTick = 12
+	if cells := deltaCells(server, client); len(cells) != 0 {
+		t.Fatalf("acked change resent: delta %v", cells)
+	}
+
+	// Сдвиг меньше порога не отправляется
+	cell.Position.X += DeltaPositionThreshold / 2
+	world.CurrentTick = 15
+	if cells := deltaCells(server, client); len(cells) != 0 {
+		t.Fatalf("change below the threshold sent: delta %v", cells)
+	}
+}
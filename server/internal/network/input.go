@@ -0,0 +1,147 @@
+package network
+
+import (
+	"agario-server/internal/events"
+	"agario-server/internal/game"
+	"agario-server/internal/replay"
+	"agario-server/pkg/protocol"
+	"sort"
+)
+
+// Параметры очереди ввода клиента
+const (
+	MaxQueuedInputs  = 64 // при переполнении отбрасываются самые старые
+	MaxInputsPerTick = 8  // остальное применяется на следующих тиках
+)
+
+// queuedInput - ввод, ожидающий применения в тике
+type queuedInput struct {
+	Type protocol.MessageType
+	Seq  uint32
+	Time int64
+	Move *protocol.MoveData
+}
+
+// InputState - очередь ввода клиента и последнее подтверждение (используется только из Run)
+type InputState struct {
+	queue []queuedInput
+
+	LastSeq  uint32 // Последний применённый seq
+	LastTime int64  // Время клиента из этого ввода
+	LastTick int64  // Тик, на котором он применён
+	ackedSeq uint32 // Последний seq, отправленный клиенту в delta
+}
+
+func NewInputState() *InputState {
+	return &InputState{}
+}
+
+// push - поставить ввод в очередь; повторы и устаревшие seq отбрасываются
+func (is *InputState) push(input queuedInput) {
+	if input.Seq != 0 {
+		last := is.LastSeq
+		if n := len(is.queue); n > 0 {
+			last = is.queue[n-1].Seq
+		}
+		if last != 0 && !protocol.SeqNewer(input.Seq, last) {
+			return
+		}
+	}
+
+	if len(is.queue) >= MaxQueuedInputs {
+		is.queue = is.queue[1:]
+	}
+	is.queue = append(is.queue, input)
+}
+
+// pendingAck - есть применённый ввод, о котором клиент ещё не знает
+func (is *InputState) pendingAck() bool {
+	return is.LastSeq != is.ackedSeq
+}
+
+// queueInput - ввод клиента ждёт ближайшего тика
+func (s *Server) queueInput(cmd *PlayerCommand) {
+	s.mu.RLock()
+	client, ok := s.Clients[cmd.ClientID]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	input := queuedInput{Type: cmd.Type}
+	switch data := cmd.Data.(type) {
+	case *protocol.MoveData:
+		input.Seq, input.Time, input.Move = data.Seq, data.Timestamp, data
+	case *protocol.ActionData:
+		input.Seq, input.Time = data.Seq, data.Timestamp
+	default:
+		return
+	}
+	client.Input.push(input)
+}
+
+// applyInputsUnlocked - применить очереди ввода в начале тика (под локом мира)
+// Ввод помечается тиком, в котором он впервые повлияет на симуляцию
+func (s *Server) applyInputsUnlocked() {
+	tick := s.World.CurrentTick + 1
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Ввод применяется в порядке ID игроков: split и eject создают entity,
+	// и их порядок не должен зависеть от порядка map (детерминированный мир)
+	clients := make([]*Client, 0, len(s.Clients))
+	for _, client := range s.Clients {
+		clients = append(clients, client)
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].PlayerID < clients[j].PlayerID
+	})
+
+	for _, client := range clients {
+		in := client.Input
+		if len(in.queue) == 0 {
+			continue
+		}
+
+		n := len(in.queue)
+		if n > MaxInputsPerTick {
+			n = MaxInputsPerTick
+		}
+		player, alive := s.World.Players[client.PlayerID]
+		for _, input := range in.queue[:n] {
+			if alive {
+				s.applyInput(player, input)
+			}
+			if input.Seq != 0 {
+				in.LastSeq = input.Seq
+				in.LastTime = input.Time
+				in.LastTick = tick
+			}
+		}
+		in.queue = in.queue[n:]
+	}
+}
+
+func (s *Server) applyInput(player *game.Player, input queuedInput) {
+	switch input.Type {
+	case protocol.MsgTypeMove:
+		player.SetTarget(input.Move.X, input.Move.Y, s.World.Now())
+		s.record(replay.Entry{Kind: replay.KindMove, Player: player.ID, X: input.Move.X, Y: input.Move.Y})
+	case protocol.MsgTypeSplit:
+		s.World.SplitPlayerUnlocked(player)
+		s.record(replay.Entry{Kind: replay.KindSplit, Player: player.ID})
+	case protocol.MsgTypeEject:
+		s.World.EjectPlayerUnlocked(player)
+		s.record(replay.Entry{Kind: replay.KindEject, Player: player.ID})
+	}
+}
+
+// attachAck - подтвердить клиенту применённый ввод в его delta
+func (s *Server) attachAck(client *Client, delta *events.StateDeltaEvent) {
+	in := client.Input
+	delta.AckSeq = in.LastSeq
+	delta.AckTimestamp = in.LastTime
+	delta.AckTick = in.LastTick
+	in.ackedSeq = in.LastSeq
+}
@@ -0,0 +1,127 @@
+package network
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"agario-server/internal/events"
+	"agario-server/internal/game"
+	"agario-server/pkg/protocol"
+)
+
+func TestInputPushOrdering(t *testing.T) {
+	tests := []struct {
+		name    string
+		lastSeq uint32 // уже применённый seq
+		pushed  []uint32
+		want    []uint32
+	}{
+		{name: "in order", pushed: []uint32{1, 2, 3}, want: []uint32{1, 2, 3}},
+		{name: "duplicate dropped", pushed: []uint32{1, 2, 2, 3}, want: []uint32{1, 2, 3}},
+		{name: "reordered dropped", pushed: []uint32{1, 3, 2, 4}, want: []uint32{1, 3, 4}},
+		{name: "gaps kept", pushed: []uint32{1, 5, 9}, want: []uint32{1, 5, 9}},
+		{name: "older than applied", lastSeq: 10, pushed: []uint32{9, 10, 11}, want: []uint32{11}},
+		{name: "wraparound", pushed: []uint32{math.MaxUint32 - 1, math.MaxUint32, 1, 2}, want: []uint32{math.MaxUint32 - 1, math.MaxUint32, 1, 2}},
+		{name: "no seq from old clients", pushed: []uint32{0, 0, 0}, want: []uint32{0, 0, 0}},
+		{name: "no seq between sequenced", pushed: []uint32{5, 0, 6}, want: []uint32{5, 0, 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := NewInputState()
+			is.LastSeq = tt.lastSeq
+			for _, seq := range tt.pushed {
+				is.push(queuedInput{Type: protocol.MsgTypeSplit, Seq: seq})
+			}
+			got := []uint32{}
+			for _, input := range is.queue {
+				got = append(got, input.Seq)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("queue %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInputQueueDropsOldest(t *testing.T) {
+	is := NewInputState()
+	for seq := uint32(1); seq <= MaxQueuedInputs+5; seq++ {
+		is.push(queuedInput{Type: protocol.MsgTypeSplit, Seq: seq})
+	}
+	if len(is.queue) != MaxQueuedInputs {
+		t.Fatalf("queue holds %d inputs, want %d", len(is.queue), MaxQueuedInputs)
+	}
+	if first := is.queue[0].Seq; first != 6 {
+		t.Fatalf("oldest queued seq %d, want 6", first)
+	}
+}
+
+func TestInputAppliedAndAcked(t *testing.T) {
+	tests := []struct {
+		name     string
+		inputs   int
+		ticks    int   // тиков до полного применения очереди
+		wantTick int64 // тик, на котором применён последний ввод
+	}{
+		{name: "one input", inputs: 1, ticks: 1, wantTick: 1},
+		{name: "a full tick", inputs: MaxInputsPerTick, ticks: 1, wantTick: 1},
+		{name: "spills to the next tick", inputs: MaxInputsPerTick + 1, ticks: 2, wantTick: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(game.NewDeterministicWorld(nil, 1))
+			client := addTestClient(s, "c1")
+			player := s.World.AddPlayerUnlocked("mover", "#FFFFFF", false)
+			client.PlayerID = player.ID
+
+			for i := 1; i <= tt.inputs; i++ {
+				s.queueInput(&PlayerCommand{
+					Type:     protocol.MsgTypeMove,
+					ClientID: client.ID,
+					Data: &protocol.MoveData{
+						InputHeader: protocol.InputHeader{Seq: uint32(i), Timestamp: int64(1000 + i)},
+						X:           float64(100 * i),
+						Y:           200,
+					},
+				})
+			}
+
+			for tick := 1; tick <= tt.ticks; tick++ {
+				if len(client.Input.queue) == 0 {
+					t.Fatalf("queue drained after %d ticks, want %d", tick-1, tt.ticks)
+				}
+				s.applyInputsUnlocked()
+				s.World.CurrentTick++
+			}
+			if len(client.Input.queue) != 0 {
+				t.Fatalf("%d inputs left after %d ticks", len(client.Input.queue), tt.ticks)
+			}
+
+			in := client.Input
+			if !in.pendingAck() {
+				t.Fatal("applied input is not pending an ack")
+			}
+			if in.LastSeq != uint32(tt.inputs) || in.LastTime != int64(1000+tt.inputs) || in.LastTick != tt.wantTick {
+				t.Fatalf("last input seq=%d time=%d tick=%d, want seq=%d time=%d tick=%d",
+					in.LastSeq, in.LastTime, in.LastTick, tt.inputs, 1000+tt.inputs, tt.wantTick)
+			}
+			if player.TargetPos.X != float64(100*tt.inputs) {
+				t.Fatalf("target %+v, want the last move", player.TargetPos)
+			}
+
+			delta := &events.StateDeltaEvent{}
+			s.attachAck(client, delta)
+			if delta.AckSeq != in.LastSeq || delta.AckTick != in.LastTick || delta.AckTimestamp != in.LastTime {
+				t.Fatalf("delta ack %d/%d/%d, want %d/%d/%d",
+					delta.AckSeq, delta.AckTick, delta.AckTimestamp, in.LastSeq, in.LastTick, in.LastTime)
+			}
+			// Подтверждение уходит один раз
+			if in.pendingAck() {
+				t.Fatal("ack still pending after attachAck")
+			}
+		})
+	}
+}
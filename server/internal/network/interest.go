@@ -0,0 +1,354 @@
+package network
+
+import (
+	"agario-server/internal/events"
+	"agario-server/internal/game"
+	"math"
+)
+
+// Параметры области видимости клиента
+const (
+	ViewBaseWidth   = 1920.0 // видимая область маленькой клетки
+	ViewBaseHeight  = 1080.0
+	ViewMassZoom    = 20.0  // масштаб растёт как 1 + sqrt(mass)/ViewMassZoom
+	ViewMargin      = 150.0 // запас, чтобы entity появлялись до края экрана
+	ViewLeaveMargin = 300.0 // гистерезис: entity уходит только за этим запасом
+	ViewFreeZoom    = 2.0   // масштаб свободной камеры зрителя
+)
+
+// ViewRect - прямоугольник видимости в координатах мира
+type ViewRect struct {
+	MinX float64
+	MinY float64
+	MaxX float64
+	MaxY float64
+}
+
+func (r ViewRect) Expand(margin float64) ViewRect {
+	return ViewRect{
+		MinX: r.MinX - margin,
+		MinY: r.MinY - margin,
+		MaxX: r.MaxX + margin,
+		MaxY: r.MaxY + margin,
+	}
+}
+
+// Intersects - круг пересекает прямоугольник (по bounding box)
+func (r ViewRect) Intersects(x, y, radius float64) bool {
+	return x+radius >= r.MinX && x-radius <= r.MaxX && y+radius >= r.MinY && y-radius <= r.MaxY
+}
+
+// Interest - что конкретный клиент видит и о чём уже знает
+type Interest struct {
+	View    ViewRect
+	HasView bool
+	Known   map[string]struct{}
+}
+
+func NewInterest() *Interest {
+	return &Interest{
+		Known: make(map[string]struct{}),
+	}
+}
+
+func (in *Interest) knows(id string) bool {
+	_, ok := in.Known[id]
+	return ok
+}
+
+// visible - entity в области видимости (для новых entity из событий)
+func (in *Interest) visible(x, y, radius float64) bool {
+	return in.HasView && in.View.Intersects(x, y, radius)
+}
+
+// viewForPlayer - область видимости по клеткам и массе игрока (под локом мира)
+func viewForPlayer(player *game.Player) (ViewRect, bool) {
+	player.Mu.RLock()
+	defer player.Mu.RUnlock()
+
+	if len(player.Cells) == 0 {
+		return ViewRect{}, false
+	}
+
+	bounds := ViewRect{MinX: math.MaxFloat64, MinY: math.MaxFloat64, MaxX: -math.MaxFloat64, MaxY: -math.MaxFloat64}
+	totalMass := 0.0
+	for _, cell := range player.Cells {
+		bounds.MinX = math.Min(bounds.MinX, cell.Position.X-cell.Radius)
+		bounds.MinY = math.Min(bounds.MinY, cell.Position.Y-cell.Radius)
+		bounds.MaxX = math.Max(bounds.MaxX, cell.Position.X+cell.Radius)
+		bounds.MaxY = math.Max(bounds.MaxY, cell.Position.Y+cell.Radius)
+		totalMass += cell.Mass()
+	}
+
+	// Чем больше масса, тем дальше видно; разлетевшиеся клетки всегда в кадре
+	zoom := 1 + math.Sqrt(totalMass)/ViewMassZoom
+	centerX := (bounds.MinX + bounds.MaxX) / 2
+	centerY := (bounds.MinY + bounds.MaxY) / 2
+	halfW := math.Max(ViewBaseWidth/2*zoom, (bounds.MaxX-bounds.MinX)/2) + ViewMargin
+	halfH := math.Max(ViewBaseHeight/2*zoom, (bounds.MaxY-bounds.MinY)/2) + ViewMargin
+
+	return ViewRect{
+		MinX: centerX - halfW,
+		MinY: centerY - halfH,
+		MaxX: centerX + halfW,
+		MaxY: centerY + halfH,
+	}, true
+}
+
+// viewAt - область свободной камеры с центром в (x, y)
+func viewAt(x, y float64) ViewRect {
+	halfW := ViewBaseWidth/2*ViewFreeZoom + ViewMargin
+	halfH := ViewBaseHeight/2*ViewFreeZoom + ViewMargin
+	return ViewRect{MinX: x - halfW, MinY: y - halfH, MaxX: x + halfW, MaxY: y + halfH}
+}
+
+// updateView - пересчитать область видимости клиента (под локом мира)
+// Мёртвый игрок продолжает видеть последнюю область, зритель видит то, что под камерой
+func (s *Server) updateView(client *Client) {
+	if client.viewer {
+		s.updateCameraUnlocked(client)
+		return
+	}
+	player, exists := s.World.Players[client.PlayerID]
+	if !exists {
+		return
+	}
+	if view, ok := viewForPlayer(player); ok {
+		client.Interest.View = view
+		client.Interest.HasView = true
+	}
+}
+
+// filterEvents - оставить клиенту только события про то, что он видит
+// Обновляет множество известных клиенту entity
+func (s *Server) filterEvents(client *Client, batch []*events.Event) []*events.Event {
+	in := client.Interest
+	filtered := make([]*events.Event, 0, len(batch))
+	keep := func(event *events.Event, data interface{}) {
+		filtered = append(filtered, &events.Event{Type: event.Type, Timestamp: event.Timestamp, Data: data})
+	}
+
+	for _, event := range batch {
+		switch data := event.Data.(type) {
+		case *events.FoodSpawnedEvent:
+			if foods := in.acceptFood(data.Foods); len(foods) > 0 {
+				keep(event, &events.FoodSpawnedEvent{Foods: foods})
+			}
+
+		case *events.PlayerEjectedEvent:
+			if foods := in.acceptFood(data.Food); len(foods) > 0 {
+				keep(event, &events.PlayerEjectedEvent{PlayerID: data.PlayerID, Food: foods})
+			}
+
+		case *events.FoodEatenEvent:
+			if in.knows(data.FoodID) {
+				delete(in.Known, data.FoodID)
+				keep(event, data)
+			}
+
+		case *events.FoodPulledEvent:
+			// Притянутая еда уже в мире: о неизвестной клиенту расскажет entity_entered
+			foods := []events.FoodInfo{}
+			for _, food := range data.Food {
+				if in.knows(food.FoodID) {
+					foods = append(foods, food)
+				}
+			}
+			if len(foods) > 0 {
+				keep(event, &events.FoodPulledEvent{PlayerID: data.PlayerID, Food: foods})
+			}
+
+		case *events.PlayerJoinedEvent:
+			if in.visible(data.X, data.Y, data.Radius) {
+				in.Known[data.CellID] = struct{}{}
+				keep(event, data)
+			}
+
+		case *events.PlayerSplitEvent:
+			if cells := in.acceptCells(data.NewCells); len(cells) > 0 {
+				keep(event, &events.PlayerSplitEvent{PlayerID: data.PlayerID, NewCells: cells})
+			}
+
+		case *events.CellEatenEvent:
+			if in.knows(data.EatenCellID) {
+				delete(in.Known, data.EatenCellID)
+				keep(event, data)
+			}
+
+		case *events.CellDissolvedEvent:
+			if in.knows(data.CellID) {
+				delete(in.Known, data.CellID)
+				keep(event, data)
+			}
+
+		case *events.CellMergedEvent:
+			if in.knows(data.Cell1ID) || in.knows(data.Cell2ID) {
+				delete(in.Known, data.Cell2ID)
+				keep(event, data)
+			}
+
+		case *events.VirusSpawnedEvent:
+			viruses := []events.VirusInfo{}
+			for _, virus := range data.Viruses {
+				if in.visible(virus.X, virus.Y, virus.Radius) {
+					in.Known[virus.VirusID] = struct{}{}
+					viruses = append(viruses, virus)
+				}
+			}
+			if len(viruses) > 0 {
+				keep(event, &events.VirusSpawnedEvent{Viruses: viruses})
+			}
+
+		case *events.VirusFedEvent:
+			if in.knows(data.VirusID) {
+				delete(in.Known, data.FoodID)
+				keep(event, data)
+			}
+
+		case *events.VirusPoppedEvent:
+			if in.knows(data.VirusID) || in.knows(data.CellID) {
+				delete(in.Known, data.VirusID)
+				keep(event, &events.VirusPoppedEvent{
+					VirusID:  data.VirusID,
+					PlayerID: data.PlayerID,
+					CellID:   data.CellID,
+					Radius:   data.Radius,
+					NewCells: in.acceptCells(data.NewCells),
+				})
+			}
+
+		case *events.PowerUpSpawnedEvent:
+			powerUps := []events.PowerUpInfo{}
+			for _, powerUp := range data.PowerUps {
+				if in.visible(powerUp.X, powerUp.Y, powerUp.Radius) {
+					in.Known[powerUp.PowerUpID] = struct{}{}
+					powerUps = append(powerUps, powerUp)
+				}
+			}
+			if len(powerUps) > 0 {
+				keep(event, &events.PowerUpSpawnedEvent{PowerUps: powerUps})
+			}
+
+		case *events.PowerUpCollectedEvent:
+			if in.knows(data.PowerUpID) || in.knows(data.CellID) {
+				delete(in.Known, data.PowerUpID)
+				keep(event, data)
+			}
+
+		case *events.PlayerLeftEvent:
+			// Итоги для статистики: клетки ушедшего игрока клиент уберёт по entity_left
+
+		default:
+			// player_died, player_effects, config_changed и прочие глобальные события - всем
+			keep(event, event.Data)
+		}
+	}
+
+	return filtered
+}
+
+func (in *Interest) acceptFood(foods []events.FoodInfo) []events.FoodInfo {
+	accepted := []events.FoodInfo{}
+	for _, food := range foods {
+		if in.visible(food.X, food.Y, food.Radius) {
+			in.Known[food.FoodID] = struct{}{}
+			accepted = append(accepted, food)
+		}
+	}
+	return accepted
+}
+
+func (in *Interest) acceptCells(cells []events.CellInfo) []events.CellInfo {
+	accepted := []events.CellInfo{}
+	for _, cell := range cells {
+		if in.visible(cell.X, cell.Y, cell.Radius) {
+			in.Known[cell.CellID] = struct{}{}
+			accepted = append(accepted, cell)
+		}
+	}
+	return accepted
+}
+
+// diffInterest - события входа/выхода entity через границу видимости (под локом мира)
+func (s *Server) diffInterest(client *Client) []*events.Event {
+	in := client.Interest
+	if !in.HasView {
+		return nil
+	}
+
+	entered := &events.EntityEnteredEvent{}
+	for _, entry := range s.World.EntitiesInRect(in.View.MinX, in.View.MinY, in.View.MaxX, in.View.MaxY) {
+		if in.knows(entry.ID) {
+			continue
+		}
+		in.Known[entry.ID] = struct{}{}
+		appendEntered(entered, entry)
+	}
+
+	// Уходят только entity за пределами расширенной области (или исчезнувшие из мира)
+	leaveRect := in.View.Expand(ViewLeaveMargin)
+	retained := make(map[string]struct{}, len(in.Known))
+	for _, entry := range s.World.EntitiesInRect(leaveRect.MinX, leaveRect.MinY, leaveRect.MaxX, leaveRect.MaxY) {
+		retained[entry.ID] = struct{}{}
+	}
+
+	left := &events.EntityLeftEvent{IDs: []string{}}
+	for id := range in.Known {
+		if _, ok := retained[id]; !ok {
+			delete(in.Known, id)
+			left.IDs = append(left.IDs, id)
+		}
+	}
+
+	result := []*events.Event{}
+	if len(entered.Cells)+len(entered.Food)+len(entered.Viruses)+len(entered.PowerUps) > 0 {
+		result = append(result, events.NewEvent(events.EventEntityEntered, entered))
+	}
+	if len(left.IDs) > 0 {
+		result = append(result, events.NewEvent(events.EventEntityLeft, left))
+	}
+	return result
+}
+
+func appendEntered(entered *events.EntityEnteredEvent, entry *game.SpatialEntry) {
+	switch entry.Kind {
+	case game.EntityCell:
+		entered.Cells = append(entered.Cells, events.EnteredCell{
+			PlayerID: entry.Player.ID,
+			Name:     entry.Player.Name,
+			Color:    entry.Player.Color,
+			IsBot:    entry.Player.IsBot,
+			CellID:   entry.Cell.ID,
+			X:        entry.Cell.Position.X,
+			Y:        entry.Cell.Position.Y,
+			Radius:   entry.Cell.Radius,
+		})
+	case game.EntityFood:
+		entered.Food = append(entered.Food, events.FoodInfo{
+			FoodID: entry.Food.ID,
+			X:      entry.Food.Position.X,
+			Y:      entry.Food.Position.Y,
+			Radius: entry.Food.Radius,
+			Color:  entry.Food.Color,
+			VelX:   entry.Food.Velocity.X,
+			VelY:   entry.Food.Velocity.Y,
+		})
+	case game.EntityVirus:
+		entered.Viruses = append(entered.Viruses, events.VirusInfo{
+			VirusID: entry.Virus.ID,
+			X:       entry.Virus.Position.X,
+			Y:       entry.Virus.Position.Y,
+			Radius:  entry.Virus.Radius,
+			VelX:    entry.Virus.Velocity.X,
+			VelY:    entry.Virus.Velocity.Y,
+		})
+	case game.EntityPowerUp:
+		entered.PowerUps = append(entered.PowerUps, events.PowerUpInfo{
+			PowerUpID: entry.PowerUp.ID,
+			Kind:      string(entry.PowerUp.Kind),
+			X:         entry.PowerUp.Position.X,
+			Y:         entry.PowerUp.Position.Y,
+			Radius:    entry.PowerUp.Radius,
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package network
+
+import (
+	"testing"
+
+	"agario-server/internal/events"
+	"agario-server/internal/game"
+)
+
+// wholeWorldClient - клиент, который видит весь мир
+func wholeWorldClient(world *game.World) *Client {
+	client := &Client{Interest: NewInterest(), Delta: NewDeltaTracker()}
+	client.Interest.View = ViewRect{MaxX: world.Config.WorldWidth, MaxY: world.Config.WorldHeight}
+	client.Interest.HasView = true
+	return client
+}
+
+// interestChanges - ID из entity_entered и entity_left
+func interestChanges(batch []*events.Event) (entered, left map[string]bool) {
+	entered, left = map[string]bool{}, map[string]bool{}
+	for _, event := range batch {
+		switch data := event.Data.(type) {
+		case *events.EntityEnteredEvent:
+			for _, cell := range data.Cells {
+				entered[cell.CellID] = true
+			}
+			for _, food := range data.Food {
+				entered[food.FoodID] = true
+			}
+			for _, virus := range data.Viruses {
+				entered[virus.VirusID] = true
+			}
+		case *events.EntityLeftEvent:
+			for _, id := range data.IDs {
+				left[id] = true
+			}
+		}
+	}
+	return entered, left
+}
+
+func TestDiffInterestHysteresis(t *testing.T) {
+	world := game.NewDeterministicWorld(nil, 1)
+	server := NewServer(world)
+	cell := world.AddPlayerUnlocked("watched", "#FFFFFF", false).Cells[0]
+	at := func(dx float64) ViewRect {
+		x, y := cell.Position.X+dx, cell.Position.Y
+		return ViewRect{MinX: x - 1, MinY: y - 1, MaxX: x + 1, MaxY: y + 1}
+	}
+
+	tests := []struct {
+		name    string
+		view    ViewRect
+		entered bool
+		left    bool
+	}{
+		{name: "enters the view", view: at(0), entered: true},
+		{name: "stays while in view", view: at(0)},
+		{name: "kept inside the leave margin", view: at(cell.Radius + ViewLeaveMargin/2)},
+		{name: "leaves past the margin", view: at(cell.Radius + ViewLeaveMargin*2), left: true},
+		{name: "enters again", view: at(0), entered: true},
+	}
+
+	// Шаги идут последовательно над одним клиентом
+	client := &Client{Interest: NewInterest()}
+	client.Interest.HasView = true
+	for _, tt := range tests {
+		client.Interest.View = tt.view
+		entered, left := interestChanges(server.diffInterest(client))
+		if entered[cell.ID] != tt.entered || left[cell.ID] != tt.left {
+			t.Fatalf("%s: entered %v left %v, want %v and %v", tt.name, entered[cell.ID], left[cell.ID], tt.entered, tt.left)
+		}
+	}
+}
+
+func TestFilterEventsByInterest(t *testing.T) {
+	world := game.NewDeterministicWorld(nil, 1)
+	server := NewServer(world)
+	cell := world.AddPlayerUnlocked("watched", "#FFFFFF", false).Cells[0]
+
+	client := wholeWorldClient(world)
+	entered, _ := interestChanges(server.diffInterest(client))
+	if want := len(world.Food) + len(world.Viruses) + 1; len(entered) != want {
+		t.Fatalf("%d entities entered the whole-world view, want %d", len(entered), want)
+	}
+
+	batch := []*events.Event{
+		events.NewEvent(events.EventCellEaten, &events.CellEatenEvent{EatenCellID: "unknown"}),
+		events.NewEvent(events.EventCellEaten, &events.CellEatenEvent{EatenCellID: cell.ID}),
+		events.NewEvent(events.EventPlayerDied, &events.PlayerDiedEvent{PlayerID: "far away"}),
+	}
+	filtered := server.filterEvents(client, batch)
+	if len(filtered) != 2 {
+		t.Fatalf("%d events kept, want the known cell and the global death", len(filtered))
+	}
+	if eaten := filtered[0].Data.(*events.CellEatenEvent); eaten.EatenCellID != cell.ID {
+		t.Fatalf("kept cell_eaten for %s, want %s", eaten.EatenCellID, cell.ID)
+	}
+	if client.Interest.knows(cell.ID) {
+		t.Fatal("eaten cell still known to the client")
+	}
+}
+
+func TestPowerUpVisibility(t *testing.T) {
+	world := game.NewDeterministicWorld(nil, 1)
+	server := NewServer(world)
+	client := wholeWorldClient(world)
+	world.EventBus.FlushEvents()
+
+	world.UpdateUnlocked(game.TickDuration.Seconds())
+	if len(world.PowerUps) == 0 {
+		t.Fatal("no power-up spawned on the first tick")
+	}
+	var powerUpID string
+	for id := range world.PowerUps {
+		powerUpID = id
+	}
+
+	// Событие появления делает бонус известным, а diffInterest не должен тут же его убрать
+	server.filterEvents(client, world.EventBus.FlushEvents())
+	if !client.Interest.knows(powerUpID) {
+		t.Fatalf("power-up %s not accepted from powerup_spawned", powerUpID)
+	}
+	for _, event := range server.diffInterest(client) {
+		if left, ok := event.Data.(*events.EntityLeftEvent); ok {
+			for _, id := range left.IDs {
+				if id == powerUpID {
+					t.Fatalf("power-up %s reported as left while still on the map", powerUpID)
+				}
+			}
+		}
+	}
+
+	// Клиент, подключившийся позже, получает бонус в снимке
+	late := wholeWorldClient(world)
+	snapshot := server.buildSnapshot(late)
+	found := false
+	for _, powerUp := range snapshot.PowerUps {
+		found = found || powerUp.ID == powerUpID
+	}
+	if !found {
+		t.Fatalf("snapshot has no power-up %s: %+v", powerUpID, snapshot.PowerUps)
+	}
+
+	// И в entity_entered, если бонус попал в область видимости без события
+	late.Interest.Known = map[string]struct{}{}
+	var entered *events.EntityEnteredEvent
+	for _, event := range server.diffInterest(late) {
+		if data, ok := event.Data.(*events.EntityEnteredEvent); ok {
+			entered = data
+		}
+	}
+	if entered == nil || len(entered.PowerUps) != len(world.PowerUps) {
+		t.Fatalf("entity_entered power-ups = %+v, want %d", entered, len(world.PowerUps))
+	}
+}
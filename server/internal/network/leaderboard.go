@@ -0,0 +1,164 @@
+package network
+
+import (
+	"agario-server/pkg/protocol"
+	"math"
+	"sort"
+	"time"
+)
+
+// LeaderboardSize - игроков в топе
+const LeaderboardSize = 10
+
+// rankedPlayer - игрок в общем рейтинге комнаты
+type rankedPlayer struct {
+	entry     protocol.LeaderEntry
+	spawnedAt time.Time
+}
+
+// rankPlayersUnlocked - все живые игроки по убыванию счёта (под локом мира)
+// При равном счёте выше тот, кто дольше в игре, затем по ID: порядок не
+// прыгает между рассылками
+func (s *Server) rankPlayersUnlocked() []protocol.LeaderEntry {
+	ranked := make([]rankedPlayer, 0, len(s.World.Players))
+	for _, player := range s.World.Players {
+		if !player.IsAlive() {
+			continue
+		}
+		ranked = append(ranked, rankedPlayer{
+			entry: protocol.LeaderEntry{
+				PlayerID: player.ID,
+				Name:     player.Name,
+				Score:    player.GetScore(),
+				IsBot:    player.IsBot,
+				Team:     player.Team,
+			},
+			spawnedAt: player.SpawnedAt,
+		})
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.entry.Score != b.entry.Score {
+			return a.entry.Score > b.entry.Score
+		}
+		if !a.spawnedAt.Equal(b.spawnedAt) {
+			return a.spawnedAt.Before(b.spawnedAt)
+		}
+		return a.entry.PlayerID < b.entry.PlayerID
+	})
+
+	entries := make([]protocol.LeaderEntry, len(ranked))
+	for i := range ranked {
+		entries[i] = ranked[i].entry
+		entries[i].Rank = i + 1
+	}
+	return entries
+}
+
+// rankTeamsUnlocked - команды по убыванию массы, при равенстве - по номеру (под локом мира)
+// nil - комната не в командном режиме
+func (s *Server) rankTeamsUnlocked() []protocol.TeamEntry {
+	totals := s.World.TeamTotalsUnlocked()
+	if len(totals) == 0 {
+		return nil
+	}
+
+	teams := make([]protocol.TeamEntry, len(totals))
+	for i, total := range totals {
+		teams[i] = protocol.TeamEntry{
+			Team:    total.Team,
+			Color:   total.Color,
+			Mass:    int(math.Floor(total.Mass)),
+			Players: total.Players,
+		}
+	}
+	sort.SliceStable(teams, func(i, j int) bool {
+		return teams[i].Mass > teams[j].Mass
+	})
+	return teams
+}
+
+// leaderboardView - что клиент уже знает о таблице лидеров
+type leaderboardView struct {
+	sent  bool
+	top   []protocol.LeaderEntry
+	own   *protocol.LeaderEntry
+	total int
+	teams []protocol.TeamEntry
+}
+
+// broadcastLeaderboard - разослать изменения таблицы лидеров
+// Топ уходит только если он изменился для клиента, своё место - всегда в сообщении
+func (s *Server) broadcastLeaderboard() {
+	s.World.Mu.RLock()
+	ranking := s.rankPlayersUnlocked()
+	teams := s.rankTeamsUnlocked()
+	s.World.Mu.RUnlock()
+
+	ranks := make(map[string]int, len(ranking))
+	for i, entry := range ranking {
+		ranks[entry.PlayerID] = i
+	}
+	top := ranking[:min(LeaderboardSize, len(ranking))]
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, client := range s.Clients {
+		var own *protocol.LeaderEntry
+		if i, ok := ranks[client.PlayerID]; ok {
+			entry := ranking[i]
+			own = &entry
+		}
+
+		view := &client.board
+		topChanged := !view.sent || !sameEntries(view.top, top)
+		if !topChanged && sameEntry(view.own, own) && view.total == len(ranking) && sameTeams(view.teams, teams) {
+			continue
+		}
+
+		data := protocol.LeaderboardData{Own: own, Total: len(ranking), Teams: teams}
+		if topChanged {
+			data.Leaders = top
+		}
+		client.reply(protocol.MsgTypeLeaderboard, data)
+
+		view.sent = true
+		view.top = top
+		view.own = own
+		view.total = len(ranking)
+		view.teams = teams
+	}
+}
+
+func sameEntries(a, b []protocol.LeaderEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameEntry(a, b *protocol.LeaderEntry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameTeams(a, b []protocol.TeamEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
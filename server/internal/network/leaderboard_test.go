@@ -0,0 +1,147 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"agario-server/internal/game"
+	"agario-server/pkg/protocol"
+)
+
+// addRankedPlayer - игрок с заданной массой, появившийся в spawnedAt
+func addRankedPlayer(w *game.World, name string, mass float64, spawnedAt time.Time) *game.Player {
+	player := w.AddPlayerUnlocked(name, "#FFFFFF", false)
+	player.Cells[0].SetMass(mass)
+	player.SpawnedAt = spawnedAt
+	return player
+}
+
+// takeLeaderboards - сообщения leaderboard из исходящей очереди клиента
+func takeLeaderboards(t *testing.T, client *Client) []protocol.LeaderboardData {
+	t.Helper()
+	boards := []protocol.LeaderboardData{}
+	for _, reply := range takeReplies(t, client) {
+		if reply.Type != protocol.MsgTypeLeaderboard {
+			continue
+		}
+		var msg struct {
+			Data protocol.LeaderboardData `json:"data"`
+		}
+		if err := json.Unmarshal(reply.raw, &msg); err != nil {
+			t.Fatal(err)
+		}
+		boards = append(boards, msg.Data)
+	}
+	return boards
+}
+
+func TestRankPlayersTieBreak(t *testing.T) {
+	w := game.NewDeterministicWorld(nil, 1)
+	s := NewServer(w)
+	early := time.Now().Add(-time.Minute)
+	late := time.Now()
+
+	small := addRankedPlayer(w, "small", 50.5, early)
+	newcomer := addRankedPlayer(w, "newcomer", 200.5, late)
+	veteran := addRankedPlayer(w, "veteran", 200.5, early)
+	twinA := addRankedPlayer(w, "twin", 100.5, early)
+	twinB := addRankedPlayer(w, "twin", 100.5, early)
+	if twinB.ID < twinA.ID {
+		twinA, twinB = twinB, twinA
+	}
+
+	want := []string{veteran.ID, newcomer.ID, twinA.ID, twinB.ID, small.ID}
+	// Повторный расчёт даёт тот же порядок
+	for round := 0; round < 3; round++ {
+		ranking := s.rankPlayersUnlocked()
+		if len(ranking) != len(want) {
+			t.Fatalf("%d ranked players, want %d", len(ranking), len(want))
+		}
+		for i, entry := range ranking {
+			if entry.PlayerID != want[i] || entry.Rank != i+1 {
+				t.Fatalf("round %d: place %d is %s (%s, rank %d), want %s", round, i+1, entry.Name, entry.PlayerID, entry.Rank, want[i])
+			}
+		}
+	}
+}
+
+func TestLeaderboardIncremental(t *testing.T) {
+	w := game.NewDeterministicWorld(nil, 1)
+	s := NewServer(w)
+	client := addTestClient(s, "c1")
+	leader := addRankedPlayer(w, "leader", 300, time.Now())
+	own := addRankedPlayer(w, "own", 100, time.Now())
+	client.PlayerID = own.ID
+
+	steps := []struct {
+		name    string
+		change  func()
+		sent    bool
+		leaders bool // в сообщении есть топ
+	}{
+		{name: "first message has the top", sent: true, leaders: true},
+		{name: "nothing changed", change: func() {}},
+		{name: "another player in the top", change: func() { addRankedPlayer(w, "rival", 200, time.Now()) }, sent: true, leaders: true},
+		{name: "spawn time alone changes nothing", change: func() { leader.SpawnedAt = leader.SpawnedAt.Add(-time.Second) }},
+		{name: "own score changes the top", change: func() { own.Cells[0].SetMass(150) }, sent: true, leaders: true},
+	}
+
+	for _, step := range steps {
+		if step.change != nil {
+			step.change()
+		}
+		s.broadcastLeaderboard()
+		boards := takeLeaderboards(t, client)
+		if len(boards) > 1 || (len(boards) == 1) != step.sent {
+			t.Fatalf("%s: %d leaderboard messages, want sent %v", step.name, len(boards), step.sent)
+		}
+		if !step.sent {
+			continue
+		}
+		if (boards[0].Leaders != nil) != step.leaders {
+			t.Fatalf("%s: leaders %+v, want present %v", step.name, boards[0].Leaders, step.leaders)
+		}
+		if boards[0].Own == nil || boards[0].Own.PlayerID != own.ID {
+			t.Fatalf("%s: own entry %+v, want %s", step.name, boards[0].Own, own.ID)
+		}
+	}
+}
+
+func TestLeaderboardOwnRankOutsideTop(t *testing.T) {
+	w := game.NewDeterministicWorld(nil, 1)
+	s := NewServer(w)
+	client := addTestClient(s, "c1")
+	spectator := addTestClient(s, "c2")
+	for i := 0; i < LeaderboardSize+2; i++ {
+		addRankedPlayer(w, fmt.Sprintf("big%d", i), float64(1000+10*i), time.Now())
+	}
+	own := addRankedPlayer(w, "own", 50, time.Now())
+	client.PlayerID = own.ID
+
+	s.broadcastLeaderboard()
+
+	boards := takeLeaderboards(t, client)
+	if len(boards) != 1 {
+		t.Fatalf("%d leaderboard messages, want 1", len(boards))
+	}
+	board := boards[0]
+	if len(board.Leaders) != LeaderboardSize || board.Total != LeaderboardSize+3 {
+		t.Fatalf("%d leaders of %d players, want %d of %d", len(board.Leaders), board.Total, LeaderboardSize, LeaderboardSize+3)
+	}
+	for _, entry := range board.Leaders {
+		if entry.PlayerID == own.ID {
+			t.Fatalf("own player %s in the top", own.ID)
+		}
+	}
+	if board.Own == nil || board.Own.Rank != LeaderboardSize+3 || board.Own.Score != own.GetScore() {
+		t.Fatalf("own entry %+v, want rank %d", board.Own, LeaderboardSize+3)
+	}
+
+	// Клиент без игрока видит топ без своего места
+	boards = takeLeaderboards(t, spectator)
+	if len(boards) != 1 || boards[0].Own != nil || len(boards[0].Leaders) != LeaderboardSize {
+		t.Fatalf("leaderboard without a player %+v", boards)
+	}
+}
@@ -0,0 +1,89 @@
+package network
+
+import (
+	"agario-server/internal/game"
+	"agario-server/pkg/protocol"
+	"log"
+	"math"
+	"time"
+)
+
+// matchStateUnlocked - состояние матча для клиентов (под локом мира); nil - бесконечная игра
+func (s *Server) matchStateUnlocked() *protocol.MatchStateData {
+	match, ok := s.World.MatchUnlocked()
+	if !ok {
+		return nil
+	}
+
+	now := s.World.Now()
+	state := &protocol.MatchStateData{
+		Match:      match.Number,
+		Phase:      string(match.Phase),
+		MinPlayers: s.World.Config.MatchMinPlayers,
+		ZoneX:      match.Zone.Center.X,
+		ZoneY:      match.Zone.Center.Y,
+		ZoneRadius: match.Zone.Radius,
+		ZoneFinal:  match.Zone.Radius,
+		WinnerID:   match.WinnerID,
+		WinnerName: match.WinnerName,
+		WinnerTeam: match.WinnerTeam,
+	}
+	for _, player := range s.World.Players {
+		if player.IsAlive() {
+			state.Alive++
+		}
+	}
+
+	switch match.Phase {
+	case game.MatchCountdown, game.MatchEnded:
+		state.TimeLeft = math.Max(0, match.PhaseEnds.Sub(now).Seconds())
+	case game.MatchRunning:
+		state.ZoneFinal = math.Min(s.World.Config.ZoneMinRadius, match.Zone.Radius)
+		state.ShrinkLeft = math.Max(0, s.World.ShrinkEndsUnlocked().Sub(now).Seconds())
+	}
+	return state
+}
+
+// broadcastMatchState - разослать состояние матча: раз в секунду и сразу при смене этапа
+func (s *Server) broadcastMatchState() {
+	s.World.Mu.RLock()
+	state := s.matchStateUnlocked()
+	s.World.Mu.RUnlock()
+	if state == nil {
+		s.match = nil
+		return
+	}
+
+	changed := s.match == nil || s.match.Match != state.Match || s.match.Phase != state.Phase
+	if !changed && time.Since(s.lastMatchState) < time.Second {
+		return
+	}
+	s.match = state
+	s.lastMatchState = time.Now()
+
+	if changed {
+		log.Printf("[MATCH] Room %q match #%d: %s (alive=%d, winner=%q)", s.Room, state.Match, state.Phase, state.Alive, state.WinnerName)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, client := range s.Clients {
+		client.reply(protocol.MsgTypeMatchState, state)
+	}
+}
+
+// waitForMatch - вход закрыт идущим матчем: клиент смотрит его зрителем
+// Имя и цвет запоминаются, чтобы войти через respawn в следующем лобби
+func (s *Server) waitForMatch(clientID, name, color string) {
+	s.mu.RLock()
+	client, ok := s.Clients[clientID]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	client.name, client.color = name, color
+	s.spectate(clientID, &protocol.SpectateData{Mode: protocol.SpectateLeader})
+	client.replyError(&protocol.Error{Code: protocol.ErrCodeMatchRunning, Message: "match in progress, join after it ends", Type: protocol.MsgTypeJoin})
+	log.Printf("[MATCH] %s (%s) waits for the next match in room %q", name, clientID, s.Room)
+}
@@ -0,0 +1,441 @@
+package network
+
+import (
+	"agario-server/internal/events"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Параметры исходящей очереди клиента
+const (
+	DegradeLag       = 250 * time.Millisecond // отставание, после которого delta отправляются реже
+	RecoverLag       = 80 * time.Millisecond  // отставание, при котором частота восстанавливается
+	RateAdjustPeriod = time.Second            // не чаще одного изменения частоты
+	MaxRateDivisor   = 8                      // самое редкое - каждая 8-я delta
+	ResyncLag        = 5 * time.Second        // события старше - очередь сжимается до одного снимка
+	ResyncItems      = 256                    // элементов с событиями больше - то же
+	WriteTimeout     = 10 * time.Second       // запись одного кадра; не успели - клиент отключается
+
+	// Жёсткий предел - исключение: после сжатия в очереди остаются только личные
+	// сообщения и обязательные события. Столько их набирается, только если клиент
+	// не читает совсем, а запись ещё не упала по WriteTimeout
+	MaxOutboundItems = 16384    // элементов в очереди; больше - клиент отключается
+	MaxOutboundBytes = 64 << 20 // байт готовых сообщений в очереди; больше - клиент отключается
+)
+
+// outboundItem - элемент очереди: готовый JSON кадр, события или сброс таблицы ID
+type outboundItem struct {
+	raw        []byte
+	events     []*events.Event
+	delta      bool // Элемент - state delta (может быть заменён более новой)
+	kept       bool // Обязательные события, оставшиеся после сжатия очереди
+	worldW     float64
+	worldH     float64
+	resetCodec bool
+	queuedAt   time.Time
+}
+
+// Outbound - исходящая очередь клиента
+// События доставляются по порядку. Неотправленная state delta заменяется
+// более новой: delta считается от подтверждённого клиентом состояния, поэтому
+// новая включает в себя всё, что было в старой.
+// Если клиент не успевает читать, события мира, delta и снимки выбрасываются,
+// а комната присылает вместо них один свежий снимок (resync). Личные сообщения
+// и обязательные события (смерть, правила, матч) остаются в очереди.
+type Outbound struct {
+	mu           sync.Mutex
+	items        []outboundItem
+	rawBytes     int  // Размер готовых сообщений в items
+	resync       bool // Очередь сжата, комната должна прислать снимок
+	closed       bool
+	closeCode    int       // Код закрытия соединения; 0 - обычное закрытие
+	writingSince time.Time // Самый старый элемент, который сейчас пишется в сокет
+	notify       chan struct{}
+
+	// Метрики
+	coalesced   uint64
+	resyncs     uint64
+	overflowed  bool
+	sentFrames  uint64
+	sentBytes   uint64
+	rateDivisor int // Копия Client.rateDivisor для админки
+}
+
+func NewOutbound() *Outbound {
+	return &Outbound{
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (o *Outbound) wake() {
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+// PushRaw - готовое сообщение (init, error, welcome...)
+func (o *Outbound) PushRaw(data []byte) {
+	o.push(outboundItem{raw: data})
+}
+
+// PushEvents - события тика; state delta из batch может быть вытеснена следующей
+func (o *Outbound) PushEvents(batch []*events.Event, worldW, worldH float64) {
+	var rest []*events.Event
+	var delta *events.Event
+	snapshot := false
+	for _, event := range batch {
+		switch event.Data.(type) {
+		case *events.StateDeltaEvent:
+			delta = event
+		case *events.WorldSnapshotEvent:
+			snapshot = true
+			rest = append(rest, event)
+		default:
+			rest = append(rest, event)
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return
+	}
+
+	// Snapshot и новая delta делают ожидающую delta ненужной
+	if snapshot || delta != nil {
+		o.dropDelta()
+	}
+	if snapshot {
+		o.resync = false
+	}
+
+	now := time.Now()
+	if len(rest) > 0 {
+		o.items = append(o.items, outboundItem{events: rest, worldW: worldW, worldH: worldH, queuedAt: now})
+	}
+	if delta != nil {
+		o.items = append(o.items, outboundItem{events: []*events.Event{delta}, delta: true, worldW: worldW, worldH: worldH, queuedAt: now})
+	}
+	o.checkBacklog()
+	o.checkOverflow()
+	o.wake()
+}
+
+// dropDelta - убрать неотправленную delta (под o.mu)
+// Время ожидания сохраняется у следующего элемента, чтобы отставание не обнулялось
+func (o *Outbound) dropDelta() {
+	for i, item := range o.items {
+		if !item.delta {
+			continue
+		}
+		o.items = append(o.items[:i], o.items[i+1:]...)
+		o.coalesced++
+		if i < len(o.items) && item.queuedAt.Before(o.items[i].queuedAt) {
+			o.items[i].queuedAt = item.queuedAt
+		}
+		return
+	}
+}
+
+// ResetCodec - следующие кадры строятся с новой таблицей ID бинарного протокола
+func (o *Outbound) ResetCodec() {
+	o.push(outboundItem{resetCodec: true})
+}
+
+func (o *Outbound) push(item outboundItem) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return
+	}
+	item.queuedAt = time.Now()
+	o.items = append(o.items, item)
+	o.rawBytes += len(item.raw)
+	o.checkOverflow()
+	o.wake()
+}
+
+// mustDeliver - событие нельзя заменить снимком: его нет в состоянии мира
+func mustDeliver(event *events.Event) bool {
+	switch event.Type {
+	case events.EventPlayerDied, events.EventConfigChanged,
+		events.EventMatchLobby, events.EventMatchCountdown, events.EventMatchStarted, events.EventMatchEnded:
+		return true
+	}
+	return false
+}
+
+// checkBacklog - клиент не успевает читать события мира (под o.mu)
+// События нельзя выбросить выборочно: клиент разошёлся бы с миром. Все они
+// вместе с delta и снимками заменяются одним снимком, который пришлёт комната
+func (o *Outbound) checkBacklog() {
+	if o.resync {
+		return
+	}
+	pending := 0
+	var oldest time.Time
+	for _, item := range o.items {
+		if item.events == nil || item.kept {
+			continue
+		}
+		pending++
+		if oldest.IsZero() || item.queuedAt.Before(oldest) {
+			oldest = item.queuedAt
+		}
+	}
+	if pending <= ResyncItems && (oldest.IsZero() || time.Since(oldest) <= ResyncLag) {
+		return
+	}
+
+	// Сброс таблицы ID идёт вместе со снимком: он выбрасывается с ним
+	items := o.items[:0]
+	for _, item := range o.items {
+		switch {
+		case item.raw != nil:
+			items = append(items, item)
+		case item.events != nil:
+			var kept []*events.Event
+			for _, event := range item.events {
+				if mustDeliver(event) {
+					kept = append(kept, event)
+				}
+			}
+			if len(kept) > 0 {
+				item.events, item.delta, item.kept = kept, false, true
+				items = append(items, item)
+			}
+		}
+	}
+	log.Printf("[OUTBOUND] Client lags behind (%d pending items), %d items dropped, waiting for resync", pending, len(o.items)-len(items))
+	o.items = items
+	o.resync = true
+	o.resyncs++
+}
+
+// NeedsResync - очередь сжата, клиенту нужен свежий снимок вместо выброшенных событий
+func (o *Outbound) NeedsResync() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.resync
+}
+
+// checkOverflow - предел очереди превышен даже после сжатия (под o.mu)
+// Исключение: личные сообщения выбросить нельзя, поэтому очередь сбрасывается
+// целиком и соединение закрывается; клиент вернётся через resume и получит снимок
+func (o *Outbound) checkOverflow() {
+	if len(o.items) <= MaxOutboundItems && o.rawBytes <= MaxOutboundBytes {
+		return
+	}
+	log.Printf("[OUTBOUND] Queue overflow (%d items, %d bytes), closing connection", len(o.items), o.rawBytes)
+	o.items = nil
+	o.rawBytes = 0
+	o.overflowed = true
+	o.closed = true
+	o.closeCode = websocket.CloseTryAgainLater
+}
+
+// Close - отправить оставшееся и закрыть соединение
+func (o *Outbound) Close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closed = true
+	o.wake()
+}
+
+// CloseWithError - то же, но с кодом ошибки протокола
+func (o *Outbound) CloseWithError() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closeCode = websocket.CloseProtocolError
+	o.closed = true
+	o.wake()
+}
+
+// take - забрать всё накопленное для записи (только из writePump)
+// closeCode - код закрытия соединения после записи; 0 - обычное закрытие
+func (o *Outbound) take() (items []outboundItem, closed bool, closeCode int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	items = o.items
+	o.items = nil
+	o.rawBytes = 0
+	if len(items) > 0 {
+		o.writingSince = items[0].queuedAt
+		for _, item := range items {
+			if item.queuedAt.Before(o.writingSince) {
+				o.writingSince = item.queuedAt
+			}
+		}
+	}
+	return items, o.closed, o.closeCode
+}
+
+// written - запись забранных элементов завершена (только из writePump)
+func (o *Outbound) written(frames, bytes int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.writingSince = time.Time{}
+	o.sentFrames += uint64(frames)
+	o.sentBytes += uint64(bytes)
+}
+
+// Lag - как давно ждёт самое старое неотправленное сообщение
+func (o *Outbound) Lag() time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	oldest := o.writingSince
+	for _, item := range o.items {
+		if oldest.IsZero() || item.queuedAt.Before(oldest) {
+			oldest = item.queuedAt
+		}
+	}
+	if oldest.IsZero() {
+		return 0
+	}
+	return time.Since(oldest)
+}
+
+// OutboundStats - метрики очереди для админки
+type OutboundStats struct {
+	Queued      int     `json:"queued"`
+	LagMs       float64 `json:"lagMs"`
+	Coalesced   uint64  `json:"coalesced"`
+	Resyncs     uint64  `json:"resyncs"`              // Сколько раз очередь сжималась до снимка
+	Overflowed  bool    `json:"overflowed,omitempty"` // Очередь переполнилась, соединение закрыто
+	SentFrames  uint64  `json:"sentFrames"`
+	SentBytes   uint64  `json:"sentBytes"`
+	RateDivisor int     `json:"rateDivisor"` // delta отправляется каждый N-й интервал
+}
+
+func (o *Outbound) Stats() OutboundStats {
+	lag := o.Lag()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return OutboundStats{
+		Queued:      len(o.items),
+		LagMs:       float64(lag) / float64(time.Millisecond),
+		Coalesced:   o.coalesced,
+		Resyncs:     o.resyncs,
+		Overflowed:  o.overflowed,
+		SentFrames:  o.sentFrames,
+		SentBytes:   o.sentBytes,
+		RateDivisor: max(o.rateDivisor, 1),
+	}
+}
+
+// ClientStats - состояние исходящей очереди клиента
+type ClientStats struct {
+	ID       string `json:"id"`
+	PlayerID string `json:"playerId"`
+	Binary   bool   `json:"binary"`
+	OutboundStats
+}
+
+// ClientStats - метрики очередей всех клиентов комнаты (самые отстающие первыми)
+func (s *Server) ClientStats() []ClientStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := make([]ClientStats, 0, len(s.Clients))
+	for _, client := range s.Clients {
+		stats = append(stats, ClientStats{
+			ID:            client.ID,
+			PlayerID:      client.PlayerID,
+			Binary:        client.Binary,
+			OutboundStats: client.Out.Stats(),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].LagMs > stats[j].LagMs
+	})
+	return stats
+}
+
+// encodeItems - кадры для записи в сокет (только из writePump, владеет codec)
+// Подряд идущие события объединяются в один кадр
+func (c *Client) encodeItems(items []outboundItem) [][]byte {
+	frames := [][]byte{}
+	var batch []*events.Event
+	var worldW, worldH float64
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		var data []byte
+		var err error
+		if c.Binary {
+			data, err = c.codec.encode(batch, worldW, worldH)
+		} else {
+			data, err = events.SerializeBatch(batch)
+		}
+		if err != nil {
+			log.Printf("[CLIENT %s] Error serializing events: %v", c.ID, err)
+		} else {
+			frames = append(frames, data)
+		}
+		batch = nil
+	}
+
+	for _, item := range items {
+		switch {
+		case item.resetCodec:
+			flush()
+			if c.Binary {
+				c.codec = newBinaryCodec()
+			}
+		case item.raw != nil:
+			flush()
+			frames = append(frames, item.raw)
+		default:
+			// Размер мира задаёт квантование координат - при его смене новый кадр
+			if len(batch) > 0 && (item.worldW != worldW || item.worldH != worldH) {
+				flush()
+			}
+			worldW, worldH = item.worldW, item.worldH
+			batch = append(batch, item.events...)
+		}
+	}
+	flush()
+	return frames
+}
+
+// adjustRate - реже delta для отстающего клиента, постепенное восстановление (только из Run)
+func (c *Client) adjustRate(lag time.Duration) {
+	if c.rateDivisor == 0 {
+		c.rateDivisor = 1
+	}
+	if time.Since(c.rateAdjusted) < RateAdjustPeriod {
+		return
+	}
+
+	switch {
+	case lag > DegradeLag && c.rateDivisor < MaxRateDivisor:
+		c.rateDivisor *= 2
+	case lag < RecoverLag && c.rateDivisor > 1:
+		c.rateDivisor /= 2
+	default:
+		return
+	}
+	c.rateAdjusted = time.Now()
+	c.Out.mu.Lock()
+	c.Out.rateDivisor = c.rateDivisor
+	c.Out.mu.Unlock()
+	log.Printf("[CLIENT %s] Lag %v, state delta every %d interval(s)", c.ID, lag.Round(time.Millisecond), c.rateDivisor)
+}
+
+// deltaDue - отправлять ли delta клиенту на этом тике delta
+func (c *Client) deltaDue(tick int64, interval int) bool {
+	if c.rateDivisor <= 1 {
+		return true
+	}
+	return (tick/int64(interval))%int64(c.rateDivisor) == 0
+}
@@ -0,0 +1,192 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"agario-server/internal/events"
+	"agario-server/internal/game"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestOutboundBacklog(t *testing.T) {
+	event := func() []*events.Event {
+		return []*events.Event{events.NewEvent(events.EventFoodEaten, &events.FoodEatenEvent{FoodID: "f"})}
+	}
+	delta := func() []*events.Event {
+		return []*events.Event{events.NewEvent(events.EventStateDelta, &events.StateDeltaEvent{})}
+	}
+	died := func() []*events.Event {
+		return []*events.Event{
+			events.NewEvent(events.EventFoodEaten, &events.FoodEatenEvent{FoodID: "f"}),
+			events.NewEvent(events.EventPlayerDied, &events.PlayerDiedEvent{PlayerID: "p"}),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		fill     func(o *Outbound)
+		resync   bool
+		want     []string // типы оставшихся элементов: raw или тип события
+		overflow bool
+	}{
+		{
+			name: "deltas are coalesced",
+			fill: func(o *Outbound) {
+				for i := 0; i < 10*ResyncItems; i++ {
+					o.PushEvents(delta(), 5000, 5000)
+				}
+			},
+			want: []string{string(events.EventStateDelta)},
+		},
+		{
+			name: "events up to the limit",
+			fill: func(o *Outbound) {
+				for i := 0; i < ResyncItems; i++ {
+					o.PushEvents(event(), 5000, 5000)
+				}
+			},
+		},
+		{
+			name: "too many events collapse",
+			fill: func(o *Outbound) {
+				o.PushRaw([]byte(`{"type":"init"}`))
+				o.PushEvents(died(), 5000, 5000)
+				o.ResetCodec()
+				o.PushEvents(delta(), 5000, 5000)
+				// Последнее событие превышает предел вместе с двумя элементами выше
+				for i := 0; i < ResyncItems-1; i++ {
+					o.PushEvents(event(), 5000, 5000)
+				}
+			},
+			resync: true,
+			want:   []string{"raw", string(events.EventPlayerDied)},
+		},
+		{
+			name: "stale events collapse",
+			fill: func(o *Outbound) {
+				o.PushEvents(event(), 5000, 5000)
+				o.items[0].queuedAt = time.Now().Add(-ResyncLag - time.Second)
+				o.PushEvents(delta(), 5000, 5000)
+			},
+			resync: true,
+			want:   []string{},
+		},
+		{
+			name: "too many messages",
+			fill: func(o *Outbound) {
+				for i := 0; i <= MaxOutboundItems; i++ {
+					o.PushRaw([]byte("{}"))
+				}
+			},
+			overflow: true,
+		},
+		{
+			name: "too many bytes",
+			fill: func(o *Outbound) {
+				for i := 0; i < 5; i++ {
+					o.PushRaw(make([]byte, MaxOutboundBytes/4))
+				}
+			},
+			overflow: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewOutbound()
+			tt.fill(o)
+			resync := o.NeedsResync()
+			items, closed, closeCode := o.take()
+
+			if tt.overflow {
+				if !closed || closeCode != websocket.CloseTryAgainLater || len(items) != 0 {
+					t.Fatalf("closed=%v code=%d with %d items, want the queue dropped and closed with %d",
+						closed, closeCode, len(items), websocket.CloseTryAgainLater)
+				}
+				if !o.Stats().Overflowed {
+					t.Fatal("overflow is not reported in stats")
+				}
+
+				// После закрытия очередь больше не растёт
+				o.PushRaw([]byte("{}"))
+				if items, _, _ := o.take(); len(items) != 0 {
+					t.Fatalf("%d items queued after overflow", len(items))
+				}
+				return
+			}
+
+			if closed || resync != tt.resync {
+				t.Fatalf("closed=%v resync=%v, want an open queue with resync=%v", closed, resync, tt.resync)
+			}
+			if tt.want == nil {
+				return
+			}
+			got := []string{}
+			for _, item := range items {
+				if item.raw != nil {
+					got = append(got, "raw")
+					continue
+				}
+				for _, event := range item.events {
+					got = append(got, string(event.Type))
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("queue holds %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("queue holds %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestOutboundResyncClearedBySnapshot(t *testing.T) {
+	o := NewOutbound()
+	for i := 0; i <= ResyncItems; i++ {
+		o.PushEvents([]*events.Event{events.NewEvent(events.EventFoodEaten, &events.FoodEatenEvent{FoodID: "f"})}, 5000, 5000)
+	}
+	if !o.NeedsResync() {
+		t.Fatal("backlog did not ask for a resync")
+	}
+
+	// Пока снимка нет, очередь не сжимается повторно
+	o.PushEvents([]*events.Event{events.NewEvent(events.EventFoodEaten, &events.FoodEatenEvent{FoodID: "f"})}, 5000, 5000)
+	if stats := o.Stats(); stats.Resyncs != 1 || stats.Queued != 1 {
+		t.Fatalf("%d resyncs with %d items queued, want 1 and 1", stats.Resyncs, stats.Queued)
+	}
+
+	o.ResetCodec()
+	o.PushEvents([]*events.Event{events.NewEvent(events.EventWorldSnapshot, &events.WorldSnapshotEvent{})}, 5000, 5000)
+	if o.NeedsResync() {
+		t.Fatal("snapshot did not clear the resync request")
+	}
+}
+
+func TestBroadcastResyncsLaggingClient(t *testing.T) {
+	s := NewServer(game.NewDeterministicWorld(nil, 1))
+	s.lastSnapshotTime = time.Now() // Проверяется resync, а не плановый снимок
+	client := wholeWorldClient(s.World)
+	client.ID, client.Out = "slow", NewOutbound()
+	s.Clients[client.ID] = client
+
+	for i := 0; i <= ResyncItems; i++ {
+		client.Out.PushEvents([]*events.Event{events.NewEvent(events.EventFoodEaten, &events.FoodEatenEvent{FoodID: "f"})}, 5000, 5000)
+	}
+	s.broadcastEvents()
+
+	items, closed, _ := client.Out.take()
+	snapshot := false
+	for _, item := range items {
+		for _, event := range item.events {
+			snapshot = snapshot || event.Type == events.EventWorldSnapshot
+		}
+	}
+	if closed || !snapshot || client.Out.NeedsResync() {
+		t.Fatalf("closed=%v, snapshot sent %v, want the lagging client kept and resynced", closed, snapshot)
+	}
+}
@@ -0,0 +1,252 @@
+package network
+
+import (
+	"agario-server/internal/bot"
+	"agario-server/internal/game"
+	"agario-server/internal/replay"
+	"agario-server/pkg/protocol"
+	"errors"
+	"log"
+	"time"
+)
+
+// ReplayDir - каталог файлов реплеев по умолчанию
+const ReplayDir = "replays"
+
+var (
+	ErrRecording        = errors.New("room is already recording")
+	ErrNotRecording     = errors.New("room is not recording")
+	ErrReplayRoom       = errors.New("room plays a replay")
+	ErrRecordingTimeout = errors.New("recording request was not applied in time")
+)
+
+// RecordingRequest - начать или остановить запись реплея, применяется в Run между тиками
+type RecordingRequest struct {
+	Path   string // "" - остановить запись
+	Result chan RecordingResult
+}
+
+type RecordingResult struct {
+	Path      string
+	StartTick int64
+	EndTick   int64 // 0 - запись продолжается
+	Entries   int
+	Err       error
+}
+
+// StartRecording - начать запись реплея комнаты в path
+// Комната должна быть детерминированной (создана с seed)
+func (s *Server) StartRecording(path string) RecordingResult {
+	return s.requestRecording(&RecordingRequest{Path: path, Result: make(chan RecordingResult, 1)})
+}
+
+// StopRecording - остановить запись и закрыть файл реплея
+func (s *Server) StopRecording() RecordingResult {
+	return s.requestRecording(&RecordingRequest{Result: make(chan RecordingResult, 1)})
+}
+
+func (s *Server) requestRecording(req *RecordingRequest) RecordingResult {
+	select {
+	case s.Recordings <- req:
+	case <-s.done:
+		return RecordingResult{Err: ErrRoomNotFound}
+	}
+
+	select {
+	case result := <-req.Result:
+		return result
+	case <-time.After(5 * time.Second):
+		return RecordingResult{Err: ErrRecordingTimeout}
+	}
+}
+
+// RecordingPath - файл текущей записи ("" - запись не идёт)
+func (s *Server) RecordingPath() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.recorder == nil {
+		return ""
+	}
+	return s.recorder.Path
+}
+
+// applyRecording - вызывается из Run между тиками
+// Начальное состояние берётся в том же локе, с которого пишутся записи
+func (s *Server) applyRecording(req *RecordingRequest, botManager *bot.BotManager) {
+	s.World.Mu.Lock()
+	defer s.World.Mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case s.Playback != nil:
+		req.Result <- RecordingResult{Err: ErrReplayRoom}
+	case req.Path == "" && s.recorder == nil:
+		req.Result <- RecordingResult{Err: ErrNotRecording}
+	case req.Path == "":
+		req.Result <- s.stopRecordingLocked()
+	case s.recorder != nil:
+		req.Result <- RecordingResult{Path: s.recorder.Path, Err: ErrRecording}
+	default:
+		start, err := replay.CaptureUnlocked(s.World, botManager, s.idle)
+		if err != nil {
+			req.Result <- RecordingResult{Err: err}
+			return
+		}
+		rec, err := replay.Create(req.Path, s.Room, start)
+		if err != nil {
+			req.Result <- RecordingResult{Err: err}
+			return
+		}
+		s.recorder = rec
+		log.Printf("[REPLAY] Room %q recording to %s from tick %d", s.Room, rec.Path, rec.StartTick)
+		req.Result <- RecordingResult{Path: rec.Path, StartTick: rec.StartTick}
+	}
+}
+
+// stopRecordingLocked - закрыть текущую запись (под локом мира и s.mu)
+func (s *Server) stopRecordingLocked() RecordingResult {
+	rec := s.recorder
+	if rec == nil {
+		return RecordingResult{Err: ErrNotRecording}
+	}
+	s.recorder = nil
+
+	result := RecordingResult{
+		Path:      rec.Path,
+		StartTick: rec.StartTick,
+		EndTick:   s.World.CurrentTick,
+		Entries:   rec.Entries(),
+		Err:       rec.Close(s.World.CurrentTick),
+	}
+	log.Printf("[REPLAY] Room %q recording %s stopped at tick %d (%d entries, err=%v)",
+		s.Room, rec.Path, result.EndTick, result.Entries, result.Err)
+	return result
+}
+
+// record - записать воздействие на мир, если комната пишет реплей
+// Вызывается под локом мира или s.mu (recorder меняется только под обоими)
+// вместе с самим изменением, чтобы порядок записей совпадал с порядком изменений
+func (s *Server) record(entry replay.Entry) {
+	if s.recorder == nil {
+		return
+	}
+	entry.Tick = s.World.CurrentTick
+	s.recorder.Record(entry)
+}
+
+// processReplayControl - пауза, перемотка и скорость общие для комнаты, камера - своя у клиента
+func (s *Server) processReplayControl(cmd *PlayerCommand) {
+	data, ok := cmd.Data.(*protocol.ReplayControlData)
+	if !ok {
+		return
+	}
+
+	s.mu.RLock()
+	client, ok := s.Clients[cmd.ClientID]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	if s.Playback == nil || !client.viewer {
+		client.replyError(&protocol.Error{Code: protocol.ErrCodeNotReplay, Message: "room is not playing a replay", Type: cmd.Type})
+		return
+	}
+
+	s.World.Mu.Lock()
+	p := s.Playback
+	seeked := false
+	switch data.Action {
+	case protocol.ReplayPlay:
+		if p.AtEnd() {
+			p.SeekUnlocked(p.Replay.StartTick())
+			seeked = true
+		}
+		p.Paused = p.Err != nil
+	case protocol.ReplayPause:
+		p.Paused = true
+	case protocol.ReplaySeek:
+		p.SeekUnlocked(data.Tick)
+		seeked = true
+	case protocol.ReplaySpeed:
+		p.SetSpeed(data.Speed)
+	case protocol.ReplayFollow:
+		client.camera.follow(data.PlayerID)
+	}
+	s.World.Mu.Unlock()
+
+	if seeked {
+		s.resyncViewers()
+	}
+	s.broadcastReplayState()
+}
+
+// resyncViewers - после перемотки мир другой: всем клиентам новый snapshot
+func (s *Server) resyncViewers() {
+	s.mu.RLock()
+	clients := make([]*Client, 0, len(s.Clients))
+	for _, client := range s.Clients {
+		clients = append(clients, client)
+	}
+	s.mu.RUnlock()
+
+	for _, client := range clients {
+		client.Delta = NewDeltaTracker()
+		s.sendSnapshot(client)
+	}
+}
+
+// advancePlaybackUnlocked - тик комнаты реплея (под локом мира)
+func (s *Server) advancePlaybackUnlocked() {
+	p := s.Playback
+	wasPaused, failed := p.Paused, p.Err != nil
+	p.AdvanceUnlocked()
+	if p.Err != nil && !failed {
+		log.Printf("[REPLAY] ❌ Room %q: %v", s.Room, p.Err)
+	}
+	if p.Paused != wasPaused {
+		// Конец записи или расхождение - зрители узнают сразу
+		s.lastReplayState = time.Time{}
+	}
+}
+
+// broadcastReplayState - состояние воспроизведения всем зрителям
+func (s *Server) broadcastReplayState() {
+	s.World.Mu.RLock()
+	defer s.World.Mu.RUnlock()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, client := range s.Clients {
+		if client.viewer {
+			client.reply(protocol.MsgTypeReplayState, s.replayStateUnlocked(client))
+		}
+	}
+	s.lastReplayState = time.Now()
+}
+
+func (s *Server) sendReplayState(client *Client) {
+	s.World.Mu.RLock()
+	defer s.World.Mu.RUnlock()
+	client.reply(protocol.MsgTypeReplayState, s.replayStateUnlocked(client))
+}
+
+// replayStateUnlocked - состояние воспроизведения для клиента (под локом мира)
+func (s *Server) replayStateUnlocked(client *Client) protocol.ReplayStateData {
+	p := s.Playback
+	state := protocol.ReplayStateData{
+		Tick:      p.Tick(),
+		StartTick: p.Replay.StartTick(),
+		EndTick:   p.Replay.EndTick,
+		TickRate:  game.TickRate,
+		Paused:    p.Paused,
+		Speed:     p.Speed,
+	}
+	if player := s.followedUnlocked(client); player != nil {
+		state.Following = player.ID
+	}
+	if p.Err != nil {
+		state.Error = p.Err.Error()
+	}
+	return state
+}
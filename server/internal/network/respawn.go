@@ -0,0 +1,95 @@
+package network
+
+import (
+	"agario-server/internal/events"
+	"agario-server/internal/replay"
+	"agario-server/pkg/protocol"
+	"log"
+)
+
+// notifyDeaths - погибшим игрокам персональное сообщение death
+// Клиент остаётся в комнате зрителем до respawn
+func (s *Server) notifyDeaths(tickEvents []*events.Event) {
+	deaths := make(map[string]*events.PlayerDiedEvent)
+	for _, event := range tickEvents {
+		if died, ok := event.Data.(*events.PlayerDiedEvent); ok {
+			deaths[died.PlayerID] = died
+		}
+	}
+	if len(deaths) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, client := range s.Clients {
+		died, ok := deaths[client.PlayerID]
+		if !ok {
+			continue
+		}
+
+		// Возвращаться некуда: сессия закрывается вместе с игроком
+		if client.session != nil {
+			s.closeSession(client.session)
+			client.session = nil
+		}
+		client.PlayerID = ""
+
+		client.reply(protocol.MsgTypeDeath, protocol.DeathData{
+			PlayerID:   died.PlayerID,
+			Cause:      died.Cause,
+			KillerID:   died.KillerID,
+			KillerName: died.KillerName,
+			FinalMass:  died.FinalMass,
+			TimeAlive:  died.TimeAlive,
+			Kills:      died.Kills,
+		})
+		log.Printf("[SERVER] Player %s (%s) died (%s, killer %q) after %.0fs", died.PlayerID, client.name, died.Cause, died.KillerName, died.TimeAlive)
+	}
+}
+
+// processRespawn - новый игрок для того же соединения после гибели
+func (s *Server) processRespawn(cmd *PlayerCommand) {
+	data, ok := cmd.Data.(*protocol.RespawnData)
+	if !ok {
+		return
+	}
+
+	s.mu.RLock()
+	client, ok := s.Clients[cmd.ClientID]
+	playing := ok && client.PlayerID != ""
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	if playing {
+		client.replyError(&protocol.Error{Code: protocol.ErrCodeAlreadyPlaying, Message: "player is still alive", Type: cmd.Type})
+		return
+	}
+	if client.name == "" {
+		client.replyError(&protocol.Error{Code: protocol.ErrCodeNotJoined, Message: "join before respawn", Type: cmd.Type})
+		return
+	}
+	if data.Name != "" {
+		client.name = data.Name
+	}
+
+	s.World.Mu.Lock()
+	if !s.World.JoinOpenUnlocked() {
+		s.World.Mu.Unlock()
+		client.replyError(&protocol.Error{Code: protocol.ErrCodeMatchRunning, Message: "match in progress, join after it ends", Type: cmd.Type})
+		return
+	}
+	player := s.World.AddPlayerUnlocked(client.name, client.color, false)
+	s.record(replay.Entry{Kind: replay.KindJoin, Player: player.ID, Name: client.name, Color: client.color})
+	s.World.Mu.Unlock()
+
+	s.mu.Lock()
+	client.PlayerID = player.ID
+	client.viewer = false
+	client.reply(protocol.MsgTypeInit, s.initData(s.openSession(client, player), false))
+	s.mu.Unlock()
+
+	log.Printf("Player respawned: %s (%s)", client.name, player.ID)
+}
@@ -0,0 +1,130 @@
+package network
+
+import (
+	"testing"
+
+	"agario-server/internal/events"
+	"agario-server/internal/game"
+	"agario-server/pkg/protocol"
+)
+
+// killClient - игрок клиента погиб в тике, сервер разослал итоги
+func killClient(s *Server, client *Client) string {
+	playerID := client.PlayerID
+	s.World.RemovePlayerUnlocked(playerID)
+	s.notifyDeaths([]*events.Event{events.NewEvent(events.EventPlayerDied, &events.PlayerDiedEvent{
+		PlayerID:   playerID,
+		KillerID:   "killer",
+		KillerName: "bob",
+	})})
+	return playerID
+}
+
+func TestRespawn(t *testing.T) {
+	tests := []struct {
+		name     string
+		joined   bool
+		died     bool
+		respawn  string // имя в respawn
+		wantName string
+		wantCode protocol.ErrorCode
+	}{
+		{name: "after death", joined: true, died: true, wantName: "alice"},
+		{name: "with a new name", joined: true, died: true, respawn: "carol", wantName: "carol"},
+		{name: "while alive", joined: true, wantCode: protocol.ErrCodeAlreadyPlaying},
+		{name: "before join", wantCode: protocol.ErrCodeNotJoined},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(game.NewDeterministicWorld(nil, 1))
+			client := addTestClient(s, "c1")
+			if tt.joined {
+				s.processJoin(&PlayerCommand{Type: protocol.MsgTypeJoin, ClientID: "c1", Data: &protocol.JoinData{Name: "alice"}})
+			}
+			var deadID string
+			if tt.died {
+				deadID = killClient(s, client)
+			}
+			replies := takeReplies(t, client)
+			if tt.died {
+				last := replies[len(replies)-1]
+				if last.Type != protocol.MsgTypeDeath || last.Data.PlayerID != deadID || client.PlayerID != "" {
+					t.Fatalf("replies %+v, want death of %s", replies, deadID)
+				}
+			}
+
+			s.processRespawn(&PlayerCommand{Type: protocol.MsgTypeRespawn, ClientID: "c1", Data: &protocol.RespawnData{Name: tt.respawn}})
+
+			replies = takeReplies(t, client)
+			if len(replies) != 1 {
+				t.Fatalf("respawn replies %+v, want one", replies)
+			}
+			if tt.wantCode != "" {
+				if replies[0].Type != protocol.MsgTypeError || replies[0].Data.Code != tt.wantCode {
+					t.Fatalf("respawn reply %+v, want %s", replies[0], tt.wantCode)
+				}
+				return
+			}
+
+			player, ok := s.World.Players[client.PlayerID]
+			if replies[0].Type != protocol.MsgTypeInit || !ok || client.PlayerID == deadID {
+				t.Fatalf("respawn reply %+v, player %q, want a new player", replies[0], client.PlayerID)
+			}
+			if player.Name != tt.wantName {
+				t.Fatalf("respawned as %q, want %q", player.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestRespawnAfterResume(t *testing.T) {
+	tests := []struct {
+		name    string
+		restore bool // сессия из сохранения мира, а не после обрыва связи
+	}{
+		{name: "after reconnect"},
+		{name: "after world restore", restore: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(game.NewDeterministicWorld(nil, 1))
+			first := addTestClient(s, "c1")
+			s.processJoin(&PlayerCommand{Type: protocol.MsgTypeJoin, ClientID: "c1", Data: &protocol.JoinData{Name: "alice"}})
+			player := s.World.Players[first.PlayerID]
+			token := first.session.Token
+
+			if tt.restore {
+				world, err := game.NewWorldFromState(s.World.SaveStateUnlocked())
+				if err != nil {
+					t.Fatal(err)
+				}
+				s = NewServer(world)
+				s.adoptPlayersLocked(map[string]string{token: player.ID})
+			} else {
+				s.disconnectPlayer(first)
+				delete(s.Clients, first.ID)
+			}
+
+			client := addTestClient(s, "c2")
+			s.processResume(&PlayerCommand{Type: protocol.MsgTypeResume, ClientID: "c2", Data: &protocol.ResumeData{Token: token}})
+			if client.PlayerID != player.ID {
+				t.Fatalf("resumed as %q, want %s", client.PlayerID, player.ID)
+			}
+			killClient(s, client)
+			takeReplies(t, client)
+
+			s.processRespawn(&PlayerCommand{Type: protocol.MsgTypeRespawn, ClientID: "c2", Data: &protocol.RespawnData{}})
+
+			replies := takeReplies(t, client)
+			if len(replies) != 1 || replies[0].Type != protocol.MsgTypeInit {
+				t.Fatalf("respawn replies %+v, want init", replies)
+			}
+			respawned := s.World.Players[client.PlayerID]
+			if respawned == nil || respawned.Name != player.Name || respawned.Color != player.Color {
+				t.Fatalf("respawned as %+v, want %q %s", respawned, player.Name, player.Color)
+			}
+		})
+	}
+}
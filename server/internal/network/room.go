@@ -0,0 +1,420 @@
+package network
+
+import (
+	"agario-server/internal/bot"
+	"agario-server/internal/game"
+	"agario-server/internal/replay"
+	"agario-server/internal/stats"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultRoomName - комната, в которую попадают клиенты без выбора
+const DefaultRoomName = "default"
+
+var (
+	ErrRoomExists      = errors.New("room already exists")
+	ErrRoomNotFound    = errors.New("room not found")
+	ErrRoomNameEmpty   = errors.New("room name is empty")
+	ErrDefaultRoom     = errors.New("default room cannot be destroyed")
+	ErrNoRoomAvailable = errors.New("no room available")
+)
+
+// RoomSettings - настройки отдельной комнаты
+type RoomSettings struct {
+	MaxPlayers int              `json:"maxPlayers"`
+	Bots       int              `json:"bots"`
+	Teams      int              `json:"teams,omitempty"`  // 2-4 - командный режим поверх правил; 0 - как в правилах
+	Mode       string           `json:"mode,omitempty"`   // Режим поверх правил (ffa, battle_royale); "" - как в правилах
+	Map        string           `json:"map,omitempty"`    // Файл карты поверх правил; "" - как в правилах
+	Config     *game.GameConfig `json:"-"`                // nil - правила менеджера комнат
+	State      *game.WorldState `json:"-"`                // Сохранённый мир (правила берутся из него); nil - новый мир
+	Seed       *int64           `json:"seed,omitempty"`   // Детерминированный мир с этим seed; nil - обычный
+	Replay     string           `json:"replay,omitempty"` // Файл реплея, который воспроизводит комната
+}
+
+func DefaultRoomSettings() RoomSettings {
+	return RoomSettings{
+		MaxPlayers: 50,
+		Bots:       10,
+	}
+}
+
+// Room - отдельный мир со своим игровым циклом, EventBus и ботами
+type Room struct {
+	Name       string
+	Settings   RoomSettings
+	World      *game.World
+	BotManager *bot.BotManager
+	Server     *Server
+	CreatedAt  time.Time
+}
+
+// PlayerCount - количество подключенных клиентов комнаты без зрителей
+func (r *Room) PlayerCount() int {
+	players, _ := r.Server.clientCounts()
+	return players
+}
+
+// SpectatorCount - зрители комнаты (не занимают места игроков)
+func (r *Room) SpectatorCount() int {
+	_, spectators := r.Server.clientCounts()
+	return spectators
+}
+
+// IsFull - достигнут лимит игроков
+func (r *Room) IsFull() bool {
+	return r.Settings.MaxPlayers > 0 && r.PlayerCount() >= r.Settings.MaxPlayers
+}
+
+// mapName - название карты комнаты (без названия - файл); "" - пустой мир
+func mapName(cfg *game.GameConfig) string {
+	if cfg.Map == nil {
+		return ""
+	}
+	if cfg.Map.Name != "" {
+		return cfg.Map.Name
+	}
+	return cfg.MapFile
+}
+
+// RoomManager - управление комнатами одного процесса
+type RoomManager struct {
+	rooms   map[string]*Room
+	config  *game.GameConfig // Правила по умолчанию для новых комнат
+	results *stats.Recorder  // nil - результаты игроков не сохраняются
+	mu      sync.RWMutex
+}
+
+// NewRoomManager - менеджер комнат с правилами cfg (nil - правила по умолчанию)
+func NewRoomManager(cfg *game.GameConfig) *RoomManager {
+	if cfg == nil {
+		cfg = game.DefaultGameConfig()
+	}
+	return &RoomManager{
+		rooms:  make(map[string]*Room),
+		config: cfg,
+	}
+}
+
+// CreateRoom - создать комнату и запустить её игровой цикл
+func (rm *RoomManager) CreateRoom(name string, settings RoomSettings) (*Room, error) {
+	if name == "" {
+		return nil, ErrRoomNameEmpty
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if _, exists := rm.rooms[name]; exists {
+		return nil, ErrRoomExists
+	}
+
+	// Каждая комната получает свою копию правил
+	if settings.State != nil {
+		settings.Config = settings.State.Config
+	}
+	if settings.Config == nil {
+		settings.Config = rm.config
+	}
+	settings.Config = settings.Config.Clone()
+	if settings.Teams != 0 && settings.State == nil {
+		settings.Config.Teams = settings.Teams
+	}
+	if settings.Mode != "" && settings.State == nil {
+		settings.Config.Mode = settings.Mode
+	}
+	if settings.Map != "" && settings.State == nil {
+		layout, err := game.LoadMapLayout(settings.Map)
+		if err != nil {
+			return nil, err
+		}
+		settings.Config.Map, settings.Config.MapFile = layout, settings.Map
+	}
+	if err := settings.Config.Validate(); err != nil {
+		return nil, err
+	}
+
+	var world *game.World
+	if settings.State != nil {
+		var err error
+		if world, err = game.NewWorldFromState(settings.State); err != nil {
+			return nil, err
+		}
+	} else if settings.Seed != nil {
+		world = game.NewDeterministicWorld(settings.Config, *settings.Seed)
+	} else {
+		world = game.NewWorld(settings.Config)
+	}
+	botManager := bot.NewBotManager(world, settings.Bots)
+	botManager.AdoptBotsUnlocked()
+	botManager.SpawnBots()
+
+	server := NewServer(world)
+	server.Room = name
+	server.Rooms = rm
+	if settings.State != nil {
+		// Игроки из сохранения ждут переподключения по своим токенам
+		world.Mu.Lock()
+		server.mu.Lock()
+		server.adoptPlayersLocked(settings.State.Sessions)
+		server.mu.Unlock()
+		world.Mu.Unlock()
+	}
+
+	room := &Room{
+		Name:       name,
+		Settings:   settings,
+		World:      world,
+		BotManager: botManager,
+		Server:     server,
+		CreatedAt:  time.Now(),
+	}
+	rm.rooms[name] = room
+	if rm.results != nil {
+		rm.results.Attach(world.EventBus, name)
+	}
+
+	go server.Run(botManager)
+
+	log.Printf("[ROOMS] Room %q created (maxPlayers=%d, bots=%d, teams=%d, mode=%s, map=%q, seed=%d, deterministic=%v)",
+		name, settings.MaxPlayers, settings.Bots, world.Config.Teams, world.Config.Mode, mapName(world.Config), world.Seed(), world.Deterministic())
+	return room, nil
+}
+
+// SetResultStore - сохранять результаты игроков всех комнат в store
+func (rm *RoomManager) SetResultStore(store stats.Store) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.results = stats.NewRecorder(store)
+	for name, room := range rm.rooms {
+		if room.Server.Playback == nil {
+			rm.results.Attach(room.World.EventBus, name)
+		}
+	}
+}
+
+// ResultStore - хранилище результатов (nil - не подключено)
+func (rm *RoomManager) ResultStore() stats.Store {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	if rm.results == nil {
+		return nil
+	}
+	return rm.results.Store
+}
+
+// CreatePlaybackRoom - комната, воспроизводящая реплей из файла path
+// Зрители входят в неё только по имени; результаты игроков не сохраняются
+func (rm *RoomManager) CreatePlaybackRoom(name, path string) (*Room, error) {
+	if name == "" {
+		return nil, ErrRoomNameEmpty
+	}
+
+	rep, err := replay.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	playback, err := replay.NewPlayback(rep)
+	if err != nil {
+		return nil, err
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if _, exists := rm.rooms[name]; exists {
+		return nil, ErrRoomExists
+	}
+
+	server := NewServer(playback.World)
+	server.Room = name
+	server.Rooms = rm
+	server.Playback = playback
+
+	room := &Room{
+		Name: name,
+		Settings: RoomSettings{
+			Bots:   rep.Header.Start.MaxBots,
+			Config: playback.World.Config,
+			Replay: path,
+		},
+		World:      playback.World,
+		BotManager: playback.Bots,
+		Server:     server,
+		CreatedAt:  time.Now(),
+	}
+	rm.rooms[name] = room
+
+	go server.Run(playback.Bots)
+
+	log.Printf("[ROOMS] Room %q plays replay %s of room %q (ticks %d-%d, %d entries)",
+		name, path, rep.Header.Room, rep.StartTick(), rep.EndTick, len(rep.Entries))
+	return room, nil
+}
+
+// DestroyRoom - остановить игровой цикл комнаты и отключить её клиентов
+func (rm *RoomManager) DestroyRoom(name string) error {
+	if name == DefaultRoomName {
+		return ErrDefaultRoom
+	}
+
+	rm.mu.Lock()
+	room, exists := rm.rooms[name]
+	if exists {
+		delete(rm.rooms, name)
+	}
+	rm.mu.Unlock()
+
+	if !exists {
+		return ErrRoomNotFound
+	}
+
+	room.Server.Stop()
+	log.Printf("[ROOMS] Room %q destroyed", name)
+	return nil
+}
+
+func (rm *RoomManager) GetRoom(name string) (*Room, bool) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	room, exists := rm.rooms[name]
+	return room, exists
+}
+
+// Rooms - все комнаты, отсортированные по имени
+func (rm *RoomManager) Rooms() []*Room {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	rooms := make([]*Room, 0, len(rm.rooms))
+	for _, room := range rm.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].Name < rooms[j].Name
+	})
+	return rooms
+}
+
+// Match - выбрать комнату для клиента
+// Явно запрошенная комната используется если существует и не заполнена,
+// иначе выбирается самая заполненная комната со свободными местами (кроме реплеев)
+func (rm *RoomManager) Match(name string) (*Room, error) {
+	if name != "" {
+		if room, exists := rm.GetRoom(name); exists && !room.IsFull() {
+			return room, nil
+		}
+		log.Printf("[ROOMS] Requested room %q unavailable, matching another", name)
+	}
+
+	var best *Room
+	bestCount := -1
+	for _, room := range rm.Rooms() {
+		if room.IsFull() || room.Server.Playback != nil {
+			continue
+		}
+		if count := room.PlayerCount(); count > bestCount {
+			best = room
+			bestCount = count
+		}
+	}
+
+	if best == nil {
+		return nil, ErrNoRoomAvailable
+	}
+	return best, nil
+}
+
+// roomForSession - комната, выдавшая reconnect-токен
+func (rm *RoomManager) roomForSession(token string) (*Room, bool) {
+	for _, room := range rm.Rooms() {
+		if room.Server.HasSession(token) {
+			return room, true
+		}
+	}
+	return nil, false
+}
+
+// HandleWebSocket - точка входа для клиентов; комната выбирается в join
+func (rm *RoomManager) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[WEBSOCKET] New connection request from %s", r.RemoteAddr)
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("[WEBSOCKET] ❌ Upgrade error: %v", err)
+		return
+	}
+
+	client := newClient(conn)
+	client.Rooms = rm
+
+	log.Printf("[WEBSOCKET] Created lobby client with ID: %s", client.ID)
+
+	go client.writePump()
+	go client.readPump()
+}
+
+// joinRoom - перевести клиента в комнату (вызывается из readPump клиента)
+// Зритель остаётся в своей комнате, только если в ней есть место игроку
+func (rm *RoomManager) joinRoom(c *Client, name string) error {
+	if c.Server != nil && (name == "" || c.Server.Room == name) {
+		room, exists := rm.GetRoom(c.Server.Room)
+		if !exists || !c.Server.isViewer(c) || !room.IsFull() {
+			return nil
+		}
+	}
+
+	room, err := rm.Match(name)
+	if err != nil {
+		return err
+	}
+	if c.Server == room.Server {
+		return nil
+	}
+	rm.enterRoom(c, room)
+	return nil
+}
+
+// spectateRoom - перевести клиента в комнату зрителем (вызывается из readPump клиента)
+// Лимит игроков не проверяется; без имени - текущая комната или комната по умолчанию
+func (rm *RoomManager) spectateRoom(c *Client, name string) error {
+	if c.Server != nil && (name == "" || c.Server.Room == name) {
+		return nil
+	}
+	if name == "" {
+		name = DefaultRoomName
+	}
+
+	room, exists := rm.GetRoom(name)
+	if !exists {
+		return ErrRoomNotFound
+	}
+	if c.Server != room.Server {
+		rm.enterRoom(c, room)
+	}
+	return nil
+}
+
+// enterRoom - клиент уходит из прежней комнаты и регистрируется в room
+func (rm *RoomManager) enterRoom(c *Client, room *Room) {
+	// Уходим из предыдущей комнаты, не закрывая соединение
+	if c.Server != nil {
+		c.Server.detach(c)
+	}
+
+	c.Server = room.Server
+	c.Interest = NewInterest()
+	c.Input = NewInputState()
+	c.Delta = NewDeltaTracker()
+	c.board = leaderboardView{}
+	c.viewer, c.camera = false, camera{}
+	c.Out.ResetCodec()
+	room.Server.addClient(c)
+	log.Printf("[ROOMS] Client %s joined room %q", c.ID, room.Name)
+}
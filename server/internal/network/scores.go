@@ -0,0 +1,68 @@
+package network
+
+import (
+	"agario-server/internal/stats"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ограничения выдачи результатов
+const (
+	DefaultScoresLimit = 10
+	MaxScoresLimit     = 100
+)
+
+// resultStore - хранилище результатов или 503, если оно не подключено
+func (a *AdminServer) resultStore(c *gin.Context) (stats.Store, bool) {
+	store := a.Rooms.ResultStore()
+	if store == nil {
+		c.JSON(503, gin.H{"success": false, "error": "result store is not configured"})
+		return nil, false
+	}
+	return store, true
+}
+
+// scoresLimit - параметр ?limit= в пределах 1..MaxScoresLimit
+func scoresLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		return DefaultScoresLimit
+	}
+	return min(limit, MaxScoresLimit)
+}
+
+// getScores - рекорды за всё время, сутки или неделю (?period=all|day|week)
+func (a *AdminServer) getScores(c *gin.Context) {
+	store, ok := a.resultStore(c)
+	if !ok {
+		return
+	}
+	period, err := stats.ParsePeriod(c.Query("period"))
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	top, err := store.Top(period, scoresLimit(c))
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"success": true, "period": period, "scores": top})
+}
+
+// getHistory - последние сыгранные жизни (?name= - одного игрока)
+func (a *AdminServer) getHistory(c *gin.Context) {
+	store, ok := a.resultStore(c)
+	if !ok {
+		return
+	}
+
+	history, err := store.History(c.Query("name"), scoresLimit(c))
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"success": true, "history": history})
+}
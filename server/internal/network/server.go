@@ -4,7 +4,8 @@ import (
 	"agario-server/internal/bot"
 	"agario-server/internal/events"
 	"agario-server/internal/game"
-	"encoding/json"
+	"agario-server/internal/replay"
+	"agario-server/pkg/protocol"
 	"log"
 	"net/http"
 	"sync"
@@ -16,6 +17,8 @@ import (
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	// Клиент выбирает формат при подключении; без subprotocol - JSON
+	Subprotocols: []string{protocol.SubprotocolBinary, protocol.SubprotocolJSON},
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
@@ -24,9 +27,46 @@ var upgrader = websocket.Upgrader{
 type Client struct {
 	ID       string
 	Conn     *websocket.Conn
-	Send     chan []byte
+	Out      *Outbound    // Исходящая очередь (пишет writePump)
 	PlayerID string
-	Server   *Server
+	Server   *Server      // Комната, в которой находится клиент (nil - ещё не вошёл)
+	Rooms    *RoomManager // nil - клиент подключен напрямую к одному Server
+	Interest *Interest    // Область видимости (используется только из Run)
+	Input    *InputState  // Очередь ввода и подтверждения (используется только из Run)
+	Delta    *DeltaTracker // Базовое состояние для state delta (используется только из Run)
+	Binary   bool         // Согласован бинарный протокол
+	Version  int          // Согласованная версия протокола (hello)
+	codec    *binaryCodec // Таблица ID сессии для бинарного протокола
+	session  *session     // Сессия игрока для resume (под Server.mu)
+	name     string       // Имя и цвет игрока для respawn (используется только из Run)
+	color    string
+	board    leaderboardView // Последняя отправленная таблица лидеров (используется только из Run)
+	viewer   bool            // Зритель без игрока: spectate или комната реплея (под Server.mu)
+	camera   camera          // Камера зрителя (используется только из Run)
+
+	// Частота delta для отстающего клиента (используется только из Run)
+	rateDivisor  int
+	rateAdjusted time.Time
+}
+
+// newClient - клиент нового соединения; формат выбран через subprotocol
+// Сообщение больше protocol.MaxMessageSize закрывает соединение, не попадая в память
+func newClient(conn *websocket.Conn) *Client {
+	conn.SetReadLimit(protocol.MaxMessageSize)
+	client := &Client{
+		ID:       generateClientID(),
+		Conn:     conn,
+		Out:      NewOutbound(),
+		Interest: NewInterest(),
+		Input:    NewInputState(),
+		Delta:    NewDeltaTracker(),
+		Version:  protocol.MinVersion,
+	}
+	if conn.Subprotocol() == protocol.SubprotocolBinary {
+		client.Binary = true
+		client.codec = newBinaryCodec()
+	}
+	return client
 }
 
 type Server struct {
@@ -37,13 +77,44 @@ type Server struct {
 	Commands   chan *PlayerCommand
 	mu         sync.RWMutex
 	
+	// Изменения правил на лету и их журнал
+	ConfigUpdates chan *ConfigUpdate
+	Audit         *ConfigAudit
+	
+	// Загрузка сохранённого мира
+	Restores chan *WorldRestore
+	
+	// Запись реплея (recorder меняется под локом мира и s.mu) и воспроизведение
+	Recordings      chan *RecordingRequest
+	recorder        *replay.Recorder
+	Playback        *replay.Playback // nil - обычная комната
+	lastReplayState time.Time
+	
+	// Последнее разосланное состояние матча (используется только из Run)
+	match          *protocol.MatchStateData
+	lastMatchState time.Time
+	
+	// Сессии для переподключения и управление клетками отключившихся игроков
+	sessions map[string]*session
+	idle     map[string]*bot.Bot // playerID -> пассивный контроллер (только из Run)
+	
+	// Комната, которую обслуживает сервер
+	Room     string
+	Rooms    *RoomManager
+	done     chan struct{}
+	stopOnce sync.Once
+	
 	// Для периодической синхронизации
 	lastSnapshotTime time.Time
 	snapshotInterval time.Duration
+	
+	// Таблица лидеров
+	lastLeaderboardTime time.Time
+	leaderboardInterval time.Duration
 }
 
 type PlayerCommand struct {
-	Type     string
+	Type     protocol.MessageType
 	ClientID string
 	Data     interface{}
 }
@@ -55,8 +126,17 @@ func NewServer(world *game.World) *Server {
 		Register:         make(chan *Client, 10),
 		Unregister:       make(chan *Client, 10),
 		Commands:         make(chan *PlayerCommand, 100),
+		ConfigUpdates:    make(chan *ConfigUpdate, 10),
+		Restores:         make(chan *WorldRestore, 1),
+		Recordings:       make(chan *RecordingRequest, 1),
+		Audit:            &ConfigAudit{},
+		sessions:         make(map[string]*session),
+		idle:             make(map[string]*bot.Bot),
+		Room:             DefaultRoomName,
+		done:             make(chan struct{}),
 		lastSnapshotTime: time.Now(),
 		snapshotInterval: 10 * time.Second, // Редкий snapshot для подстраховки (основная синхронизация через cell_updated)
+		leaderboardInterval: time.Second,
 	}
 }
 
@@ -71,13 +151,25 @@ func (s *Server) Run(botManager *bot.BotManager) {
 	ticker := time.NewTicker(game.TickDuration)
 	defer ticker.Stop()
 
-	botUpdateCounter := 0
-	botUpdateInterval := 10
-
 	log.Println("[SERVER] Entering main loop...")
 
 	for {
 		select {
+		case <-s.done:
+			s.shutdown()
+			log.Printf("[SERVER] Room %q stopped", s.Room)
+			return
+
+		case update := <-s.ConfigUpdates:
+			// Между тиками: следующий Update уже идёт по новым правилам
+			s.applyConfigUpdate(update)
+
+		case restore := <-s.Restores:
+			s.applyRestore(restore, botManager)
+
+		case req := <-s.Recordings:
+			s.applyRecording(req, botManager)
+
 		case client := <-s.Register:
 			log.Printf("[SERVER] Register case triggered for client %s", client.ID)
 			s.mu.Lock()
@@ -86,23 +178,18 @@ func (s *Server) Run(botManager *bot.BotManager) {
 			log.Printf("Client registered: %s", client.ID)
 
 		case client := <-s.Unregister:
+			s.World.Mu.Lock()
 			s.mu.Lock()
 			if _, ok := s.Clients[client.ID]; ok {
 				delete(s.Clients, client.ID)
-				
-				func() {
-					defer func() {
-						if r := recover(); r != nil {}
-					}()
-					close(client.Send)
-				}()
+				client.Out.Close()
 
 				if client.PlayerID != "" {
-					s.World.RemovePlayer(client.PlayerID)
-					log.Printf("[SERVER] Player %s removed from world", client.PlayerID)
+					s.disconnectPlayer(client)
 				}
 			}
 			s.mu.Unlock()
+			s.World.Mu.Unlock()
 			log.Printf("Client unregistered: %s", client.ID)
 
 		case <-ticker.C:
@@ -119,233 +206,329 @@ func (s *Server) Run(botManager *bot.BotManager) {
 
 		UpdateWorld:
 			s.World.Mu.Lock()
-			s.World.UpdateUnlocked(game.TickDuration.Seconds())
-			botUpdateCounter++
-			if botUpdateCounter >= botUpdateInterval {
-				botUpdateCounter = 0
-				botManager.Update()
+			if s.Playback != nil {
+				s.advancePlaybackUnlocked()
+			} else {
+				s.simulateUnlocked(botManager)
 			}
 			s.World.Mu.Unlock()
 			
 			// Отправляем события вместо полного состояния!
 			s.broadcastEvents()
+			
+			if time.Since(s.lastLeaderboardTime) >= s.leaderboardInterval {
+				s.broadcastLeaderboard()
+				s.lastLeaderboardTime = time.Now()
+			}
+			
+			if s.Playback != nil && time.Since(s.lastReplayState) >= time.Second {
+				s.broadcastReplayState()
+			}
+			
+			s.broadcastMatchState()
 		}
 	}
 }
 
+// simulateUnlocked - тик обычной комнаты (под локом мира)
+// Боты решают на тиках, кратных bot.UpdateInterval: так же их обновляет воспроизведение реплея
+func (s *Server) simulateUnlocked(botManager *bot.BotManager) {
+	s.applyInputsUnlocked()
+	s.World.UpdateUnlocked(game.TickDuration.Seconds())
+	updateBots := s.World.CurrentTick%bot.UpdateInterval == 0
+	if updateBots {
+		botManager.Update()
+	}
+	s.updateSessionsUnlocked(updateBots)
+}
+
+// Stop - остановить игровой цикл (комната уничтожается)
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// shutdown - отключить всех клиентов остановленной комнаты
+func (s *Server) shutdown() {
+	s.World.Mu.Lock()
+	defer s.World.Mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.recorder != nil {
+		s.stopRecordingLocked()
+	}
+	for id, client := range s.Clients {
+		delete(s.Clients, id)
+		client.Out.Close()
+	}
+}
+
+// addClient - синхронная регистрация (join из RoomManager идёт сразу следом)
+func (s *Server) addClient(client *Client) {
+	s.mu.Lock()
+	s.Clients[client.ID] = client
+	s.mu.Unlock()
+	log.Printf("Client registered in room %q: %s", s.Room, client.ID)
+}
+
+// detach - убрать клиента из комнаты без закрытия соединения (переход в другую комнату)
+func (s *Server) detach(client *Client) {
+	s.World.Mu.Lock()
+	defer s.World.Mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.Clients, client.ID)
+	s.dropPlayerLocked(client, events.LeaveQuit)
+}
+
+// unregister - отписка клиента; не блокируется если комната уже остановлена
+func (s *Server) unregister(client *Client) {
+	select {
+	case s.Unregister <- client:
+	case <-s.done:
+	}
+}
+
+// enqueue - команда в очередь комнаты; не блокируется если комната уже остановлена
+func (s *Server) enqueue(cmd *PlayerCommand) {
+	select {
+	case s.Commands <- cmd:
+	case <-s.done:
+	}
+}
+
 // broadcastEvents - отправка только событий клиентам
+// Каждый клиент получает только то, что попадает в его область видимости
 func (s *Server) broadcastEvents() {
 	// Получаем накопленные события
-	events := s.World.EventBus.FlushEvents()
+	tickEvents := s.World.EventBus.FlushEvents()
+	
+	// Итоги жизни погибшим - после событий тика
+	defer s.notifyDeaths(tickEvents)
 	
 	// Проверяем нужен ли snapshot
 	needSnapshot := time.Since(s.lastSnapshotTime) >= s.snapshotInterval
 	
 	if needSnapshot {
 		// Создаем и отправляем snapshot
-		s.broadcastSnapshot()
+		s.broadcastSnapshot(tickEvents)
 		s.lastSnapshotTime = time.Now()
-	} else if len(events) > 0 {
-		// Отправляем только события
-		data, err := s.World.EventBus.SerializeEvents(events)
-		if err != nil {
-			log.Printf("[BROADCAST] Error serializing events: %v", err)
-			return
-		}
+		return
+	}
+	
+	s.World.Mu.RLock()
+	s.mu.RLock()
+	deltaTick := s.World.IsDeltaTick()
+	for _, client := range s.Clients {
+		// Медленный клиент получает delta реже; если очередь всё равно копится,
+		// она сжимается и клиент получает снимок. Отключает только ошибка записи
+		lag := client.Out.Lag()
 		
-		if data == nil {
-			return
-		}
+		s.updateView(client)
 		
-		// Отправляем events batch всем клиентам
-		s.mu.RLock()
-		deadClients := []*Client{}
-		for _, client := range s.Clients {
-			select {
-			case client.Send <- data:
-			default:
-				log.Printf("[BROADCAST] Client %s has full/closed channel, marking for removal", client.ID)
-				deadClients = append(deadClients, client)
-			}
-		}
-		s.mu.RUnlock()
-		
-		// Удаляем мертвые клиенты
-		if len(deadClients) > 0 {
-			for _, client := range deadClients {
-				log.Printf("[BROADCAST] Force unregistering dead client %s", client.ID)
-				select {
-				case s.Unregister <- client:
-				default:
+		var batch []*events.Event
+		switch {
+		case client.Out.NeedsResync():
+			log.Printf("[BROADCAST] Client %s fell behind (lag %v), resending snapshot", client.ID, lag.Round(time.Millisecond))
+			batch = s.resync(client, tickEvents)
+		case client.Delta.needsResync(s.World.CurrentTick):
+			log.Printf("[BROADCAST] Client %s stopped acking (last tick %d), resending snapshot", client.ID, client.Delta.AckedTick)
+			batch = s.resync(client, tickEvents)
+		default:
+			// Сначала события (они обновляют известные клиенту entity), затем вход/выход и delta
+			batch = s.filterEvents(client, tickEvents)
+			batch = append(batch, s.diffInterest(client)...)
+			if deltaTick {
+				client.adjustRate(lag)
+				if client.deltaDue(s.World.CurrentTick, s.World.Config.DeltaInterval) {
+					batch = s.appendDelta(client, batch)
 				}
 			}
 		}
+		if len(batch) == 0 {
+			continue
+		}
+		
+		client.Out.PushEvents(batch, s.World.Config.WorldWidth, s.World.Config.WorldHeight)
 	}
+	s.mu.RUnlock()
+	s.World.Mu.RUnlock()
 }
 
-// broadcastSnapshot - отправка полного снимка мира для синхронизации
-func (s *Server) broadcastSnapshot() {
+// broadcastSnapshot - отправка снимка видимой области для синхронизации
+// События тика идут следом: снимок не должен их поглощать
+func (s *Server) broadcastSnapshot(tickEvents []*events.Event) {
 	s.World.Mu.RLock()
 	defer s.World.Mu.RUnlock()
 
-	players := []events.PlayerState{}
-	for _, p := range s.World.Players {
-		p.Mu.RLock()
-		cells := []events.CellState{}
-		for _, cell := range p.Cells {
-			cells = append(cells, events.CellState{
-				ID:     cell.ID,
-				X:      cell.Position.X,
-				Y:      cell.Position.Y,
-				Radius: cell.Radius,
-			})
+	s.mu.RLock()
+	sent := 0
+	for _, client := range s.Clients {
+		s.updateView(client)
+		if !client.Interest.HasView {
+			continue
 		}
-		players = append(players, events.PlayerState{
-			ID:    p.ID,
-			Name:  p.Name,
-			Color: p.Color,
-			IsBot: p.IsBot,
-			Score: p.GetScore(),
-			Cells: cells,
-		})
-		p.Mu.RUnlock()
+
+		snapshot := s.buildSnapshot(client)
+		client.Delta.recordSnapshot(snapshot)
+		batch := []*events.Event{events.NewEvent(events.EventWorldSnapshot, snapshot)}
+		batch = append(batch, s.filterEvents(client, tickEvents)...)
+		client.Out.PushEvents(batch, s.World.Config.WorldWidth, s.World.Config.WorldHeight)
+		sent++
 	}
+	s.mu.RUnlock()
+	
+	log.Printf("[SNAPSHOT] Sent snapshot to %d clients", sent)
+}
 
+// buildSnapshot - снимок области видимости клиента (под локом мира)
+// Множество известных клиенту entity заменяется содержимым снимка
+func (s *Server) buildSnapshot(client *Client) *events.WorldSnapshotEvent {
+	in := client.Interest
+	in.Known = make(map[string]struct{})
+	
+	players := []events.PlayerState{}
+	playerIndex := make(map[string]int)
 	food := []events.FoodState{}
-	for _, f := range s.World.Food {
-		food = append(food, events.FoodState{
-			ID:     f.ID,
-			X:      f.Position.X,
-			Y:      f.Position.Y,
-			Radius: f.Radius,
-			Color:  f.Color,
-		})
+	viruses := []events.VirusState{}
+	powerUps := []events.PowerUpState{}
+	now := s.World.Now()
+	
+	for _, entry := range s.World.EntitiesInRect(in.View.MinX, in.View.MinY, in.View.MaxX, in.View.MaxY) {
+		in.Known[entry.ID] = struct{}{}
+		
+		switch entry.Kind {
+		case game.EntityCell:
+			p := entry.Player
+			idx, ok := playerIndex[p.ID]
+			if !ok {
+				idx = len(players)
+				playerIndex[p.ID] = idx
+				players = append(players, events.PlayerState{
+					ID:      p.ID,
+					Name:    p.Name,
+					Color:   p.Color,
+					IsBot:   p.IsBot,
+					Score:   p.GetScore(),
+					Cells:   []events.CellState{},
+					Effects: p.ActiveEffects(now),
+				})
+			}
+			players[idx].Cells = append(players[idx].Cells, events.CellState{
+				ID:     entry.Cell.ID,
+				X:      entry.Cell.Position.X,
+				Y:      entry.Cell.Position.Y,
+				Radius: entry.Cell.Radius,
+			})
+		case game.EntityFood:
+			food = append(food, events.FoodState{
+				ID:     entry.Food.ID,
+				X:      entry.Food.Position.X,
+				Y:      entry.Food.Position.Y,
+				Radius: entry.Food.Radius,
+				Color:  entry.Food.Color,
+			})
+		case game.EntityVirus:
+			viruses = append(viruses, events.VirusState{
+				ID:     entry.Virus.ID,
+				X:      entry.Virus.Position.X,
+				Y:      entry.Virus.Position.Y,
+				Radius: entry.Virus.Radius,
+			})
+		case game.EntityPowerUp:
+			powerUps = append(powerUps, events.PowerUpState{
+				ID:     entry.PowerUp.ID,
+				Kind:   string(entry.PowerUp.Kind),
+				X:      entry.PowerUp.Position.X,
+				Y:      entry.PowerUp.Position.Y,
+				Radius: entry.PowerUp.Radius,
+			})
+		}
 	}
 
-	snapshot := &events.WorldSnapshotEvent{
+	return &events.WorldSnapshotEvent{
+		Tick:      s.World.CurrentTick,
 		Timestamp: time.Now().UnixMilli(),
 		Players:   players,
 		Food:      food,
+		Viruses:   viruses,
+		PowerUps:  powerUps,
 	}
-
-	event := events.NewEvent(events.EventWorldSnapshot, snapshot)
-	data, err := json.Marshal(event)
-	if err != nil {
-		log.Printf("[SNAPSHOT] Error marshaling snapshot: %v", err)
-		return
-	}
-
-	// Отправляем snapshot всем клиентам
-	s.mu.RLock()
-	for _, client := range s.Clients {
-		select {
-		case client.Send <- data:
-		default:
-			// Пропускаем если канал заполнен
-		}
-	}
-	s.mu.RUnlock()
-	
-	log.Printf("[SNAPSHOT] Sent snapshot: %d players, %d food", len(players), len(food))
 }
 
 // Остальные методы остаются без изменений...
 func (s *Server) handleCommand(cmd *PlayerCommand) {
 	switch cmd.Type {
-	case "join":
+	case protocol.MsgTypeJoin:
+		if s.Playback != nil {
+			// В реплее играть нельзя - только смотреть
+			s.spectate(cmd.ClientID, &protocol.SpectateData{Mode: protocol.SpectateLeader})
+			return
+		}
 		s.processJoin(cmd)
-	case "move":
-		s.processMove(cmd)
-	case "split":
-		s.processSplit(cmd)
-	case "eject":
-		s.processEject(cmd)
+	case protocol.MsgTypeMove, protocol.MsgTypeSplit, protocol.MsgTypeEject:
+		if s.Playback != nil {
+			return // В реплее управлять некем
+		}
+		s.queueInput(cmd)
+	case protocol.MsgTypeAck:
+		s.processAck(cmd)
+	case protocol.MsgTypeResume:
+		s.processResume(cmd)
+	case protocol.MsgTypeRespawn:
+		s.processRespawn(cmd)
+	case protocol.MsgTypeReplayControl:
+		s.processReplayControl(cmd)
+	case protocol.MsgTypeSpectate:
+		s.processSpectate(cmd)
 	}
 }
 
 func (s *Server) processJoin(cmd *PlayerCommand) {
-	joinData := cmd.Data.(map[string]interface{})
-	name := joinData["name"].(string)
-	log.Printf("[SERVER] Processing join for %s, name: %s", cmd.ClientID, name)
-
-	s.World.Mu.Lock()
-	color := randomPlayerColor()
-	player := s.World.AddPlayerUnlocked(name, color, false)
-	s.World.Mu.Unlock()
-
-	s.mu.Lock()
-	if client, ok := s.Clients[cmd.ClientID]; ok {
-		client.PlayerID = player.ID
-	}
-	s.mu.Unlock()
-
-	// Отправляем init сообщение (старый формат для обратной совместимости)
-	initData := map[string]interface{}{
-		"playerId": player.ID,
-		"worldSize": map[string]float64{
-			"width":  game.WorldWidth,
-			"height": game.WorldHeight,
-		},
-	}
-
-	msg := map[string]interface{}{
-		"type": "init",
-		"data": initData,
-	}
-
-	msgData, _ := json.Marshal(msg)
-
-	s.mu.RLock()
-	if client, ok := s.Clients[cmd.ClientID]; ok {
-		client.Send <- msgData
+	joinData, ok := cmd.Data.(*protocol.JoinData)
+	if !ok {
+		return
 	}
-	s.mu.RUnlock()
-
-	log.Printf("Player joined: %s (%s)", name, player.ID)
-}
+	name := joinData.Name
+	log.Printf("[SERVER] Processing join for %s, name: %s", cmd.ClientID, name)
 
-func (s *Server) processMove(cmd *PlayerCommand) {
-	moveData := cmd.Data.(map[string]interface{})
-	x := moveData["x"].(float64)
-	y := moveData["y"].(float64)
-	
+	// Второй игрок того же соединения остался бы без клиента и без истечения сессии
 	s.mu.RLock()
 	client, ok := s.Clients[cmd.ClientID]
+	playing := ok && client.PlayerID != ""
 	s.mu.RUnlock()
-
-	if !ok || client.PlayerID == "" {
+	if !ok {
 		return
 	}
-
-	player, exists := s.World.GetPlayer(client.PlayerID)
-	if !exists {
+	if playing {
+		client.replyError(&protocol.Error{Code: protocol.ErrCodeAlreadyPlaying, Message: "already playing", Type: cmd.Type})
 		return
 	}
 
-	player.SetTarget(x, y)
-	// Позиции обновляются через state delta
-}
-
-func (s *Server) processSplit(cmd *PlayerCommand) {
-	s.mu.RLock()
-	client, ok := s.Clients[cmd.ClientID]
-	s.mu.RUnlock()
-
-	if !ok || client.PlayerID == "" {
+	s.World.Mu.Lock()
+	color := randomPlayerColor()
+	if !s.World.JoinOpenUnlocked() {
+		s.World.Mu.Unlock()
+		s.waitForMatch(cmd.ClientID, name, color)
 		return
 	}
-	s.World.Split(client.PlayerID)
-}
-
-func (s *Server) processEject(cmd *PlayerCommand) {
-	s.mu.RLock()
-	client, ok := s.Clients[cmd.ClientID]
-	s.mu.RUnlock()
+	player := s.World.AddPlayerUnlocked(name, color, false)
+	s.record(replay.Entry{Kind: replay.KindJoin, Player: player.ID, Name: name, Color: color})
+	s.World.Mu.Unlock()
 
-	if !ok || client.PlayerID == "" {
-		return
+	s.mu.Lock()
+	if client, ok := s.Clients[cmd.ClientID]; ok {
+		client.PlayerID = player.ID
+		client.viewer = false
+		client.name, client.color = name, color
+		client.reply(protocol.MsgTypeInit, s.initData(s.openSession(client, player), false))
 	}
-	s.World.Eject(client.PlayerID)
+	s.mu.Unlock()
+
+	log.Printf("Player joined: %s (%s)", name, player.ID)
 }
 
 func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
@@ -358,12 +541,8 @@ func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[WEBSOCKET] ✅ WebSocket upgraded successfully")
 
-	client := &Client{
-		ID:     generateClientID(),
-		Conn:   conn,
-		Send:   make(chan []byte, 16),
-		Server: s,
-	}
+	client := newClient(conn)
+	client.Server = s
 
 	log.Printf("[WEBSOCKET] Created client with ID: %s", client.ID)
 	s.Register <- client
@@ -375,7 +554,9 @@ func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 func (c *Client) readPump() {
 	defer func() {
 		log.Printf("[CLIENT %s] readPump closing", c.ID)
-		c.Server.Unregister <- c
+		if c.Server != nil {
+			c.Server.unregister(c)
+		}
 		c.Conn.Close()
 	}()
 
@@ -408,16 +589,40 @@ func (c *Client) writePump() {
 
 	for {
 		select {
-		case message, ok := <-c.Send:
-			c.Conn.SetWriteDeadline(time.Now().Add(3 * time.Second))
-			if !ok {
-				log.Printf("[CLIENT %s] Send channel closed", c.ID)
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
+		case <-c.Out.notify:
+			items, closed, closeCode := c.Out.take()
+			frames := c.encodeItems(items)
+			bytes := 0
+			for _, message := range frames {
+				// Отключает клиента только кадр, не записанный за WriteTimeout: отставание
+				// очередь переживает сжатием до снимка
+				c.Conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+
+				// JSON уходит текстовым кадром, бинарный протокол - бинарным
+				frameType := websocket.TextMessage
+				if len(message) > 0 && message[0] == protocol.BinaryMagic {
+					frameType = websocket.BinaryMessage
+				}
 
-			if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
-				log.Printf("[CLIENT %s] Write error: %v", c.ID, err)
+				if err := c.Conn.WriteMessage(frameType, message); err != nil {
+					log.Printf("[CLIENT %s] Write error: %v", c.ID, err)
+					return
+				}
+				bytes += len(message)
+			}
+			c.Out.written(len(frames), bytes)
+
+			if closed {
+				log.Printf("[CLIENT %s] Outbound queue closed", c.ID)
+				c.Conn.SetWriteDeadline(time.Now().Add(3 * time.Second))
+				switch closeCode {
+				case websocket.CloseProtocolError:
+					c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, "protocol error"))
+				case websocket.CloseTryAgainLater:
+					c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, "outbound queue overflow"))
+				default:
+					c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				}
 				return
 			}
 
@@ -431,49 +636,105 @@ func (c *Client) writePump() {
 	}
 }
 
+// handleMessage - разобрать сообщение клиента и передать команду в комнату
+// Некорректные сообщения не доходят до Run: клиент получает error
 func (c *Client) handleMessage(message []byte) {
-	var clientMsg map[string]interface{}
-	if err := json.Unmarshal(message, &clientMsg); err != nil {
-		log.Printf("[CLIENT %s] Error unmarshaling message: %v", c.ID, err)
+	msgType, data, err := protocol.DecodeClientMessage(message)
+	if err != nil {
+		log.Printf("[CLIENT %s] Rejected message: %v", c.ID, err)
+		c.replyError(err)
 		return
 	}
 
-	msgType, ok := clientMsg["type"].(string)
-	if !ok {
+	switch msgType {
+	case protocol.MsgTypeHello:
+		c.handleHello(data.(*protocol.HelloData))
 		return
-	}
-
-	data := clientMsg["data"]
 
-	switch msgType {
-	case "join":
-		c.Server.Commands <- &PlayerCommand{
-			Type:     "join",
-			ClientID: c.ID,
-			Data:     data,
+	case protocol.MsgTypeResume:
+		// Возврат к игроку: комната определяется по токену
+		if c.Rooms != nil {
+			room, ok := c.Rooms.roomForSession(data.(*protocol.ResumeData).Token)
+			if !ok {
+				c.replyError(&protocol.Error{Code: protocol.ErrCodeSessionExpired, Message: "session expired, join again", Type: msgType})
+				return
+			}
+			if err := c.Rooms.joinRoom(c, room.Name); err != nil {
+				c.replyError(&protocol.Error{Code: protocol.ErrCodeRoomUnavailable, Message: err.Error(), Type: msgType})
+				return
+			}
 		}
 
-	case "move":
-		c.Server.Commands <- &PlayerCommand{
-			Type:     "move",
-			ClientID: c.ID,
-			Data:     data,
+	case protocol.MsgTypeJoin:
+		// Вход через RoomManager: комната выбирается в join
+		if c.Rooms != nil {
+			roomName := data.(*protocol.JoinData).Room
+			if err := c.Rooms.joinRoom(c, roomName); err != nil {
+				log.Printf("[CLIENT %s] Cannot join room %q: %v", c.ID, roomName, err)
+				c.replyError(&protocol.Error{Code: protocol.ErrCodeRoomUnavailable, Message: err.Error(), Type: msgType})
+				return
+			}
 		}
 
-	case "split":
-		c.Server.Commands <- &PlayerCommand{
-			Type:     "split",
-			ClientID: c.ID,
-			Data:     nil,
+	case protocol.MsgTypeSpectate:
+		// Зрителю место игрока не нужно: заполненная комната тоже подходит
+		if c.Rooms != nil {
+			roomName := data.(*protocol.SpectateData).Room
+			if err := c.Rooms.spectateRoom(c, roomName); err != nil {
+				c.replyError(&protocol.Error{Code: protocol.ErrCodeRoomUnavailable, Message: err.Error(), Type: msgType})
+				return
+			}
 		}
+	}
 
-	case "eject":
-		c.Server.Commands <- &PlayerCommand{
-			Type:     "eject",
-			ClientID: c.ID,
-			Data:     nil,
-		}
+	// До входа в комнату команды обрабатывать некому
+	if c.Server == nil {
+		c.replyError(&protocol.Error{Code: protocol.ErrCodeNotJoined, Message: "join a room first", Type: msgType})
+		return
+	}
+
+	c.Server.enqueue(&PlayerCommand{
+		Type:     msgType,
+		ClientID: c.ID,
+		Data:     data,
+	})
+}
+
+// handleHello - согласование версии протокола
+// Без общей версии соединение закрывается
+func (c *Client) handleHello(hello *protocol.HelloData) {
+	version, err := protocol.NegotiateVersion(hello)
+	if err != nil {
+		log.Printf("[CLIENT %s] Version negotiation failed: %v (client offers %v)", c.ID, err, hello.Versions)
+		c.replyError(err)
+		c.Out.CloseWithError()
+		return
+	}
+
+	c.Version = version
+	c.reply(protocol.MsgTypeWelcome, protocol.WelcomeData{
+		Version:           version,
+		SupportedVersions: protocol.SupportedVersions(),
+	})
+}
+
+// reply - JSON сообщение клиенту; не блокируется и не паникует на закрытом канале
+func (c *Client) reply(msgType protocol.MessageType, data interface{}) {
+	msg, err := protocol.EncodeServerMessage(msgType, data)
+	if err != nil {
+		log.Printf("[CLIENT %s] Error encoding %s: %v", c.ID, msgType, err)
+		return
+	}
+
+	c.Out.PushRaw(msg)
+}
+
+func (c *Client) replyError(err error) {
+	protoErr, ok := err.(*protocol.Error)
+	if !ok {
+		protoErr = &protocol.Error{Code: protocol.ErrCodeMalformed, Message: err.Error()}
 	}
+	c.reply(protocol.MsgTypeError, protoErr.Data())
 }
 
 func generateClientID() string {
@@ -0,0 +1,119 @@
+package network
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"agario-server/internal/game"
+	"agario-server/pkg/protocol"
+
+	"github.com/gorilla/websocket"
+)
+
+// addTestClient - клиент без сокета, подключённый к комнате
+func addTestClient(s *Server, id string) *Client {
+	client := &Client{
+		ID:       id,
+		Server:   s,
+		Out:      NewOutbound(),
+		Interest: NewInterest(),
+		Input:    NewInputState(),
+		Delta:    NewDeltaTracker(),
+		Version:  protocol.MinVersion,
+	}
+	s.mu.Lock()
+	s.Clients[id] = client
+	s.mu.Unlock()
+	return client
+}
+
+type testReply struct {
+	Type protocol.MessageType `json:"type"`
+	Data struct {
+		Code     protocol.ErrorCode `json:"code"`
+		PlayerID string             `json:"playerId"`
+	} `json:"data"`
+	raw []byte
+}
+
+// takeReplies - ответы, накопленные в исходящей очереди клиента
+func takeReplies(t *testing.T, client *Client) []testReply {
+	t.Helper()
+	items, _, _ := client.Out.take()
+	replies := []testReply{}
+	for _, item := range items {
+		if item.raw == nil {
+			continue
+		}
+		reply := testReply{raw: item.raw}
+		if err := json.Unmarshal(item.raw, &reply); err != nil {
+			t.Fatal(err)
+		}
+		replies = append(replies, reply)
+	}
+	return replies
+}
+
+func humans(w *game.World) int {
+	count := 0
+	for _, player := range w.Players {
+		if !player.IsBot {
+			count++
+		}
+	}
+	return count
+}
+
+func TestSecondJoinIsRejected(t *testing.T) {
+	s := NewServer(game.NewDeterministicWorld(nil, 1))
+	client := addTestClient(s, "c1")
+	join := &PlayerCommand{Type: protocol.MsgTypeJoin, ClientID: "c1", Data: &protocol.JoinData{Name: "alice"}}
+
+	s.processJoin(join)
+	replies := takeReplies(t, client)
+	if len(replies) != 1 || replies[0].Type != protocol.MsgTypeInit {
+		t.Fatalf("first join replies %+v, want init", replies)
+	}
+	playerID := client.PlayerID
+
+	s.processJoin(join)
+	replies = takeReplies(t, client)
+	if len(replies) != 1 || replies[0].Type != protocol.MsgTypeError || replies[0].Data.Code != protocol.ErrCodeAlreadyPlaying {
+		t.Fatalf("second join replies %+v, want %s", replies, protocol.ErrCodeAlreadyPlaying)
+	}
+	if client.PlayerID != playerID || humans(s.World) != 1 || len(s.sessions) != 1 {
+		t.Fatalf("second join left %d players and %d sessions (client plays %s, was %s)",
+			humans(s.World), len(s.sessions), client.PlayerID, playerID)
+	}
+}
+
+func TestOversizedMessageClosesConnection(t *testing.T) {
+	rooms := NewRoomManager(nil)
+	srv := httptest.NewServer(http.HandlerFunc(rooms.HandleWebSocket))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteMessage(websocket.TextMessage, make([]byte, protocol.MaxMessageSize+1)); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+			t.Fatalf("connection ended with %v, want close %d", err, websocket.CloseMessageTooBig)
+		}
+		return
+	}
+}
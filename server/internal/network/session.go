@@ -0,0 +1,245 @@
+package network
+
+import (
+	"agario-server/internal/bot"
+	"agario-server/internal/events"
+	"agario-server/internal/game"
+	"agario-server/internal/replay"
+	"agario-server/pkg/protocol"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sort"
+	"time"
+)
+
+// session - связь reconnect-токена с игроком комнаты (под s.mu)
+type session struct {
+	Token          string
+	PlayerID       string
+	Name           string // Имя и цвет игрока: resume возвращает их клиенту для respawn
+	Color          string
+	ClientID       string    // "" - клиент отключен и ждёт переподключения
+	DisconnectedAt time.Time // Время мира
+}
+
+func newSessionToken() string {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// HasSession - токен выдан в этой комнате и ещё действителен
+func (s *Server) HasSession(token string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.sessions[token]
+	return ok
+}
+
+// openSession - новая сессия игрока клиента (под s.mu)
+func (s *Server) openSession(client *Client, player *game.Player) *session {
+	sess := &session{
+		Token:    newSessionToken(),
+		PlayerID: player.ID,
+		Name:     player.Name,
+		Color:    player.Color,
+		ClientID: client.ID,
+	}
+	s.sessions[sess.Token] = sess
+	client.session = sess
+	return sess
+}
+
+// closeSession - игрок ушёл окончательно (под s.mu)
+func (s *Server) closeSession(sess *session) {
+	delete(s.sessions, sess.Token)
+	delete(s.idle, sess.PlayerID)
+}
+
+// removePlayerLocked - убрать живого игрока из мира по причине cause (events.Leave*)
+// (под локом мира и s.mu)
+func (s *Server) removePlayerLocked(playerID, cause string) {
+	if _, exists := s.World.Players[playerID]; !exists {
+		return
+	}
+	delete(s.idle, playerID)
+	s.World.LeavePlayerUnlocked(playerID, cause)
+	s.record(replay.Entry{Kind: replay.KindLeave, Player: playerID})
+}
+
+// disconnectPlayer - клиент отключился; игрок ждёт переподключения ReconnectGrace секунд
+// (под локом мира и s.mu)
+func (s *Server) disconnectPlayer(client *Client) {
+	sess := client.session
+	player, alive := s.World.Players[client.PlayerID]
+
+	if sess == nil || !alive || !player.IsAlive() || s.World.Config.ReconnectGrace <= 0 {
+		if sess != nil {
+			s.closeSession(sess)
+		}
+		s.removePlayerLocked(client.PlayerID, events.LeaveQuit)
+		log.Printf("[SERVER] Player %s removed from world", client.PlayerID)
+		return
+	}
+
+	sess.ClientID = ""
+	sess.DisconnectedAt = s.World.Now()
+	s.idle[player.ID] = bot.NewIdleController(player, s.World)
+	s.record(replay.Entry{Kind: replay.KindIdle, Player: player.ID})
+	log.Printf("[SESSION] Player %s disconnected, waiting %.0fs for resume", player.ID, s.World.Config.ReconnectGrace)
+}
+
+// updateSessionsUnlocked - управление отключёнными игроками и истечение сессий (под локом мира)
+// Срок отсчитывается по времени мира: в записи и при воспроизведении сессия истекает на одном тике
+// Контроллеры обновляются до истечения сессий, как при воспроизведении реплея:
+// там игрок с истёкшей сессией убирается записью leave уже после тика
+func (s *Server) updateSessionsUnlocked(updateIdle bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if updateIdle {
+		ids := make([]string, 0, len(s.idle))
+		for id := range s.idle {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			s.idle[id].Update()
+		}
+	}
+
+	grace := time.Duration(s.World.Config.ReconnectGrace * float64(time.Second))
+	for _, sess := range s.sessions {
+		player, exists := s.World.Players[sess.PlayerID]
+		if !exists || len(player.Cells) == 0 {
+			// Игрок съеден - возвращаться некуда
+			s.closeSession(sess)
+			continue
+		}
+		if sess.ClientID == "" && s.World.Now().Sub(sess.DisconnectedAt) > grace {
+			s.closeSession(sess)
+			s.removePlayerLocked(sess.PlayerID, events.LeaveExpired)
+			log.Printf("[SESSION] Player %s did not resume in time, removed", sess.PlayerID)
+		}
+	}
+}
+
+// KickPlayer - убрать игрока из мира; его клиент получает death и может сделать respawn
+func (s *Server) KickPlayer(playerID string) bool {
+	s.World.Mu.Lock()
+	defer s.World.Mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.World.Players[playerID]; !exists {
+		return false
+	}
+	for _, sess := range s.sessions {
+		if sess.PlayerID == playerID {
+			s.closeSession(sess)
+		}
+	}
+	for _, client := range s.Clients {
+		if client.PlayerID == playerID {
+			client.PlayerID = ""
+			client.session = nil
+			client.reply(protocol.MsgTypeDeath, protocol.DeathData{PlayerID: playerID})
+		}
+	}
+	s.removePlayerLocked(playerID, events.LeaveKicked)
+	log.Printf("[SERVER] Player %s kicked", playerID)
+	return true
+}
+
+// processResume - вернуть клиента к его игроку по reconnect-токену
+func (s *Server) processResume(cmd *PlayerCommand) {
+	data, ok := cmd.Data.(*protocol.ResumeData)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	client, ok := s.Clients[cmd.ClientID]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	sess, ok := s.sessions[data.Token]
+	if !ok || (client.PlayerID != "" && client.PlayerID != sess.PlayerID) {
+		s.mu.Unlock()
+		client.replyError(&protocol.Error{Code: protocol.ErrCodeSessionExpired, Message: "session expired, join again", Type: cmd.Type})
+		return
+	}
+
+	// Старое соединение могло ещё не заметить обрыв - забираем игрока у него
+	if sess.ClientID != "" && sess.ClientID != client.ID {
+		if old, exists := s.Clients[sess.ClientID]; exists {
+			old.PlayerID = ""
+			old.session = nil
+			delete(s.Clients, old.ID)
+			old.Out.Close()
+		}
+	}
+
+	// Токен одноразовый: при каждом resume выдаётся новый
+	delete(s.sessions, sess.Token)
+	if _, idle := s.idle[sess.PlayerID]; idle {
+		delete(s.idle, sess.PlayerID)
+		s.record(replay.Entry{Kind: replay.KindActive, Player: sess.PlayerID})
+	}
+	sess.Token = newSessionToken()
+	sess.ClientID = client.ID
+	sess.DisconnectedAt = time.Time{}
+	s.sessions[sess.Token] = sess
+
+	client.PlayerID = sess.PlayerID
+	client.name, client.color = sess.Name, sess.Color
+	client.session = sess
+	client.viewer = false
+	s.mu.Unlock()
+
+	client.reply(protocol.MsgTypeInit, s.initData(sess, true))
+	s.sendSnapshot(client)
+	log.Printf("[SESSION] Player %s resumed by client %s", sess.PlayerID, client.ID)
+}
+
+// initData - init для клиента сессии
+func (s *Server) initData(sess *session, resumed bool) protocol.InitData {
+	return protocol.InitData{
+		PlayerID: sess.PlayerID,
+		Room:     s.Room,
+		WorldSize: protocol.WorldSize{
+			Width:  s.World.Config.WorldWidth,
+			Height: s.World.Config.WorldHeight,
+		},
+		Config:         s.World.Config,
+		ReconnectToken: sess.Token,
+		Resumed:        resumed,
+		Match:          s.match,
+		Map:            s.mapLayout(),
+	}
+}
+
+// mapLayout - карта комнаты для init (nil, а не типизированный nil - поле не попадёт в JSON)
+func (s *Server) mapLayout() interface{} {
+	if s.World.Config.Map == nil {
+		return nil
+	}
+	return s.World.Config.Map
+}
+
+// sendSnapshot - полный снимок области видимости одному клиенту
+func (s *Server) sendSnapshot(client *Client) {
+	s.World.Mu.RLock()
+	defer s.World.Mu.RUnlock()
+
+	s.updateView(client)
+	client.Out.ResetCodec()
+	snapshot := s.buildSnapshot(client)
+	client.Delta.recordSnapshot(snapshot)
+	client.Out.PushEvents([]*events.Event{events.NewEvent(events.EventWorldSnapshot, snapshot)},
+		s.World.Config.WorldWidth, s.World.Config.WorldHeight)
+}
@@ -0,0 +1,59 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"agario-server/internal/events"
+	"agario-server/internal/game"
+	"agario-server/pkg/protocol"
+)
+
+func TestSessionExpiresOnWorldTick(t *testing.T) {
+	cfg := game.DefaultGameConfig()
+	cfg.ReconnectGrace = 1
+	world := game.NewDeterministicWorld(cfg, 1)
+	s := NewServer(world)
+	client := addTestClient(s, "c1")
+	s.processJoin(&PlayerCommand{Type: protocol.MsgTypeJoin, ClientID: "c1", Data: &protocol.JoinData{Name: "alice"}})
+	playerID := client.PlayerID
+
+	world.Mu.Lock()
+	defer world.Mu.Unlock()
+	s.mu.Lock()
+	s.disconnectPlayer(client)
+	delete(s.Clients, client.ID)
+	s.mu.Unlock()
+
+	// Первый тик, на котором с отключения прошло больше ReconnectGrace
+	grace := time.Duration(cfg.ReconnectGrace * float64(time.Second))
+	want := int64(grace/game.TickDuration) + 1
+	start := world.CurrentTick
+	for n := int64(1); n <= want; n++ {
+		world.CurrentTick = start + n
+		s.updateSessionsUnlocked(false)
+		_, exists := world.Players[playerID]
+		if n < want && !exists {
+			t.Fatalf("player removed %d ticks after disconnect, grace is %d ticks", n, want)
+		}
+		if n == want && exists {
+			t.Fatalf("player still waits %d ticks after disconnect", n)
+		}
+	}
+	if len(s.sessions) != 0 {
+		t.Fatalf("%d sessions left after expiry", len(s.sessions))
+	}
+	if cause := leaveCause(world, playerID); cause != events.LeaveExpired {
+		t.Fatalf("player_left cause %q, want %q", cause, events.LeaveExpired)
+	}
+}
+
+// leaveCause - причина из опубликованного player_left игрока ("" - события нет)
+func leaveCause(world *game.World, playerID string) string {
+	for _, event := range world.EventBus.FlushEvents() {
+		if data, ok := event.Data.(*events.PlayerLeftEvent); ok && data.PlayerID == playerID {
+			return data.Cause
+		}
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+package network
+
+import (
+	"agario-server/internal/events"
+	"agario-server/internal/game"
+	"agario-server/pkg/protocol"
+	"log"
+	"math"
+)
+
+// camera - камера клиента без игрока (используется только из Run)
+type camera struct {
+	Mode     protocol.SpectateMode
+	PlayerID string  // Для SpectatePlayer
+	X, Y     float64 // Центр для SpectateFree
+
+	sent protocol.SpectateStateData // Последнее отправленное клиенту состояние камеры
+}
+
+// follow - камера у игрока; "" - у лидера
+func (c *camera) follow(playerID string) {
+	c.Mode, c.PlayerID = protocol.SpectatePlayer, playerID
+	if playerID == "" {
+		c.Mode = protocol.SpectateLeader
+	}
+}
+
+// processSpectate - клиент смотрит комнату без игрока
+func (s *Server) processSpectate(cmd *PlayerCommand) {
+	data, ok := cmd.Data.(*protocol.SpectateData)
+	if !ok {
+		return
+	}
+	s.spectate(cmd.ClientID, data)
+}
+
+// spectate - сделать клиента зрителем или перевести камеру зрителя
+// Игрок клиента уходит из мира; init и snapshot получает только новый зритель
+func (s *Server) spectate(clientID string, data *protocol.SpectateData) {
+	s.World.Mu.Lock()
+	s.mu.Lock()
+	client, ok := s.Clients[clientID]
+	if !ok {
+		s.mu.Unlock()
+		s.World.Mu.Unlock()
+		return
+	}
+
+	entering := !client.viewer
+	if client.PlayerID != "" {
+		log.Printf("[SPECTATE] Player %s leaves to spectate", client.PlayerID)
+		s.dropPlayerLocked(client, events.LeaveSpectate)
+		entering = true
+	}
+	sent := client.camera.sent
+	if entering {
+		sent = protocol.SpectateStateData{}
+	}
+	client.viewer = true
+	client.camera = camera{
+		Mode:     data.Mode,
+		PlayerID: data.PlayerID,
+		X:        math.Max(0, math.Min(s.World.Config.WorldWidth, data.X)),
+		Y:        math.Max(0, math.Min(s.World.Config.WorldHeight, data.Y)),
+		sent:     sent,
+	}
+	s.mu.Unlock()
+	s.World.Mu.Unlock()
+
+	if !entering {
+		return // Новая область придёт с ближайшим тиком через entity_entered/left
+	}
+
+	client.reply(protocol.MsgTypeInit, protocol.InitData{
+		Room: s.Room,
+		WorldSize: protocol.WorldSize{
+			Width:  s.World.Config.WorldWidth,
+			Height: s.World.Config.WorldHeight,
+		},
+		Config:    s.World.Config,
+		Replay:    s.Playback != nil,
+		Spectator: true,
+		Match:     s.match,
+		Map:       s.mapLayout(),
+	})
+	s.sendSnapshot(client)
+	if s.Playback != nil {
+		s.sendReplayState(client)
+	}
+	log.Printf("[SPECTATE] Client %s spectates room %q (%s)", client.ID, s.Room, data.Mode)
+}
+
+// dropPlayerLocked - убрать игрока клиента из мира вместе с сессией (под локом мира и s.mu)
+func (s *Server) dropPlayerLocked(client *Client, cause string) {
+	playerID := client.PlayerID
+	client.PlayerID = ""
+	if client.session != nil {
+		s.closeSession(client.session)
+		client.session = nil
+	}
+	s.removePlayerLocked(playerID, cause)
+}
+
+// updateCameraUnlocked - область видимости зрителя (под локом мира)
+// О смене режима или игрока под камерой клиенту сообщается spectate_state
+func (s *Server) updateCameraUnlocked(client *Client) {
+	state := protocol.SpectateStateData{Mode: client.camera.Mode}
+	if client.camera.Mode == protocol.SpectateFree {
+		state.X, state.Y = client.camera.X, client.camera.Y
+		client.Interest.View = viewAt(state.X, state.Y)
+		client.Interest.HasView = true
+	} else if player := s.followedUnlocked(client); player != nil {
+		state.Following = player.ID
+		if view, ok := viewForPlayer(player); ok {
+			client.Interest.View = view
+			client.Interest.HasView = true
+		}
+	}
+
+	if state.Mode != client.camera.sent.Mode || state.Following != client.camera.sent.Following {
+		client.camera.sent = state
+		client.reply(protocol.MsgTypeSpectateState, state)
+	}
+}
+
+// followedUnlocked - игрок под камерой зрителя: выбранный, а если его нет - лидер
+// У свободной камеры игрока нет
+func (s *Server) followedUnlocked(client *Client) *game.Player {
+	switch client.camera.Mode {
+	case protocol.SpectateFree:
+		return nil
+	case protocol.SpectatePlayer:
+		if player, exists := s.World.Players[client.camera.PlayerID]; exists && player.IsAlive() {
+			return player
+		}
+	}
+
+	var leader *game.Player
+	bestScore := -1
+	for _, player := range s.World.Players {
+		if !player.IsAlive() {
+			continue
+		}
+		score := player.GetScore()
+		if score > bestScore || (score == bestScore && player.ID < leader.ID) {
+			leader, bestScore = player, score
+		}
+	}
+	return leader
+}
+
+// clientCounts - игроки и зрители среди клиентов комнаты
+func (s *Server) clientCounts() (players, spectators int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, client := range s.Clients {
+		if client.viewer {
+			spectators++
+		} else {
+			players++
+		}
+	}
+	return players, spectators
+}
+
+// isViewer - клиент смотрит комнату без игрока
+func (s *Server) isViewer(client *Client) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return client.viewer
+}
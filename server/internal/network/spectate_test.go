@@ -0,0 +1,155 @@
+package network
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"agario-server/internal/bot"
+	"agario-server/internal/events"
+	"agario-server/internal/game"
+	"agario-server/internal/replay"
+	"agario-server/pkg/protocol"
+)
+
+func TestSpectateDropsPlayer(t *testing.T) {
+	s := NewServer(game.NewDeterministicWorld(nil, 1))
+	client := addTestClient(s, "c1")
+	s.processJoin(&PlayerCommand{Type: protocol.MsgTypeJoin, ClientID: "c1", Data: &protocol.JoinData{Name: "alice"}})
+	playerID := client.PlayerID
+	takeReplies(t, client)
+
+	path := filepath.Join(t.TempDir(), "room.replay")
+	start := &RecordingRequest{Path: path, Result: make(chan RecordingResult, 1)}
+	s.applyRecording(start, bot.NewBotManager(s.World, 0))
+	if result := <-start.Result; result.Err != nil {
+		t.Fatal(result.Err)
+	}
+
+	s.spectate("c1", &protocol.SpectateData{Mode: protocol.SpectateLeader})
+
+	if _, exists := s.World.Players[playerID]; exists || client.PlayerID != "" || !client.viewer {
+		t.Fatalf("player %s still in the world after spectate (client player %q)", playerID, client.PlayerID)
+	}
+	if client.session != nil {
+		t.Fatal("session kept for a spectator")
+	}
+	if cause := leaveCause(s.World, playerID); cause != events.LeaveSpectate {
+		t.Fatalf("player_left cause %q, want %q", cause, events.LeaveSpectate)
+	}
+	replies := takeReplies(t, client)
+	if len(replies) == 0 || replies[0].Type != protocol.MsgTypeInit {
+		t.Fatalf("spectate replies %+v, want init", replies)
+	}
+
+	stop := &RecordingRequest{Result: make(chan RecordingResult, 1)}
+	s.applyRecording(stop, nil)
+	if result := <-stop.Result; result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	rec, err := replay.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	left := false
+	for _, entry := range rec.Entries {
+		left = left || (entry.Kind == replay.KindLeave && entry.Player == playerID)
+	}
+	if !left {
+		t.Fatalf("replay entries %+v have no leave of %s", rec.Entries, playerID)
+	}
+}
+
+func TestSpectateCamera(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      protocol.SpectateData
+		kill      bool // выбранный игрок погибает
+		wantMode  protocol.SpectateMode
+		following string // "leader", "small" или "" - камера без игрока
+		x, y      float64
+	}{
+		{name: "leader", data: protocol.SpectateData{Mode: protocol.SpectateLeader}, wantMode: protocol.SpectateLeader, following: "leader"},
+		{name: "chosen player", data: protocol.SpectateData{Mode: protocol.SpectatePlayer}, wantMode: protocol.SpectatePlayer, following: "small"},
+		{name: "chosen player died", data: protocol.SpectateData{Mode: protocol.SpectatePlayer}, kill: true, wantMode: protocol.SpectatePlayer, following: "leader"},
+		{name: "free camera", data: protocol.SpectateData{Mode: protocol.SpectateFree, X: 100, Y: 200}, wantMode: protocol.SpectateFree, x: 100, y: 200},
+		{name: "free camera clamped to the world", data: protocol.SpectateData{Mode: protocol.SpectateFree, X: -500, Y: 1e9}, wantMode: protocol.SpectateFree, y: game.DefaultGameConfig().WorldHeight},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(game.NewDeterministicWorld(nil, 1))
+			players := map[string]*game.Player{
+				"leader": s.World.AddPlayerUnlocked("leader", "#FFFFFF", false),
+				"small":  s.World.AddPlayerUnlocked("small", "#FFFFFF", false),
+			}
+			players["leader"].Cells[0].SetMass(500)
+			client := addTestClient(s, "c1")
+
+			data := tt.data
+			if data.Mode == protocol.SpectatePlayer {
+				data.PlayerID = players["small"].ID
+			}
+			s.spectate("c1", &data)
+			if tt.kill {
+				s.World.RemovePlayerUnlocked(players["small"].ID)
+				s.updateCameraUnlocked(client)
+			}
+
+			if client.camera.X != tt.x || client.camera.Y != tt.y {
+				t.Fatalf("camera at (%.0f, %.0f), want (%.0f, %.0f)", client.camera.X, client.camera.Y, tt.x, tt.y)
+			}
+			state := client.camera.sent
+			want := ""
+			if tt.following != "" {
+				want = players[tt.following].ID
+			}
+			if state.Mode != tt.wantMode || state.Following != want {
+				t.Fatalf("spectate_state %+v, want mode %s following %s", state, tt.wantMode, tt.following)
+			}
+			sent := 0
+			for _, reply := range takeReplies(t, client) {
+				if reply.Type == protocol.MsgTypeSpectateState {
+					sent++
+				}
+			}
+			// После гибели выбранного игрока камера сообщает о переходе к лидеру
+			wantSent := 1
+			if tt.kill {
+				wantSent = 2
+			}
+			if sent != wantSent {
+				t.Fatalf("%d spectate_state messages, want %d", sent, wantSent)
+			}
+
+			// Камера не меняется - повторного spectate_state нет
+			s.updateCameraUnlocked(client)
+			if replies := takeReplies(t, client); len(replies) != 0 {
+				t.Fatalf("unchanged camera sent %+v", replies)
+			}
+		})
+	}
+}
+
+func TestSpectatorsDoNotTakePlayerSlots(t *testing.T) {
+	s := NewServer(game.NewDeterministicWorld(nil, 1))
+	room := &Room{Name: "arena", Settings: RoomSettings{MaxPlayers: 2}, World: s.World, Server: s}
+
+	addTestClient(s, "p1")
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("viewer%d", i)
+		addTestClient(s, id)
+		s.spectate(id, &protocol.SpectateData{Mode: protocol.SpectateLeader})
+	}
+	if players, spectators := s.clientCounts(); players != 1 || spectators != 3 {
+		t.Fatalf("counts %d players, %d spectators, want 1 and 3", players, spectators)
+	}
+	if room.IsFull() {
+		t.Fatal("room full with one player and spectators")
+	}
+
+	addTestClient(s, "p2")
+	if !room.IsFull() || room.SpectatorCount() != 3 {
+		t.Fatalf("room full %v with %d spectators, want full with 3", room.IsFull(), room.SpectatorCount())
+	}
+}
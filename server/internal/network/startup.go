@@ -0,0 +1,70 @@
+package network
+
+import (
+	"agario-server/internal/game"
+	"agario-server/internal/stats"
+	"flag"
+	"fmt"
+	"log"
+)
+
+// DefaultResultsFile - файл результатов игроков по умолчанию
+const DefaultResultsFile = "stats/results.jsonl"
+
+// StartupConfig - настройки запуска процесса (флаги командной строки)
+type StartupConfig struct {
+	ConfigFile  string // Правила игры; "" - DefaultGameConfig
+	StateFile   string // Сохранение мира для комнаты по умолчанию; "" - новый мир
+	ResultsFile string // Результаты игроков для /api/scores и /api/history; "" - не сохраняются
+}
+
+func DefaultStartupConfig() StartupConfig {
+	return StartupConfig{ResultsFile: DefaultResultsFile}
+}
+
+// RegisterFlags - флаги -config, -state и -results
+func (c *StartupConfig) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.ConfigFile, "config", c.ConfigFile, "game rules file (YAML or JSON)")
+	fs.StringVar(&c.StateFile, "state", c.StateFile, "world save to restore into the default room")
+	fs.StringVar(&c.ResultsFile, "results", c.ResultsFile, "player results file (JSON Lines); empty disables scores")
+}
+
+// StartRoomManager - менеджер комнат с хранилищем результатов и комнатой по умолчанию
+// С StateFile комната продолжает сохранённый мир (его правила важнее ConfigFile)
+func StartRoomManager(cfg StartupConfig) (*RoomManager, error) {
+	var rules *game.GameConfig
+	if cfg.ConfigFile != "" {
+		var err error
+		if rules, err = game.LoadGameConfig(cfg.ConfigFile); err != nil {
+			return nil, err
+		}
+	}
+	settings := DefaultRoomSettings()
+	if cfg.StateFile != "" {
+		state, err := game.ReadWorldState(cfg.StateFile)
+		if err != nil {
+			return nil, err
+		}
+		settings.State = state
+		log.Printf("[SAVE] Default room starts from %s (tick %d, %d players)", cfg.StateFile, state.Tick, len(state.Players))
+	}
+
+	// Хранилище подключается до создания комнаты, чтобы не пропустить её результаты
+	rooms := NewRoomManager(rules)
+	var store *stats.FileStore
+	if cfg.ResultsFile != "" {
+		var err error
+		if store, err = stats.OpenFileStore(cfg.ResultsFile); err != nil {
+			return nil, fmt.Errorf("open results: %w", err)
+		}
+		rooms.SetResultStore(store)
+	}
+
+	if _, err := rooms.CreateRoom(DefaultRoomName, settings); err != nil {
+		if store != nil {
+			store.Close()
+		}
+		return nil, fmt.Errorf("create default room: %w", err)
+	}
+	return rooms, nil
+}
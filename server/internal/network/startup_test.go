@@ -0,0 +1,105 @@
+package network
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"agario-server/internal/events"
+	"agario-server/internal/game"
+)
+
+// stopRooms - остановить игровые циклы всех комнат менеджера
+func stopRooms(t *testing.T, rooms *RoomManager) {
+	t.Cleanup(func() {
+		for _, room := range rooms.Rooms() {
+			room.Server.Stop()
+		}
+	})
+}
+
+func TestStartRoomManagerRestoresState(t *testing.T) {
+	saved := game.NewDeterministicWorld(nil, 3)
+	saved.Mu.Lock()
+	player := saved.AddPlayerUnlocked("saved", "#123456", false)
+	for i := 0; i < 30; i++ {
+		saved.UpdateUnlocked(game.TickDuration.Seconds())
+	}
+	state := saved.SaveStateUnlocked()
+	saved.Mu.Unlock()
+	state.Sessions = map[string]string{"token": player.ID}
+
+	path := filepath.Join(t.TempDir(), "default.json")
+	if err := game.WriteWorldState(path, state); err != nil {
+		t.Fatal(err)
+	}
+
+	rooms, err := StartRoomManager(StartupConfig{StateFile: path})
+	if err != nil {
+		t.Fatalf("StartRoomManager: %v", err)
+	}
+	stopRooms(t, rooms)
+
+	room, exists := rooms.GetRoom(DefaultRoomName)
+	if !exists {
+		t.Fatal("no default room")
+	}
+	room.World.Mu.Lock()
+	tick, restored := room.World.CurrentTick, room.World.Players[player.ID]
+	room.World.Mu.Unlock()
+	if tick < state.Tick || restored == nil || restored.Name != "saved" {
+		t.Fatalf("default room at tick %d with player %v, want saved world from tick %d", tick, restored, state.Tick)
+	}
+	if !room.Server.HasSession("token") {
+		t.Fatal("saved reconnect token is not accepted")
+	}
+}
+
+func TestStartRoomManagerErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	for _, cfg := range []StartupConfig{{ConfigFile: missing}, {StateFile: missing}} {
+		if rooms, err := StartRoomManager(cfg); err == nil {
+			stopRooms(t, rooms)
+			t.Errorf("StartRoomManager(%+v) started without its file", cfg)
+		}
+	}
+}
+
+func TestStartRoomManagerRecordsResults(t *testing.T) {
+	cfg := DefaultStartupConfig()
+	cfg.ResultsFile = filepath.Join(t.TempDir(), "stats", "results.jsonl")
+	rooms, err := StartRoomManager(cfg)
+	if err != nil {
+		t.Fatalf("StartRoomManager: %v", err)
+	}
+	stopRooms(t, rooms)
+
+	store := rooms.ResultStore()
+	if store == nil {
+		t.Fatal("result store is not wired: /api/scores would answer 503")
+	}
+
+	room, _ := rooms.GetRoom(DefaultRoomName)
+	room.World.EventBus.PublishEvent(events.EventPlayerDied, &events.PlayerDiedEvent{
+		PlayerID: "p1", Name: "alice", FinalMass: 50, PeakMass: 300,
+	})
+
+	// Recorder пишет результаты в своей горутине
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		history, err := store.History("alice", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) == 1 {
+			if history[0].Room != DefaultRoomName || history[0].PeakMass != 300 {
+				t.Fatalf("recorded %+v", history[0])
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("death in the default room was not recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
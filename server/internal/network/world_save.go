@@ -0,0 +1,158 @@
+package network
+
+import (
+	"agario-server/internal/bot"
+	"agario-server/internal/game"
+	"agario-server/pkg/protocol"
+	"errors"
+	"log"
+	"time"
+)
+
+// SaveDir - каталог сохранений мира, доступных из админки
+const SaveDir = "saves"
+
+var ErrWorldRestoreTimeout = errors.New("world restore was not applied in time")
+
+// WorldRestore - запрос на загрузку сохранения, применяется в Run между тиками
+type WorldRestore struct {
+	State  *game.WorldState
+	Result chan error
+}
+
+// SaveWorld - сохранить мир комнаты вместе с токенами переподключения
+func (s *Server) SaveWorld(path string) (*game.WorldState, error) {
+	s.World.Mu.Lock()
+	state := s.World.SaveStateUnlocked()
+	s.mu.RLock()
+	state.Sessions = make(map[string]string, len(s.sessions))
+	for token, sess := range s.sessions {
+		state.Sessions[token] = sess.PlayerID
+	}
+	s.mu.RUnlock()
+	s.World.Mu.Unlock()
+
+	if err := game.WriteWorldState(path, state); err != nil {
+		return nil, err
+	}
+	log.Printf("[SAVE] Room %q saved to %s (tick %d, %d players)", s.Room, path, state.Tick, len(state.Players))
+	return state, nil
+}
+
+// RestoreWorld - загрузить сохранение в работающую комнату и дождаться применения
+func (s *Server) RestoreWorld(state *game.WorldState) error {
+	restore := &WorldRestore{
+		State:  state,
+		Result: make(chan error, 1),
+	}
+
+	select {
+	case s.Restores <- restore:
+	case <-s.done:
+		return ErrRoomNotFound
+	}
+
+	select {
+	case err := <-restore.Result:
+		return err
+	case <-time.After(5 * time.Second):
+		return ErrWorldRestoreTimeout
+	}
+}
+
+// applyRestore - вызывается из Run между тиками
+// Клиенты, чьих игроков нет в сохранении, получают death и могут сделать respawn
+func (s *Server) applyRestore(restore *WorldRestore, botManager *bot.BotManager) {
+	if s.Playback != nil {
+		restore.Result <- ErrReplayRoom
+		return
+	}
+
+	s.World.Mu.Lock()
+	if s.RecordingPath() != "" && restore.State.Validate() == nil {
+		// Запись описывает прежний мир: загрузка её завершает
+		s.mu.Lock()
+		s.stopRecordingLocked()
+		s.mu.Unlock()
+	}
+	if err := s.World.RestoreStateUnlocked(restore.State); err != nil {
+		s.World.Mu.Unlock()
+		restore.Result <- err
+		return
+	}
+	botManager.AdoptBotsUnlocked()
+
+	s.mu.Lock()
+	lost := s.adoptPlayersLocked(restore.State.Sessions)
+	clients := make([]*Client, 0, len(s.Clients))
+	for _, client := range s.Clients {
+		clients = append(clients, client)
+	}
+	s.mu.Unlock()
+	s.World.Mu.Unlock()
+
+	for client, playerID := range lost {
+		client.reply(protocol.MsgTypeDeath, protocol.DeathData{PlayerID: playerID})
+	}
+	// Тики и entity сменились: базовые состояния клиентов больше не годятся
+	for _, client := range clients {
+		client.Delta = NewDeltaTracker()
+		s.sendSnapshot(client)
+	}
+
+	log.Printf("[SAVE] Room %q restored to tick %d (%d players, %d clients lost their player)",
+		s.Room, restore.State.Tick, len(restore.State.Players), len(lost))
+	restore.Result <- nil
+}
+
+// adoptPlayersLocked - связать игроков восстановленного мира с клиентами и сессиями
+// (под локом мира и s.mu). Игроки без соединения ждут resume ReconnectGrace секунд.
+// Возвращает клиентов, чьих игроков больше нет, с ID прежних игроков.
+func (s *Server) adoptPlayersLocked(tokens map[string]string) map[*Client]string {
+	s.sessions = make(map[string]*session)
+	s.idle = make(map[string]*bot.Bot)
+	attached := make(map[string]bool)
+	lost := make(map[*Client]string)
+
+	for _, client := range s.Clients {
+		if client.PlayerID == "" {
+			continue
+		}
+		player, exists := s.World.Players[client.PlayerID]
+		if !exists || player.IsBot {
+			lost[client] = client.PlayerID
+			client.PlayerID = ""
+			client.session = nil
+			continue
+		}
+		if client.session != nil && client.session.PlayerID == player.ID {
+			s.sessions[client.session.Token] = client.session
+		} else {
+			s.openSession(client, player)
+		}
+		attached[player.ID] = true
+	}
+
+	wait := func(token string, player *game.Player) {
+		s.sessions[token] = &session{
+			Token:          token,
+			PlayerID:       player.ID,
+			Name:           player.Name,
+			Color:          player.Color,
+			DisconnectedAt: s.World.Now(),
+		}
+		s.idle[player.ID] = bot.NewIdleController(player, s.World)
+		attached[player.ID] = true
+	}
+	for token, playerID := range tokens {
+		if player, exists := s.World.Players[playerID]; exists && !player.IsBot && !attached[playerID] {
+			wait(token, player)
+		}
+	}
+	for id, player := range s.World.Players {
+		if !player.IsBot && !attached[id] {
+			wait(newSessionToken(), player)
+		}
+	}
+	return lost
+}
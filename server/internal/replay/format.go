@@ -0,0 +1,168 @@
+package replay
+
+import (
+	"agario-server/internal/bot"
+	"agario-server/internal/game"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// FormatVersion - версия формата файла реплея
+const FormatVersion = 1
+
+var (
+	ErrFormatVersion    = errors.New("unsupported replay version")
+	ErrNotDeterministic = errors.New("replay needs a deterministic world")
+)
+
+// Kind - тип записи реплея
+type Kind string
+
+const (
+	KindJoin      Kind = "join"  // Игрок вошёл (Player - ожидаемый ID, Name, Color)
+	KindLeave     Kind = "leave" // Игрок убран из мира вместе с пассивным контроллером
+	KindMove      Kind = "move"  // Цель движения (X, Y)
+	KindSplit     Kind = "split"
+	KindEject     Kind = "eject"
+	KindIdle      Kind = "idle"       // Игрок отключился, клетками управляет пассивный контроллер
+	KindActive    Kind = "active"     // Игрок переподключился
+	KindBotAdd    Kind = "bot_add"    // Бот добавлен вручную (Name; Count - новый MaxBots)
+	KindBotRemove Kind = "bot_remove" // Бот убран вручную (Count - новый MaxBots)
+	KindFood      Kind = "food"       // Еда добавлена вручную (Count)
+	KindConfig    Kind = "config"     // Новые правила (Config)
+	KindEnd       Kind = "end"        // Запись остановлена
+)
+
+// Entry - одно внешнее воздействие на мир
+// Tick - номер тика мира в момент воздействия: при воспроизведении записи
+// применяются перед переходом к тику Tick+1 в порядке записи
+type Entry struct {
+	Tick   int64            `json:"t"`
+	Kind   Kind             `json:"k"`
+	Player string           `json:"p,omitempty"`
+	X      float64          `json:"x,omitempty"`
+	Y      float64          `json:"y,omitempty"`
+	Name   string           `json:"n,omitempty"`
+	Color  string           `json:"c,omitempty"`
+	Count  int              `json:"m,omitempty"`
+	Config *game.GameConfig `json:"cfg,omitempty"`
+}
+
+// Controller - бот или пассивный контроллер игрока
+type Controller struct {
+	PlayerID     string    `json:"playerId"`
+	NextDecision time.Time `json:"nextDecision,omitempty"`
+}
+
+// Snapshot - состояние симуляции: мир, боты и пассивные контроллеры
+type Snapshot struct {
+	World        *game.WorldState `json:"world"`
+	MaxBots      int              `json:"maxBots"`
+	BotNameIndex int              `json:"botNameIndex"`
+	Bots         []Controller     `json:"bots"` // В порядке обновления
+	Idle         []Controller     `json:"idle"` // По ID игрока
+}
+
+// CaptureUnlocked - снимок симуляции (под Lock мира)
+func CaptureUnlocked(world *game.World, bots *bot.BotManager, idle map[string]*bot.Bot) (*Snapshot, error) {
+	if !world.Deterministic() {
+		return nil, ErrNotDeterministic
+	}
+
+	snap := &Snapshot{
+		World:        world.SaveStateUnlocked(),
+		MaxBots:      bots.MaxBots,
+		BotNameIndex: bots.NameIndex,
+		Bots:         make([]Controller, 0, len(bots.Bots)),
+		Idle:         make([]Controller, 0, len(idle)),
+	}
+	for _, b := range bots.Bots {
+		snap.Bots = append(snap.Bots, Controller{PlayerID: b.Player.ID, NextDecision: b.NextDecision})
+	}
+	for id, controller := range idle {
+		snap.Idle = append(snap.Idle, Controller{PlayerID: id, NextDecision: controller.NextDecision})
+	}
+	sort.Slice(snap.Idle, func(i, j int) bool { return snap.Idle[i].PlayerID < snap.Idle[j].PlayerID })
+	return snap, nil
+}
+
+// Header - первая строка файла реплея
+type Header struct {
+	Version    int       `json:"version"`
+	Room       string    `json:"room"`
+	RecordedAt time.Time `json:"recordedAt"`
+	Start      *Snapshot `json:"start"`
+}
+
+// Replay - прочитанный файл реплея
+type Replay struct {
+	Header  Header
+	Entries []Entry
+	EndTick int64 // Последний записанный тик
+}
+
+// StartTick - тик, с которого начинается запись
+func (r *Replay) StartTick() int64 {
+	return r.Header.Start.World.Tick
+}
+
+// Load - прочитать реплей (gzip, JSON Lines: заголовок, затем записи)
+// Файл без записи end (сервер остановился во время записи) читается до последней записи
+func Load(path string) (*Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read replay: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("read replay %s: %w", path, err)
+	}
+	dec := json.NewDecoder(bufio.NewReader(gz))
+
+	rep := &Replay{}
+	if err := dec.Decode(&rep.Header); err != nil {
+		return nil, fmt.Errorf("read replay %s header: %w", path, err)
+	}
+	if rep.Header.Version != FormatVersion {
+		return nil, fmt.Errorf("%w: %d (expected %d)", ErrFormatVersion, rep.Header.Version, FormatVersion)
+	}
+	if rep.Header.Start == nil || rep.Header.Start.World == nil {
+		return nil, fmt.Errorf("replay %s has no start state", path)
+	}
+	if err := rep.Header.Start.World.Validate(); err != nil {
+		return nil, fmt.Errorf("replay %s start state: %w", path, err)
+	}
+	if !rep.Header.Start.World.Deterministic {
+		return nil, fmt.Errorf("replay %s: %w", path, ErrNotDeterministic)
+	}
+
+	rep.EndTick = rep.StartTick()
+	for {
+		var entry Entry
+		err := dec.Decode(&entry)
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read replay %s entry %d: %w", path, len(rep.Entries)+1, err)
+		}
+		if entry.Tick < rep.EndTick {
+			return nil, fmt.Errorf("replay %s entry %d goes back in time", path, len(rep.Entries)+1)
+		}
+		rep.EndTick = entry.Tick
+		if entry.Kind == KindEnd {
+			break
+		}
+		rep.Entries = append(rep.Entries, entry)
+	}
+	return rep, nil
+}
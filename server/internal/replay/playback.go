@@ -0,0 +1,315 @@
+package replay
+
+import (
+	"agario-server/internal/bot"
+	"agario-server/internal/game"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Параметры воспроизведения
+const (
+	KeyframeInterval = 20 * game.TickRate // Тиков между ключевыми кадрами для перемотки
+	MinSpeed         = 0.25
+	MaxSpeed         = 8.0
+)
+
+var ErrDiverged = errors.New("replay diverged from recording")
+
+// keyframe - состояние симуляции на тике перед применением его записей
+type keyframe struct {
+	tick  int64
+	next  int // Индекс первой записи этого тика
+	state *Snapshot
+}
+
+// Playback - повторная симуляция записанного матча
+// Мир, боты и пассивные контроллеры обновляются так же, как в Run комнаты,
+// поэтому с тем же начальным состоянием и записями мир проходит те же состояния.
+// Все методы вызываются под Lock мира
+type Playback struct {
+	Replay *Replay
+	World  *game.World
+	Bots   *bot.BotManager
+	Idle   map[string]*bot.Bot
+
+	Paused bool
+	Speed  float64
+	Err    error // Расхождение с записью: воспроизведение остановлено
+
+	next      int        // Индекс следующей записи
+	keyframes []keyframe // По возрастанию тика
+	budget    float64    // Накопленные доли тиков при скорости не 1
+}
+
+// NewPlayback - воспроизведение с начала записи (на паузе)
+func NewPlayback(rep *Replay) (*Playback, error) {
+	world, err := game.NewWorldFromState(rep.Header.Start.World)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Playback{
+		Replay: rep,
+		World:  world,
+		Bots:   bot.NewBotManager(world, rep.Header.Start.MaxBots),
+		Idle:   make(map[string]*bot.Bot),
+		Paused: true,
+		Speed:  1,
+	}
+	if err := p.restoreUnlocked(rep.Header.Start); err != nil {
+		return nil, err
+	}
+	p.keyframes = []keyframe{{tick: rep.StartTick(), state: rep.Header.Start}}
+	if err := p.settle(); err != nil {
+		p.stop(err)
+	}
+	return p, nil
+}
+
+// Tick - текущий тик воспроизведения
+func (p *Playback) Tick() int64 {
+	return p.World.CurrentTick
+}
+
+// AtEnd - запись воспроизведена до конца
+func (p *Playback) AtEnd() bool {
+	return p.World.CurrentTick >= p.Replay.EndTick
+}
+
+// SetSpeed - скорость воспроизведения (ограничивается MinSpeed..MaxSpeed)
+func (p *Playback) SetSpeed(speed float64) {
+	p.Speed = math.Max(MinSpeed, math.Min(MaxSpeed, speed))
+}
+
+// AdvanceUnlocked - воспроизвести один тик сервера с учётом скорости
+// Возвращает количество сделанных тиков симуляции
+func (p *Playback) AdvanceUnlocked() int {
+	if p.Paused || p.Err != nil {
+		return 0
+	}
+
+	p.budget += p.Speed
+	steps := 0
+	for p.budget >= 1 && !p.AtEnd() {
+		p.budget--
+		if err := p.stepUnlocked(); err != nil {
+			p.stop(err)
+			break
+		}
+		steps++
+	}
+	if p.AtEnd() {
+		p.Paused = true
+		p.budget = 0
+	}
+	return steps
+}
+
+// SeekUnlocked - перейти к тику (от ближайшего ключевого кадра)
+// События пропущенных тиков отбрасываются: клиентам нужен новый snapshot
+func (p *Playback) SeekUnlocked(tick int64) error {
+	tick = max(p.Replay.StartTick(), min(p.Replay.EndTick, tick))
+
+	// Вперёд от текущего тика, если ключевого кадра ближе нет
+	i := sort.Search(len(p.keyframes), func(i int) bool { return p.keyframes[i].tick > tick }) - 1
+	current := p.World.CurrentTick
+	if p.Err != nil || current > tick || p.keyframes[i].tick > current {
+		kf := p.keyframes[i]
+		if err := p.restoreUnlocked(kf.state); err != nil {
+			return err
+		}
+		p.next = kf.next
+		p.Err = nil
+		if err := p.settle(); err != nil {
+			p.stop(err)
+		}
+	}
+
+	for p.World.CurrentTick < tick {
+		if err := p.stepUnlocked(); err != nil {
+			p.stop(err)
+			break
+		}
+	}
+	p.budget = 0
+	p.World.EventBus.FlushEvents()
+	return p.Err
+}
+
+func (p *Playback) stop(err error) {
+	p.Err = err
+	p.Paused = true
+	p.budget = 0
+}
+
+// stepUnlocked - записи текущего тика, затем тик симуляции (как в Run комнаты)
+func (p *Playback) stepUnlocked() error {
+	if err := p.applyEntries(p.World.CurrentTick); err != nil {
+		return err
+	}
+
+	p.World.UpdateUnlocked(game.TickDuration.Seconds())
+	if p.World.CurrentTick%bot.UpdateInterval == 0 {
+		p.Bots.Update()
+		p.updateIdle()
+	}
+
+	tick := p.World.CurrentTick
+	if tick == p.Replay.EndTick {
+		return p.settle()
+	}
+	if last := p.keyframes[len(p.keyframes)-1]; tick >= last.tick+KeyframeInterval {
+		state, err := CaptureUnlocked(p.World, p.Bots, p.Idle)
+		if err != nil {
+			return err
+		}
+		p.keyframes = append(p.keyframes, keyframe{tick: tick, next: p.next, state: state})
+	}
+	return nil
+}
+
+// settle - на последнем тике применить оставшиеся записи
+// (запись остановлена между тиками, после них)
+func (p *Playback) settle() error {
+	if !p.AtEnd() {
+		return nil
+	}
+	return p.applyEntries(p.World.CurrentTick)
+}
+
+// updateIdle - пассивные контроллеры по ID игрока; убранные из мира забываются
+func (p *Playback) updateIdle() {
+	ids := make([]string, 0, len(p.Idle))
+	for id := range p.Idle {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if player, exists := p.World.Players[id]; !exists || !player.IsAlive() {
+			delete(p.Idle, id)
+			continue
+		}
+		p.Idle[id].Update()
+	}
+}
+
+// applyEntries - применить записи с тиком не больше tick
+func (p *Playback) applyEntries(tick int64) error {
+	entries := p.Replay.Entries
+	for p.next < len(entries) && entries[p.next].Tick <= tick {
+		if err := p.apply(entries[p.next]); err != nil {
+			return fmt.Errorf("%w: tick %d, %s: %v", ErrDiverged, entries[p.next].Tick, entries[p.next].Kind, err)
+		}
+		p.next++
+	}
+	return nil
+}
+
+func (p *Playback) apply(e Entry) error {
+	w := p.World
+
+	switch e.Kind {
+	case KindJoin:
+		player := w.AddPlayerUnlocked(e.Name, e.Color, false)
+		if player.ID != e.Player {
+			return fmt.Errorf("player %s joined as %s", e.Player, player.ID)
+		}
+
+	case KindLeave:
+		w.RemovePlayerUnlocked(e.Player)
+		delete(p.Idle, e.Player)
+
+	case KindMove, KindSplit, KindEject:
+		player, exists := w.Players[e.Player]
+		if !exists {
+			return fmt.Errorf("no player %s", e.Player)
+		}
+		switch e.Kind {
+		case KindMove:
+			player.SetTarget(e.X, e.Y, w.Now())
+		case KindSplit:
+			w.SplitPlayerUnlocked(player)
+		case KindEject:
+			w.EjectPlayerUnlocked(player)
+		}
+
+	case KindIdle:
+		player, exists := w.Players[e.Player]
+		if !exists {
+			return fmt.Errorf("no player %s", e.Player)
+		}
+		p.Idle[e.Player] = bot.NewIdleController(player, w)
+
+	case KindActive:
+		delete(p.Idle, e.Player)
+
+	case KindBotAdd:
+		b := bot.NewBotUnlocked(e.Name, w)
+		if b.Player.ID != e.Player {
+			return fmt.Errorf("bot %s joined as %s", e.Player, b.Player.ID)
+		}
+		p.Bots.Bots = append(p.Bots.Bots, b)
+		p.Bots.MaxBots = e.Count
+
+	case KindBotRemove:
+		for i, b := range p.Bots.Bots {
+			if b.Player.ID == e.Player {
+				p.Bots.Bots = append(p.Bots.Bots[:i], p.Bots.Bots[i+1:]...)
+				break
+			}
+		}
+		w.RemovePlayerUnlocked(e.Player)
+		p.Bots.MaxBots = e.Count
+
+	case KindFood:
+		for i := 0; i < e.Count; i++ {
+			w.SpawnFoodUnlocked()
+		}
+
+	case KindConfig:
+		if e.Config == nil {
+			return errors.New("no config")
+		}
+		if err := e.Config.Validate(); err != nil {
+			return err
+		}
+		w.ApplyConfigUnlocked(e.Config.Clone())
+
+	default:
+		return fmt.Errorf("unknown entry kind %q", e.Kind)
+	}
+	return nil
+}
+
+// restoreUnlocked - вернуть мир, ботов и пассивные контроллеры к снимку
+// Контроллеры игроков, которых уже нет в мире, не восстанавливаются:
+// в Run они были бы удалены на ближайшем обновлении, ничего не сделав
+func (p *Playback) restoreUnlocked(snap *Snapshot) error {
+	if err := p.World.RestoreStateUnlocked(snap.World); err != nil {
+		return err
+	}
+
+	p.Bots.MaxBots = snap.MaxBots
+	p.Bots.NameIndex = snap.BotNameIndex
+	p.Bots.Bots = make([]*bot.Bot, 0, len(snap.Bots))
+	for _, c := range snap.Bots {
+		if player, exists := p.World.Players[c.PlayerID]; exists {
+			b := bot.NewController(player, p.World)
+			b.NextDecision = c.NextDecision
+			p.Bots.Bots = append(p.Bots.Bots, b)
+		}
+	}
+
+	p.Idle = make(map[string]*bot.Bot, len(snap.Idle))
+	for _, c := range snap.Idle {
+		if player, exists := p.World.Players[c.PlayerID]; exists {
+			idle := bot.NewIdleController(player, p.World)
+			idle.NextDecision = c.NextDecision
+			p.Idle[c.PlayerID] = idle
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package replay
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Recorder - запись воздействий на мир в файл реплея
+// Record вызывается из разных горутин (Run, админка), порядок записей -
+// порядок вызовов, поэтому вызывающий держит лок мира на время изменения и записи
+type Recorder struct {
+	Path      string
+	StartTick int64
+
+	file    *os.File
+	gz      *gzip.Writer
+	enc     *json.Encoder
+	entries int
+	err     error // Первая ошибка записи; дальше записи отбрасываются
+	mu      sync.Mutex
+}
+
+// Create - начать запись: заголовок с начальным состоянием start
+func Create(path, room string, start *Snapshot) (*Recorder, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create replay: %w", err)
+	}
+
+	gz := gzip.NewWriter(f)
+	r := &Recorder{
+		Path:      path,
+		StartTick: start.World.Tick,
+		file:      f,
+		gz:        gz,
+		enc:       json.NewEncoder(gz),
+	}
+	header := Header{
+		Version:    FormatVersion,
+		Room:       room,
+		RecordedAt: time.Now(),
+		Start:      start,
+	}
+	if err := r.enc.Encode(header); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("write replay header: %w", err)
+	}
+	return r, nil
+}
+
+// Record - добавить запись
+func (r *Recorder) Record(entry Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil || r.enc == nil {
+		return
+	}
+	if err := r.enc.Encode(entry); err != nil {
+		r.err = err
+		log.Printf("[REPLAY] ❌ Recording %s failed: %v", r.Path, err)
+		return
+	}
+	r.entries++
+}
+
+// Entries - количество записей
+func (r *Recorder) Entries() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.entries
+}
+
+// Close - завершить запись на тике tick
+func (r *Recorder) Close(tick int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.enc == nil {
+		return r.err
+	}
+	if r.err == nil {
+		r.err = r.enc.Encode(Entry{Tick: tick, Kind: KindEnd})
+	}
+	r.enc = nil
+	if err := r.gz.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
@@ -0,0 +1,392 @@
+package replay
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"agario-server/internal/bot"
+	"agario-server/internal/game"
+)
+
+const recordTicks = 2*KeyframeInterval + KeyframeInterval/2 // несколько ключевых кадров
+
+// session - комната, которая пишет реплей: воздействие применяется к миру и записывается
+type session struct {
+	world *game.World
+	bots  *bot.BotManager
+	idle  map[string]*bot.Bot
+	rec   *Recorder
+}
+
+func (s *session) do(entry Entry) {
+	w := s.world
+	entry.Tick = w.CurrentTick
+	switch entry.Kind {
+	case KindJoin:
+		entry.Player = w.AddPlayerUnlocked(entry.Name, entry.Color, false).ID
+	case KindMove:
+		w.Players[entry.Player].SetTarget(entry.X, entry.Y, w.Now())
+	case KindSplit:
+		w.SplitPlayerUnlocked(w.Players[entry.Player])
+	case KindEject:
+		w.EjectPlayerUnlocked(w.Players[entry.Player])
+	case KindIdle:
+		s.idle[entry.Player] = bot.NewIdleController(w.Players[entry.Player], w)
+	case KindActive:
+		delete(s.idle, entry.Player)
+	case KindFood:
+		for i := 0; i < entry.Count; i++ {
+			w.SpawnFoodUnlocked()
+		}
+	case KindConfig:
+		w.ApplyConfigUnlocked(entry.Config.Clone())
+	}
+	s.rec.Record(entry)
+}
+
+// step - тик как в Run комнаты
+func (s *session) step() {
+	s.world.UpdateUnlocked(game.TickDuration.Seconds())
+	if s.world.CurrentTick%bot.UpdateInterval == 0 {
+		s.bots.Update()
+		ids := make([]string, 0, len(s.idle))
+		for id := range s.idle {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			if player, exists := s.world.Players[id]; !exists || !player.IsAlive() {
+				delete(s.idle, id)
+				continue
+			}
+			s.idle[id].Update()
+		}
+	}
+	s.world.EventBus.FlushEvents()
+}
+
+// worldJSON - сохранение мира для сравнения состояний
+func worldJSON(t *testing.T, w *game.World) string {
+	t.Helper()
+	data, err := json.Marshal(w.SaveStateUnlocked())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// record - записать сессию с ботами и двумя людьми; возвращает путь и
+// состояния мира в начале, в конце и через offsets тиков после начала
+func record(t *testing.T, offsets ...int) (string, map[int64]string) {
+	t.Helper()
+	w := game.NewDeterministicWorld(nil, 5)
+	bots := bot.NewBotManager(w, 4)
+	bots.SpawnBotsUnlocked()
+	for i := 0; i < 30; i++ {
+		w.UpdateUnlocked(game.TickDuration.Seconds())
+	}
+	w.EventBus.FlushEvents()
+
+	start, err := CaptureUnlocked(w, bots, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "replays", "session.jsonl.gz")
+	rec, err := Create(path, "test", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &session{world: w, bots: bots, idle: map[string]*bot.Bot{}, rec: rec}
+
+	states := map[int64]string{w.CurrentTick: worldJSON(t, w)}
+	var alice, bob string
+	for i := 0; i < recordTicks; i++ {
+		switch i {
+		case 0:
+			s.do(Entry{Kind: KindJoin, Name: "alice", Color: "#FF0000"})
+			s.do(Entry{Kind: KindJoin, Name: "bob", Color: "#0000FF"})
+			players := []string{}
+			for id, player := range w.Players {
+				if !player.IsBot {
+					players = append(players, id)
+				}
+			}
+			sort.Strings(players)
+			alice, bob = players[0], players[1]
+		case 50:
+			s.do(Entry{Kind: KindIdle, Player: bob})
+		case 200:
+			s.do(Entry{Kind: KindFood, Count: 20})
+		case 300:
+			cfg := w.Config.Clone()
+			cfg.BaseSpeed *= 1.5
+			s.do(Entry{Kind: KindConfig, Config: cfg})
+		case 450:
+			if _, ok := w.Players[bob]; ok {
+				s.do(Entry{Kind: KindActive, Player: bob})
+			}
+		}
+		if _, ok := w.Players[alice]; ok {
+			switch i % 45 {
+			case 0:
+				s.do(Entry{Kind: KindMove, Player: alice, X: float64(i%7) * 500, Y: float64(i%5) * 700})
+			case 15:
+				s.do(Entry{Kind: KindSplit, Player: alice})
+			case 30:
+				s.do(Entry{Kind: KindEject, Player: alice})
+			}
+		}
+		s.step()
+		for _, offset := range offsets {
+			if i+1 == offset {
+				states[w.CurrentTick] = worldJSON(t, w)
+			}
+		}
+	}
+	states[w.CurrentTick] = worldJSON(t, w)
+	if err := rec.Close(w.CurrentTick); err != nil {
+		t.Fatal(err)
+	}
+	return path, states
+}
+
+func TestPlaybackResimulates(t *testing.T) {
+	const midOffset = recordTicks/2 + 7 // между ключевыми кадрами
+	path, states := record(t, midOffset)
+	rep, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.EndTick-rep.StartTick() != recordTicks {
+		t.Fatalf("replay covers %d ticks, want %d", rep.EndTick-rep.StartTick(), recordTicks)
+	}
+	mid := rep.StartTick() + midOffset
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, p *Playback)
+		tick int64 // тик, на котором должно остановиться воспроизведение
+	}{
+		{
+			name: "play to the end",
+			run: func(t *testing.T, p *Playback) {
+				p.Paused = false
+				for !p.AtEnd() {
+					if p.AdvanceUnlocked() != 1 {
+						t.Fatalf("tick %d: playback stopped: %v", p.Tick(), p.Err)
+					}
+				}
+			},
+			tick: rep.EndTick,
+		},
+		{
+			name: "fast forward",
+			run: func(t *testing.T, p *Playback) {
+				p.Paused = false
+				p.SetSpeed(MaxSpeed)
+				for !p.AtEnd() && p.Err == nil {
+					p.AdvanceUnlocked()
+				}
+			},
+			tick: rep.EndTick,
+		},
+		{
+			name: "seek forward",
+			run: func(t *testing.T, p *Playback) {
+				if err := p.SeekUnlocked(mid); err != nil {
+					t.Fatal(err)
+				}
+			},
+			tick: mid,
+		},
+		{
+			name: "seek back from the end",
+			run: func(t *testing.T, p *Playback) {
+				if err := p.SeekUnlocked(rep.EndTick); err != nil {
+					t.Fatal(err)
+				}
+				if err := p.SeekUnlocked(mid); err != nil {
+					t.Fatal(err)
+				}
+			},
+			tick: mid,
+		},
+		{
+			name: "seek past the end clamps",
+			run: func(t *testing.T, p *Playback) {
+				if err := p.SeekUnlocked(rep.EndTick + 1000); err != nil {
+					t.Fatal(err)
+				}
+			},
+			tick: rep.EndTick,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPlayback(rep)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Tick() != rep.StartTick() || !p.Paused {
+				t.Fatalf("new playback at tick %d (paused %v), want paused at %d", p.Tick(), p.Paused, rep.StartTick())
+			}
+			if got := worldJSON(t, p.World); got != states[rep.StartTick()] {
+				t.Fatal("playback does not start from the recorded state")
+			}
+
+			tt.run(t, p)
+			if p.Err != nil {
+				t.Fatalf("playback diverged: %v", p.Err)
+			}
+			if p.Tick() != tt.tick {
+				t.Fatalf("playback at tick %d, want %d", p.Tick(), tt.tick)
+			}
+			if got := worldJSON(t, p.World); got != states[tt.tick] {
+				t.Fatalf("tick %d: replayed world differs from the recording", tt.tick)
+			}
+		})
+	}
+}
+
+// writeReplay - файл реплея из заголовка и записей (как пишет Recorder)
+func writeReplay(t *testing.T, header Header, entries ...Entry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "replay.jsonl.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	enc := json.NewEncoder(gz)
+	if err := enc.Encode(header); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadReplay(t *testing.T) {
+	start := func(deterministic bool) *Snapshot {
+		w := game.NewDeterministicWorld(nil, 1)
+		snap, err := CaptureUnlocked(w, bot.NewBotManager(w, 0), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snap.World.Deterministic = deterministic
+		return snap
+	}
+	header := func(version int, deterministic bool) Header {
+		return Header{Version: version, Room: "test", Start: start(deterministic)}
+	}
+
+	tests := []struct {
+		name    string
+		header  Header
+		entries []Entry
+		wantErr error  // ошибка формата
+		errText string // часть текста ошибки
+		count   int    // записей без end
+		endTick int64
+	}{
+		{
+			name:    "with end",
+			header:  header(FormatVersion, true),
+			entries: []Entry{{Tick: 0, Kind: KindFood, Count: 1}, {Tick: 5, Kind: KindFood, Count: 1}, {Tick: 9, Kind: KindEnd}},
+			count:   2,
+			endTick: 9,
+		},
+		{
+			name:    "cut off without end",
+			header:  header(FormatVersion, true),
+			entries: []Entry{{Tick: 3, Kind: KindFood, Count: 1}},
+			count:   1,
+			endTick: 3,
+		},
+		{
+			name:    "entries after end ignored",
+			header:  header(FormatVersion, true),
+			entries: []Entry{{Tick: 2, Kind: KindEnd}, {Tick: 4, Kind: KindFood, Count: 1}},
+			endTick: 2,
+		},
+		{name: "wrong version", header: header(FormatVersion+1, true), wantErr: ErrFormatVersion},
+		{name: "not deterministic", header: header(FormatVersion, false), wantErr: ErrNotDeterministic},
+		{name: "no start state", header: Header{Version: FormatVersion}, errText: "no start state"},
+		{
+			name:    "back in time",
+			header:  header(FormatVersion, true),
+			entries: []Entry{{Tick: 5, Kind: KindFood, Count: 1}, {Tick: 4, Kind: KindFood, Count: 1}},
+			errText: "goes back in time",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep, err := Load(writeReplay(t, tt.header, tt.entries...))
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Load error %v, want %v", err, tt.wantErr)
+				}
+				return
+			case tt.errText != "":
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Fatalf("Load error %v, want %q", err, tt.errText)
+				}
+				return
+			case err != nil:
+				t.Fatal(err)
+			}
+			if len(rep.Entries) != tt.count || rep.EndTick != tt.endTick {
+				t.Fatalf("%d entries ending at %d, want %d ending at %d", len(rep.Entries), rep.EndTick, tt.count, tt.endTick)
+			}
+		})
+	}
+}
+
+func TestPlaybackDiverges(t *testing.T) {
+	w := game.NewDeterministicWorld(nil, 1)
+	start, err := CaptureUnlocked(w, bot.NewBotManager(w, 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep := &Replay{
+		Header: Header{Version: FormatVersion, Start: start},
+		Entries: []Entry{
+			{Tick: 2, Kind: KindJoin, Player: "not-the-id-the-world-gives", Name: "alice"},
+			{Tick: 4, Kind: KindSplit, Player: "not-the-id-the-world-gives"},
+		},
+		EndTick: 10,
+	}
+
+	p, err := NewPlayback(rep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Paused = false
+	for i := 0; i < 10 && p.Err == nil; i++ {
+		p.AdvanceUnlocked()
+	}
+	if !errors.Is(p.Err, ErrDiverged) || !p.Paused {
+		t.Fatalf("playback err %v (paused %v), want %v", p.Err, p.Paused, ErrDiverged)
+	}
+	if p.Tick() != 2 {
+		t.Fatalf("playback stopped at tick %d, want 2", p.Tick())
+	}
+}
@@ -0,0 +1,133 @@
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrStoreClosed = errors.New("result store is closed")
+
+// FileStore - результаты в файле JSON Lines (одна строка - один результат)
+// Файл только дописывается и целиком читается при открытии
+type FileStore struct {
+	mu      sync.RWMutex
+	file    *os.File
+	results []Result // По порядку записи
+}
+
+// OpenFileStore - открыть или создать файл результатов
+func OpenFileStore(path string) (*FileStore, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &FileStore{file: file}
+	if err := store.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	log.Printf("[STATS] Loaded %d results from %s", len(store.results), path)
+	return store, nil
+}
+
+// load - прочитать сохранённые результаты; повреждённые строки пропускаются
+func (fs *FileStore) load() error {
+	scanner := bufio.NewScanner(fs.file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var result Result
+		if err := json.Unmarshal([]byte(text), &result); err != nil {
+			// Например, оборванная запись при падении процесса
+			log.Printf("[STATS] Skipping line %d of %s: %v", line, fs.file.Name(), err)
+			continue
+		}
+		fs.results = append(fs.results, result)
+	}
+	return scanner.Err()
+}
+
+func (fs *FileStore) Record(result Result) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.file == nil {
+		return ErrStoreClosed
+	}
+	if _, err := fs.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	fs.results = append(fs.results, result)
+	return nil
+}
+
+func (fs *FileStore) Top(period Period, limit int) ([]Result, error) {
+	since := period.Since(time.Now())
+
+	fs.mu.RLock()
+	top := []Result{}
+	for _, result := range fs.results {
+		if result.EndedAt.Before(since) {
+			continue
+		}
+		top = append(top, result)
+	}
+	fs.mu.RUnlock()
+
+	rankResults(top)
+	if limit > 0 && len(top) > limit {
+		top = top[:limit]
+	}
+	return top, nil
+}
+
+func (fs *FileStore) History(name string, limit int) ([]Result, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	history := []Result{}
+	for i := len(fs.results) - 1; i >= 0; i-- {
+		if limit > 0 && len(history) >= limit {
+			break
+		}
+		if name != "" && fs.results[i].Name != name {
+			continue
+		}
+		history = append(history, fs.results[i])
+	}
+	return history, nil
+}
+
+func (fs *FileStore) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.file == nil {
+		return nil
+	}
+	err := fs.file.Close()
+	fs.file = nil
+	return err
+}
@@ -0,0 +1,210 @@
+package stats
+
+import (
+	"agario-server/internal/events"
+	"log"
+	"sync"
+	"time"
+)
+
+// staleLifeTimeout - о конце жизни игрока не сообщили, счётчики больше не нужны
+const staleLifeTimeout = time.Hour
+
+// life - счётчики игрока, которых нет в PlayerDiedEvent и PlayerLeftEvent
+type life struct {
+	cellsEaten int
+	updatedAt  time.Time
+}
+
+// Recorder - собирает результаты из событий комнат и сохраняет в Store
+type Recorder struct {
+	Store       Store
+	IncludeBots bool // По умолчанию сохраняются только люди
+
+	mu    sync.Mutex
+	lives map[string]*life // playerID -> счётчики текущей жизни
+
+	// Очередь событий всех комнат; разбирается одной горутиной в порядке публикации
+	queueMu sync.Mutex
+	queue   []recorderItem
+	wake    chan struct{}
+}
+
+// recorderItem - событие комнаты или отметка Flush (done)
+type recorderItem struct {
+	event *events.Event
+	room  string
+	done  chan struct{}
+}
+
+func NewRecorder(store Store) *Recorder {
+	r := &Recorder{
+		Store: store,
+		lives: make(map[string]*life),
+		wake:  make(chan struct{}, 1),
+	}
+	go r.run()
+	return r
+}
+
+// Attach - подписаться на события мира комнаты
+// Обработчики только ставят событие в очередь: запись в Store не держит лок мира
+func (r *Recorder) Attach(bus *events.EventBus, room string) {
+	enqueue := func(event *events.Event) {
+		r.push(recorderItem{event: event, room: room})
+	}
+	bus.Subscribe(events.EventCellEaten, enqueue)
+	bus.Subscribe(events.EventPlayerDied, enqueue)
+	bus.Subscribe(events.EventPlayerLeft, enqueue)
+}
+
+// Flush - дождаться обработки всех событий, полученных до вызова
+func (r *Recorder) Flush() {
+	done := make(chan struct{})
+	r.push(recorderItem{done: done})
+	<-done
+}
+
+func (r *Recorder) push(item recorderItem) {
+	r.queueMu.Lock()
+	r.queue = append(r.queue, item)
+	r.queueMu.Unlock()
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run - разбор очереди; cell_eaten игрока всегда учитывается до его player_died
+func (r *Recorder) run() {
+	for range r.wake {
+		r.queueMu.Lock()
+		items := r.queue
+		r.queue = nil
+		r.queueMu.Unlock()
+
+		for _, item := range items {
+			if item.done != nil {
+				close(item.done)
+				continue
+			}
+			r.handle(item.event, item.room)
+		}
+	}
+}
+
+func (r *Recorder) handle(event *events.Event, room string) {
+	at := time.UnixMilli(event.Timestamp)
+	switch data := event.Data.(type) {
+	case *events.CellEatenEvent:
+		r.cellEaten(data)
+	case *events.PlayerDiedEvent:
+		r.playerDied(data, room, at)
+	case *events.PlayerLeftEvent:
+		r.playerLeft(data, room, at)
+	}
+}
+
+func (r *Recorder) cellEaten(data *events.CellEatenEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.lives[data.EatenBy]
+	if !ok {
+		l = &life{}
+		r.lives[data.EatenBy] = l
+	}
+	l.cellsEaten++
+	l.updatedAt = time.Now()
+}
+
+func (r *Recorder) playerDied(data *events.PlayerDiedEvent, room string, at time.Time) {
+	if data.IsBot && !r.IncludeBots {
+		r.endLife(data.PlayerID)
+		return
+	}
+
+	result := Result{
+		PlayerID:   data.PlayerID,
+		Name:       data.Name,
+		Room:       room,
+		PeakMass:   data.PeakMass,
+		FinalMass:  data.FinalMass,
+		Kills:      data.Kills,
+		CellsEaten: r.endLife(data.PlayerID),
+		TimeAlive:  data.TimeAlive,
+		Cause:      CauseUnknown,
+		KillerName: data.KillerName,
+		EndedAt:    at.UTC(),
+	}
+	switch {
+	case data.Cause == events.DeathZone:
+		result.Cause = CauseZone
+	case data.KillerID != "":
+		result.Cause = CauseEaten
+	}
+	r.record(result)
+}
+
+// leaveCauses - причина результата по причине ухода из мира
+var leaveCauses = map[string]string{
+	events.LeaveQuit:     CauseQuit,
+	events.LeaveExpired:  CauseExpired,
+	events.LeaveSpectate: CauseSpectate,
+	events.LeaveKicked:   CauseKicked,
+	events.LeaveRestore:  CauseRestore,
+}
+
+func (r *Recorder) playerLeft(data *events.PlayerLeftEvent, room string, at time.Time) {
+	if data.IsBot && !r.IncludeBots {
+		r.endLife(data.PlayerID)
+		return
+	}
+
+	cause, ok := leaveCauses[data.Cause]
+	if !ok {
+		cause = CauseUnknown
+	}
+	r.record(Result{
+		PlayerID:   data.PlayerID,
+		Name:       data.Name,
+		Room:       room,
+		PeakMass:   data.PeakMass,
+		FinalMass:  data.FinalMass,
+		Kills:      data.Kills,
+		CellsEaten: r.endLife(data.PlayerID),
+		TimeAlive:  data.TimeAlive,
+		Cause:      cause,
+		EndedAt:    at.UTC(),
+	})
+}
+
+// endLife - забыть счётчики закончившейся жизни игрока; возвращает съеденные клетки
+func (r *Recorder) endLife(playerID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cellsEaten := 0
+	if l, ok := r.lives[playerID]; ok {
+		cellsEaten = l.cellsEaten
+		delete(r.lives, playerID)
+	}
+	r.pruneLocked()
+	return cellsEaten
+}
+
+func (r *Recorder) record(result Result) {
+	if err := r.Store.Record(result); err != nil {
+		log.Printf("[STATS] Cannot record result of %s: %v", result.PlayerID, err)
+	}
+}
+
+// pruneLocked - забыть игроков, давно не подававших признаков жизни (под r.mu)
+func (r *Recorder) pruneLocked() {
+	for id, l := range r.lives {
+		if time.Since(l.updatedAt) > staleLifeTimeout {
+			delete(r.lives, id)
+		}
+	}
+}
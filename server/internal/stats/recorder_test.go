@@ -0,0 +1,147 @@
+package stats
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"agario-server/internal/events"
+)
+
+func TestRecorderPlayerDied(t *testing.T) {
+	tests := []struct {
+		name        string
+		died        events.PlayerDiedEvent
+		includeBots bool
+		cellsEaten  int
+		recorded    bool
+		cause       string
+	}{
+		{name: "eaten by a player", died: events.PlayerDiedEvent{PlayerID: "p", Name: "alice", KillerID: "k", KillerName: "bob"}, cellsEaten: 3, recorded: true, cause: CauseEaten},
+		{name: "no killer", died: events.PlayerDiedEvent{PlayerID: "p", Name: "alice"}, recorded: true, cause: CauseUnknown},
+		{name: "bot skipped", died: events.PlayerDiedEvent{PlayerID: "p", Name: "bot", IsBot: true}, cellsEaten: 1},
+		{name: "bot included", died: events.PlayerDiedEvent{PlayerID: "p", Name: "bot", IsBot: true}, includeBots: true, recorded: true, cause: CauseUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := OpenFileStore(filepath.Join(t.TempDir(), "results.jsonl"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			r := NewRecorder(store)
+			r.IncludeBots = tt.includeBots
+
+			for i := 0; i < tt.cellsEaten; i++ {
+				r.cellEaten(&events.CellEatenEvent{EatenBy: tt.died.PlayerID})
+			}
+			r.cellEaten(&events.CellEatenEvent{EatenBy: "someone else"})
+			r.playerDied(&tt.died, "arena", time.Now())
+
+			if _, ok := r.lives[tt.died.PlayerID]; ok {
+				t.Fatal("counters of the dead player kept")
+			}
+			history, _ := store.History("", 0)
+			if !tt.recorded {
+				if len(history) != 0 {
+					t.Fatalf("recorded %+v, want nothing", history)
+				}
+				return
+			}
+			if len(history) != 1 {
+				t.Fatalf("%d results, want 1", len(history))
+			}
+			result := history[0]
+			if result.Name != tt.died.Name || result.Room != "arena" || result.Cause != tt.cause || result.CellsEaten != tt.cellsEaten {
+				t.Fatalf("result %+v, want cause %s with %d cells eaten", result, tt.cause, tt.cellsEaten)
+			}
+		})
+	}
+}
+
+func TestRecorderPlayerLeft(t *testing.T) {
+	tests := []struct {
+		name        string
+		left        events.PlayerLeftEvent
+		includeBots bool
+		recorded    bool
+		cause       string
+	}{
+		{name: "quit", left: events.PlayerLeftEvent{PlayerID: "p", Name: "alice", Cause: events.LeaveQuit}, recorded: true, cause: CauseQuit},
+		{name: "reconnect expired", left: events.PlayerLeftEvent{PlayerID: "p", Name: "alice", Cause: events.LeaveExpired}, recorded: true, cause: CauseExpired},
+		{name: "spectate", left: events.PlayerLeftEvent{PlayerID: "p", Name: "alice", Cause: events.LeaveSpectate}, recorded: true, cause: CauseSpectate},
+		{name: "world restore", left: events.PlayerLeftEvent{PlayerID: "p", Name: "alice", Cause: events.LeaveRestore}, recorded: true, cause: CauseRestore},
+		{name: "unknown cause", left: events.PlayerLeftEvent{PlayerID: "p", Name: "alice", Cause: "teleport"}, recorded: true, cause: CauseUnknown},
+		{name: "bot skipped", left: events.PlayerLeftEvent{PlayerID: "p", Name: "bot", IsBot: true, Cause: events.LeaveQuit}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := OpenFileStore(filepath.Join(t.TempDir(), "results.jsonl"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			r := NewRecorder(store)
+			r.IncludeBots = tt.includeBots
+
+			r.cellEaten(&events.CellEatenEvent{EatenBy: tt.left.PlayerID})
+			r.cellEaten(&events.CellEatenEvent{EatenBy: tt.left.PlayerID})
+			r.playerLeft(&tt.left, "arena", time.Now())
+
+			if _, ok := r.lives[tt.left.PlayerID]; ok {
+				t.Fatal("counters of the player who left kept")
+			}
+			history, _ := store.History("", 0)
+			if !tt.recorded {
+				if len(history) != 0 {
+					t.Fatalf("recorded %+v, want nothing", history)
+				}
+				return
+			}
+			if len(history) != 1 {
+				t.Fatalf("%d results, want 1", len(history))
+			}
+			result := history[0]
+			if result.Name != tt.left.Name || result.Room != "arena" || result.Cause != tt.cause || result.CellsEaten != 2 {
+				t.Fatalf("result %+v, want cause %s with 2 cells eaten", result, tt.cause)
+			}
+		})
+	}
+}
+
+func TestRecorderKeepsEventOrder(t *testing.T) {
+	store, err := OpenFileStore(filepath.Join(t.TempDir(), "results.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	r := NewRecorder(store)
+	bus := events.NewEventBus()
+	r.Attach(bus, "arena")
+
+	// Каждая жизнь заканчивается сразу после своих cell_eaten
+	const lives, eaten = 50, 20
+	for i := 0; i < lives; i++ {
+		for j := 0; j < eaten; j++ {
+			bus.PublishEvent(events.EventCellEaten, &events.CellEatenEvent{EatenBy: "p"})
+		}
+		if i%2 == 0 {
+			bus.PublishEvent(events.EventPlayerDied, &events.PlayerDiedEvent{PlayerID: "p", Name: "alice"})
+		} else {
+			bus.PublishEvent(events.EventPlayerLeft, &events.PlayerLeftEvent{PlayerID: "p", Name: "alice", Cause: events.LeaveQuit})
+		}
+	}
+	r.Flush()
+
+	history, _ := store.History("", 0)
+	if len(history) != lives {
+		t.Fatalf("%d results, want %d", len(history), lives)
+	}
+	for _, result := range history {
+		if result.CellsEaten != eaten {
+			t.Fatalf("result %+v, want %d cells eaten in every life", result, eaten)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package stats
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Period - за какой срок строится таблица рекордов
+type Period string
+
+const (
+	PeriodAll  Period = "all"
+	PeriodDay  Period = "day"  // С начала текущих суток (UTC)
+	PeriodWeek Period = "week" // С понедельника текущей недели (UTC)
+)
+
+// Причина окончания игровой сессии
+const (
+	CauseEaten   = "eaten"   // Последнюю клетку съел другой игрок
+	CauseZone    = "zone"    // Последняя клетка растворилась вне зоны королевской битвы
+	CauseUnknown = "unknown" // Клетки исчезли без убийцы
+
+	// Игрок ушёл живым
+	CauseQuit     = "quit"     // Отключился без ожидания переподключения или ушёл в другую комнату
+	CauseExpired  = "expired"  // Не переподключился за отведённое время
+	CauseSpectate = "spectate" // Перешёл в зрители
+	CauseKicked   = "kicked"   // Убран администратором
+	CauseRestore  = "restore"  // Мир загружен из сохранения без этого игрока
+)
+
+var ErrUnknownPeriod = errors.New("unknown period, use all, day or week")
+
+// ParsePeriod - период из параметра запроса (пусто - за всё время)
+func ParsePeriod(s string) (Period, error) {
+	switch Period(s) {
+	case "", PeriodAll:
+		return PeriodAll, nil
+	case PeriodDay, PeriodWeek:
+		return Period(s), nil
+	}
+	return "", ErrUnknownPeriod
+}
+
+// Since - начало периода относительно now (нулевое время - без ограничения)
+func (p Period) Since(now time.Time) time.Time {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch p {
+	case PeriodDay:
+		return day
+	case PeriodWeek:
+		// Неделя начинается с понедельника
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	}
+	return time.Time{}
+}
+
+// Result - итог одной жизни игрока
+type Result struct {
+	PlayerID   string    `json:"playerId"`
+	Name       string    `json:"name"`
+	Room       string    `json:"room"`
+	PeakMass   float64   `json:"peakMass"`
+	FinalMass  float64   `json:"finalMass"`
+	Kills      int       `json:"kills"`
+	CellsEaten int       `json:"cellsEaten"` // Съеденные чужие клетки
+	TimeAlive  float64   `json:"timeAlive"`  // Секунды
+	Cause      string    `json:"cause"`
+	KillerName string    `json:"killerName,omitempty"`
+	EndedAt    time.Time `json:"endedAt"`
+}
+
+// Store - хранилище результатов, переживающее перезапуск сервера
+type Store interface {
+	// Record - сохранить результат
+	Record(result Result) error
+	// Top - лучшие результаты периода по наибольшей массе
+	Top(period Period, limit int) ([]Result, error)
+	// History - последние результаты, новые первыми (name "" - все игроки)
+	History(name string, limit int) ([]Result, error)
+	Close() error
+}
+
+// rankResults - по убыванию массы; при равенстве выше более ранний результат
+func rankResults(results []Result) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].PeakMass != results[j].PeakMass {
+			return results[i].PeakMass > results[j].PeakMass
+		}
+		return results[i].EndedAt.Before(results[j].EndedAt)
+	})
+}
@@ -0,0 +1,175 @@
+package stats
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPeriodSince(t *testing.T) {
+	wednesday := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		period Period
+		now    time.Time
+		want   time.Time
+	}{
+		{name: "all time", period: PeriodAll, now: wednesday},
+		{name: "day", period: PeriodDay, now: wednesday, want: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)},
+		{name: "week from wednesday", period: PeriodWeek, now: wednesday, want: time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		{name: "week from monday", period: PeriodWeek, now: time.Date(2026, 10, 12, 0, 0, 1, 0, time.UTC), want: time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		{name: "week from sunday", period: PeriodWeek, now: time.Date(2026, 10, 18, 23, 59, 0, 0, time.UTC), want: time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		{name: "day in UTC", period: PeriodDay, now: time.Date(2026, 10, 15, 1, 0, 0, 0, time.FixedZone("UTC+3", 3*3600)), want: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.period.Since(tt.now); !got.Equal(tt.want) {
+				t.Fatalf("since %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Period
+		err  error
+	}{
+		{in: "", want: PeriodAll},
+		{in: "all", want: PeriodAll},
+		{in: "day", want: PeriodDay},
+		{in: "week", want: PeriodWeek},
+		{in: "month", err: ErrUnknownPeriod},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePeriod(tt.in)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Fatalf("ParsePeriod(%q) = %q, %v, want %q, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+// names - имена результатов по порядку
+func names(results []Result) []string {
+	out := []string{}
+	for _, result := range results {
+		out = append(out, result.Name)
+	}
+	return out
+}
+
+func TestFileStoreTop(t *testing.T) {
+	now := time.Now().UTC()
+	dayStart := PeriodDay.Since(now)
+	weekStart := PeriodWeek.Since(now)
+
+	results := []Result{
+		{Name: "old giant", PeakMass: 5000, EndedAt: weekStart.Add(-time.Hour)},
+		{Name: "this week", PeakMass: 3000, EndedAt: weekStart.Add(time.Minute)},
+		{Name: "yesterday", PeakMass: 2000, EndedAt: dayStart.Add(-time.Minute)},
+		{Name: "today", PeakMass: 1000, EndedAt: now},
+		{Name: "today tie", PeakMass: 1000, EndedAt: now.Add(time.Millisecond)},
+	}
+	// Ожидание каждого периода - результаты после его начала, по убыванию массы
+	want := func(since time.Time) []string {
+		out := []string{}
+		for _, result := range results {
+			if !result.EndedAt.Before(since) {
+				out = append(out, result.Name)
+			}
+		}
+		return out
+	}
+
+	store, err := OpenFileStore(filepath.Join(t.TempDir(), "results.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for _, result := range results {
+		if err := store.Record(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		period Period
+		limit  int
+		want   []string
+	}{
+		{period: PeriodAll, want: want(time.Time{})},
+		{period: PeriodWeek, want: want(weekStart)},
+		{period: PeriodDay, want: want(dayStart)},
+		{period: PeriodAll, limit: 2, want: []string{"old giant", "this week"}},
+	}
+
+	for _, tt := range tests {
+		top, err := store.Top(tt.period, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names(top); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("top %s (limit %d) = %v, want %v", tt.period, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestFileStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats", "results.jsonl")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"alice", "bob", "alice"} {
+		if err := store.Record(Result{Name: name, PeakMass: 100, EndedAt: time.Now().UTC()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Record(Result{Name: "late"}); !errors.Is(err, ErrStoreClosed) {
+		t.Fatalf("record after close: %v, want %v", err, ErrStoreClosed)
+	}
+
+	// Оборванная последняя строка не мешает прочитать остальные
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"name":"torn","peakM`)
+	file.Close()
+
+	store, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []string
+	}{
+		{want: []string{"alice", "bob", "alice"}},
+		{name: "alice", want: []string{"alice", "alice"}},
+		{name: "alice", limit: 1, want: []string{"alice"}},
+		{limit: 2, want: []string{"alice", "bob"}},
+		{name: "nobody", want: []string{}},
+	}
+	for _, tt := range tests {
+		history, err := store.History(tt.name, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names(history); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("history %q (limit %d) = %v, want %v", tt.name, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,531 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Бинарный протокол (сервер -> клиент)
+//
+// Выбирается через WebSocket subprotocol при подключении; без него - JSON.
+// Все многобайтовые числа little-endian, uvarint/varint - как в encoding/binary.
+//
+// Кадр:   [BinaryMagic][float32 worldWidth][float32 worldHeight] записи...
+// Запись: [u8 тип] payload
+//
+// Координаты - uint16, нормированные на размер мира из заголовка кадра.
+// Радиус - uint16 в 1/RadiusScale единицы. Скорость - int16 в целых единицах.
+// Цвет - 3 байта RGB. Строка - uvarint длина + UTF-8.
+//
+// Entity адресуются короткими числовыми ID сессии. Перед первой ссылкой
+// сервер отправляет RecBind с исходным строковым ID. ID забывается
+// обеими сторонами после RecEntityLeft, RecFoodEaten, RecSnapshot
+// (после снимка остаются только ID, упомянутые в нём и в следующей за ним
+// RecPowerUps) и JSON-события powerup_collected. При пересинхронизации
+// сервер начинает таблицу заново, и все ID снимка объявляются повторно.
+//
+// Номера ID одной таблицы только растут, новая таблица начинается с 1:
+// RecBind с номером 1 очищает таблицу получателя. Все RecBind кадра идут
+// перед остальными записями, поэтому при разборе снимка получатель не
+// забывает ID, объявленные в этом же кадре: их могут использовать записи
+// после снимка.
+//
+// Состояние клеток в RecStateDelta считается от последнего тика,
+// подтверждённого клиентом сообщением ack.
+const (
+	SubprotocolBinary = "agario-binary-v1"
+	SubprotocolJSON   = "agario-json-v1"
+
+	BinaryMagic byte = 0xB1
+	RadiusScale      = 8.0
+)
+
+// Типы записей бинарного кадра
+const (
+	RecBind          byte = 0x00 // uvarint id, string stringId
+	RecStateDelta    byte = 0x10 // uvarint tick, uvarint timestamp, uvarint ackSeq, varint ackTimestamp, uvarint ackTick, uvarint n, n*{id, x, y, r, targetX, targetY}
+	RecFoodSpawned   byte = 0x11 // uvarint n, n*{id, x, y, r, rgb, velX, velY}
+	RecSnapshot      byte = 0x12 // uvarint tick, uvarint timestamp, players, food, viruses (см. binaryCodec.snapshot в network)
+	RecEntityEntered byte = 0x13 // cells, food, viruses
+	RecEntityLeft    byte = 0x14 // uvarint n, n*id
+	RecFoodEaten     byte = 0x15 // foodId, playerId, cellId
+	RecPowerUps      byte = 0x16 // uvarint n, n*{id, x, y, r, string kind}; всегда после RecSnapshot, после RecEntityEntered - если вошли бонусы
+	RecJSON          byte = 0x7F // uvarint длина, JSON события (остальные типы)
+)
+
+// BinaryIDs - таблица ID сессии на стороне получателя (номер -> строковый ID)
+type BinaryIDs map[uint64]string
+
+// BinaryFrame - разобранный бинарный кадр
+// Records: *StateDeltaRecord, *FoodSpawnedRecord, *SnapshotRecord, *EntityEnteredRecord,
+// *EntityLeftRecord, *FoodEatenRecord, *PowerUpsRecord или json.RawMessage (RecJSON)
+type BinaryFrame struct {
+	WorldWidth  float64
+	WorldHeight float64
+	Records     []interface{}
+}
+
+type StateDeltaRecord struct {
+	Tick         int64
+	Timestamp    int64
+	AckSeq       uint32
+	AckTimestamp int64
+	AckTick      int64
+	Entities     []DeltaEntityRecord
+}
+
+type DeltaEntityRecord struct {
+	ID      string
+	X       float64
+	Y       float64
+	Radius  float64
+	TargetX float64
+	TargetY float64
+}
+
+type FoodSpawnedRecord struct {
+	Foods []FoodRecord
+}
+
+// FoodRecord - еда; в снимке скорость не передаётся
+type FoodRecord struct {
+	ID     string
+	X      float64
+	Y      float64
+	Radius float64
+	Color  string
+	VelX   float64
+	VelY   float64
+}
+
+// VirusRecord - вирус; в снимке скорость не передаётся
+type VirusRecord struct {
+	ID     string
+	X      float64
+	Y      float64
+	Radius float64
+	VelX   float64
+	VelY   float64
+}
+
+// SnapshotRecord - RecSnapshot вместе со следующей за ним RecPowerUps
+type SnapshotRecord struct {
+	Tick      int64
+	Timestamp int64
+	Players   []PlayerRecord
+	Food      []FoodRecord
+	Viruses   []VirusRecord
+	PowerUps  []PowerUpRecord
+}
+
+type PlayerRecord struct {
+	ID    string
+	Name  string
+	Color string
+	IsBot bool
+	Score int
+	Cells []CellRecord
+}
+
+type CellRecord struct {
+	ID     string
+	X      float64
+	Y      float64
+	Radius float64
+}
+
+type EntityEnteredRecord struct {
+	Cells   []EnteredCellRecord
+	Food    []FoodRecord
+	Viruses []VirusRecord
+}
+
+type EnteredCellRecord struct {
+	CellID   string
+	PlayerID string
+	Name     string
+	Color    string
+	IsBot    bool
+	X        float64
+	Y        float64
+	Radius   float64
+}
+
+type EntityLeftRecord struct {
+	IDs []string
+}
+
+type FoodEatenRecord struct {
+	FoodID   string
+	PlayerID string
+	CellID   string
+}
+
+type PowerUpsRecord struct {
+	PowerUps []PowerUpRecord
+}
+
+type PowerUpRecord struct {
+	ID     string
+	X      float64
+	Y      float64
+	Radius float64
+	Kind   string
+}
+
+// DecodeBinary - разобрать кадр бинарного протокола
+// ids - таблица ID соединения: пополняется из RecBind и забывает ID так же, как сервер
+func DecodeBinary(frame []byte, ids BinaryIDs) (*BinaryFrame, error) {
+	r := &binaryReader{buf: frame, ids: ids}
+	if r.byte() != BinaryMagic {
+		return nil, errors.New("binary frame: bad magic")
+	}
+	decoded := &BinaryFrame{
+		WorldWidth:  float64(math.Float32frombits(r.uint32())),
+		WorldHeight: float64(math.Float32frombits(r.uint32())),
+	}
+	r.worldW, r.worldH = decoded.WorldWidth, decoded.WorldHeight
+
+	// Объявленные в этом кадре ID - с номера firstBound
+	firstBound := uint64(math.MaxUint64)
+	for r.err == nil && r.pos < len(r.buf) && r.buf[r.pos] == RecBind {
+		r.pos++
+		num := r.uvarint()
+		id := r.str()
+		if r.err == nil {
+			if num == 1 {
+				clear(ids)
+			}
+			ids[num] = id
+			firstBound = min(firstBound, num)
+		}
+	}
+
+	for r.err == nil && r.pos < len(r.buf) {
+		recType := r.byte()
+		var record interface{}
+		switch recType {
+		case RecStateDelta:
+			record = r.stateDelta()
+		case RecFoodSpawned:
+			record = &FoodSpawnedRecord{Foods: r.foods()}
+		case RecSnapshot:
+			record = r.snapshot(firstBound)
+		case RecEntityEntered:
+			record = r.entityEntered()
+		case RecEntityLeft:
+			left := &EntityLeftRecord{}
+			nums := make([]uint64, r.count())
+			for i := range nums {
+				nums[i] = r.uvarint()
+				left.IDs = append(left.IDs, r.lookup(nums[i]))
+			}
+			for _, num := range nums {
+				delete(ids, num)
+			}
+			record = left
+		case RecFoodEaten:
+			foodNum := r.uvarint()
+			record = &FoodEatenRecord{FoodID: r.lookup(foodNum), PlayerID: r.id(), CellID: r.id()}
+			delete(ids, foodNum)
+		case RecPowerUps:
+			record = &PowerUpsRecord{PowerUps: r.powerUps()}
+		case RecJSON:
+			raw := r.bytes(r.count())
+			record = json.RawMessage(append([]byte(nil), raw...))
+			r.forgetCollected(raw)
+		default:
+			r.fail(fmt.Sprintf("unknown record 0x%02X", recType))
+		}
+		if r.err == nil {
+			decoded.Records = append(decoded.Records, record)
+		}
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return decoded, nil
+}
+
+// binaryReader - чтение записей кадра; первая ошибка останавливает разбор
+type binaryReader struct {
+	buf    []byte
+	pos    int
+	err    error
+	ids    BinaryIDs
+	worldW float64
+	worldH float64
+	refs   map[uint64]bool // ID, упомянутые в снимке (nil - не снимок)
+}
+
+func (r *binaryReader) fail(reason string) {
+	if r.err == nil {
+		r.err = fmt.Errorf("binary frame: %s at offset %d", reason, r.pos)
+	}
+	r.pos = len(r.buf)
+}
+
+func (r *binaryReader) bytes(n int) []byte {
+	if r.err != nil || n > len(r.buf)-r.pos {
+		r.fail("truncated")
+		return nil
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *binaryReader) byte() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *binaryReader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *binaryReader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *binaryReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		r.fail("bad uvarint")
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+func (r *binaryReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf[r.pos:])
+	if n <= 0 {
+		r.fail("bad varint")
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+// count - длина списка; не больше оставшихся байт, чтобы битый кадр не съел память
+func (r *binaryReader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.buf)-r.pos) {
+		r.fail("bad length")
+		return 0
+	}
+	return int(n)
+}
+
+func (r *binaryReader) str() string {
+	return string(r.bytes(r.count()))
+}
+
+func (r *binaryReader) lookup(num uint64) string {
+	id, ok := r.ids[num]
+	if !ok && r.err == nil {
+		r.fail(fmt.Sprintf("unbound id %d", num))
+	}
+	if r.refs != nil {
+		r.refs[num] = true
+	}
+	return id
+}
+
+func (r *binaryReader) id() string {
+	return r.lookup(r.uvarint())
+}
+
+func (r *binaryReader) position() (float64, float64) {
+	x := float64(r.uint16()) / math.MaxUint16 * r.worldW
+	y := float64(r.uint16()) / math.MaxUint16 * r.worldH
+	return x, y
+}
+
+func (r *binaryReader) radius() float64 {
+	return float64(r.uint16()) / RadiusScale
+}
+
+func (r *binaryReader) velocity() (float64, float64) {
+	return float64(int16(r.uint16())), float64(int16(r.uint16()))
+}
+
+func (r *binaryReader) color() string {
+	b := r.bytes(3)
+	if b == nil {
+		return ""
+	}
+	return fmt.Sprintf("#%02X%02X%02X", b[0], b[1], b[2])
+}
+
+func (r *binaryReader) flag() bool {
+	return r.byte() != 0
+}
+
+func (r *binaryReader) stateDelta() *StateDeltaRecord {
+	delta := &StateDeltaRecord{
+		Tick:         int64(r.uvarint()),
+		Timestamp:    int64(r.uvarint()),
+		AckSeq:       uint32(r.uvarint()),
+		AckTimestamp: r.varint(),
+		AckTick:      int64(r.uvarint()),
+	}
+	n := r.count()
+	for i := 0; i < n && r.err == nil; i++ {
+		e := DeltaEntityRecord{ID: r.id()}
+		e.X, e.Y = r.position()
+		e.Radius = r.radius()
+		e.TargetX, e.TargetY = r.position()
+		delta.Entities = append(delta.Entities, e)
+	}
+	return delta
+}
+
+func (r *binaryReader) foods() []FoodRecord {
+	foods := []FoodRecord{}
+	n := r.count()
+	for i := 0; i < n && r.err == nil; i++ {
+		f := FoodRecord{ID: r.id()}
+		f.X, f.Y = r.position()
+		f.Radius = r.radius()
+		f.Color = r.color()
+		f.VelX, f.VelY = r.velocity()
+		foods = append(foods, f)
+	}
+	return foods
+}
+
+func (r *binaryReader) viruses() []VirusRecord {
+	viruses := []VirusRecord{}
+	n := r.count()
+	for i := 0; i < n && r.err == nil; i++ {
+		v := VirusRecord{ID: r.id()}
+		v.X, v.Y = r.position()
+		v.Radius = r.radius()
+		v.VelX, v.VelY = r.velocity()
+		viruses = append(viruses, v)
+	}
+	return viruses
+}
+
+func (r *binaryReader) powerUps() []PowerUpRecord {
+	powerUps := []PowerUpRecord{}
+	n := r.count()
+	for i := 0; i < n && r.err == nil; i++ {
+		pu := PowerUpRecord{ID: r.id()}
+		pu.X, pu.Y = r.position()
+		pu.Radius = r.radius()
+		pu.Kind = r.str()
+		powerUps = append(powerUps, pu)
+	}
+	return powerUps
+}
+
+// snapshot - RecSnapshot и обязательная RecPowerUps следом
+// После них таблица ID сокращается до упомянутых и объявленных в этом кадре
+func (r *binaryReader) snapshot(firstBound uint64) *SnapshotRecord {
+	r.refs = make(map[uint64]bool)
+	defer func() { r.refs = nil }()
+
+	snapshot := &SnapshotRecord{
+		Tick:      int64(r.uvarint()),
+		Timestamp: int64(r.uvarint()),
+		Players:   []PlayerRecord{},
+		Food:      []FoodRecord{},
+		Viruses:   []VirusRecord{},
+	}
+
+	n := r.count()
+	for i := 0; i < n && r.err == nil; i++ {
+		p := PlayerRecord{ID: r.id(), Name: r.str(), Color: r.color(), IsBot: r.flag(), Score: int(r.uvarint()), Cells: []CellRecord{}}
+		cells := r.count()
+		for j := 0; j < cells && r.err == nil; j++ {
+			c := CellRecord{ID: r.id()}
+			c.X, c.Y = r.position()
+			c.Radius = r.radius()
+			p.Cells = append(p.Cells, c)
+		}
+		snapshot.Players = append(snapshot.Players, p)
+	}
+
+	n = r.count()
+	for i := 0; i < n && r.err == nil; i++ {
+		f := FoodRecord{ID: r.id()}
+		f.X, f.Y = r.position()
+		f.Radius = r.radius()
+		f.Color = r.color()
+		snapshot.Food = append(snapshot.Food, f)
+	}
+
+	n = r.count()
+	for i := 0; i < n && r.err == nil; i++ {
+		v := VirusRecord{ID: r.id()}
+		v.X, v.Y = r.position()
+		v.Radius = r.radius()
+		snapshot.Viruses = append(snapshot.Viruses, v)
+	}
+
+	if r.byte() != RecPowerUps {
+		r.fail("snapshot without power-ups record")
+		return snapshot
+	}
+	snapshot.PowerUps = r.powerUps()
+
+	for num := range r.ids {
+		if !r.refs[num] && num < firstBound {
+			delete(r.ids, num)
+		}
+	}
+	return snapshot
+}
+
+func (r *binaryReader) entityEntered() *EntityEnteredRecord {
+	entered := &EntityEnteredRecord{Cells: []EnteredCellRecord{}}
+	n := r.count()
+	for i := 0; i < n && r.err == nil; i++ {
+		c := EnteredCellRecord{CellID: r.id(), PlayerID: r.id(), Name: r.str(), Color: r.color(), IsBot: r.flag()}
+		c.X, c.Y = r.position()
+		c.Radius = r.radius()
+		entered.Cells = append(entered.Cells, c)
+	}
+	entered.Food = r.foods()
+	entered.Viruses = r.viruses()
+	return entered
+}
+
+// forgetCollected - подобранный бонус (JSON powerup_collected) забывается, как на сервере
+func (r *binaryReader) forgetCollected(raw []byte) {
+	var event struct {
+		Type string `json:"type"`
+		Data struct {
+			PowerUpID string `json:"powerUpId"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(raw, &event) != nil || event.Type != "powerup_collected" {
+		return
+	}
+	for num, id := range r.ids {
+		if id == event.Data.PowerUpID {
+			delete(r.ids, num)
+		}
+	}
+}
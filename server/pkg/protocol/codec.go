@@ -0,0 +1,308 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Версии протокола
+const (
+	MinVersion = 1
+	MaxVersion = 1
+
+	MaxNameLength     = 20
+	MaxRoomLength     = 32
+	MaxMessageSize    = 4096
+	MaxTokenLength    = 128
+	MaxIDLength       = 64
+	MaxCoordinate     = 1e6 // |x|, |y| цели движения
+	DefaultPlayerName = "Player"
+)
+
+// ErrorCode - машиночитаемый код ошибки
+type ErrorCode string
+
+const (
+	ErrCodeMalformed          ErrorCode = "malformed"
+	ErrCodeUnknownType        ErrorCode = "unknown_type"
+	ErrCodeInvalidData        ErrorCode = "invalid_data"
+	ErrCodeUnsupportedVersion ErrorCode = "unsupported_version"
+	ErrCodeRoomUnavailable    ErrorCode = "room_unavailable"
+	ErrCodeNotJoined          ErrorCode = "not_joined"
+	ErrCodeSessionExpired     ErrorCode = "session_expired"
+	ErrCodeAlreadyPlaying     ErrorCode = "already_playing"
+	ErrCodeNotReplay          ErrorCode = "not_replay"
+	ErrCodeMatchRunning       ErrorCode = "match_running"
+)
+
+// Error - ошибка протокола, отправляемая клиенту
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Type    MessageType
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Data - содержимое для ответа клиенту
+func (e *Error) Data() ErrorData {
+	return ErrorData{Code: e.Code, Message: e.Message, Type: e.Type}
+}
+
+func newError(code ErrorCode, msgType MessageType, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), Type: msgType}
+}
+
+// DecodeClientMessage - разобрать и проверить сообщение клиента
+// Возвращает тип и типизированные данные: *HelloData, *JoinData, *ResumeData,
+// *RespawnData, *MoveData, *ActionData, *AckData, *ReplayControlData или *SpectateData
+func DecodeClientMessage(raw []byte) (MessageType, interface{}, error) {
+	if len(raw) > MaxMessageSize {
+		return "", nil, newError(ErrCodeMalformed, "", "message too large (%d bytes)", len(raw))
+	}
+
+	var msg ClientMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return "", nil, newError(ErrCodeMalformed, "", "invalid JSON: %v", err)
+	}
+
+	switch msg.Type {
+	case MsgTypeHello:
+		var data HelloData
+		if err := decodeData(msg, &data); err != nil {
+			return msg.Type, nil, err
+		}
+		return msg.Type, &data, nil
+
+	case MsgTypeJoin:
+		var data JoinData
+		if err := decodeData(msg, &data); err != nil {
+			return msg.Type, nil, err
+		}
+		if err := data.Validate(); err != nil {
+			return msg.Type, nil, err
+		}
+		return msg.Type, &data, nil
+
+	case MsgTypeMove:
+		var data MoveData
+		if err := decodeData(msg, &data); err != nil {
+			return msg.Type, nil, err
+		}
+		if err := data.Validate(); err != nil {
+			return msg.Type, nil, err
+		}
+		return msg.Type, &data, nil
+
+	case MsgTypeResume:
+		var data ResumeData
+		if err := decodeData(msg, &data); err != nil {
+			return msg.Type, nil, err
+		}
+		if data.Token == "" || len(data.Token) > MaxTokenLength {
+			return msg.Type, nil, newError(ErrCodeInvalidData, msg.Type, "token must be 1-%d characters", MaxTokenLength)
+		}
+		return msg.Type, &data, nil
+
+	case MsgTypeAck:
+		var data AckData
+		if err := decodeData(msg, &data); err != nil {
+			return msg.Type, nil, err
+		}
+		if data.Tick < 0 {
+			return msg.Type, nil, newError(ErrCodeInvalidData, msg.Type, "tick must not be negative")
+		}
+		return msg.Type, &data, nil
+
+	case MsgTypeRespawn:
+		var data RespawnData
+		if len(msg.Data) > 0 && string(msg.Data) != "null" {
+			if err := decodeData(msg, &data); err != nil {
+				return msg.Type, nil, err
+			}
+		}
+		name, err := normalizeName(data.Name, msg.Type)
+		if err != nil {
+			return msg.Type, nil, err
+		}
+		data.Name = name
+		return msg.Type, &data, nil
+
+	case MsgTypeSplit, MsgTypeEject:
+		var data ActionData
+		if len(msg.Data) > 0 && string(msg.Data) != "null" {
+			if err := decodeData(msg, &data); err != nil {
+				return msg.Type, nil, err
+			}
+		}
+		return msg.Type, &data, nil
+
+	case MsgTypeReplayControl:
+		var data ReplayControlData
+		if err := decodeData(msg, &data); err != nil {
+			return msg.Type, nil, err
+		}
+		if err := data.Validate(); err != nil {
+			return msg.Type, nil, err
+		}
+		return msg.Type, &data, nil
+
+	case MsgTypeSpectate:
+		var data SpectateData
+		if len(msg.Data) > 0 && string(msg.Data) != "null" {
+			if err := decodeData(msg, &data); err != nil {
+				return msg.Type, nil, err
+			}
+		}
+		if err := data.Validate(); err != nil {
+			return msg.Type, nil, err
+		}
+		return msg.Type, &data, nil
+	}
+
+	return msg.Type, nil, newError(ErrCodeUnknownType, msg.Type, "unknown message type %q", msg.Type)
+}
+
+func decodeData(msg ClientMessage, target interface{}) error {
+	if len(msg.Data) == 0 || string(msg.Data) == "null" {
+		return newError(ErrCodeInvalidData, msg.Type, "data is required")
+	}
+	if err := json.Unmarshal(msg.Data, target); err != nil {
+		return newError(ErrCodeInvalidData, msg.Type, "invalid data: %v", err)
+	}
+	return nil
+}
+
+// Validate - имя нормализуется (пробелы по краям, пустое - DefaultPlayerName)
+func (d *JoinData) Validate() error {
+	name, err := normalizeName(d.Name, MsgTypeJoin)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = DefaultPlayerName
+	}
+	d.Name = name
+
+	d.Room = strings.TrimSpace(d.Room)
+	if len(d.Room) > MaxRoomLength {
+		return newError(ErrCodeInvalidData, MsgTypeJoin, "room name longer than %d characters", MaxRoomLength)
+	}
+	return nil
+}
+
+// normalizeName - имя без пробелов по краям; пустое остаётся пустым
+func normalizeName(name string, msgType MessageType) (string, error) {
+	name = strings.TrimSpace(name)
+	if !utf8.ValidString(name) {
+		return "", newError(ErrCodeInvalidData, msgType, "name is not valid UTF-8")
+	}
+	if utf8.RuneCountInString(name) > MaxNameLength {
+		return "", newError(ErrCodeInvalidData, msgType, "name longer than %d characters", MaxNameLength)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return "", newError(ErrCodeInvalidData, msgType, "name contains control characters")
+		}
+	}
+	return name, nil
+}
+
+func (d *MoveData) Validate() error {
+	if !isFinite(d.X) || !isFinite(d.Y) {
+		return newError(ErrCodeInvalidData, MsgTypeMove, "coordinates must be finite numbers")
+	}
+	if math.Abs(d.X) > MaxCoordinate || math.Abs(d.Y) > MaxCoordinate {
+		return newError(ErrCodeInvalidData, MsgTypeMove, "coordinates out of range")
+	}
+	return nil
+}
+
+func (d *ReplayControlData) Validate() error {
+	switch d.Action {
+	case ReplayPlay, ReplayPause:
+	case ReplaySeek:
+		if d.Tick < 0 {
+			return newError(ErrCodeInvalidData, MsgTypeReplayControl, "tick must not be negative")
+		}
+	case ReplaySpeed:
+		if !isFinite(d.Speed) || d.Speed <= 0 {
+			return newError(ErrCodeInvalidData, MsgTypeReplayControl, "speed must be a positive number")
+		}
+	case ReplayFollow:
+		if len(d.PlayerID) > MaxIDLength {
+			return newError(ErrCodeInvalidData, MsgTypeReplayControl, "playerId longer than %d characters", MaxIDLength)
+		}
+	default:
+		return newError(ErrCodeInvalidData, MsgTypeReplayControl, "unknown action %q", d.Action)
+	}
+	return nil
+}
+
+// Validate - пустой режим означает leader
+func (d *SpectateData) Validate() error {
+	d.Room = strings.TrimSpace(d.Room)
+	if len(d.Room) > MaxRoomLength {
+		return newError(ErrCodeInvalidData, MsgTypeSpectate, "room name longer than %d characters", MaxRoomLength)
+	}
+
+	switch d.Mode {
+	case "":
+		d.Mode = SpectateLeader
+	case SpectateLeader:
+	case SpectatePlayer:
+		if d.PlayerID == "" || len(d.PlayerID) > MaxIDLength {
+			return newError(ErrCodeInvalidData, MsgTypeSpectate, "playerId must be 1-%d characters", MaxIDLength)
+		}
+	case SpectateFree:
+		if !isFinite(d.X) || !isFinite(d.Y) || math.Abs(d.X) > MaxCoordinate || math.Abs(d.Y) > MaxCoordinate {
+			return newError(ErrCodeInvalidData, MsgTypeSpectate, "camera coordinates out of range")
+		}
+	default:
+		return newError(ErrCodeInvalidData, MsgTypeSpectate, "unknown mode %q", d.Mode)
+	}
+	return nil
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// SeqNewer - a новее b с учётом переполнения uint32
+func SeqNewer(a, b uint32) bool {
+	return int32(a-b) > 0
+}
+
+// NegotiateVersion - наибольшая версия, поддерживаемая обеими сторонами
+func NegotiateVersion(hello *HelloData) (int, error) {
+	best := 0
+	for _, v := range hello.Versions {
+		if v >= MinVersion && v <= MaxVersion && v > best {
+			best = v
+		}
+	}
+	if best == 0 {
+		return 0, newError(ErrCodeUnsupportedVersion, MsgTypeHello, "no common protocol version (server supports %d-%d)", MinVersion, MaxVersion)
+	}
+	return best, nil
+}
+
+// SupportedVersions - версии, которые понимает сервер
+func SupportedVersions() []int {
+	versions := []int{}
+	for v := MinVersion; v <= MaxVersion; v++ {
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+// EncodeServerMessage - сообщение сервера в JSON
+func EncodeServerMessage(msgType MessageType, data interface{}) ([]byte, error) {
+	return json.Marshal(ServerMessage{Type: msgType, Data: data})
+}
@@ -0,0 +1,105 @@
+package protocol
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeClientMessageRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		code    ErrorCode
+		msgType MessageType // тип, который попадёт в ответ с ошибкой
+	}{
+		{name: "too large", raw: `{"type":"move","data":{"x":1,"y":1,"pad":"` + strings.Repeat("a", MaxMessageSize) + `"}}`, code: ErrCodeMalformed},
+		{name: "not JSON", raw: `move 1 1`, code: ErrCodeMalformed},
+		{name: "not an object", raw: `[1, 2]`, code: ErrCodeMalformed},
+		{name: "unknown type", raw: `{"type":"teleport"}`, code: ErrCodeUnknownType, msgType: "teleport"},
+		{name: "missing type", raw: `{"data":{}}`, code: ErrCodeUnknownType},
+		{name: "join without data", raw: `{"type":"join"}`, code: ErrCodeInvalidData, msgType: MsgTypeJoin},
+		{name: "move with null data", raw: `{"type":"move","data":null}`, code: ErrCodeInvalidData, msgType: MsgTypeMove},
+		{name: "hello with wrong types", raw: `{"type":"hello","data":{"versions":"1"}}`, code: ErrCodeInvalidData, msgType: MsgTypeHello},
+		{name: "move with string coordinate", raw: `{"type":"move","data":{"x":"1","y":1}}`, code: ErrCodeInvalidData, msgType: MsgTypeMove},
+		{name: "move out of range", raw: `{"type":"move","data":{"x":2e6,"y":1}}`, code: ErrCodeInvalidData, msgType: MsgTypeMove},
+		{name: "name too long", raw: `{"type":"join","data":{"name":"` + strings.Repeat("я", MaxNameLength+1) + `"}}`, code: ErrCodeInvalidData, msgType: MsgTypeJoin},
+		{name: "name with control characters", raw: `{"type":"join","data":{"name":"a\u0007b"}}`, code: ErrCodeInvalidData, msgType: MsgTypeJoin},
+		{name: "room too long", raw: `{"type":"join","data":{"name":"a","room":"` + strings.Repeat("r", MaxRoomLength+1) + `"}}`, code: ErrCodeInvalidData, msgType: MsgTypeJoin},
+		{name: "respawn name too long", raw: `{"type":"respawn","data":{"name":"` + strings.Repeat("a", MaxNameLength+1) + `"}}`, code: ErrCodeInvalidData, msgType: MsgTypeRespawn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, data, err := DecodeClientMessage([]byte(tt.raw))
+			if data != nil {
+				t.Fatalf("rejected message returned data %+v", data)
+			}
+			var protoErr *Error
+			if !errors.As(err, &protoErr) {
+				t.Fatalf("error %v, want *Error", err)
+			}
+			if protoErr.Code != tt.code || protoErr.Type != tt.msgType {
+				t.Fatalf("error %s for %q, want %s for %q", protoErr.Code, protoErr.Type, tt.code, tt.msgType)
+			}
+		})
+	}
+}
+
+func TestDecodeClientMessageNormalizes(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		room string
+		want string
+	}{
+		{name: "trims spaces", raw: `{"type":"join","data":{"name":"  Bob  ","room":" arena "}}`, want: "Bob", room: "arena"},
+		{name: "empty name gets default", raw: `{"type":"join","data":{"name":"   "}}`, want: DefaultPlayerName},
+		{name: "long name in runes fits", raw: `{"type":"join","data":{"name":"` + strings.Repeat("я", MaxNameLength) + `"}}`, want: strings.Repeat("я", MaxNameLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgType, data, err := DecodeClientMessage([]byte(tt.raw))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			join, ok := data.(*JoinData)
+			if msgType != MsgTypeJoin || !ok {
+				t.Fatalf("decoded %q as %T", msgType, data)
+			}
+			if join.Name != tt.want || join.Room != tt.room {
+				t.Fatalf("name %q room %q, want %q and %q", join.Name, join.Room, tt.want, tt.room)
+			}
+		})
+	}
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []int
+		want     int
+	}{
+		{name: "supported", versions: []int{MinVersion}, want: MinVersion},
+		{name: "picks the highest common", versions: []int{MaxVersion + 5, MaxVersion, MinVersion}, want: MaxVersion},
+		{name: "only newer versions", versions: []int{MaxVersion + 1}},
+		{name: "no versions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NegotiateVersion(&HelloData{Versions: tt.versions})
+			if tt.want == 0 {
+				var protoErr *Error
+				if !errors.As(err, &protoErr) || protoErr.Code != ErrCodeUnsupportedVersion {
+					t.Fatalf("version %d error %v, want %s", got, err, ErrCodeUnsupportedVersion)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("version %d error %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -7,16 +7,28 @@ type MessageType string
 
 const (
 	// Client -> Server
+	MsgTypeHello MessageType = "hello"
 	MsgTypeJoin  MessageType = "join"
 	MsgTypeMove  MessageType = "move"
 	MsgTypeSplit MessageType = "split"
 	MsgTypeEject MessageType = "eject"
+	MsgTypeAck           MessageType = "ack"
+	MsgTypeResume        MessageType = "resume"
+	MsgTypeRespawn       MessageType = "respawn"
+	MsgTypeReplayControl MessageType = "replay_control"
+	MsgTypeSpectate      MessageType = "spectate"
 
 	// Server -> Client
+	MsgTypeWelcome     MessageType = "welcome"
+	MsgTypeError       MessageType = "error"
 	MsgTypeInit        MessageType = "init"
 	MsgTypeState       MessageType = "state"
 	MsgTypePlayerDied  MessageType = "player_died"
+	MsgTypeDeath       MessageType = "death"
 	MsgTypeLeaderboard MessageType = "leaderboard"
+	MsgTypeReplayState MessageType = "replay_state"
+	MsgTypeSpectateState MessageType = "spectate_state"
+	MsgTypeMatchState    MessageType = "match_state"
 )
 
 // ClientMessage - базовая структура сообщения от клиента
@@ -33,20 +45,115 @@ type ServerMessage struct {
 
 // === Client -> Server ===
 
+// HelloData - согласование версии протокола (необязательно, по умолчанию MinVersion)
+type HelloData struct {
+	Versions []int `json:"versions"`
+}
+
 type JoinData struct {
 	Name string `json:"name"`
+	Room string `json:"room,omitempty"` // Комната; пусто - подбор сервером
+}
+
+// InputHeader - порядковый номер и время ввода клиента (для reconciliation)
+// Seq 0 - клиент без нумерации, ввод применяется без подтверждения
+type InputHeader struct {
+	Seq       uint32 `json:"seq,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"` // Время клиента, возвращается в ack
 }
 
 type MoveData struct {
+	InputHeader
 	X float64 `json:"x"`
 	Y float64 `json:"y"`
 }
 
+// ActionData - split/eject (data необязательно)
+type ActionData struct {
+	InputHeader
+}
+
+// ResumeData - вернуться к своему игроку после переподключения
+type ResumeData struct {
+	Token string `json:"token"` // reconnectToken из init
+}
+
+// RespawnData - снова в игру после гибели (data необязательно)
+type RespawnData struct {
+	Name string `json:"name,omitempty"` // Пусто - прежнее имя
+}
+
+// ReplayAction - управление воспроизведением реплея
+type ReplayAction string
+
+const (
+	ReplayPlay   ReplayAction = "play"
+	ReplayPause  ReplayAction = "pause"
+	ReplaySeek   ReplayAction = "seek"   // Tick
+	ReplaySpeed  ReplayAction = "speed"  // Speed
+	ReplayFollow ReplayAction = "follow" // PlayerID; пусто - лидер
+)
+
+// ReplayControlData - команда зрителя реплея
+type ReplayControlData struct {
+	Action   ReplayAction `json:"action"`
+	Tick     int64        `json:"tick,omitempty"`
+	Speed    float64      `json:"speed,omitempty"`
+	PlayerID string       `json:"playerId,omitempty"`
+}
+
+// SpectateMode - камера зрителя
+type SpectateMode string
+
+const (
+	SpectateLeader SpectateMode = "leader" // Лидер комнаты
+	SpectatePlayer SpectateMode = "player" // PlayerID; выбывший игрок сменяется лидером
+	SpectateFree   SpectateMode = "free"   // Центр камеры X, Y
+)
+
+// SpectateData - смотреть комнату без игрока (не занимает место игрока)
+// Повторное сообщение в той же комнате только переводит камеру
+type SpectateData struct {
+	Room     string       `json:"room,omitempty"` // Пусто - текущая комната или комната по умолчанию
+	Mode     SpectateMode `json:"mode,omitempty"` // Пусто - leader
+	PlayerID string       `json:"playerId,omitempty"`
+	X        float64      `json:"x,omitempty"`
+	Y        float64      `json:"y,omitempty"`
+}
+
+// AckData - клиент получил state_delta или world_snapshot этого тика
+type AckData struct {
+	Tick int64 `json:"tick"`
+}
+
 // === Server -> Client ===
 
+// WelcomeData - выбранная сервером версия протокола
+type WelcomeData struct {
+	Version           int   `json:"version"`
+	SupportedVersions []int `json:"supportedVersions"`
+}
+
+// ErrorData - ответ на некорректное сообщение клиента
+type ErrorData struct {
+	Code    ErrorCode   `json:"code"`
+	Message string      `json:"message"`
+	Type    MessageType `json:"type,omitempty"` // Тип сообщения, вызвавшего ошибку
+}
+
 type InitData struct {
-	PlayerID  string    `json:"playerId"`
-	WorldSize WorldSize `json:"worldSize"`
+	PlayerID  string      `json:"playerId"`
+	Room      string      `json:"room"`
+	WorldSize WorldSize   `json:"worldSize"`
+	Config    interface{} `json:"config"` // Правила игры комнаты
+
+	ReconnectToken string `json:"reconnectToken,omitempty"` // Для resume после обрыва соединения
+	Resumed        bool   `json:"resumed,omitempty"`        // Клиент вернулся к существующему игроку
+	Replay         bool   `json:"replay,omitempty"`         // Комната воспроизводит реплей: клиент - зритель без игрока
+	Spectator      bool   `json:"spectator,omitempty"`      // Клиент смотрит без игрока (spectate или реплей)
+
+	Match *MatchStateData `json:"match,omitempty"` // Матч королевской битвы; nil - бесконечная игра
+	Map   interface{}     `json:"map,omitempty"`   // Карта комнаты (стены, зоны, точки появления); нет - пустой мир
 }
 
 type WorldSize struct {
@@ -88,11 +195,79 @@ type PlayerDiedData struct {
 	KillerID string `json:"killerId,omitempty"`
 }
 
+// DeathData - итоги жизни погибшему игроку; дальше клиент может отправить respawn
+type DeathData struct {
+	PlayerID   string  `json:"playerId"`
+	Cause      string  `json:"cause,omitempty"` // eaten, zone
+	KillerID   string  `json:"killerId,omitempty"`
+	KillerName string  `json:"killerName,omitempty"`
+	FinalMass  float64 `json:"finalMass"`
+	TimeAlive  float64 `json:"timeAlive"` // Секунды
+	Kills      int     `json:"kills"`
+}
+
+// LeaderboardData - таблица лидеров; отправляется только при изменениях
 type LeaderboardData struct {
-	Leaders []LeaderEntry `json:"leaders"`
+	Leaders []LeaderEntry `json:"leaders"` // null - топ не изменился с прошлого сообщения
+	Own     *LeaderEntry  `json:"own"`     // Место клиента (null - не в игре)
+	Total   int           `json:"total"`   // Игроков в комнате
+	Teams   []TeamEntry   `json:"teams,omitempty"` // Командный режим: команды по убыванию массы
 }
 
 type LeaderEntry struct {
-	Name  string `json:"name"`
-	Score int    `json:"score"`
+	Rank     int    `json:"rank"` // С 1
+	PlayerID string `json:"playerId"`
+	Name     string `json:"name"`
+	Score    int    `json:"score"`
+	IsBot    bool   `json:"isBot,omitempty"`
+	Team     int    `json:"team,omitempty"` // Командный режим: номер команды с 1
+}
+
+// TeamEntry - итог команды в командном режиме
+type TeamEntry struct {
+	Team    int    `json:"team"`
+	Color   string `json:"color"`
+	Mass    int    `json:"mass"`    // Суммарная масса живых игроков
+	Players int    `json:"players"`
+}
+
+// ReplayStateData - состояние воспроизведения реплея
+// Отправляется зрителям раз в секунду и после каждой команды
+type ReplayStateData struct {
+	Tick      int64   `json:"tick"`
+	StartTick int64   `json:"startTick"`
+	EndTick   int64   `json:"endTick"`
+	TickRate  int     `json:"tickRate"`
+	Paused    bool    `json:"paused"`
+	Speed     float64 `json:"speed"`
+	Following string  `json:"following,omitempty"` // Игрок под камерой; пусто - лидер
+	Error     string  `json:"error,omitempty"`     // Воспроизведение разошлось с записью
+}
+
+// SpectateStateData - камера зрителя
+// Отправляется после spectate и при смене игрока под камерой
+type SpectateStateData struct {
+	Mode      SpectateMode `json:"mode"`
+	Following string       `json:"following,omitempty"` // Игрок под камерой; пусто - свободная камера или игроков нет
+	X         float64      `json:"x,omitempty"`         // Центр свободной камеры
+	Y         float64      `json:"y,omitempty"`
+}
+
+// MatchStateData - матч королевской битвы
+// Отправляется раз в секунду и сразу при смене этапа; между сообщениями
+// клиент сам сужает зону от ZoneRadius до ZoneFinal за ShrinkLeft секунд
+type MatchStateData struct {
+	Match      int     `json:"match"`
+	Phase      string  `json:"phase"`              // lobby, countdown, running, ended
+	TimeLeft   float64 `json:"timeLeft,omitempty"` // Секунды до старта (countdown) или до нового лобби (ended)
+	Alive      int     `json:"alive"`
+	MinPlayers int     `json:"minPlayers"` // Живых людей для начала отсчёта
+	ZoneX      float64 `json:"zoneX"`
+	ZoneY      float64 `json:"zoneY"`
+	ZoneRadius float64 `json:"zoneRadius"`
+	ZoneFinal  float64 `json:"zoneFinal"`
+	ShrinkLeft float64 `json:"shrinkLeft,omitempty"` // Секунды до конца сужения
+	WinnerID   string  `json:"winnerId,omitempty"`
+	WinnerName string  `json:"winnerName,omitempty"`
+	WinnerTeam int     `json:"winnerTeam,omitempty"`
 }